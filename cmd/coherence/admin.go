@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/admin"
+	"github.com/SimonRichardson/coherence/pkg/certs"
+	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
+	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	"github.com/SimonRichardson/flagset"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAdminTimeout      = time.Second * 10
+	defaultAdminPollInterval = time.Second
+)
+
+func runAdmin(args []string) error {
+	if len(args) < 1 {
+		adminUsage()
+		return errors.New("missing admin command")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "decommission":
+		return runDecommission(args[1:])
+	case "keyring":
+		return runKeyring(args[1:])
+	case "keygen":
+		return runKeygen(args[1:])
+	default:
+		adminUsage()
+		return errors.Errorf("invalid admin command (%s)", args[0])
+	}
+}
+
+func adminUsage() {
+	fmt.Fprintf(os.Stderr, "USAGE\n")
+	fmt.Fprintf(os.Stderr, "  %s admin <command> [flags]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "COMMANDS\n")
+	fmt.Fprintf(os.Stderr, "  decommission <node>          Drain a node and remove it from the cluster\n")
+	fmt.Fprintf(os.Stderr, "  keyring <node> <op> [key]    List, install, use or remove a gossip key across the cluster\n")
+	fmt.Fprintf(os.Stderr, "  keygen                       Generate a new gossip key\n")
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+func runDecommission(args []string) error {
+	var (
+		flags = flagset.NewFlagSet("decommission", flag.ExitOnError)
+
+		timeout = flags.Duration("timeout", defaultAdminTimeout, "timeout for each request to the node")
+		wait    = flags.Bool("wait", false, "wait for the node to drain and leave the cluster")
+		access  = newAdminFlags(flags)
+	)
+
+	flags.Usage = usageFor(flags, "admin decommission [flags] <node>")
+	if err := flags.Parse(args); err != nil {
+		return nil
+	}
+
+	if flags.NArg() != 1 {
+		return errorFor(flags, "admin decommission [flags] <node>", errors.New("expected the API address of the node"))
+	}
+
+	client, err := access.client(*timeout)
+	if err != nil {
+		return err
+	}
+	url := adminURL(client.scheme, flags.Arg(0), admin.APIPathDecommission)
+
+	status, err := decommissionRequest(client, "POST", url)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%s: %s\n", flags.Arg(0), status.State)
+
+	for *wait {
+		switch status.State {
+		case hashring.DecommissionLeft:
+			return nil
+		case hashring.DecommissionFailed:
+			return errors.Errorf("decommission failed: %s", status.Error)
+		}
+
+		time.Sleep(defaultAdminPollInterval)
+
+		next, err := decommissionRequest(client, "GET", url)
+		if err != nil {
+			return err
+		}
+		if next.State != status.State {
+			fmt.Fprintf(os.Stdout, "%s: %s\n", flags.Arg(0), next.State)
+		}
+		status = next
+	}
+
+	return nil
+}
+
+func decommissionRequest(client *adminClient, method, url string) (status hashring.DecommissionStatus, err error) {
+	err = client.request(method, url, nil, &status)
+	return
+}
+
+// keyringOps maps each keyring operation to the path of the admin API.
+var keyringOps = map[string]string{
+	"list":    admin.APIPathKeyring,
+	"install": admin.APIPathKeyringInstall,
+	"use":     admin.APIPathKeyringUse,
+	"remove":  admin.APIPathKeyringRemove,
+}
+
+func runKeyring(args []string) error {
+	var (
+		flags = flagset.NewFlagSet("keyring", flag.ExitOnError)
+
+		timeout = flags.Duration("timeout", defaultAdminTimeout, "timeout for the request to the node")
+		access  = newAdminFlags(flags)
+	)
+
+	const usage = "admin keyring [flags] <node> <list|install|use|remove> [key]"
+	flags.Usage = usageFor(flags, usage)
+	if err := flags.Parse(args); err != nil {
+		return nil
+	}
+
+	if flags.NArg() < 2 {
+		return errorFor(flags, usage, errors.New("expected the API address of the node and an operation"))
+	}
+
+	op := strings.ToLower(flags.Arg(1))
+	path, ok := keyringOps[op]
+	if !ok {
+		return errorFor(flags, usage, errors.Errorf("invalid keyring operation (%s)", flags.Arg(1)))
+	}
+
+	client, err := access.client(*timeout)
+	if err != nil {
+		return err
+	}
+
+	// The key is sent in the body, so that it doesn't end up in any logs.
+	var (
+		method, u = "GET", adminURL(client.scheme, flags.Arg(0), path)
+		body      interface{}
+	)
+	if op != "list" {
+		if flags.NArg() != 3 {
+			return errorFor(flags, usage, errors.Errorf("expected a key to %s", op))
+		}
+		method, body = "POST", admin.KeyringRequest{Key: flags.Arg(2)}
+	}
+
+	var status members.KeyringStatus
+	if err := client.request(method, u, body, &status); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(status.Keys))
+	for k := range status.Keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(os.Stdout, "%s [%d/%d]\n", k, status.Keys[k], status.NumNodes)
+	}
+	fmt.Fprintf(os.Stdout, "%s: %d/%d nodes responded\n", op, status.NumResp, status.NumNodes)
+	return nil
+}
+
+func runKeygen(args []string) error {
+	// 32 bytes selects AES-256 for the gossip encryption.
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return errors.Wrap(err, "generate key")
+	}
+	fmt.Fprintln(os.Stdout, base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// adminFlags are the flags that every admin command uses to reach the admin
+// API of a node, once it's served over TLS or behind auth.
+type adminFlags struct {
+	ca, cert, key, token *string
+}
+
+func newAdminFlags(flags *flagset.FlagSet) adminFlags {
+	return adminFlags{
+		ca:    flags.String("tls.ca", "", "optional, CA file to verify the certificate of the node with, turns on TLS"),
+		cert:  flags.String("tls.cert", "", "optional, client certificate file to present to the node, turns on TLS"),
+		key:   flags.String("tls.key", "", "optional, key file of the client certificate"),
+		token: flags.String("token", "", "optional, bearer token to authenticate with"),
+	}
+}
+
+// client creates the adminClient from the flags, when any of the TLS flags are
+// given the node is reached over HTTPS, with the same TLS config that the
+// nodes use to talk to each other.
+func (f adminFlags) client(timeout time.Duration) (*adminClient, error) {
+	client := &adminClient{
+		client: &http.Client{Timeout: timeout},
+		scheme: "http",
+		token:  *f.token,
+	}
+
+	config := certs.Config{
+		CertFile: *f.cert,
+		KeyFile:  *f.key,
+		CAFile:   *f.ca,
+	}
+	if config.Enabled() || config.CAFile != "" {
+		reloader, err := certs.NewClientReloader(config)
+		if err != nil {
+			return nil, err
+		}
+		transport := cleanhttp.DefaultTransport()
+		transport.TLSClientConfig = reloader.ClientConfig()
+		client.client.Transport = transport
+		client.scheme = "https"
+	}
+	return client, nil
+}
+
+// adminClient sends the requests to the admin API of a node.
+type adminClient struct {
+	client *http.Client
+	scheme string
+	token  string
+}
+
+// request sends a request to the admin API of a node, with the body encoded
+// as JSON if there is one, and decodes the response into v.
+func (c *adminClient) request(method, url string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Description string `json:"description"`
+		}
+		if e := json.NewDecoder(resp.Body).Decode(&body); e == nil && body.Description != "" {
+			return errors.Errorf("invalid status code: %d (%s)", resp.StatusCode, body.Description)
+		}
+		return errors.Errorf("invalid status code: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// adminURL creates the URL for the admin API of a node, the node can either be
+// a host:port, which is reached with the scheme, or a URL.
+func adminURL(scheme, node, path string) string {
+	if !strings.Contains(node, "://") {
+		node = fmt.Sprintf("%s://%s", scheme, node)
+	}
+	return fmt.Sprintf("%s/admin%s", strings.TrimSuffix(node, "/"), path)
+}
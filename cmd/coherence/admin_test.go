@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestAdminURL(t *testing.T) {
+	for _, testcase := range []struct {
+		scheme string
+		node   string
+		want   string
+	}{
+		{"http", "foo:8080", "http://foo:8080/admin/decommission"},
+		{"https", "foo:8080", "https://foo:8080/admin/decommission"},
+		{"https", "http://foo:8080", "http://foo:8080/admin/decommission"},
+		{"http", "https://foo:8080/", "https://foo:8080/admin/decommission"},
+	} {
+		if expected, actual := testcase.want, adminURL(testcase.scheme, testcase.node, "/decommission"); expected != actual {
+			t.Errorf("(%q): expected: %q, actual: %q", testcase.node, expected, actual)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
@@ -8,23 +9,37 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/SimonRichardson/coherence/pkg/admin"
 	apiFarm "github.com/SimonRichardson/coherence/pkg/api/farm"
+	"github.com/SimonRichardson/coherence/pkg/api/memcache"
+	"github.com/SimonRichardson/coherence/pkg/api/resp"
+	"github.com/SimonRichardson/coherence/pkg/api/rpc"
 	apiStore "github.com/SimonRichardson/coherence/pkg/api/store"
+	"github.com/SimonRichardson/coherence/pkg/api/tcp"
 	"github.com/SimonRichardson/coherence/pkg/api/transports"
+	"github.com/SimonRichardson/coherence/pkg/auth"
+	"github.com/SimonRichardson/coherence/pkg/certs"
 	"github.com/SimonRichardson/coherence/pkg/cluster"
 	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
 	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
 	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	"github.com/SimonRichardson/coherence/pkg/keyspaces"
+	"github.com/SimonRichardson/coherence/pkg/ratelimit"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/SimonRichardson/coherence/pkg/status"
 	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/SimonRichardson/coherence/pkg/trace"
 	"github.com/SimonRichardson/flagset"
 	"github.com/SimonRichardson/gexec"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/hashicorp/go-cleanhttp"
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soheilhy/cmux"
 	"github.com/trussle/fsys"
 )
 
@@ -33,8 +48,15 @@ const (
 	defaultCacheBuckets           = 10
 	defaultCacheReplicationFactor = 2
 	defaultNodeReplicationFactor  = 3
+	defaultNodeWeight             = 1
+	defaultNodePlacement          = hashring.PlacementConsistent
+	defaultRebalanceRate          = 100
+	defaultDecommissionGrace      = time.Second * 5
 	defaultMetricsRegistration    = true
 	defaultTransportProtocol      = "http"
+	defaultChangesMaxEntries      = 1000000
+	defaultChangesMaxAge          = time.Hour * 24
+	defaultTraceRatio             = 1.0
 )
 
 func runCache(args []string) error {
@@ -45,13 +67,31 @@ func runCache(args []string) error {
 		debug                  = flags.Bool("debug", false, "debug logging")
 		debugCluster           = flags.Bool("debug.cluster", false, "debug cluster logging")
 		apiAddr                = flags.String("api", defaultAPIAddr, "listen address for query API")
+		apiTLSCert             = flags.String("api.tls.cert", "", "optional, certificate file to serve the API over TLS")
+		apiTLSKey              = flags.String("api.tls.key", "", "optional, key file of the API certificate")
+		apiTLSCA               = flags.String("api.tls.ca", "", "optional, CA file to verify the certificates of other nodes with (mutual TLS)")
+		apiAuthConfig          = flags.String("api.auth.config", "", "optional, JSON file of the tokens, keys and ACL to authenticate and authorize the API with")
+		apiRateLimitConfig     = flags.String("api.ratelimit.config", "", "optional, JSON file of the rate limits of the clients and key prefixes, and the number of writes to queue before shedding them")
 		clusterBindAddr        = flags.String("cluster", defaultClusterAddr, "listen address for cluster")
 		clusterAdvertiseAddr   = flags.String("cluster.advertise-addr", "", "optional, explicit address to advertise in cluster")
+		clusterKeyring         = flags.String("cluster.keyring", "", "optional, keyring file (JSON list of base64 keys, primary first) to encrypt the gossip with")
 		cacheSize              = flags.Uint("cache.size", defaultCacheSize, "number items the cache should hold")
 		cacheBuckets           = flags.Uint("cache.buckets", defaultCacheBuckets, "number of buckets to use with the cache")
+		cacheKeyspaces         = flags.String("cache.keyspaces", "", "optional, JSON file of the keyspaces, with their sizes, quotas and default quorums")
 		cacheReplicationFactor = flags.Int("cache.replication.factor", defaultCacheReplicationFactor, "replication factor for remote configuration")
 		nodeReplicationFactor  = flags.Int("node.replication.factor", defaultNodeReplicationFactor, "replication factor for node configuration")
-		transportProtocol      = flags.String("transport.protocol", defaultTransportProtocol, "protocol used to talk to remote nodes (http)")
+		nodeWeight             = flags.Int("node.weight", defaultNodeWeight, "weight of the node on the hash ring, relative to other nodes")
+		nodePlacement          = flags.String("node.placement", defaultNodePlacement, "placement algorithm for keys on the hash ring (consistent, rendezvous, bounded)")
+		rebalanceRate          = flags.Int("rebalance.rate", defaultRebalanceRate, "maximum number of keys a second to stream to new owners when the cluster changes")
+		decommissionGrace      = flags.Duration("decommission.grace", defaultDecommissionGrace, "time to wait for writes to stop after marking the node as leaving, before draining")
+		transportProtocol      = flags.String("transport.protocol", defaultTransportProtocol, "protocol used to talk to remote nodes (http, https, grpc, tcp)")
+		respAddr               = flags.String("resp.addr", "", "optional, listen address for the redis protocol API (not allowed with -api.auth.config)")
+		memcacheAddr           = flags.String("memcache.addr", "", "optional, listen address for the memcached protocol API (not allowed with -api.auth.config)")
+		changesDir             = flags.String("store.changes.dir", "", "optional, directory to keep a durable log of every change applied to the store")
+		changesMaxEntries      = flags.Int("store.changes.max-entries", defaultChangesMaxEntries, "number of changes to retain with in the change log, 0 retains everything")
+		changesMaxAge          = flags.Duration("store.changes.max-age", defaultChangesMaxAge, "how long to retain changes with in the change log, 0 retains everything")
+		traceEndpoint          = flags.String("trace.otlp.endpoint", "", "optional, URL of the OTLP/HTTP collector to export the traces of the API to (e.g. http://localhost:4318)")
+		traceRatio             = flags.Float64("trace.ratio", defaultTraceRatio, "ratio of the traces to sample, between 0 and 1")
 		metricsRegistration    = flags.Bool("metrics.registration", defaultMetricsRegistration, "Registration of metrics on launch")
 		clusterPeers           = stringslice{}
 	)
@@ -86,11 +126,101 @@ func runCache(args []string) error {
 		Help:      "API request duration in seconds.",
 		Buckets:   prometheus.DefBuckets,
 	}, []string{"method", "path", "status_code"})
+	replicaDivergence := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "replica_divergence_total",
+		Help:      "Number of reads where the replicas returned different results, by method.",
+	}, []string{"method"})
+
+	keyspaceRequests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "keyspace_requests_total",
+		Help:      "Number of cache API requests, by keyspace and status code.",
+	}, []string{"keyspace", "status_code"})
+	keyspaceBytes := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "coherence",
+		Name:      "keyspace_bytes",
+		Help:      "Number of bytes of the values held by the store, by keyspace.",
+	}, []string{"keyspace"})
+	keyspaceRejected := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "keyspace_quota_rejected_total",
+		Help:      "Number of members rejected for going over the quota of the keyspace, by keyspace.",
+	}, []string{"keyspace"})
+
+	apiRejected := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "api_rejected_total",
+		Help:      "Number of API requests rejected by the rate limits or shed, by reason.",
+	}, []string{"reason"})
+
+	quorumOutcomes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "quorum_outcomes_total",
+		Help:      "Number of reads and writes by method and the outcome of their quorum (met, partial, failed).",
+	}, []string{"method", "outcome"})
+	replicaDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "coherence",
+		Name:      "replica_request_duration_seconds",
+		Help:      "Duration of the requests to each replica in seconds, by host and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"host", "method"})
+	replicaErrors := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "replica_errors_total",
+		Help:      "Number of requests to each replica that failed, by host and method.",
+	}, []string{"host", "method"})
+	repairs := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "repairs_total",
+		Help:      "Number of members found by read repairs, by outcome (repaired, skipped, failed).",
+	}, []string{"outcome"})
+
+	bucketMembers := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "coherence",
+		Name:      "bucket_members",
+		Help:      "Number of members held by each bucket of the store, by keyspace and bucket.",
+	}, []string{"keyspace", "bucket"})
+	evictions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "evictions_total",
+		Help:      "Number of members evicted from the buckets of the store, by keyspace and reason (popped is a full bucket).",
+	}, []string{"keyspace", "reason"})
+
+	bloomFill := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "coherence",
+		Name:      "bloom_fill_ratio",
+		Help:      "Ratio of the bits of the bloom of each host that are set, by host.",
+	}, []string{"host"})
+	ringChanges := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "coherence",
+		Name:      "ring_membership_changes_total",
+		Help:      "Number of hosts that joined, left or changed their weight on the hash ring, by change.",
+	}, []string{"change"})
+	circuitOpen := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "coherence",
+		Name:      "circuit_open",
+		Help:      "State of the circuit breakers, 1 whilst open, by circuit (farm or the host of a node).",
+	}, []string{"circuit"})
 
 	if *metricsRegistration {
 		prometheus.MustRegister(
 			connectedClients,
 			apiDuration,
+			replicaDivergence,
+			keyspaceRequests,
+			keyspaceBytes,
+			keyspaceRejected,
+			apiRejected,
+			quorumOutcomes,
+			replicaDuration,
+			replicaErrors,
+			repairs,
+			bucketMembers,
+			evictions,
+			bloomFill,
+			ringChanges,
+			circuitOpen,
 		)
 	}
 
@@ -104,30 +234,196 @@ func runCache(args []string) error {
 	}
 	level.Debug(logger).Log("API", fmt.Sprintf("%s://%s", apiNetwork, apiAddress))
 
+	// When there is a certificate, every API is served over TLS and the same
+	// certificate is presented to the other nodes. A CA turns on mutual TLS,
+	// where the gRPC and TCP APIs, which are only used by other nodes, require
+	// a verified client certificate.
+	var (
+		reloader         *certs.Reloader
+		transportOptions = []transports.Option{
+			transports.WithCircuitStates(circuitOpen),
+		}
+	)
+	if tlsConfig := (certs.Config{
+		CertFile: *apiTLSCert,
+		KeyFile:  *apiTLSKey,
+		CAFile:   *apiTLSCA,
+	}); tlsConfig.Enabled() {
+		if reloader, err = certs.NewReloader(tlsConfig); err != nil {
+			return err
+		}
+		apiListener = tls.NewListener(apiListener, reloader.ServerConfig())
+		transportOptions = append(transportOptions, transports.WithTLS(reloader.ClientConfig()))
+	}
+
+	// With an auth config, the cache API is checked against the ACL and the
+	// store API is only for the other nodes, which either present a client
+	// certificate or sign their requests with the peer key. The admin API is
+	// for the other nodes and the admin tokens.
+	var authConfig *auth.Config
+	if *apiAuthConfig != "" {
+		mutual := reloader != nil && reloader.Mutual()
+		config, err := auth.Load(*apiAuthConfig, mutual)
+		if err != nil {
+			return err
+		}
+		if config.Signer != nil {
+			transportOptions = append(transportOptions, transports.WithSigner(config.Signer))
+		} else if !mutual && (*transportProtocol == "http" || *transportProtocol == "https") {
+			return errors.New("expected a peer key or mutual TLS, so that the nodes can authenticate each other")
+		}
+		// Only the HTTP requests are signed with the peer key, the gRPC and
+		// TCP connections are only authenticated by their certificate.
+		if !mutual && (*transportProtocol == "grpc" || *transportProtocol == "tcp") {
+			return errors.Errorf("expected mutual TLS, so that the nodes can authenticate each other over %s", *transportProtocol)
+		}
+		authConfig = &config
+	}
+
+	// The HTTP, gRPC and TCP APIs are served from the same listener, the gRPC
+	// requests are matched on their content-type and the TCP connections on
+	// their magic prefix. With an auth config, the gRPC and TCP APIs are only
+	// served with mutual TLS, as otherwise anyone could write to the store
+	// through them.
+	var (
+		apiMux                    = cmux.New(apiListener)
+		tcpListener, grpcListener net.Listener
+	)
+	if mutual := reloader != nil && reloader.Mutual(); mutual || authConfig == nil {
+		tcpListener = apiMux.Match(cmux.PrefixMatcher(tcp.Magic))
+		grpcListener = apiMux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+		if mutual {
+			tcpListener = certs.NewPeerListener(tcpListener)
+			grpcListener = certs.NewPeerListener(grpcListener)
+		}
+	}
+	httpListener := apiMux.Match(cmux.Any())
+
+	var rateLimitConfig ratelimit.Config
+	if *apiRateLimitConfig != "" {
+		if rateLimitConfig, err = ratelimit.Load(*apiRateLimitConfig); err != nil {
+			return err
+		}
+	}
+
+	// The RESP and memcached APIs have no way to authenticate their clients,
+	// so they'd bypass the tokens and the ACL of the cache API.
+	if authConfig != nil && (*respAddr != "" || *memcacheAddr != "") {
+		return errors.New("expected the RESP and memcached APIs to be disabled, as they can't be authenticated with an auth config")
+	}
+
+	var respListener net.Listener
+	if *respAddr != "" {
+		respNetwork, respAddress, err := parseAddr(*respAddr, defaultRESPPort)
+		if err != nil {
+			return err
+		}
+		if respListener, err = net.Listen(respNetwork, respAddress); err != nil {
+			return err
+		}
+		level.Debug(logger).Log("RESP", fmt.Sprintf("%s://%s", respNetwork, respAddress))
+	}
+
+	var memcacheListener net.Listener
+	if *memcacheAddr != "" {
+		memcacheNetwork, memcacheAddress, err := parseAddr(*memcacheAddr, defaultMemcachePort)
+		if err != nil {
+			return err
+		}
+		if memcacheListener, err = net.Listen(memcacheNetwork, memcacheAddress); err != nil {
+			return err
+		}
+		level.Debug(logger).Log("Memcache", fmt.Sprintf("%s://%s", memcacheNetwork, memcacheAddress))
+	}
+
 	clusterAPIAddress, clusterAPIPort, err := parseClusterAddr(*apiAddr, defaultAPIPort)
 	if err != nil {
 		return err
 	}
 
-	peer, err := configureRemoteCache(*debugCluster,
+	peer, keyring, err := configureRemoteCache(*debugCluster,
 		logger,
 		*cacheReplicationFactor,
+		*nodeWeight,
 		clusterAPIAddress, clusterAPIPort,
 		*clusterBindAddr,
 		*clusterAdvertiseAddr,
+		*clusterKeyring,
 		clusterPeers.Slice(),
 	)
 	if err != nil {
 		return err
 	}
 
-	transport, err := transports.Parse(*transportProtocol)
+	transport, err := transports.Parse(*transportProtocol, transportOptions...)
+	if err != nil {
+		return err
+	}
+
+	placement, err := hashring.ParsePlacement(*nodePlacement, *nodeReplicationFactor)
 	if err != nil {
 		return err
 	}
 
+	var (
+		storeOptions = []store.Option{
+			store.WithKeyspaceMetrics(keyspaceBytes, keyspaceRejected),
+			store.WithBucketMetrics(bucketMembers, evictions),
+		}
+		keyspaceConfigs = make(map[selectors.Keyspace]apiFarm.Keyspace)
+	)
+	if *cacheKeyspaces != "" {
+		configs, err := keyspaces.Load(*cacheKeyspaces)
+		if err != nil {
+			return err
+		}
+		for _, v := range configs {
+			config := store.KeyspaceConfig{
+				Buckets: v.Buckets,
+				Size:    v.Size,
+				Bytes:   v.Bytes,
+			}
+			if config.Buckets == 0 {
+				config.Buckets = *cacheBuckets
+			}
+			if config.Size == 0 {
+				config.Size = *cacheSize
+			}
+			storeOptions = append(storeOptions, store.WithKeyspace(v.Name, config))
+			keyspaceConfigs[v.Name] = apiFarm.Keyspace{
+				Quorum:      v.Quorum,
+				Replication: v.Replication,
+			}
+		}
+	}
+	if *changesDir != "" {
+		changes, err := store.NewChangeLog(store.ChangeLogConfig{
+			Dir:        *changesDir,
+			MaxEntries: *changesMaxEntries,
+			MaxAge:     *changesMaxAge,
+		}, log.With(logger, "component", "changes"))
+		if err != nil {
+			return err
+		}
+		defer changes.Close()
+
+		storeOptions = append(storeOptions, store.WithChangeLog(changes))
+	}
+
+	// The tracer is nil, tracing nothing, unless there's a collector to
+	// export the spans to.
+	var tracer *trace.Tracer
+	if *traceEndpoint != "" {
+		tracer = trace.NewTracer(
+			trace.NewOTLPExporter(cleanhttp.DefaultPooledClient(), *traceEndpoint, "coherence"),
+			log.With(logger, "component", "trace"),
+			trace.WithRatio(*traceRatio),
+		)
+		defer tracer.Close()
+	}
+
 	fsys := fsys.NewNopFilesystem()
-	persistence, err := store.New(fsys, *cacheBuckets, *cacheSize, log.With(logger, "component", "store"))
+	persistence, err := store.New(fsys, *cacheBuckets, *cacheSize, log.With(logger, "component", "store"), storeOptions...)
 	if err != nil {
 		return err
 	}
@@ -135,11 +431,31 @@ func runCache(args []string) error {
 	var (
 		cluster = hashring.NewCluster(peer,
 			transport,
-			*nodeReplicationFactor,
+			placement,
+			persistence,
 			apiAddress,
 			log.With(logger, "component", "cluster"),
+			hashring.WithMembershipChanges(ringChanges),
+			hashring.WithBloomFill(bloomFill),
+		)
+		rebalancer = hashring.NewRebalancer(cluster,
+			*rebalanceRate,
+			log.With(logger, "component", "rebalancer"),
+		)
+		decommissioner = hashring.NewDecommissioner(peer,
+			cluster,
+			rebalancer,
+			*decommissionGrace,
+			log.With(logger, "component", "decommissioner"),
+		)
+		supervisor = farm.NewReal(cluster,
+			farm.WithDivergence(replicaDivergence),
+			farm.WithTracer(tracer),
+			farm.WithQuorumOutcomes(quorumOutcomes),
+			farm.WithReplicaMetrics(replicaDuration, replicaErrors),
+			farm.WithRepairs(repairs),
+			farm.WithCircuitState(circuitOpen.WithLabelValues("farm")),
 		)
-		supervisor = farm.NewReal(cluster)
 	)
 
 	// Execution group.
@@ -173,11 +489,21 @@ func runCache(args []string) error {
 	}
 	{
 		g.Add(func() error {
+			return rebalancer.Run()
+		}, func(error) {
+			rebalancer.Stop()
+		})
+	}
+	{
+		g.Add(func() error {
+			shed := apiRejected.WithLabelValues(ratelimit.ReasonShed)
+
 			storeAPI := apiStore.NewAPI(
 				persistence,
 				log.With(logger, "component", "store_api"),
 				connectedClients.WithLabelValues("api"),
 				apiDuration,
+				apiStore.WithShedder(ratelimit.NewShedder(rateLimitConfig.MaxPendingWrites), shed),
 			)
 			defer storeAPI.Close()
 
@@ -186,27 +512,134 @@ func runCache(args []string) error {
 				log.With(logger, "component", "farm_api"),
 				connectedClients.WithLabelValues("api"),
 				apiDuration,
+				apiFarm.WithKeyspaces(keyspaceConfigs),
+				apiFarm.WithKeyspaceMetrics(keyspaceRequests),
+				apiFarm.WithShedder(ratelimit.NewShedder(rateLimitConfig.MaxPendingWrites), shed),
 			)
 			defer farmAPI.Close()
 
+			// The clients are identified by their principal, when they're
+			// authenticated, otherwise by their host.
+			var storeHandler, farmHandler http.Handler = storeAPI, ratelimit.NewHandler(
+				farmAPI,
+				rateLimitConfig.Client,
+				rateLimitConfig.Prefixes,
+				func(r *http.Request) string {
+					if principal, ok := auth.FromContext(r.Context()); ok {
+						return principal.Name
+					}
+					return ratelimit.RemoteHost(r)
+				},
+				apiRejected,
+				log.With(logger, "component", "ratelimit"),
+			)
+			if authConfig != nil {
+				authLogger := log.With(logger, "component", "auth")
+				storeHandler = auth.NewPeerHandler(storeHandler, authConfig.Authenticator, authLogger)
+				farmHandler = auth.NewHandler(farmHandler, authConfig.Authenticator, authConfig.ACL, authLogger)
+			}
+
 			mux := http.NewServeMux()
-			mux.Handle("/store/", http.StripPrefix("/store", storeAPI))
-			mux.Handle("/cache/", http.StripPrefix("/cache", farmAPI))
+			mux.Handle("/store/", trace.Handler(tracer, http.StripPrefix("/store", storeHandler)))
+			mux.Handle("/cache/", trace.Handler(tracer, http.StripPrefix("/cache", farmHandler)))
 			mux.Handle("/status/", http.StripPrefix("/status", status.NewAPI(
 				supervisor,
+				cluster,
+				rebalancer,
 				log.With(logger, "component", "status_api"),
 				connectedClients.WithLabelValues("status"),
 				apiDuration,
+				status.WithPeer(peer),
+				status.WithStore(persistence),
 			)))
 
+			registerAdmin(mux, admin.NewAPI(
+				decommissioner,
+				keyring,
+				log.With(logger, "component", "admin_api"),
+				connectedClients.WithLabelValues("admin"),
+				apiDuration,
+			), authConfig, logger)
+
 			registerMetrics(mux)
 			registerProfile(mux)
 
-			return http.Serve(apiListener, mux)
+			server := &http.Server{
+				Handler:     mux,
+				ConnContext: auth.ConnContext,
+			}
+			return server.Serve(httpListener)
+		}, func(error) {
+			httpListener.Close()
+		})
+	}
+	if grpcListener != nil {
+		storeServer := rpc.NewServer(
+			persistence,
+			log.With(logger, "component", "store_rpc"),
+		)
+		grpcServer := rpc.Register(storeServer,
+			connectedClients.WithLabelValues("rpc"),
+			apiDuration,
+		)
+		g.Add(func() error {
+			return grpcServer.Serve(grpcListener)
+		}, func(error) {
+			grpcServer.Stop()
+			storeServer.Close()
+		})
+	}
+	if tcpListener != nil {
+		tcpServer := tcp.NewServer(
+			persistence,
+			log.With(logger, "component", "store_tcp"),
+			connectedClients.WithLabelValues("tcp"),
+			apiDuration,
+		)
+		g.Add(func() error {
+			return tcpServer.Serve(tcpListener)
+		}, func(error) {
+			tcpListener.Close()
+			tcpServer.Close()
+		})
+	}
+	{
+		g.Add(func() error {
+			return apiMux.Serve()
 		}, func(error) {
 			apiListener.Close()
 		})
 	}
+	if respListener != nil {
+		respServer := resp.NewServer(
+			supervisor,
+			selectors.Strong,
+			log.With(logger, "component", "resp_api"),
+			connectedClients.WithLabelValues("resp"),
+			apiDuration,
+		)
+		g.Add(func() error {
+			return respServer.Serve(respListener)
+		}, func(error) {
+			respListener.Close()
+			respServer.Close()
+		})
+	}
+	if memcacheListener != nil {
+		memcacheServer := memcache.NewServer(
+			supervisor,
+			selectors.Strong,
+			log.With(logger, "component", "memcache_api"),
+			connectedClients.WithLabelValues("memcache"),
+			apiDuration,
+		)
+		g.Add(func() error {
+			return memcacheServer.Serve(memcacheListener)
+		}, func(error) {
+			memcacheListener.Close()
+			memcacheServer.Close()
+		})
+	}
 	{
 		cancel := make(chan struct{})
 		g.Add(func() error {
@@ -224,6 +657,28 @@ func runCache(args []string) error {
 			close(cancel)
 		})
 	}
+	if reloader != nil {
+		cancel := make(chan struct{})
+		g.Add(func() error {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, syscall.SIGHUP)
+			defer signal.Stop(c)
+			for {
+				select {
+				case <-c:
+					if err := reloader.Reload(); err != nil {
+						level.Warn(logger).Log("component", "certs", "err", err)
+						continue
+					}
+					level.Info(logger).Log("component", "certs", "state", "reloaded")
+				case <-cancel:
+					return nil
+				}
+			}
+		}, func(error) {
+			close(cancel)
+		})
+	}
 	gexec.Interrupt(g)
 	return g.Run()
 }
@@ -243,13 +698,15 @@ func (e EventHandler) HandleEvent(event members.Event) error {
 func configureRemoteCache(debugCluster bool,
 	logger log.Logger,
 	replicationFactor int,
+	weight int,
 	apiAddr string, apiPort int,
 	bindAddr, advertiseAddr string,
+	keyringFile string,
 	peers []string,
-) (cluster.Peer, error) {
+) (cluster.Peer, members.Keyring, error) {
 	clusterBindHost, clusterBindPort, err := parseClusterAddr(bindAddr, defaultClusterPort)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	level.Info(logger).Log("cluster_bind", fmt.Sprintf("%s:%d", clusterBindHost, clusterBindPort))
 
@@ -260,7 +717,7 @@ func configureRemoteCache(debugCluster bool,
 	if advertiseAddr != "" {
 		clusterAdvertiseHost, clusterAdvertisePort, err = parseClusterAddr(advertiseAddr, defaultClusterPort)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		level.Info(logger).Log("cluster_advertise", fmt.Sprintf("%s:%d", clusterAdvertiseHost, clusterAdvertisePort))
 	}
@@ -278,24 +735,26 @@ func configureRemoteCache(debugCluster bool,
 		members.WithPeerType(cluster.PeerTypeStore),
 		members.WithNodeName(uuid.New()),
 		members.WithAPIAddrPort(apiAddr, apiPort),
+		members.WithWeight(weight),
 		members.WithBindAddrPort(clusterBindHost, clusterBindPort),
 		members.WithAdvertiseAddrPort(clusterAdvertiseHost, clusterAdvertisePort),
 		members.WithExisting(peers),
+		members.WithKeyringFile(keyringFile),
 		members.WithLogOutput(membersLogOutput{
 			output: debugCluster,
 			logger: log.With(logger, "component", "cluster"),
 		}),
 	)
 	if err != nil {
-		return nil, errors.Wrap(err, "members remote config")
+		return nil, nil, errors.Wrap(err, "members remote config")
 	}
 
 	cacheMembers, err := members.NewRealMembers(cacheMembersConfig, log.With(logger, "component", "members"))
 	if err != nil {
-		return nil, errors.Wrap(err, "members remote")
+		return nil, nil, errors.Wrap(err, "members remote")
 	}
 
-	return cluster.NewPeer(cacheMembers, log.With(logger, "component", "peer")), nil
+	return cluster.NewPeer(cacheMembers, log.With(logger, "component", "peer")), cacheMembers.Keyring(), nil
 }
 
 type membersLogOutput struct {
@@ -28,9 +28,11 @@ const (
 var version = "dev"
 
 const (
-	defaultAPIPort     = 8080
-	defaultClusterPort = 8079
-	defaultAddr        = "0.0.0.0:0"
+	defaultAPIPort      = 8080
+	defaultClusterPort  = 8079
+	defaultRESPPort     = 6379
+	defaultMemcachePort = 11211
+	defaultAddr         = "0.0.0.0:0"
 )
 
 var (
@@ -63,6 +65,8 @@ func main() {
 	switch strings.ToLower(args[1]) {
 	case "cache":
 		cmd = runCache
+	case "admin":
+		cmd = runAdmin
 	default:
 		usage()
 		os.Exit(1)
@@ -78,6 +82,7 @@ func usage() {
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "MODES\n")
 	fmt.Fprintf(os.Stderr, "  cache       Cache service\n")
+	fmt.Fprintf(os.Stderr, "  admin       Administer a running cluster\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	fmt.Fprintf(os.Stderr, "VERSION\n")
 	fmt.Fprintf(os.Stderr, "  %s (%s)\n", version, runtime.Version())
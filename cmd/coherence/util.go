@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/SimonRichardson/coherence/pkg/auth"
+	"github.com/go-kit/kit/log"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -98,6 +100,15 @@ func isUnroutable(addr string) bool {
 	return false
 }
 
+// registerAdmin puts the admin API on the mux. When there's an auth config,
+// only the other nodes and the admin tokens can reach it.
+func registerAdmin(mux *http.ServeMux, api http.Handler, config *auth.Config, logger log.Logger) {
+	if config != nil {
+		api = auth.NewAdminHandler(api, config.Authenticator, log.With(logger, "component", "auth"))
+	}
+	mux.Handle("/admin/", http.StripPrefix("/admin", api))
+}
+
 func registerMetrics(mux *http.ServeMux) {
 	mux.Handle("/metrics", promhttp.Handler())
 }
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/auth"
+	"github.com/go-kit/kit/log"
 )
 
 func TestStringSlice(t *testing.T) {
@@ -161,3 +166,55 @@ func TestIsUnroutable(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterAdmin(t *testing.T) {
+	config, err := auth.Parse(strings.NewReader(`{
+		"tokens": [
+			{"principal": "team-a", "token": "token-a"},
+			{"principal": "ops", "token": "token-o", "admin": true}
+		]
+	}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var called bool
+	mux := http.NewServeMux()
+	registerAdmin(mux, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}), &config, log.NewNopLogger())
+
+	for _, testcase := range []struct {
+		name  string
+		url   string
+		token string
+		code  int
+	}{
+		{"decommission without credentials", "/admin/decommission", "", http.StatusUnauthorized},
+		{"keyring without credentials", "/admin/keyring/install", "", http.StatusUnauthorized},
+		{"keyring list without credentials", "/admin/keyring", "", http.StatusUnauthorized},
+		{"decommission with a token", "/admin/decommission", "token-a", http.StatusForbidden},
+		{"keyring with a token", "/admin/keyring/install", "token-a", http.StatusForbidden},
+		{"decommission with an admin token", "/admin/decommission", "token-o", http.StatusOK},
+		{"keyring with an admin token", "/admin/keyring/install", "token-o", http.StatusOK},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			called = false
+
+			r := httptest.NewRequest("POST", testcase.url, strings.NewReader(`{"key":"a"}`))
+			if testcase.token != "" {
+				r.Header.Set("Authorization", "Bearer "+testcase.token)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if expected, actual := testcase.code, w.Code; expected != actual {
+				t.Errorf("expected: %d, actual: %d", expected, actual)
+			}
+			if expected, actual := testcase.code == http.StatusOK, called; expected != actual {
+				t.Errorf("expected: %t, actual: %t", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	errs "github.com/SimonRichardson/coherence/pkg/api/http"
+	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
+	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// These are the admin API URL paths.
+const (
+	APIPathDecommission = "/decommission"
+
+	APIPathKeyring        = "/keyring"
+	APIPathKeyringInstall = "/keyring/install"
+	APIPathKeyringUse     = "/keyring/use"
+	APIPathKeyringRemove  = "/keyring/remove"
+)
+
+// maxKeyringBody is the largest body of a keyring request, it's far larger
+// than any key, so that a client can't make the node read an unbounded body.
+const maxKeyringBody = 4096
+
+// KeyringRequest is the body of the requests that install, use or remove a
+// key. The key is sent in the body, so that it never ends up in the logs of
+// the node or of a proxy along the way.
+type KeyringRequest struct {
+	Key string `json:"key"`
+}
+
+// Decommissioner gracefully removes the local node from the cluster.
+type Decommissioner interface {
+	// Decommission starts decommissioning the local node.
+	Decommission() error
+
+	// Status returns the status of the decommissioning of the local node.
+	Status() hashring.DecommissionStatus
+}
+
+// API serves the admin API
+type API struct {
+	decommissioner Decommissioner
+	keyring        members.Keyring
+	logger         log.Logger
+	clients        metrics.Gauge
+	duration       metrics.HistogramVec
+	errors         errs.Error
+}
+
+// NewAPI creates a API with the correct dependencies.
+func NewAPI(decommissioner Decommissioner,
+	keyring members.Keyring,
+	logger log.Logger,
+	clients metrics.Gauge,
+	duration metrics.HistogramVec,
+) *API {
+	return &API{
+		decommissioner: decommissioner,
+		keyring:        keyring,
+		logger:         logger,
+		clients:        clients,
+		duration:       duration,
+		errors:         errs.NewError(logger),
+	}
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Only the path is logged, nothing that's sent to the admin API is safe
+	// to write to the logs.
+	level.Info(a.logger).Log("method", r.Method, "path", r.URL.Path)
+
+	iw := &interceptingWriter{http.StatusOK, w}
+	w = iw
+
+	// Metrics
+	a.clients.Inc()
+	defer a.clients.Dec()
+
+	defer func(begin time.Time) {
+		a.duration.WithLabelValues(
+			r.Method,
+			r.URL.Path,
+			strconv.Itoa(iw.code),
+		).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	// Routing table
+	method, path := r.Method, r.URL.Path
+	switch {
+	case method == "GET" && path == APIPathDecommission:
+		a.handleDecommissionStatus(w, r)
+	case method == "POST" && path == APIPathDecommission:
+		a.handleDecommission(w, r)
+	case method == "GET" && path == APIPathKeyring:
+		a.handleListKeys(w, r)
+	case method == "POST" && path == APIPathKeyringInstall:
+		a.handleKeyring(w, r, a.keyring.InstallKey)
+	case method == "POST" && path == APIPathKeyringUse:
+		a.handleKeyring(w, r, a.keyring.UseKey)
+	case method == "POST" && path == APIPathKeyringRemove:
+		a.handleKeyring(w, r, a.keyring.RemoveKey)
+	default:
+		// Nothing found
+		a.errors.NotFound(w, r)
+	}
+}
+
+func (a *API) handleDecommissionStatus(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(a.decommissioner.Status()); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *API) handleDecommission(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if err := a.decommissioner.Decommission(); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(a.decommissioner.Status()); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *API) handleListKeys(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	status, err := a.keyring.ListKeys()
+	a.writeKeyringStatus(w, status, err)
+}
+
+// handleKeyring runs the keyring operation with the key from the body across
+// the cluster.
+func (a *API) handleKeyring(w http.ResponseWriter, r *http.Request, fn func(string) (members.KeyringStatus, error)) {
+	defer r.Body.Close()
+
+	var req KeyringRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxKeyringBody)).Decode(&req); err != nil {
+		a.errors.BadRequest(w, r, "expected a body with the 'key'")
+		return
+	}
+	if req.Key == "" {
+		a.errors.BadRequest(w, r, "missing 'key'")
+		return
+	}
+
+	status, err := fn(req.Key)
+	a.writeKeyringStatus(w, status, err)
+}
+
+func (a *API) writeKeyringStatus(w http.ResponseWriter, status members.KeyringStatus, err error) {
+	if err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type interceptingWriter struct {
+	code int
+	http.ResponseWriter
+}
+
+func (iw *interceptingWriter) WriteHeader(code int) {
+	iw.code = code
+	iw.ResponseWriter.WriteHeader(code)
+}
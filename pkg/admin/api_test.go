@@ -0,0 +1,381 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
+	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	membersMocks "github.com/SimonRichardson/coherence/pkg/cluster/members/mocks"
+	"github.com/SimonRichardson/coherence/pkg/cluster/mocks"
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/trussle/harness/matchers"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("decommission status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			peer           = mocks.NewMockPeer(ctrl)
+			decommissioner = hashring.NewDecommissioner(peer, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, members.NewNopMembers().Keyring(), log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/decommission", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/decommission", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var status hashring.DecommissionStatus
+		if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := hashring.DecommissionIdle, status.State; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("decommission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			peer     = mocks.NewMockPeer(ctrl)
+			// A long grace period prevents the drain from starting.
+			decommissioner = hashring.NewDecommissioner(peer, nil, nil, time.Hour, log.NewNopLogger())
+			api            = NewAPI(decommissioner, members.NewNopMembers().Keyring(), log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("POST", "/decommission", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		peer.EXPECT().SetStatus(members.PeerStatusLeaving).Return(nil)
+
+		response, err := http.Post(fmt.Sprintf("%s/decommission", server.URL), "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var status hashring.DecommissionStatus
+		if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := hashring.DecommissionLeaving, status.State; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("decommission failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			peer           = mocks.NewMockPeer(ctrl)
+			decommissioner = hashring.NewDecommissioner(peer, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, members.NewNopMembers().Keyring(), log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("POST", "/decommission", "409").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		peer.EXPECT().SetStatus(members.PeerStatusLeaving).Return(errors.New("bad"))
+
+		response, err := http.Post(fmt.Sprintf("%s/decommission", server.URL), "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusConflict, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("list keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			keyring        = membersMocks.NewMockKeyring(ctrl)
+			decommissioner = hashring.NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, keyring, log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/keyring", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		keyring.EXPECT().ListKeys().Return(members.KeyringStatus{
+			Keys:     map[string]int{"key": 3},
+			NumNodes: 3,
+			NumResp:  3,
+		}, nil)
+
+		response, err := http.Get(fmt.Sprintf("%s/keyring", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var status members.KeyringStatus
+		if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := 3, status.Keys["key"]; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("install key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			keyring        = membersMocks.NewMockKeyring(ctrl)
+			decommissioner = hashring.NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, keyring, log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("POST", "/keyring/install", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		key := "T9jncgl9mbLus+baTTa7q7nPSUrXwbDi2dhbtqir37s="
+		keyring.EXPECT().InstallKey(key).Return(members.KeyringStatus{NumNodes: 1, NumResp: 1}, nil)
+
+		response, err := http.Post(fmt.Sprintf("%s/keyring/install", server.URL), "application/json", keyringBody(t, key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("remove key failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			keyring        = membersMocks.NewMockKeyring(ctrl)
+			decommissioner = hashring.NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, keyring, log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("POST", "/keyring/remove", "500").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		keyring.EXPECT().RemoveKey("key").Return(members.KeyringStatus{NumErr: 1}, errors.New("bad"))
+
+		response, err := http.Post(fmt.Sprintf("%s/keyring/remove", server.URL), "application/json", keyringBody(t, "key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusInternalServerError, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("use key without key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			keyring        = membersMocks.NewMockKeyring(ctrl)
+			decommissioner = hashring.NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, keyring, log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("POST", "/keyring/use", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Post(fmt.Sprintf("%s/keyring/use", server.URL), "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			decommissioner = hashring.NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, members.NewNopMembers().Keyring(), log.NewNopLogger(), clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/bad", "404").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/bad", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusNotFound, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+	t.Run("key is never logged", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			buf            = new(bytes.Buffer)
+			logger         = log.NewLogfmtLogger(log.NewSyncWriter(buf))
+			clients        = metricMocks.NewMockGauge(ctrl)
+			duration       = metricMocks.NewMockHistogramVec(ctrl)
+			observer       = metricMocks.NewMockObserver(ctrl)
+			keyring        = membersMocks.NewMockKeyring(ctrl)
+			decommissioner = hashring.NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+			api            = NewAPI(decommissioner, keyring, logger, clients, duration)
+			server         = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(2)
+		clients.EXPECT().Dec().Times(2)
+
+		duration.EXPECT().WithLabelValues("POST", "/keyring/use", "200").Return(observer).Times(1)
+		duration.EXPECT().WithLabelValues("POST", "/keyring/use", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(2)
+
+		key := "T9jncgl9mbLus+baTTa7q7nPSUrXwbDi2dhbtqir37s="
+		keyring.EXPECT().UseKey(key).Return(members.KeyringStatus{NumNodes: 1, NumResp: 1}, nil)
+
+		response, err := http.Post(fmt.Sprintf("%s/keyring/use", server.URL), "application/json", keyringBody(t, key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+
+		// A key in the query isn't used and isn't logged either.
+		response, err = http.Post(fmt.Sprintf("%s/keyring/use?key=%s", server.URL, url.QueryEscape(key)), "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+		if strings.Contains(buf.String(), key) || strings.Contains(buf.String(), url.QueryEscape(key)) {
+			t.Errorf("expected: no key in the logs, actual: %s", buf.String())
+		}
+	})
+}
+
+func keyringBody(t *testing.T, key string) io.Reader {
+	b, err := json.Marshal(KeyringRequest{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b)
+}
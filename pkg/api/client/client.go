@@ -2,13 +2,17 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
 
+	"github.com/SimonRichardson/coherence/pkg/circuit"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
-	"github.com/SimonRichardson/resilience/breaker"
+	"github.com/SimonRichardson/coherence/pkg/trace"
 	"github.com/pkg/errors"
 )
 
@@ -19,28 +23,55 @@ const (
 
 // Client represents a http client that has a one to one relationship with a url
 type Client struct {
-	circuit        *breaker.CircuitBreaker
+	circuit        *circuit.Circuit
+	state          metrics.Gauge
 	client         *http.Client
 	protocol, host string
+	ctx            context.Context
+}
+
+// Option defines a option for configuring the Client
+type Option func(*Client)
+
+// WithCircuitState reports the state of the circuit of the Client, 1 whilst
+// it's open and refusing the requests, otherwise 0.
+func WithCircuitState(state metrics.Gauge) Option {
+	return func(c *Client) {
+		c.state = state
+	}
 }
 
 // New creates a Client with the http.Client and url
-func New(client *http.Client, protocol, host string) *Client {
-	return &Client{
-		circuit:  breaker.New(defaultFailureRate, defaultFailureTimeout),
+func New(client *http.Client, protocol, host string, opts ...Option) *Client {
+	c := &Client{
 		client:   client,
 		protocol: protocol,
 		host:     host,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.circuit = circuit.New(defaultFailureRate, defaultFailureTimeout, c.state)
+	return c
+}
+
+// WithContext returns a copy of the Client that sends the trace of the context
+// along with its requests, so that the node receiving them joins the trace.
+// The requests are cancelled once the context is done. The copy shares the
+// circuit of the Client.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	res := *c
+	res.ctx = ctx
+	return &res
 }
 
 // Get a request to the url associated.
 // If the response returns anything other than a StatusOK (200), then it
 // will return an error.
 func (c *Client) Get(u string) (b []byte, err error) {
-	err = c.circuit.Run(func() error {
+	err = c.run(func() error {
 
-		resp, err := c.client.Get(fmt.Sprintf("%s://%s%s", c.protocol, c.host, u))
+		resp, err := c.do("GET", u, nil)
 		if err != nil {
 			return err
 		}
@@ -64,9 +95,10 @@ func (c *Client) Get(u string) (b []byte, err error) {
 // If the response returns anything other than a StatusOK (200), then it
 // will return an error.
 func (c *Client) Post(u string, p []byte) (b []byte, err error) {
-	err = c.circuit.Run(func() error {
+	var quotaErr error
+	err = c.run(func() error {
 
-		resp, err := c.client.Post(fmt.Sprintf("%s://%s%s", c.protocol, c.host, u), "application/json", bytes.NewReader(p))
+		resp, err := c.do("POST", u, bytes.NewReader(p))
 		if err != nil {
 			return err
 		}
@@ -75,6 +107,12 @@ func (c *Client) Post(u string, p []byte) (b []byte, err error) {
 		if resp.StatusCode == http.StatusNotFound {
 			return selectors.NewNotFoundError(errors.Errorf("invalid status code: %d", resp.StatusCode))
 		}
+		if resp.StatusCode == http.StatusInsufficientStorage {
+			// A keyspace going over its quota isn't a failure of the node, so
+			// it's kept out of the circuit.
+			quotaErr = selectors.NewQuotaError(errors.Errorf("invalid status code: %d", resp.StatusCode))
+			return nil
+		}
 		if resp.StatusCode != http.StatusOK {
 			return errors.Errorf("invalid status code: %d", resp.StatusCode)
 		}
@@ -83,9 +121,78 @@ func (c *Client) Post(u string, p []byte) (b []byte, err error) {
 		b, requestErr = ioutil.ReadAll(resp.Body)
 		return requestErr
 	})
+	if err == nil && quotaErr != nil {
+		err = quotaErr
+	}
 	return
 }
 
+// Stream a request to the url associated, returning the body so that it can
+// be read as it arrives. The body must be closed once finished with.
+// If the response returns anything other than a StatusOK (200), then it
+// will return an error.
+func (c *Client) Stream(u string) (body io.ReadCloser, err error) {
+	err = c.run(func() error {
+
+		resp, err := c.do("GET", u, nil)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return selectors.NewNotFoundError(errors.Errorf("invalid status code: %d", resp.StatusCode))
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return errors.Errorf("invalid status code: %d", resp.StatusCode)
+		}
+
+		body = resp.Body
+		return nil
+	})
+	return
+}
+
+func (c *Client) do(method, u string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", c.protocol, c.host, u), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+		trace.Inject(c.ctx, req.Header)
+	}
+	return c.client.Do(req)
+}
+
+// run runs the request with in the circuit. A request that's cancelled by its
+// context isn't a failure of the node, so it's kept out of the circuit.
+func (c *Client) run(fn func() error) error {
+	var cancelled error
+	err := c.circuit.Run(func() error {
+		err := fn()
+		if err != nil && c.ctx != nil && c.ctx.Err() != nil {
+			cancelled = err
+			return nil
+		}
+		return err
+	})
+	if err == nil && cancelled != nil {
+		err = cancelled
+	}
+	return err
+}
+
+// CircuitOpen returns if the circuit of the Client is open, refusing the
+// requests.
+func (c *Client) CircuitOpen() bool {
+	return c.circuit.Open()
+}
+
 // Host returns the associated host
 func (c *Client) Host() string {
 	return c.host
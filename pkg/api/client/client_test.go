@@ -1,11 +1,17 @@
 package client
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 	"testing/quick"
+
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/SimonRichardson/coherence/pkg/trace"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
 )
 
 func TestClientGet(t *testing.T) {
@@ -104,6 +110,72 @@ func TestClientPost(t *testing.T) {
 	})
 }
 
+func TestClientWithContext(t *testing.T) {
+	t.Parallel()
+
+	tracer := trace.NewTracer(trace.NewInMemoryExporter(), log.NewNopLogger())
+	defer tracer.Close()
+
+	ctx, span := tracer.Start(context.Background(), trace.KindClient, "client")
+	defer span.End()
+
+	headers := make(chan string, 2)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		headers <- r.Header.Get(trace.HeaderTraceParent)
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := New(http.DefaultClient, "http", hostPort(server.URL))
+	if _, err := client.WithContext(ctx).Post("", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := trace.FormatTraceParent(span.Context()), <-headers; expected != actual {
+		t.Errorf("expected: %q, actual: %q", expected, actual)
+	}
+
+	// The Client without the context doesn't send a trace.
+	if _, err := client.Get(""); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "", <-headers; expected != actual {
+		t.Errorf("expected: %q, actual: %q", expected, actual)
+	}
+}
+
+func TestClientCircuitState(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The requests are let through until the circuit has seen enough
+	// failures, then it opens.
+	state := metricMocks.NewMockGauge(ctrl)
+	gomock.InOrder(
+		state.EXPECT().Set(float64(0)).Times(defaultFailureRate),
+		state.EXPECT().Set(float64(1)),
+	)
+
+	client := New(http.DefaultClient, "http", hostPort(server.URL), WithCircuitState(state))
+	for i := 0; i <= defaultFailureRate; i++ {
+		if _, err := client.Get(""); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
 func hostPort(path string) string {
 	u, err := url.Parse(path)
 	if err != nil {
@@ -112,3 +184,30 @@ func hostPort(path string) string {
 
 	return u.Host
 }
+
+func TestClientCancelled(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The cancelled requests fail, without counting towards opening the
+	// circuit.
+	client := New(http.DefaultClient, "http", hostPort(server.URL))
+	for i := 0; i <= defaultFailureRate; i++ {
+		if _, err := client.WithContext(ctx).Get(""); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if expected, actual := false, client.CircuitOpen(); expected != actual {
+		t.Errorf("expected: %t, actual: %t", expected, actual)
+	}
+}
@@ -1,54 +1,103 @@
 package farm
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SimonRichardson/coherence/pkg/api"
 	errs "github.com/SimonRichardson/coherence/pkg/api/http"
+	"github.com/SimonRichardson/coherence/pkg/api/sse"
+	"github.com/SimonRichardson/coherence/pkg/api/writes"
 	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
 	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/ratelimit"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
 )
 
+// These are the API URL paths, they're shared with the store API.
 const (
+	APIPathInsert      = errs.APIPathInsert
+	APIPathDelete      = errs.APIPathDelete
+	APIPathSelect      = errs.APIPathSelect
+	APIPathKeys        = errs.APIPathKeys
+	APIPathSize        = errs.APIPathSize
+	APIPathMembers     = errs.APIPathMembers
+	APIPathScore       = errs.APIPathScore
+	APIPathWatch       = errs.APIPathWatch
+	APIPathScan        = errs.APIPathScan
+	APIPathScanMembers = errs.APIPathScanMembers
+	APIPathRepair      = errs.APIPathRepair
+)
 
-	// APIPathInsert represents a way to insert a series or records.
-	APIPathInsert = "/insert"
+const (
+	defaultKeepAlive = time.Second * 15
+)
 
-	// APIPathDelete represents a way to delete a series or records.
-	APIPathDelete = "/delete"
+// API serves the cache API
+type API struct {
+	farm      farm.Farm
+	logger    log.Logger
+	clients   metrics.Gauge
+	duration  metrics.HistogramVec
+	requests  metrics.CounterVec
+	keyspaces map[selectors.Keyspace]Keyspace
+	errors    errs.Error
+	router    *errs.Router
+	shedder   *ratelimit.Shedder
+	shed      metrics.Counter
+	writes    *writes.Queue
+}
 
-	// APIPathSelect represents a way to select a record.
-	APIPathSelect = "/select"
+// Keyspace defines the quorums that the requests with in a keyspace use when
+// they don't ask for one.
+type Keyspace struct {
+	// Quorum is the quorum of the reads.
+	Quorum selectors.Quorum
 
-	// APIPathKeys represents a way to find all the keys with in the cache.
-	APIPathKeys = "/keys"
+	// Replication is the quorum of the writes, which decides how many
+	// replicas are written to.
+	Replication selectors.Quorum
+}
 
-	// APIPathSize represents a way to find the size of a key with in the cache.
-	APIPathSize = "/size"
+// Option defines a option for configuring the API
+type Option func(*API)
 
-	// APIPathMembers represents a way to find all the members for a key with in
-	// the cache.
-	APIPathMembers = "/members"
-)
+// WithKeyspaces serves the keyspaces, along with the default keyspace. The
+// requests for any other keyspace aren't found.
+func WithKeyspaces(keyspaces map[selectors.Keyspace]Keyspace) Option {
+	return func(a *API) {
+		for k, v := range keyspaces {
+			a.keyspaces[k] = v
+		}
+	}
+}
 
-// API serves the cache API
-type API struct {
-	farm     farm.Farm
-	logger   log.Logger
-	clients  metrics.Gauge
-	duration metrics.HistogramVec
-	errors   errs.Error
-	action   chan func()
-	stop     chan chan struct{}
+// WithKeyspaceMetrics counts the requests of each keyspace, by keyspace and
+// status code.
+func WithKeyspaceMetrics(requests metrics.CounterVec) Option {
+	return func(a *API) {
+		a.requests = requests
+	}
+}
+
+// WithShedder sheds the inserts and deletes once too many of them are
+// waiting for the writes before them, counting each one that's shed.
+func WithShedder(shedder *ratelimit.Shedder, shed metrics.Counter) Option {
+	return func(a *API) {
+		a.shedder = shedder
+		a.shed = shed
+	}
 }
 
 // NewAPI creates a API with the correct dependencies.
@@ -56,18 +105,37 @@ func NewAPI(farm farm.Farm,
 	logger log.Logger,
 	clients metrics.Gauge,
 	duration metrics.HistogramVec,
+	opts ...Option,
 ) *API {
 	api := &API{
 		farm:     farm,
 		logger:   logger,
 		clients:  clients,
 		duration: duration,
-		errors:   errs.NewError(logger),
-		action:   make(chan func()),
-		stop:     make(chan chan struct{}),
+		keyspaces: map[selectors.Keyspace]Keyspace{
+			selectors.DefaultKeyspace: {},
+		},
+		errors: errs.NewError(logger),
+	}
+	for _, opt := range opts {
+		opt(api)
 	}
 
-	go api.run()
+	api.router = errs.NewRouter(errs.Handlers{
+		APIPathInsert:      api.handleInsertion,
+		APIPathDelete:      api.handleDeletion,
+		APIPathSelect:      api.handleSelect,
+		APIPathKeys:        api.handleKeys,
+		APIPathSize:        api.handleSize,
+		APIPathMembers:     api.handleMembers,
+		APIPathScore:       api.handleScore,
+		APIPathWatch:       api.handleWatch,
+		APIPathScan:        api.handleScan,
+		APIPathScanMembers: api.handleScanMembers,
+		APIPathRepair:      api.handleRepair,
+	}, api.errors)
+
+	api.writes = writes.NewQueue()
 
 	return api
 }
@@ -82,53 +150,108 @@ func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	a.clients.Inc()
 	defer a.clients.Dec()
 
+	keyspace, path, err := errs.Keyspace(r)
+
 	defer func(begin time.Time) {
 		a.duration.WithLabelValues(
 			r.Method,
-			r.URL.Path,
+			path,
 			strconv.Itoa(iw.code),
 		).Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	// Routing table
-	method, path := r.Method, r.URL.Path
-	switch {
-	case method == "POST" && path == APIPathInsert:
-		a.handleInsertion(w, r)
-	case method == "POST" && path == APIPathDelete:
-		a.handleDeletion(w, r)
-	case method == "GET" && path == APIPathSelect:
-		a.handleSelect(w, r)
-	case method == "GET" && path == APIPathKeys:
-		a.handleKeys(w, r)
-	case method == "GET" && path == APIPathSize:
-		a.handleSize(w, r)
-	case method == "GET" && path == APIPathMembers:
-		a.handleMembers(w, r)
-	default:
-		// Nothing found
-		a.errors.NotFound(w, r)
+	if err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+	config, ok := a.keyspaces[keyspace]
+	if !ok {
+		a.errors.Error(w, fmt.Sprintf("unknown keyspace %q", keyspace), http.StatusNotFound)
+		return
 	}
-}
 
-func (a *API) run() {
-	for {
-		select {
-		case f := <-a.action:
-			f()
+	if a.requests != nil {
+		defer func() {
+			a.requests.WithLabelValues(keyspace.String(), strconv.Itoa(iw.code)).Inc()
+		}()
+	}
 
-		case c := <-a.stop:
-			close(c)
+	// The keys of the other keyspaces can't be reached by a key that contains
+	// the separator.
+	query := r.URL.Query()
+	for _, v := range []string{"key", "match"} {
+		if strings.Contains(query.Get(v), selectors.KeyspaceSeparator) {
+			a.errors.BadRequest(w, r, fmt.Sprintf("invalid character in %q", v))
 			return
 		}
 	}
+
+	a.router.ServeHTTP(w, withKeyspace(r, keyspace, path, config, query))
+}
+
+// withKeyspace returns the request with in the keyspace, using the quorums of
+// the keyspace if the request doesn't ask for one.
+func withKeyspace(r *http.Request,
+	keyspace selectors.Keyspace,
+	path string,
+	config Keyspace,
+	query url.Values,
+) *http.Request {
+	quorum := config.Quorum
+	if r.Method == "POST" {
+		quorum = config.Replication
+	}
+	if query.Get("quorum") == "" && quorum != "" {
+		query.Set("quorum", quorum.String())
+	}
+
+	u := *r.URL
+	u.Path = path
+	u.RawQuery = query.Encode()
+
+	res := r.WithContext(context.WithValue(r.Context(), keyspaceContextKey{}, keyspace))
+	res.URL = &u
+	return res
+}
+
+type keyspaceContextKey struct{}
+
+// keyspaceFrom returns the keyspace of the request.
+func keyspaceFrom(r *http.Request) selectors.Keyspace {
+	keyspace, _ := r.Context().Value(keyspaceContextKey{}).(selectors.Keyspace)
+	return keyspace
+}
+
+// acquire sheds the write, replying to the request, if too many writes are
+// already waiting. Every write that's acquired has to be released.
+func (a *API) acquire(w http.ResponseWriter, r *http.Request) bool {
+	if a.shedder == nil {
+		return true
+	}
+	ok, retryAfter := a.shedder.Acquire()
+	if !ok {
+		if a.shed != nil {
+			a.shed.Inc()
+		}
+		a.errors.TooManyRequests(w, r, retryAfter)
+	}
+	return ok
+}
+
+func (a *API) release() {
+	if a.shedder != nil {
+		a.shedder.Release()
+	}
+}
+
+// farmFor returns the farm that traces with in the trace of the request.
+func (a *API) farmFor(r *http.Request) farm.Farm {
+	return farm.WithContext(r.Context(), a.farm)
 }
 
 // Close out the API
 func (a *API) Close() {
-	c := make(chan struct{})
-	a.stop <- c
-	<-c
+	a.writes.Close()
 }
 
 func (a *API) handleInsertion(w http.ResponseWriter, r *http.Request) {
@@ -136,6 +259,7 @@ func (a *API) handleInsertion(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
 	// Validate user input.
 	var qp KeyQueryParams
@@ -150,21 +274,34 @@ func (a *API) handleInsertion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.acquire(w, r) {
+		return
+	}
+	defer a.release()
+
 	var (
 		internalError = make(chan error)
 		result        = make(chan selectors.ChangeSet)
 	)
-	a.action <- func() {
-		changeSet, err := a.farm.Insert(qp.Key(), members, qp.quorum)
+	key := keyspace.Key(qp.Key())
+	if err := a.writes.Apply(key, func() {
+		changeSet, err := a.farmFor(r).Insert(key, members, qp.quorum)
 		if err != nil {
 			internalError <- err
 			return
 		}
 		result <- changeSet
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
 
 	select {
 	case err := <-internalError:
+		if selectors.QuotaError(err) {
+			a.errors.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		}
 		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
 	case changeSet := <-result:
 		// Make sure we collect the document for the result.
@@ -182,6 +319,7 @@ func (a *API) handleDeletion(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
 	// Validate user input.
 	var qp KeyQueryParams
@@ -196,17 +334,26 @@ func (a *API) handleDeletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.acquire(w, r) {
+		return
+	}
+	defer a.release()
+
 	var (
 		internalError = make(chan error)
 		result        = make(chan selectors.ChangeSet)
 	)
-	a.action <- func() {
-		changeSet, err := a.farm.Delete(qp.Key(), members, qp.quorum)
+	key := keyspace.Key(qp.Key())
+	if err := a.writes.Apply(key, func() {
+		changeSet, err := a.farmFor(r).Delete(key, members, qp.quorum)
 		if err != nil {
 			internalError <- err
 			return
 		}
 		result <- changeSet
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
 
 	select {
@@ -228,6 +375,7 @@ func (a *API) handleSelect(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
 	// Validate user input.
 	var qp KeyFieldQueryParams
@@ -236,7 +384,7 @@ func (a *API) handleSelect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	member, err := a.farm.Select(qp.Key(), qp.Field(), qp.quorum)
+	member, err := a.farmFor(r).Select(keyspace.Key(qp.Key()), qp.Field(), qp.quorum)
 	if err != nil {
 		if selectors.NotFoundError(err) {
 			a.errors.NotFound(w, r)
@@ -260,8 +408,24 @@ func (a *API) handleKeys(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
-	keys, err := a.farm.Keys()
+	// Validate user input.
+	var qp KeysQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	var (
+		keys []selectors.Key
+		err  error
+	)
+	if match := keyspace.Pattern(qp.Match()); match != "" {
+		keys, err = a.farmFor(r).KeysMatching(match, qp.quorum)
+	} else {
+		keys, err = a.farmFor(r).Keys(qp.quorum)
+	}
 	if err != nil {
 		a.errors.InternalServerError(w, r, err.Error())
 		return
@@ -269,7 +433,71 @@ func (a *API) handleKeys(w http.ResponseWriter, r *http.Request) {
 
 	// Make sure we collect the document for the result.
 	qr := KeysQueryResult{Errors: a.errors}
-	qr.Keys = keys
+	qr.Keys = keyspace.Trim(keys)
+
+	// Finish
+	qr.Duration = time.Since(begin).String()
+	qr.EncodeTo(w)
+}
+
+func (a *API) handleScan(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+	keyspace := keyspaceFrom(r)
+
+	// Validate user input.
+	var qp ScanQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	// The keys of the other keyspaces are dropped from the pages of the
+	// default keyspace, so its pages can have less keys than asked for.
+	opts := qp.Options()
+	opts.Match = keyspace.Pattern(opts.Match)
+
+	page, err := a.farmFor(r).Scan(qp.Cursor(), opts, qp.quorum)
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+	page.Keys = keyspace.Trim(page.Keys)
+
+	// Make sure we collect the document for the result.
+	qr := KeysPageQueryResult{Errors: a.errors}
+	qr.KeysPage = page
+
+	// Finish
+	qr.Duration = time.Since(begin).String()
+	qr.EncodeTo(w)
+}
+
+func (a *API) handleScanMembers(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+	keyspace := keyspaceFrom(r)
+
+	// Validate user input.
+	var qp KeyScanQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	page, err := a.farmFor(r).ScanMembers(keyspace.Key(qp.Key()), qp.Cursor(), qp.Options(), qp.quorum)
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	// Make sure we collect the document for the result.
+	qr := FieldsPageQueryResult{Errors: a.errors, Params: qp}
+	qr.FieldsPage = page
 
 	// Finish
 	qr.Duration = time.Since(begin).String()
@@ -281,6 +509,7 @@ func (a *API) handleSize(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
 	// Validate user input.
 	var qp KeyQueryParams
@@ -289,7 +518,7 @@ func (a *API) handleSize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	size, err := a.farm.Size(qp.Key())
+	size, err := a.farmFor(r).Size(keyspace.Key(qp.Key()), qp.quorum)
 	if err != nil {
 		a.errors.InternalServerError(w, r, err.Error())
 		return
@@ -309,22 +538,31 @@ func (a *API) handleMembers(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
 	// Validate user input.
-	var qp KeyQueryParams
+	var qp MembersQueryParams
 	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
 		a.errors.BadRequest(w, r, err.Error())
 		return
 	}
 
-	members, err := a.farm.Members(qp.Key())
+	var (
+		members []selectors.Field
+		err     error
+	)
+	if prefix := qp.Prefix(); prefix != "" {
+		members, err = a.farmFor(r).MembersWithPrefix(keyspace.Key(qp.Key()), prefix, qp.quorum)
+	} else {
+		members, err = a.farmFor(r).Members(keyspace.Key(qp.Key()), qp.quorum)
+	}
 	if err != nil {
 		a.errors.InternalServerError(w, r, err.Error())
 		return
 	}
 
 	// Make sure we collect the document for the result.
-	qr := FieldsQueryResult{Errors: a.errors, Params: qp}
+	qr := FieldsQueryResult{Errors: a.errors, Params: qp.KeyQueryParams}
 	qr.Fields = members
 
 	// Finish
@@ -337,6 +575,7 @@ func (a *API) handleScore(w http.ResponseWriter, r *http.Request) {
 
 	// useful metrics
 	begin := time.Now()
+	keyspace := keyspaceFrom(r)
 
 	// Validate user input.
 	var qp KeyFieldQueryParams
@@ -345,7 +584,7 @@ func (a *API) handleScore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	presence, err := a.farm.Score(qp.Key(), qp.Field())
+	presence, err := a.farmFor(r).Score(keyspace.Key(qp.Key()), qp.Field(), qp.quorum)
 	if err != nil {
 		a.errors.InternalServerError(w, r, err.Error())
 		return
@@ -360,6 +599,105 @@ func (a *API) handleScore(w http.ResponseWriter, r *http.Request) {
 	qr.EncodeTo(w)
 }
 
+func (a *API) handleRepair(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+	keyspace := keyspaceFrom(r)
+
+	// Validate user input.
+	var qp KeyQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryRequired); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	members, err := ingestMembers(r.Body)
+	if err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	if err := a.farmFor(r).Repair(farm.FieldValueScoresToKeyField(keyspace.Key(qp.Key()), members)); err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	// Make sure we collect the document for the result.
+	qr := FieldsQueryResult{Errors: a.errors, Params: qp}
+	qr.Fields = extractFields(members)
+
+	// Finish
+	qr.Duration = time.Since(begin).String()
+	qr.EncodeTo(w)
+}
+
+func (a *API) handleWatch(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	keyspace := keyspaceFrom(r)
+
+	// Validate user input.
+	var qp WatchQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	sub, err := a.farmFor(r).Watch(keyspace.Key(qp.Key()), qp.Resume(), qp.quorum)
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	writer, err := sse.NewWriter(w)
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	ticker := time.NewTicker(defaultKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					level.Warn(a.logger).Log("key", qp.Key(), "err", err)
+				}
+				return
+			}
+			_, event.Key = event.Key.Keyspace()
+			if err := writeEvent(writer, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := writer.KeepAlive(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes the event using the score as the id, so that clients can
+// resume from it.
+func writeEvent(writer *sse.Writer, event selectors.Event) error {
+	b, err := json.Marshal(api.EventOutput(event))
+	if err != nil {
+		return err
+	}
+	return writer.Write(sse.Event{
+		ID:   strconv.FormatInt(event.Score, 10),
+		Type: event.Type.String(),
+		Data: b,
+	})
+}
+
 type interceptingWriter struct {
 	code int
 	http.ResponseWriter
@@ -370,6 +708,14 @@ func (iw *interceptingWriter) WriteHeader(code int) {
 	iw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush passes through to the underlying http.ResponseWriter, so that the
+// events of a watch are sent straight away.
+func (iw *interceptingWriter) Flush() {
+	if flusher, ok := iw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func ingestMembers(reader io.ReadCloser) ([]selectors.FieldValueScore, error) {
 	bytes, err := ioutil.ReadAll(reader)
 	if err != nil {
@@ -396,3 +742,11 @@ func ingestMembers(reader io.ReadCloser) ([]selectors.FieldValueScore, error) {
 
 	return res, nil
 }
+
+func extractFields(members []selectors.FieldValueScore) []selectors.Field {
+	res := make([]selectors.Field, len(members))
+	for k, v := range members {
+		res[k] = v.Field
+	}
+	return res
+}
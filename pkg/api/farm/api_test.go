@@ -7,18 +7,24 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"sort"
 	"testing"
 	"testing/quick"
+	"time"
 
 	objects "github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/sse"
 	farmMocks "github.com/SimonRichardson/coherence/pkg/cluster/farm/mocks"
 	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/SimonRichardson/coherence/pkg/ratelimit"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
 	"github.com/go-kit/kit/log"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/trussle/harness/matchers"
 )
 
@@ -372,14 +378,6 @@ func TestDeleteAPI(t *testing.T) {
 	})
 }
 
-func extractFields(members []selectors.FieldValueScore) []selectors.Field {
-	res := make([]selectors.Field, len(members))
-	for k, v := range members {
-		res[k] = v.Field
-	}
-	return res
-}
-
 func unique(a []selectors.Field) []selectors.Field {
 	x := make(map[selectors.Field]struct{})
 	for _, v := range a {
@@ -427,3 +425,837 @@ func convertToInput(members []selectors.FieldValueScore) []objects.FieldValueSco
 	}
 	return res
 }
+
+func TestWatchAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("get with offset", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/watch", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		resp, err := http.Get(fmt.Sprintf("%s/watch?key=a&offset=1", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get events", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+			feed   = store.NewFeed(10)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		feed.Publish(selectors.Event{Type: selectors.EventInsert, Key: "a", Field: "x", Score: 5})
+		feed.Publish(selectors.Event{Type: selectors.EventDelete, Key: "a", Field: "x", Score: 7})
+		sub, err := feed.Subscribe("a", selectors.ResumeFromScore(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/watch", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		farm.EXPECT().Watch(selectors.Key("a"), selectors.ResumeFromScore(5), selectors.Strong).Return(sub, nil)
+
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/watch?key=a", server.URL), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Last-Event-ID", "5")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event, err := sse.NewReader(resp.Body).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Closing the subscription ends the stream.
+		sub.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if expected, actual := "7", event.ID; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "delete", event.Type; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestScanAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("get with invalid cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/scan", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		resp, err := http.Get(fmt.Sprintf("%s/scan?cursor=bad", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+
+			cursor = selectors.NewCursor("a")
+			next   = selectors.NewCursor("c")
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/scan", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		farm.EXPECT().Scan(cursor, selectors.ScanOptions{Match: "*", Count: 2}, selectors.Strong).Return(selectors.KeysPage{
+			Keys:   []selectors.Key{"b", "c"},
+			Cursor: next,
+		}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/scan?cursor=%s&match=*&count=2", server.URL, cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records []string `json:"records"`
+			Cursor  string   `json:"cursor"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []string{"b", "c"}, output.Records; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := next.String(), output.Cursor; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := next.String(), resp.Header.Get(httpHeaderCursor); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get members without key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/hscan", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		resp, err := http.Get(fmt.Sprintf("%s/hscan", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestFilterAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("get keys with match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/keys", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		farm.EXPECT().KeysMatching("user:*", selectors.Strong).Return([]selectors.Key{"user:1", "user:2"}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/keys?match=user:*", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records []string `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []string{"user:1", "user:2"}, output.Records; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get members with prefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/members", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		farm.EXPECT().MembersWithPrefix(selectors.Key("a"), "name:", selectors.Strong).Return([]selectors.Field{"name:first"}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/members?key=a&prefix=name:", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records []string `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []string{"name:first"}, output.Records; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestReadAPI(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(ctrl *gomock.Controller, method, path, code string) (*farmMocks.MockFarm, *httptest.Server, func()) {
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues(method, path, code).Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		return farm, server, func() {
+			server.Close()
+			api.Close()
+		}
+	}
+
+	t.Run("get score with quorum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "GET", "/score", "200")
+		defer closer()
+
+		farm.EXPECT().Score(selectors.Key("a"), selectors.Field("b"), selectors.One).Return(selectors.Presence{
+			Inserted: true,
+			Present:  true,
+			Score:    5,
+		}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/score?key=a&field=b&quorum=one", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records objects.Presence `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := int64(5), output.Records.Score; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get size with quorum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "GET", "/size", "200")
+		defer closer()
+
+		farm.EXPECT().Size(selectors.Key("a"), selectors.Consensus).Return(int64(3), nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/size?key=a&quorum=consensus", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records int64 `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := int64(3), output.Records; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get keys with invalid quorum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, server, closer := newServer(ctrl, "GET", "/keys", "400")
+		defer closer()
+
+		resp, err := http.Get(fmt.Sprintf("%s/keys?quorum=bad", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("post repair", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "POST", "/repair", "200")
+		defer closer()
+
+		farm.EXPECT().Repair([]selectors.KeyFieldValue{{
+			Key:   "a",
+			Field: "b",
+			Value: []byte("value"),
+		}}).Return(nil)
+
+		b, err := json.Marshal(objects.MembersInput{
+			Members: []objects.FieldValueScore{{
+				Field: "b",
+				Value: []byte("value"),
+				Score: 1,
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Post(fmt.Sprintf("%s/repair?key=a", server.URL), "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records []string `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []string{"b"}, output.Records; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get keys with timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "GET", "/keys", "503")
+		defer closer()
+
+		done := make(chan struct{})
+		defer close(done)
+
+		farm.EXPECT().Keys(selectors.Strong).DoAndReturn(func(selectors.Quorum) ([]selectors.Key, error) {
+			<-done
+			return nil, nil
+		})
+
+		resp, err := http.Get(fmt.Sprintf("%s/keys?timeout=10ms", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusServiceUnavailable, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestKeyspaceAPI(t *testing.T) {
+	t.Parallel()
+
+	teamA := selectors.Keyspace("team-a")
+
+	newServer := func(ctrl *gomock.Controller, method, path, code string) (*farmMocks.MockFarm, *httptest.Server, func()) {
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			requests = metricMocks.NewMockCounterVec(ctrl)
+			farm     = farmMocks.NewMockFarm(ctrl)
+
+			api = NewAPI(farm, log.NewNopLogger(), clients, duration,
+				WithKeyspaces(map[selectors.Keyspace]Keyspace{
+					teamA: {Quorum: selectors.One, Replication: selectors.Consensus},
+				}),
+				WithKeyspaceMetrics(requests),
+			)
+			server = httptest.NewServer(api)
+		)
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues(method, path, code).Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		requests.EXPECT().WithLabelValues(gomock.Any(), code).Return(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "requests",
+		})).AnyTimes()
+
+		return farm, server, func() {
+			server.Close()
+			api.Close()
+		}
+	}
+
+	t.Run("get select with path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "GET", "/select", "200")
+		defer closer()
+
+		farm.EXPECT().Select(teamA.Key("a"), selectors.Field("b"), selectors.One).Return(selectors.FieldValueScore{
+			Field: "b",
+			Score: 1,
+		}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/keyspaces/team-a/select?key=a&field=b", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusOK, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "a", resp.Header.Get("X-Key"); expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("post insert with header", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "POST", "/insert", "200")
+		defer closer()
+
+		farm.EXPECT().Insert(teamA.Key("a"), gomock.Any(), selectors.Consensus).Return(selectors.ChangeSet{
+			Success: []selectors.Field{"b"},
+		}, nil)
+
+		b, err := json.Marshal(objects.MembersInput{
+			Members: []objects.FieldValueScore{{Field: "b", Score: 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("POST", fmt.Sprintf("%s/insert?key=a", server.URL), bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Coherence-Keyspace", "team-a")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusOK, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("post insert over quota", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "POST", "/insert", "507")
+		defer closer()
+
+		farm.EXPECT().Insert(teamA.Key("a"), gomock.Any(), selectors.Strong).Return(selectors.ChangeSet{}, selectors.NewQuotaError(errors.New("bad")))
+
+		b, err := json.Marshal(objects.MembersInput{
+			Members: []objects.FieldValueScore{{Field: "b", Score: 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Post(fmt.Sprintf("%s/keyspaces/team-a/insert?key=a&quorum=strong", server.URL), "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusInsufficientStorage, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "GET", "/keys", "200")
+		defer closer()
+
+		farm.EXPECT().KeysMatching(teamA.Pattern("user:*"), selectors.One).Return([]selectors.Key{
+			teamA.Key("user:1"),
+			teamA.Key("user:2"),
+		}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/keyspaces/team-a/keys?match=user:*", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records []string `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []string{"user:1", "user:2"}, output.Records; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get keys of the default keyspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm, server, closer := newServer(ctrl, "GET", "/keys", "200")
+		defer closer()
+
+		farm.EXPECT().Keys(selectors.Strong).Return([]selectors.Key{"a", teamA.Key("b")}, nil)
+
+		resp, err := http.Get(fmt.Sprintf("%s/keys", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var output struct {
+			Records []string `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []string{"a"}, output.Records; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get unknown keyspace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, server, closer := newServer(ctrl, "GET", "/keys", "404")
+		defer closer()
+
+		resp, err := http.Get(fmt.Sprintf("%s/keyspaces/team-b/keys", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusNotFound, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get key with separator", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, server, closer := newServer(ctrl, "GET", "/size", "400")
+		defer closer()
+
+		resp, err := http.Get(fmt.Sprintf("%s/size?key=%s", server.URL, url.QueryEscape(teamA.Key("a").String())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestShedAPI(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		clients  = metricMocks.NewMockGauge(ctrl)
+		duration = metricMocks.NewMockHistogramVec(ctrl)
+		observer = metricMocks.NewMockObserver(ctrl)
+		shed     = metricMocks.NewMockCounter(ctrl)
+		farm     = farmMocks.NewMockFarm(ctrl)
+		shedder  = ratelimit.NewShedder(1)
+
+		api    = NewAPI(farm, log.NewNopLogger(), clients, duration, WithShedder(shedder, shed))
+		server = httptest.NewServer(api)
+	)
+	defer api.Close()
+	defer server.Close()
+
+	clients.EXPECT().Inc().Times(2)
+	clients.EXPECT().Dec().Times(2)
+
+	duration.EXPECT().WithLabelValues("POST", "/insert", "200").Return(observer).Times(1)
+	duration.EXPECT().WithLabelValues("POST", "/insert", "429").Return(observer).Times(1)
+	observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(2)
+
+	shed.EXPECT().Inc().Times(1)
+
+	release := make(chan struct{})
+	farm.EXPECT().Insert(selectors.Key("a"), gomock.Any(), selectors.Strong).DoAndReturn(
+		func(selectors.Key, []selectors.FieldValueScore, selectors.Quorum) (selectors.ChangeSet, error) {
+			<-release
+			return selectors.ChangeSet{}, nil
+		},
+	)
+
+	b, err := json.Marshal(objects.MembersInput{
+		Members: []objects.FieldValueScore{{Field: "b", Score: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func() (*http.Response, error) {
+		return http.Post(fmt.Sprintf("%s/insert?key=a", server.URL), "application/json", bytes.NewReader(b))
+	}
+
+	done := make(chan int)
+	go func() {
+		resp, err := post()
+		if err != nil {
+			t.Error(err)
+			close(done)
+			return
+		}
+		resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+
+	for shedder.Pending() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	resp, err := post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if expected, actual := http.StatusTooManyRequests, resp.StatusCode; expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if expected, actual := "1", resp.Header.Get("Retry-After"); expected != actual {
+		t.Errorf("expected: %q, actual: %q", expected, actual)
+	}
+
+	close(release)
+	if expected, actual := http.StatusOK, <-done; expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestClosedAPI(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		clients  = metricMocks.NewMockGauge(ctrl)
+		duration = metricMocks.NewMockHistogramVec(ctrl)
+		observer = metricMocks.NewMockObserver(ctrl)
+		farm     = farmMocks.NewMockFarm(ctrl)
+
+		api    = NewAPI(farm, log.NewNopLogger(), clients, duration)
+		server = httptest.NewServer(api)
+	)
+	defer server.Close()
+
+	// Once the API is closed, the writes are turned away rather than left
+	// waiting on a queue that's no longer applying them.
+	api.Close()
+
+	clients.EXPECT().Inc().Times(2)
+	clients.EXPECT().Dec().Times(2)
+
+	duration.EXPECT().WithLabelValues("POST", "/insert", "503").Return(observer).Times(1)
+	duration.EXPECT().WithLabelValues("POST", "/delete", "503").Return(observer).Times(1)
+	observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(2)
+
+	b, err := json.Marshal(objects.MembersInput{
+		Members: []objects.FieldValueScore{{Field: "b", Score: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"insert", "delete"} {
+		resp, err := http.Post(fmt.Sprintf("%s/%s?key=a", server.URL, path), "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if expected, actual := http.StatusServiceUnavailable, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	}
+}
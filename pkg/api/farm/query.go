@@ -48,19 +48,9 @@ func (qp *KeyQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior
 	}
 	qp.key = selectors.Key(key)
 
-	var (
-		err    error
-		quorum = u.Query().Get("quorum")
-	)
-	if quorum != "" {
-		if qp.quorum, err = selectors.ParseQuorum(quorum); err != nil {
-			return errors.Errorf("expected 'quorum' but got %q", quorum)
-		}
-	} else {
-		qp.quorum = selectors.Strong
-	}
-
-	return nil
+	var err error
+	qp.quorum, err = decodeQuorum(u)
+	return err
 }
 
 // KeyFieldQueryParams defines all the dimensions of a query.
@@ -100,21 +90,170 @@ func (qp *KeyFieldQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBeh
 	}
 	qp.field = selectors.Field(field)
 
-	var (
-		err    error
-		quorum = u.Query().Get("quorum")
-	)
-	if quorum != "" {
-		if qp.quorum, err = selectors.ParseQuorum(quorum); err != nil {
-			return errors.Errorf("expected 'quorum' but got %q", quorum)
-		}
-	} else {
-		qp.quorum = selectors.Strong
+	var err error
+	qp.quorum, err = decodeQuorum(u)
+	return err
+}
+
+// WatchQueryParams defines all the dimensions of a watch query.
+type WatchQueryParams struct {
+	key    selectors.Key
+	resume selectors.Resume
+	quorum selectors.Quorum
+}
+
+// Key returns the key value from the parameters
+func (qp WatchQueryParams) Key() selectors.Key {
+	return qp.key
+}
+
+// Resume returns where the watch resumes from
+func (qp WatchQueryParams) Resume() selectors.Resume {
+	return qp.resume
+}
+
+// DecodeFrom populates a WatchQueryParams from a URL. Offsets are only valid
+// for a single node, so a watch of the cache can only resume from a score,
+// which is also used as the Last-Event-ID when a client reconnects.
+func (qp *WatchQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	key := u.Query().Get("key")
+	if key == "" {
+		return errors.Errorf("expected 'key' but got %q", key)
 	}
+	qp.key = selectors.Key(key)
+
+	if offset := u.Query().Get("offset"); offset != "" {
+		return errors.Errorf("expected 'score' but got 'offset' %q", offset)
+	}
+
+	score := u.Query().Get("score")
+	if id := h.Get("Last-Event-ID"); id != "" {
+		score = id
+	}
+
+	var err error
+	if qp.resume, err = selectors.ParseResume("", score); err != nil {
+		return err
+	}
+
+	qp.quorum, err = decodeQuorum(u)
+	return err
+}
+
+// KeysQueryParams defines all the dimensions of a keys query.
+type KeysQueryParams struct {
+	match  string
+	quorum selectors.Quorum
+}
+
+// Match returns the glob style pattern that the keys have to match, the
+// empty pattern matches every key.
+func (qp KeysQueryParams) Match() string {
+	return qp.match
+}
+
+// DecodeFrom populates a KeysQueryParams from a URL.
+func (qp *KeysQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	qp.match = u.Query().Get("match")
+
+	var err error
+	qp.quorum, err = decodeQuorum(u)
+	return err
+}
 
+// MembersQueryParams defines all the dimensions of a members query.
+type MembersQueryParams struct {
+	KeyQueryParams
+	prefix string
+}
+
+// Prefix returns the prefix that the members have to start with, the empty
+// prefix matches every member.
+func (qp MembersQueryParams) Prefix() string {
+	return qp.prefix
+}
+
+// DecodeFrom populates a MembersQueryParams from a URL.
+func (qp *MembersQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	if err := qp.KeyQueryParams.DecodeFrom(u, h, rb); err != nil {
+		return err
+	}
+	qp.prefix = u.Query().Get("prefix")
 	return nil
 }
 
+// ScanQueryParams defines all the dimensions of a scan query.
+type ScanQueryParams struct {
+	cursor selectors.Cursor
+	opts   selectors.ScanOptions
+	quorum selectors.Quorum
+}
+
+// Cursor returns the cursor to carry on the scan from
+func (qp ScanQueryParams) Cursor() selectors.Cursor {
+	return qp.cursor
+}
+
+// Options returns the options for the page of the scan
+func (qp ScanQueryParams) Options() selectors.ScanOptions {
+	return qp.opts
+}
+
+// DecodeFrom populates a ScanQueryParams from a URL.
+func (qp *ScanQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	cursor := selectors.Cursor(u.Query().Get("cursor"))
+	if _, _, err := cursor.After(); err != nil {
+		return err
+	}
+	qp.cursor = cursor
+
+	var err error
+	if qp.opts, err = selectors.ParseScanOptions(u.Query().Get("match"), u.Query().Get("count")); err != nil {
+		return err
+	}
+
+	qp.quorum, err = decodeQuorum(u)
+	return err
+}
+
+// KeyScanQueryParams defines all the dimensions of a scan query with in a
+// key.
+type KeyScanQueryParams struct {
+	ScanQueryParams
+	key selectors.Key
+}
+
+// Key returns the key value from the parameters
+func (qp KeyScanQueryParams) Key() selectors.Key {
+	return qp.key
+}
+
+// DecodeFrom populates a KeyScanQueryParams from a URL.
+func (qp *KeyScanQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	key := u.Query().Get("key")
+	if key == "" {
+		return errors.Errorf("expected 'key' but got %q", key)
+	}
+	qp.key = selectors.Key(key)
+
+	return qp.ScanQueryParams.DecodeFrom(u, h, rb)
+}
+
+// decodeQuorum returns the quorum of the query, every read and write of the
+// cache defaults to a strong quorum.
+func decodeQuorum(u *url.URL) (selectors.Quorum, error) {
+	quorum := u.Query().Get("quorum")
+	if quorum == "" {
+		return selectors.Strong, nil
+	}
+
+	res, err := selectors.ParseQuorum(quorum)
+	if err != nil {
+		return res, errors.Errorf("expected 'quorum' but got %q", quorum)
+	}
+	return res, nil
+}
+
 type queryBehavior int
 
 const (
@@ -184,10 +184,63 @@ func (qr *FieldValueScoreQueryResult) EncodeTo(w http.ResponseWriter) {
 	}
 }
 
+// KeysPageQueryResult contains statistics about the query.
+type KeysPageQueryResult struct {
+	Errors   errs.Error
+	Duration string             `json:"duration"`
+	KeysPage selectors.KeysPage `json:"page"`
+}
+
+// EncodeTo encodes the KeysPageQueryResult to the HTTP response writer. The
+// cursor is empty once the scan is finished.
+func (qr *KeysPageQueryResult) EncodeTo(w http.ResponseWriter) {
+	w.Header().Set(httpHeaderContentType, defaultContentType)
+	w.Header().Set(httpHeaderDuration, qr.Duration)
+	w.Header().Set(httpHeaderCursor, qr.KeysPage.Cursor.String())
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Records []api.Key `json:"records"`
+		Cursor  string    `json:"cursor"`
+	}{
+		Records: api.KeysOutput(qr.KeysPage.Keys),
+		Cursor:  qr.KeysPage.Cursor.String(),
+	}); err != nil {
+		qr.Errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// FieldsPageQueryResult contains statistics about the query.
+type FieldsPageQueryResult struct {
+	Errors     errs.Error
+	Params     KeyScanQueryParams   `json:"query"`
+	Duration   string               `json:"duration"`
+	FieldsPage selectors.FieldsPage `json:"page"`
+}
+
+// EncodeTo encodes the FieldsPageQueryResult to the HTTP response writer. The
+// cursor is empty once the scan is finished.
+func (qr *FieldsPageQueryResult) EncodeTo(w http.ResponseWriter) {
+	w.Header().Set(httpHeaderContentType, defaultContentType)
+	w.Header().Set(httpHeaderDuration, qr.Duration)
+	w.Header().Set(httpHeaderKey, qr.Params.Key().String())
+	w.Header().Set(httpHeaderCursor, qr.FieldsPage.Cursor.String())
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Records []api.Field `json:"records"`
+		Cursor  string      `json:"cursor"`
+	}{
+		Records: api.FieldsOutput(qr.FieldsPage.Fields),
+		Cursor:  qr.FieldsPage.Cursor.String(),
+	}); err != nil {
+		qr.Errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 const (
 	httpHeaderContentType = "Content-Type"
 	httpHeaderDuration    = "X-Duration"
 	httpHeaderKey         = "X-Key"
 	httpHeaderField       = "X-Field"
 	httpHeaderQuorum      = "X-Quorum"
+	httpHeaderCursor      = "X-Cursor"
 )
@@ -2,7 +2,10 @@ package http
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -54,3 +57,14 @@ func (e Error) BadRequest(w http.ResponseWriter, r *http.Request, err string) {
 func (e Error) InternalServerError(w http.ResponseWriter, r *http.Request, err string) {
 	e.Error(w, err, http.StatusInternalServerError)
 }
+
+// TooManyRequests to the request with an HTTP 429 too many requests error,
+// telling the client when to retry.
+func (e Error) TooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	e.Error(w, "too many requests", http.StatusTooManyRequests)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 	"testing/quick"
+	"time"
 
 	"github.com/go-kit/kit/log"
 )
@@ -116,4 +117,27 @@ func TestError(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("writes too many requests", func(t *testing.T) {
+		for _, test := range []struct {
+			retryAfter time.Duration
+			expected   string
+		}{
+			{0, "1"},
+			{time.Millisecond * 10, "1"},
+			{time.Millisecond * 1500, "2"},
+		} {
+			w := httptest.NewRecorder()
+
+			e := NewError(log.NewNopLogger())
+			e.TooManyRequests(w, nil, test.retryAfter)
+
+			if expected, actual := http.StatusTooManyRequests, w.Code; expected != actual {
+				t.Errorf("expected: %d, actual: %d", expected, actual)
+			}
+			if expected, actual := test.expected, w.Header().Get("Retry-After"); expected != actual {
+				t.Errorf("expected: %q, actual: %q", expected, actual)
+			}
+		}
+	})
 }
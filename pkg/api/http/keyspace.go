@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIPathKeyspaces represents a way to name the keyspace of a request with
+	// in the path, /keyspaces/<name>/select for example.
+	APIPathKeyspaces = "/keyspaces/"
+
+	// HeaderKeyspace names the keyspace of a request, when it isn't named with
+	// in the path.
+	HeaderKeyspace = "X-Coherence-Keyspace"
+)
+
+// Keyspace returns the keyspace that the request names and the path of the
+// request with in it. A request that doesn't name a keyspace is with in the
+// default keyspace.
+func Keyspace(r *http.Request) (selectors.Keyspace, string, error) {
+	var (
+		name = r.Header.Get(HeaderKeyspace)
+		path = r.URL.Path
+	)
+	if strings.HasPrefix(path, APIPathKeyspaces) {
+		rest := path[len(APIPathKeyspaces):]
+		i := strings.Index(rest, "/")
+		if i < 0 {
+			return selectors.DefaultKeyspace, path, errors.Errorf("expected a path after the keyspace %q", rest)
+		}
+		if name != "" && name != rest[:i] {
+			return selectors.DefaultKeyspace, path, errors.Errorf("keyspace %q of the path doesn't match the header %q", rest[:i], name)
+		}
+		name, path = rest[:i], rest[i:]
+	}
+
+	keyspace, err := selectors.ParseKeyspace(name)
+	return keyspace, path, err
+}
+
+// KeyPrefix returns the key that the request is for, taken from the "key"
+// query or, when there isn't one, from the literal prefix of the "match"
+// query. Every key that the request reads or writes starts with it.
+func KeyPrefix(r *http.Request) string {
+	query := r.URL.Query()
+	if key := query.Get("key"); key != "" {
+		return key
+	}
+	return selectors.Prefix(query.Get("match"))
+}
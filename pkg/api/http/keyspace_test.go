@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestKeyspace(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name     string
+		url      string
+		header   string
+		keyspace selectors.Keyspace
+		path     string
+		valid    bool
+	}{
+		{"default", "/select?key=a", "", selectors.DefaultKeyspace, "/select", true},
+		{"path", "/keyspaces/team-a/select?key=a", "", "team-a", "/select", true},
+		{"header", "/select?key=a", "team-a", "team-a", "/select", true},
+		{"path and header", "/keyspaces/team-a/select", "team-a", "team-a", "/select", true},
+		{"mismatch", "/keyspaces/team-a/select", "team-b", "", "", false},
+		{"no path", "/keyspaces/team-a", "", "", "", false},
+		{"invalid name", "/keyspaces/Team/select", "", "", "", false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.url, nil)
+			if test.header != "" {
+				r.Header.Set(HeaderKeyspace, test.header)
+			}
+
+			keyspace, path, err := Keyspace(r)
+			if expected, actual := test.valid, err == nil; expected != actual {
+				t.Fatalf("expected: %t, actual: %t (%v)", expected, actual, err)
+			}
+			if !test.valid {
+				return
+			}
+			if expected, actual := test.keyspace, keyspace; expected != actual {
+				t.Errorf("expected: %q, actual: %q", expected, actual)
+			}
+			if expected, actual := test.path, path; expected != actual {
+				t.Errorf("expected: %q, actual: %q", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// These are the API URL paths, which are shared by the store and the cache
+// APIs.
+const (
+
+	// APIPathInsert represents a way to insert a series or records.
+	APIPathInsert = "/insert"
+
+	// APIPathDelete represents a way to delete a series or records.
+	APIPathDelete = "/delete"
+
+	// APIPathSelect represents a way to select a record.
+	APIPathSelect = "/select"
+
+	// APIPathKeys represents a way to find all the keys with in the cache.
+	APIPathKeys = "/keys"
+
+	// APIPathSize represents a way to find the size of a key with in the cache.
+	APIPathSize = "/size"
+
+	// APIPathMembers represents a way to find all the members for a key with in
+	// the cache.
+	APIPathMembers = "/members"
+
+	// APIPathScore represents a way to find the score of a field with in a key.
+	APIPathScore = "/score"
+
+	// APIPathWatch represents a way to stream the changes of a key.
+	APIPathWatch = "/watch"
+
+	// APIPathChanges represents a way to read every change since an offset.
+	APIPathChanges = "/changes"
+
+	// APIPathScan represents a way to iterate over the keys a page at a time.
+	APIPathScan = "/scan"
+
+	// APIPathScanMembers represents a way to iterate over the members of a key
+	// a page at a time.
+	APIPathScanMembers = "/hscan"
+
+	// APIPathRepair represents a way to repair members across the replicas.
+	APIPathRepair = "/repair"
+
+	// APIPathSwap represents a way to insert a member only if its score is
+	// still the token.
+	APIPathSwap = "/swap"
+)
+
+const (
+	// maxTimeout is the longest timeout a request can ask for.
+	maxTimeout = time.Minute
+)
+
+// Route defines a path of the API and the method that it's served with.
+type Route struct {
+	Method string
+	Path   string
+
+	// Stream is set for the routes that hold on to the connection, a timeout
+	// isn't applied to them.
+	Stream bool
+
+	// Write is set for the routes that change the store, a timeout isn't
+	// applied to them either, as a write carries on once it's been sent to
+	// the replicas, so timing it out would only hide the outcome.
+	Write bool
+}
+
+// Routes is the route table that both the store and the cache APIs are
+// served from.
+var Routes = []Route{
+	{Method: "POST", Path: APIPathInsert, Write: true},
+	{Method: "POST", Path: APIPathDelete, Write: true},
+	{Method: "GET", Path: APIPathSelect},
+	{Method: "GET", Path: APIPathKeys},
+	{Method: "GET", Path: APIPathSize},
+	{Method: "GET", Path: APIPathMembers},
+	{Method: "GET", Path: APIPathScore},
+	{Method: "GET", Path: APIPathWatch, Stream: true},
+	{Method: "GET", Path: APIPathChanges},
+	{Method: "GET", Path: APIPathScan},
+	{Method: "GET", Path: APIPathScanMembers},
+	{Method: "POST", Path: APIPathRepair, Write: true},
+	{Method: "POST", Path: APIPathSwap, Write: true},
+}
+
+// Handlers maps the path of a route to the handler that serves it.
+type Handlers map[string]http.HandlerFunc
+
+type route struct {
+	method, path string
+}
+
+type handler struct {
+	Route
+	fn http.HandlerFunc
+}
+
+// Router dispatches the requests of the route table to the handlers. Any route
+// that doesn't have a handler isn't served.
+type Router struct {
+	handlers map[route]handler
+	errors   Error
+}
+
+// NewRouter creates a Router for the handlers of the route table.
+func NewRouter(handlers Handlers, errors Error) *Router {
+	r := &Router{
+		handlers: make(map[route]handler),
+		errors:   errors,
+	}
+	for _, v := range Routes {
+		if fn, ok := handlers[v.Path]; ok {
+			r.handlers[route{v.Method, v.Path}] = handler{v, fn}
+		}
+	}
+	return r
+}
+
+// ServeHTTP serves the handler of the route, any request that isn't
+// streaming or writing can set a timeout, after which it fails.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h, ok := r.handlers[route{req.Method, req.URL.Path}]
+	if !ok {
+		r.errors.NotFound(w, req)
+		return
+	}
+
+	var fn http.Handler = h.fn
+	if !h.Stream && !h.Write {
+		timeout, err := ParseTimeout(req.URL.Query().Get("timeout"))
+		if err != nil {
+			r.errors.BadRequest(w, req, err.Error())
+			return
+		}
+		if timeout > 0 {
+			// The body of a timeout is JSON, a handler that finishes in time
+			// replaces the headers with its own.
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			fn = http.TimeoutHandler(fn, timeout, timeoutBody(timeout))
+		}
+	}
+	fn.ServeHTTP(w, req)
+}
+
+// ParseTimeout parses the timeout of a request, the empty timeout means that
+// there isn't one.
+func ParseTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(s)
+	if err != nil || timeout <= 0 || timeout > maxTimeout {
+		return 0, errors.Errorf("expected 'timeout' between 0s and %s but got %q", maxTimeout, s)
+	}
+	return timeout, nil
+}
+
+func timeoutBody(timeout time.Duration) string {
+	b, err := json.Marshal(struct {
+		Description string `json:"description"`
+		Code        int    `json:"code"`
+	}{
+		Description: "timed out after " + timeout.String(),
+		Code:        http.StatusServiceUnavailable,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond * 50)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	router := NewRouter(Handlers{
+		APIPathSelect: ok,
+		APIPathKeys:   slow,
+		APIPathWatch:  slow,
+		APIPathInsert: slow,
+	}, NewError(log.NewNopLogger()))
+
+	for _, test := range []struct {
+		name   string
+		method string
+		url    string
+		code   int
+	}{
+		{"route", "GET", APIPathSelect, http.StatusOK},
+		{"wrong method", "POST", APIPathSelect, http.StatusNotFound},
+		{"no handler", "GET", APIPathSize, http.StatusNotFound},
+		{"unknown path", "GET", "/bad", http.StatusNotFound},
+		{"invalid timeout", "GET", APIPathSelect + "?timeout=bad", http.StatusBadRequest},
+		{"negative timeout", "GET", APIPathSelect + "?timeout=-1s", http.StatusBadRequest},
+		{"within timeout", "GET", APIPathKeys + "?timeout=1s", http.StatusOK},
+		{"timeout", "GET", APIPathKeys + "?timeout=1ms", http.StatusServiceUnavailable},
+		{"stream ignores timeout", "GET", APIPathWatch + "?timeout=1ms", http.StatusOK},
+		{"write ignores timeout", "POST", APIPathInsert + "?timeout=1ms", http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(test.method, test.url, nil))
+
+			if expected, actual := test.code, w.Code; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		})
+	}
+
+	t.Run("timeout body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", APIPathKeys+"?timeout=1ms", nil))
+
+		if expected, actual := "application/json; charset=utf-8", w.Header().Get("Content-Type"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		var body struct {
+			Code int `json:"code"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := http.StatusServiceUnavailable, body.Code; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package memcache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	protocolBinary = "MEMCACHE_BINARY"
+
+	magicRequest  = 0x80
+	magicResponse = 0x81
+
+	headerSize = 24
+
+	// maxBodyLength is the largest body that will be read, values over the
+	// maxValueLength are still read, so that they can be rejected without
+	// closing the connection.
+	maxBodyLength = maxValueLength * 2
+)
+
+// opcodes of the binary protocol that are supported.
+const (
+	opGet     = 0x00
+	opSet     = 0x01
+	opDelete  = 0x04
+	opQuit    = 0x07
+	opGetQ    = 0x09
+	opNoop    = 0x0a
+	opVersion = 0x0b
+	opGetK    = 0x0c
+	opGetKQ   = 0x0d
+	opSetQ    = 0x11
+	opDeleteQ = 0x14
+	opQuitQ   = 0x17
+)
+
+// response statuses of the binary protocol.
+const (
+	statusOK             = 0x0000
+	statusKeyNotFound    = 0x0001
+	statusKeyExists      = 0x0002
+	statusValueTooLarge  = 0x0003
+	statusInvalidArgs    = 0x0004
+	statusUnknownCommand = 0x0081
+	statusInternalError  = 0x0084
+)
+
+// header is the fixed size header of every binary request and response.
+type header struct {
+	magic    byte
+	opcode   byte
+	keyLen   uint16
+	extraLen byte
+	status   uint16
+	bodyLen  uint32
+	opaque   uint32
+	cas      uint64
+}
+
+type request struct {
+	header
+	extras []byte
+	key    []byte
+	value  []byte
+}
+
+func readRequest(reader io.Reader) (request, error) {
+	var buf [headerSize]byte
+	if _, err := io.ReadFull(reader, buf[:]); err != nil {
+		return request{}, err
+	}
+
+	h := header{
+		magic:    buf[0],
+		opcode:   buf[1],
+		keyLen:   binary.BigEndian.Uint16(buf[2:4]),
+		extraLen: buf[4],
+		status:   binary.BigEndian.Uint16(buf[6:8]),
+		bodyLen:  binary.BigEndian.Uint32(buf[8:12]),
+		opaque:   binary.BigEndian.Uint32(buf[12:16]),
+		cas:      binary.BigEndian.Uint64(buf[16:24]),
+	}
+	if h.magic != magicRequest {
+		return request{}, errors.Errorf("invalid magic: %x", h.magic)
+	}
+	if h.bodyLen > maxBodyLength ||
+		uint32(h.keyLen)+uint32(h.extraLen) > h.bodyLen {
+		return request{}, errors.Errorf("invalid body length: %d", h.bodyLen)
+	}
+
+	body := make([]byte, h.bodyLen)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return request{}, err
+	}
+
+	extras := int(h.extraLen)
+	keys := extras + int(h.keyLen)
+	return request{
+		header: h,
+		extras: body[:extras],
+		key:    body[extras:keys],
+		value:  body[keys:],
+	}, nil
+}
+
+func writeResponse(writer io.Writer, req request, status uint16, cas uint64, extras, key, value []byte) error {
+	var buf [headerSize]byte
+	buf[0] = magicResponse
+	buf[1] = req.opcode
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(key)))
+	buf[4] = byte(len(extras))
+	binary.BigEndian.PutUint16(buf[6:8], status)
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(extras)+len(key)+len(value)))
+	binary.BigEndian.PutUint32(buf[12:16], req.opaque)
+	binary.BigEndian.PutUint64(buf[16:24], cas)
+
+	for _, b := range [][]byte{buf[:], extras, key, value} {
+		if _, err := writer.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serveBinary handles the memcached binary protocol, until the client quits
+// or the connection fails.
+func (s *Server) serveBinary(reader *bufio.Reader, writer *bufio.Writer) error {
+	for {
+		req, err := readRequest(reader)
+		if err != nil {
+			return err
+		}
+
+		var (
+			begin  = time.Now()
+			quit   bool
+			status uint16
+		)
+		status, quit, err = s.binaryRequest(writer, req)
+		s.observe(protocolBinary, opName(req.opcode), statusName(status), begin)
+
+		if err != nil {
+			return err
+		}
+		if quit {
+			return nil
+		}
+
+		// Only flush once all the pipelined requests have been handled, which
+		// is how quiet requests are batched.
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) binaryRequest(writer *bufio.Writer, req request) (uint16, bool, error) {
+	respond := func(status uint16, cas uint64, extras, key, value []byte) (uint16, bool, error) {
+		return status, false, writeResponse(writer, req, status, cas, extras, key, value)
+	}
+	fail := func(status uint16, msg string) (uint16, bool, error) {
+		return respond(status, 0, nil, nil, []byte(msg))
+	}
+
+	switch req.opcode {
+	case opGet, opGetQ, opGetK, opGetKQ:
+		if !validKey(string(req.key)) || len(req.extras) != 0 {
+			return fail(statusInvalidArgs, "Invalid arguments")
+		}
+
+		quiet := req.opcode == opGetQ || req.opcode == opGetKQ
+		withKey := req.opcode == opGetK || req.opcode == opGetKQ
+
+		it, ok, err := s.get(string(req.key))
+		if err != nil {
+			return fail(statusInternalError, err.Error())
+		}
+		if !ok {
+			if quiet {
+				return statusKeyNotFound, false, nil
+			}
+			return fail(statusKeyNotFound, "Not found")
+		}
+
+		extras := make([]byte, 4)
+		binary.BigEndian.PutUint32(extras, it.flags)
+		var key []byte
+		if withKey {
+			key = req.key
+		}
+		return respond(statusOK, it.cas, extras, key, it.data)
+
+	case opSet, opSetQ:
+		if !validKey(string(req.key)) || len(req.extras) != 8 {
+			return fail(statusInvalidArgs, "Invalid arguments")
+		}
+
+		if len(req.value) > maxValueLength {
+			return fail(statusValueTooLarge, "Too large.")
+		}
+
+		var (
+			quiet = req.opcode == opSetQ
+			flags = binary.BigEndian.Uint32(req.extras[0:4])
+			cas   uint64
+			err   error
+		)
+		if req.cas == 0 {
+			cas, err = s.set(string(req.key), flags, req.value)
+		} else {
+			var status casStatus
+			status, cas, err = s.cas(string(req.key), flags, req.value, req.cas)
+			if err == nil && status == casExists {
+				return fail(statusKeyExists, "Data exists for key.")
+			} else if err == nil && status == casNotFound {
+				return fail(statusKeyNotFound, "Not found")
+			}
+		}
+		if err != nil {
+			return fail(statusInternalError, err.Error())
+		}
+		if quiet {
+			return statusOK, false, nil
+		}
+		return respond(statusOK, cas, nil, nil, nil)
+
+	case opDelete, opDeleteQ:
+		if !validKey(string(req.key)) || len(req.extras) != 0 {
+			return fail(statusInvalidArgs, "Invalid arguments")
+		}
+
+		found, err := s.delete(string(req.key))
+		if err != nil {
+			return fail(statusInternalError, err.Error())
+		}
+		if !found {
+			return fail(statusKeyNotFound, "Not found")
+		}
+		if req.opcode == opDeleteQ {
+			return statusOK, false, nil
+		}
+		return respond(statusOK, 0, nil, nil, nil)
+
+	case opNoop:
+		return respond(statusOK, 0, nil, nil, nil)
+
+	case opVersion:
+		return respond(statusOK, 0, nil, nil, []byte(version))
+
+	case opQuit:
+		status, _, err := respond(statusOK, 0, nil, nil, nil)
+		return status, true, err
+
+	case opQuitQ:
+		return statusOK, true, nil
+
+	default:
+		return fail(statusUnknownCommand, "Unknown command")
+	}
+}
+
+func opName(opcode byte) string {
+	switch opcode {
+	case opGet, opGetQ, opGetK, opGetKQ:
+		return "get"
+	case opSet, opSetQ:
+		return "set"
+	case opDelete, opDeleteQ:
+		return "delete"
+	case opNoop:
+		return "noop"
+	case opVersion:
+		return "version"
+	case opQuit, opQuitQ:
+		return "quit"
+	default:
+		return "unknown"
+	}
+}
+
+func statusName(status uint16) string {
+	switch status {
+	case statusOK:
+		return "ok"
+	case statusKeyNotFound:
+		return "not_found"
+	case statusKeyExists:
+		return "exists"
+	case statusInvalidArgs:
+		return "invalid_arguments"
+	case statusUnknownCommand:
+		return "unknown_command"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,194 @@
+package memcache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	farmMocks "github.com/SimonRichardson/coherence/pkg/cluster/farm/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Insert(selectors.Key("key"), []selectors.FieldValueScore{
+			{Field: Field, Value: encodeValue(5, []byte("hello")), Score: 10},
+		}, selectors.Strong).Return(selectors.ChangeSet{}, nil)
+
+		conn, closer := newConn(t, farm)
+		defer closer()
+
+		send(t, conn, opSet, 0, setExtras(5), []byte("key"), []byte("hello"))
+		res := receive(t, conn)
+		if expected, actual := uint16(statusOK), res.status; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := uint64(10), res.cas; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("cas exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := newSwapFarm(ctrl)
+		farm.MockSwapper.EXPECT().Swap(selectors.Key("key"), gomock.Any(), int64(3), selectors.Strong).Return(selectors.ChangeSet{
+			Failure: []selectors.Field{Field},
+		}, nil)
+
+		conn, closer := newConn(t, farm)
+		defer closer()
+
+		send(t, conn, opSet, 3, setExtras(0), []byte("key"), []byte("hello"))
+		if expected, actual := uint16(statusKeyExists), receive(t, conn).status; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("key"), Field, selectors.Strong).Return(selectors.FieldValueScore{
+			Field: Field,
+			Value: encodeValue(5, []byte("hello")),
+			Score: 3,
+		}, nil)
+
+		conn, closer := newConn(t, farm)
+		defer closer()
+
+		send(t, conn, opGetK, 0, nil, []byte("key"), nil)
+		res := receive(t, conn)
+		if expected, actual := uint16(statusOK), res.status; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := uint64(3), res.cas; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := uint32(5), binary.BigEndian.Uint32(res.extras); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "key", string(res.key); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "hello", string(res.value); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("quiet get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("key"), Field, selectors.Strong).Return(selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		conn, closer := newConn(t, farm)
+		defer closer()
+
+		// A quiet miss has no response, so the noop is the first response.
+		send(t, conn, opGetQ, 0, nil, []byte("key"), nil)
+		send(t, conn, opNoop, 0, nil, nil, nil)
+		if expected, actual := byte(opNoop), receive(t, conn).opcode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("key"), Field, selectors.Strong).Return(selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		conn, closer := newConn(t, farm)
+		defer closer()
+
+		send(t, conn, opDelete, 0, nil, []byte("key"), nil)
+		if expected, actual := uint16(statusKeyNotFound), receive(t, conn).status; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		conn, closer := newConn(t, farmMocks.NewMockFarm(ctrl))
+		defer closer()
+
+		send(t, conn, 0x05, 0, nil, []byte("key"), nil)
+		if expected, actual := uint16(statusUnknownCommand), receive(t, conn).status; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func setExtras(flags uint32) []byte {
+	extras := make([]byte, 8)
+	binary.BigEndian.PutUint32(extras, flags)
+	return extras
+}
+
+func send(t *testing.T, conn net.Conn, opcode byte, cas uint64, extras, key, value []byte) {
+	buf := new(bytes.Buffer)
+	if err := writeResponse(buf, request{header: header{opcode: opcode}}, 0, cas, extras, key, value); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests and responses share the same layout, only the magic differs.
+	b := buf.Bytes()
+	b[0] = magicRequest
+	if _, err := conn.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type response struct {
+	header
+	extras, key, value []byte
+}
+
+func receive(t *testing.T, conn net.Conn) response {
+	var buf [headerSize]byte
+	if _, err := io.ReadFull(conn, buf[:]); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != magicResponse {
+		t.Fatalf("unexpected magic %x", buf[0])
+	}
+
+	h := header{
+		opcode:   buf[1],
+		keyLen:   binary.BigEndian.Uint16(buf[2:4]),
+		extraLen: buf[4],
+		status:   binary.BigEndian.Uint16(buf[6:8]),
+		bodyLen:  binary.BigEndian.Uint32(buf[8:12]),
+		cas:      binary.BigEndian.Uint64(buf[16:24]),
+	}
+	body := make([]byte, h.bodyLen)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := int(h.extraLen) + int(h.keyLen)
+	return response{
+		header: h,
+		extras: body[:h.extraLen],
+		key:    body[h.extraLen:keys],
+		value:  body[keys:],
+	}
+}
@@ -0,0 +1,253 @@
+package memcache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Field is the field that every memcached key stores its value in.
+	Field = selectors.Field("memcache")
+
+	// maxKeyLength is the longest key that memcached accepts.
+	maxKeyLength = 250
+
+	// maxValueLength is the largest value that will be accepted.
+	maxValueLength = 1 << 20
+
+	version = "1.4.0-coherence"
+)
+
+// casStatus describes the outcome of a compare and swap.
+type casStatus int
+
+const (
+	casStored casStatus = iota
+	casExists
+	casNotFound
+)
+
+// item is a memcached value, the flags are opaque to the server and are
+// stored in front of the data. The cas token is the score of the member.
+type item struct {
+	flags uint32
+	data  []byte
+	cas   uint64
+}
+
+// Server maps the memcached text and binary protocols on to the farm. Each
+// memcached key is stored as a key with a single fixed field, and the score of
+// the field is used as the CAS token. Expiration times are accepted but
+// ignored, as the farm has no notion of time to live.
+type Server struct {
+	farm     farm.Farm
+	quorum   selectors.Quorum
+	logger   log.Logger
+	clients  metrics.Gauge
+	duration metrics.HistogramVec
+	now      func() int64
+	mutex    sync.Mutex
+	conns    map[net.Conn]struct{}
+}
+
+// NewServer creates a Server with the correct dependencies.
+func NewServer(farm farm.Farm,
+	quorum selectors.Quorum,
+	logger log.Logger,
+	clients metrics.Gauge,
+	duration metrics.HistogramVec,
+) *Server {
+	return &Server{
+		farm:     farm,
+		quorum:   quorum,
+		logger:   logger,
+		clients:  clients,
+		duration: duration,
+		now: func() int64 {
+			return time.Now().UnixNano()
+		},
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve accepts connections on the listener, until the listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// Close out the Server, along with any open connections.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	s.clients.Inc()
+	defer s.clients.Dec()
+
+	s.track(conn, true)
+	defer s.track(conn, false)
+	defer conn.Close()
+
+	var (
+		reader = bufio.NewReader(conn)
+		writer = bufio.NewWriter(conn)
+	)
+
+	// The protocol is chosen by the first byte, binary requests always start
+	// with the request magic.
+	b, err := reader.Peek(1)
+	if err != nil {
+		return
+	}
+
+	if b[0] == magicRequest {
+		err = s.serveBinary(reader, writer)
+	} else {
+		err = s.serveText(reader, writer)
+	}
+	if err != nil && err != io.EOF {
+		level.Debug(s.logger).Log("err", err, "remote", conn.RemoteAddr())
+	}
+	writer.Flush()
+}
+
+func (s *Server) track(conn net.Conn, open bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if open {
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+}
+
+func (s *Server) observe(protocol, command, status string, begin time.Time) {
+	s.duration.WithLabelValues(
+		protocol,
+		command,
+		status,
+	).Observe(time.Since(begin).Seconds())
+}
+
+func (s *Server) get(key string) (item, bool, error) {
+	member, err := s.farm.Select(selectors.Key(key), Field, s.quorum)
+	if err != nil {
+		if selectors.NotFoundError(err) {
+			return item{}, false, nil
+		}
+		return item{}, false, err
+	}
+
+	flags, data := decodeValue(member.Value)
+	return item{
+		flags: flags,
+		data:  data,
+		cas:   uint64(member.Score),
+	}, true, nil
+}
+
+func (s *Server) set(key string, flags uint32, data []byte) (uint64, error) {
+	score := s.now()
+	if _, err := s.farm.Insert(selectors.Key(key), []selectors.FieldValueScore{
+		{Field: Field, Value: encodeValue(flags, data), Score: score},
+	}, s.quorum); err != nil {
+		return 0, err
+	}
+	return uint64(score), nil
+}
+
+// cas only stores the value if the score still matches the token. The score
+// is compared and swapped by each replica under the lock of its bucket, so
+// of the concurrent swaps of a token only one is stored.
+func (s *Server) cas(key string, flags uint32, data []byte, token uint64) (casStatus, uint64, error) {
+	swapper, ok := s.farm.(farm.Swapper)
+	if !ok {
+		return casNotFound, 0, errors.New("farm doesn't support compare and swap")
+	}
+
+	score := s.now()
+	if score <= int64(token) {
+		score = int64(token) + 1
+	}
+	changeSet, err := swapper.Swap(selectors.Key(key), selectors.FieldValueScore{
+		Field: Field,
+		Value: encodeValue(flags, data),
+		Score: score,
+	}, int64(token), s.quorum)
+	if err != nil {
+		if selectors.NotFoundError(err) {
+			return casNotFound, 0, nil
+		}
+		return casNotFound, 0, err
+	}
+	for _, field := range changeSet.Success {
+		if field == Field {
+			return casStored, uint64(score), nil
+		}
+	}
+	return casExists, 0, nil
+}
+
+func (s *Server) delete(key string) (bool, error) {
+	_, ok, err := s.get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if _, err := s.farm.Delete(selectors.Key(key), []selectors.FieldValueScore{
+		{Field: Field, Score: s.now()},
+	}, s.quorum); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func encodeValue(flags uint32, data []byte) []byte {
+	res := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(res, flags)
+	copy(res[4:], data)
+	return res
+}
+
+func decodeValue(value []byte) (uint32, []byte) {
+	if len(value) < 4 {
+		return 0, value
+	}
+	return binary.BigEndian.Uint32(value), value[4:]
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package memcache
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
+	farmMocks "github.com/SimonRichardson/coherence/pkg/cluster/farm/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	fn := func(flags uint32, data []byte) bool {
+		f, d := decodeValue(encodeValue(flags, data))
+		return f == flags && bytes.Equal(d, data)
+	}
+	if err := quick.Check(fn, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		key  string
+		want bool
+	}{
+		{"key", true},
+		{"", false},
+		{"a key", false},
+		{"key\r\n", false},
+		{strings.Repeat("a", maxKeyLength), true},
+		{strings.Repeat("a", maxKeyLength+1), false},
+	} {
+		if expected, actual := v.want, validKey(v.key); expected != actual {
+			t.Errorf("%q expected: %v, actual: %v", v.key, expected, actual)
+		}
+	}
+}
+
+func newConn(t *testing.T, farm farm.Farm) (net.Conn, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(farm,
+		selectors.Strong,
+		log.NewNopLogger(),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+	)
+	server.now = func() int64 { return 10 }
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn, func() {
+		conn.Close()
+		listener.Close()
+		server.Close()
+	}
+}
+
+// swapFarm is a farm that can compare and swap.
+type swapFarm struct {
+	*farmMocks.MockFarm
+	*farmMocks.MockSwapper
+}
+
+func newSwapFarm(ctrl *gomock.Controller) swapFarm {
+	return swapFarm{
+		MockFarm:    farmMocks.NewMockFarm(ctrl),
+		MockSwapper: farmMocks.NewMockSwapper(ctrl),
+	}
+}
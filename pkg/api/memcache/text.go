@@ -0,0 +1,246 @@
+package memcache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	protocolText = "MEMCACHE_TEXT"
+
+	maxLineLength = 2048
+)
+
+// serveText handles the memcached text protocol, until the client quits or the
+// connection fails.
+func (s *Server) serveText(reader *bufio.Reader, writer *bufio.Writer) error {
+	for {
+		line, err := readTextLine(reader)
+		if err != nil {
+			if err == errLineTooLong {
+				fmt.Fprintf(writer, "CLIENT_ERROR line too long\r\n")
+			}
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			writer.WriteString("ERROR\r\n")
+			continue
+		}
+
+		var (
+			command = strings.ToLower(fields[0])
+			begin   = time.Now()
+			status  string
+		)
+		switch command {
+		case "get", "gets":
+			status = s.textGet(writer, fields[1:], command == "gets")
+		case "set":
+			status, err = s.textSet(reader, writer, fields[1:], false)
+		case "cas":
+			status, err = s.textSet(reader, writer, fields[1:], true)
+		case "delete":
+			status = s.textDelete(writer, fields[1:])
+		case "version":
+			status = "ok"
+			fmt.Fprintf(writer, "VERSION %s\r\n", version)
+		case "quit":
+			return nil
+		default:
+			command, status = "unknown", "error"
+			writer.WriteString("ERROR\r\n")
+		}
+		s.observe(protocolText, command, status, begin)
+
+		if err != nil {
+			return err
+		}
+
+		// Only flush once all the pipelined commands have been handled.
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) textGet(writer *bufio.Writer, keys []string, withCAS bool) string {
+	if len(keys) == 0 {
+		writer.WriteString("ERROR\r\n")
+		return "error"
+	}
+
+	// Gather all the items first, so that a failure part way through doesn't
+	// leave a partial response.
+	items := make([]item, len(keys))
+	found := make([]bool, len(keys))
+	for k, key := range keys {
+		if !validKey(key) {
+			writer.WriteString("CLIENT_ERROR bad command line format\r\n")
+			return "error"
+		}
+
+		var err error
+		if items[k], found[k], err = s.get(key); err != nil {
+			fmt.Fprintf(writer, "SERVER_ERROR %s\r\n", oneLine(err))
+			return "error"
+		}
+	}
+
+	for k, key := range keys {
+		if !found[k] {
+			continue
+		}
+		if withCAS {
+			fmt.Fprintf(writer, "VALUE %s %d %d %d\r\n", key, items[k].flags, len(items[k].data), items[k].cas)
+		} else {
+			fmt.Fprintf(writer, "VALUE %s %d %d\r\n", key, items[k].flags, len(items[k].data))
+		}
+		writer.Write(items[k].data)
+		writer.WriteString("\r\n")
+	}
+	writer.WriteString("END\r\n")
+	return "ok"
+}
+
+// textSet handles both set and cas, as they only differ by the cas token.
+//
+//	set <key> <flags> <exptime> <bytes> [noreply]
+//	cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func (s *Server) textSet(reader *bufio.Reader, writer *bufio.Writer, args []string, withCAS bool) (string, error) {
+	required := 4
+	if withCAS {
+		required = 5
+	}
+	if len(args) < required || len(args) > required+1 {
+		writer.WriteString("ERROR\r\n")
+		return "error", nil
+	}
+
+	var (
+		key      = args[0]
+		noreply  = len(args) == required+1 && args[required] == "noreply"
+		flags, e = strconv.ParseUint(args[1], 10, 32)
+		_, e2    = strconv.ParseInt(args[2], 10, 64)
+		size, e3 = strconv.Atoi(args[3])
+	)
+	if e != nil || e2 != nil || e3 != nil || size < 0 || !validKey(key) {
+		writer.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return "error", nil
+	}
+	if size > maxValueLength {
+		// The data can't be skipped safely, so the connection is closed.
+		writer.WriteString("SERVER_ERROR object too large for cache\r\n")
+		return "error", errValueTooLarge
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return "error", err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		writer.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return "error", nil
+	}
+	data = data[:size]
+
+	reply := func(msg string) {
+		if !noreply {
+			writer.WriteString(msg)
+		}
+	}
+
+	if !withCAS {
+		if _, err := s.set(key, uint32(flags), data); err != nil {
+			reply(fmt.Sprintf("SERVER_ERROR %s\r\n", oneLine(err)))
+			return "error", nil
+		}
+		reply("STORED\r\n")
+		return "ok", nil
+	}
+
+	token, err := strconv.ParseUint(args[4], 10, 64)
+	if err != nil {
+		writer.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return "error", nil
+	}
+
+	status, _, err := s.cas(key, uint32(flags), data, token)
+	if err != nil {
+		reply(fmt.Sprintf("SERVER_ERROR %s\r\n", oneLine(err)))
+		return "error", nil
+	}
+	switch status {
+	case casStored:
+		reply("STORED\r\n")
+	case casExists:
+		reply("EXISTS\r\n")
+	default:
+		reply("NOT_FOUND\r\n")
+	}
+	return "ok", nil
+}
+
+// textDelete handles delete.
+//
+//	delete <key> [noreply]
+func (s *Server) textDelete(writer *bufio.Writer, args []string) string {
+	if len(args) < 1 || len(args) > 2 || !validKey(args[0]) {
+		writer.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return "error"
+	}
+
+	noreply := len(args) == 2 && args[1] == "noreply"
+	reply := func(msg string) {
+		if !noreply {
+			writer.WriteString(msg)
+		}
+	}
+
+	found, err := s.delete(args[0])
+	if err != nil {
+		reply(fmt.Sprintf("SERVER_ERROR %s\r\n", oneLine(err)))
+		return "error"
+	}
+	if found {
+		reply("DELETED\r\n")
+	} else {
+		reply("NOT_FOUND\r\n")
+	}
+	return "ok"
+}
+
+var (
+	errLineTooLong   = errors.New("line too long")
+	errValueTooLarge = errors.New("value too large")
+)
+
+func readTextLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxLineLength {
+			return "", errLineTooLong
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+func oneLine(err error) string {
+	return strings.Replace(err.Error(), "\r\n", " ", -1)
+}
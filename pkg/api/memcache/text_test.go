@@ -0,0 +1,198 @@
+package memcache
+
+import (
+	"bufio"
+	"io"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
+	farmMocks "github.com/SimonRichardson/coherence/pkg/cluster/farm/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func TestText(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Insert(selectors.Key("key"), []selectors.FieldValueScore{
+			{Field: Field, Value: encodeValue(5, []byte("hello")), Score: 10},
+		}, selectors.Strong).Return(selectors.ChangeSet{}, nil)
+
+		text(t, farm, "set key 5 0 5\r\nhello\r\n", "STORED\r\n")
+	})
+
+	t.Run("set noreply", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Insert(selectors.Key("key"), gomock.Any(), selectors.Strong).Return(selectors.ChangeSet{}, nil)
+
+		text(t, farm, "set key 0 0 5 noreply\r\nhello\r\nversion\r\n", "VERSION "+version+"\r\n")
+	})
+
+	t.Run("set with bad data chunk", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		text(t, farmMocks.NewMockFarm(ctrl), "set key 0 0 2\r\nhello\r\n", "CLIENT_ERROR bad data chunk\r\n")
+	})
+
+	t.Run("set with error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Insert(selectors.Key("key"), gomock.Any(), selectors.Strong).Return(selectors.ChangeSet{}, errors.New("bad"))
+
+		text(t, farm, "set key 0 0 5\r\nhello\r\n", "SERVER_ERROR bad\r\n")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("a"), Field, selectors.Strong).Return(selectors.FieldValueScore{
+			Field: Field,
+			Value: encodeValue(5, []byte("hello")),
+			Score: 3,
+		}, nil)
+		farm.EXPECT().Select(selectors.Key("b"), Field, selectors.Strong).Return(selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		text(t, farm, "get a b\r\n", "VALUE a 5 5\r\nhello\r\nEND\r\n")
+	})
+
+	t.Run("gets", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("a"), Field, selectors.Strong).Return(selectors.FieldValueScore{
+			Field: Field,
+			Value: encodeValue(0, []byte("hello")),
+			Score: 3,
+		}, nil)
+
+		text(t, farm, "gets a\r\n", "VALUE a 0 5 3\r\nhello\r\nEND\r\n")
+	})
+
+	t.Run("cas", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := newSwapFarm(ctrl)
+		farm.MockSwapper.EXPECT().Swap(selectors.Key("a"), selectors.FieldValueScore{
+			Field: Field,
+			Value: encodeValue(0, []byte("world")),
+			Score: 10,
+		}, int64(3), selectors.Strong).Return(selectors.ChangeSet{
+			Success: []selectors.Field{Field},
+		}, nil)
+
+		text(t, farm, "cas a 0 0 5 3\r\nworld\r\n", "STORED\r\n")
+	})
+
+	t.Run("cas after the token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := newSwapFarm(ctrl)
+		farm.MockSwapper.EXPECT().Swap(selectors.Key("a"), selectors.FieldValueScore{
+			Field: Field,
+			Value: encodeValue(0, []byte("world")),
+			Score: 21,
+		}, int64(20), selectors.Strong).Return(selectors.ChangeSet{
+			Success: []selectors.Field{Field},
+		}, nil)
+
+		text(t, farm, "cas a 0 0 5 20\r\nworld\r\n", "STORED\r\n")
+	})
+
+	t.Run("cas exists", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := newSwapFarm(ctrl)
+		farm.MockSwapper.EXPECT().Swap(selectors.Key("a"), gomock.Any(), int64(3), selectors.Strong).Return(selectors.ChangeSet{
+			Failure: []selectors.Field{Field},
+		}, nil)
+
+		text(t, farm, "cas a 0 0 5 3\r\nworld\r\n", "EXISTS\r\n")
+	})
+
+	t.Run("cas not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := newSwapFarm(ctrl)
+		farm.MockSwapper.EXPECT().Swap(selectors.Key("a"), gomock.Any(), int64(3), selectors.Strong).Return(selectors.ChangeSet{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		text(t, farm, "cas a 0 0 5 3\r\nworld\r\n", "NOT_FOUND\r\n")
+	})
+
+	t.Run("cas without swapping", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		text(t, farmMocks.NewMockFarm(ctrl), "cas a 0 0 5 3\r\nworld\r\n", "SERVER_ERROR farm doesn't support compare and swap\r\n")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("a"), Field, selectors.Strong).Return(selectors.FieldValueScore{
+			Field: Field,
+			Score: 3,
+		}, nil)
+		farm.EXPECT().Delete(selectors.Key("a"), []selectors.FieldValueScore{
+			{Field: Field, Score: 10},
+		}, selectors.Strong).Return(selectors.ChangeSet{}, nil)
+
+		text(t, farm, "delete a\r\n", "DELETED\r\n")
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		farm.EXPECT().Select(selectors.Key("a"), Field, selectors.Strong).Return(selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		text(t, farm, "delete a\r\n", "NOT_FOUND\r\n")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		text(t, farmMocks.NewMockFarm(ctrl), "incr a 1\r\n", "ERROR\r\n")
+	})
+}
+
+// text sends the request and checks that the server replies with exactly the
+// expected response.
+func text(t *testing.T, farm farm.Farm, request, expected string) {
+	conn, closer := newConn(t, farm)
+	defer closer()
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make([]byte, len(expected))
+	if _, err := io.ReadFull(bufio.NewReader(conn), actual); err != nil {
+		t.Fatal(err)
+	}
+	if expected != string(actual) {
+		t.Errorf("expected: %q, actual: %q", expected, actual)
+	}
+}
@@ -110,6 +110,32 @@ func (mr *MockTransportMockRecorder) Members(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockTransport)(nil).Members), arg0)
 }
 
+// Scan mocks base method
+func (m *MockTransport) Scan(arg0 selectors.Cursor, arg1 selectors.ScanOptions) (selectors.KeysPage, error) {
+	ret := m.ctrl.Call(m, "Scan", arg0, arg1)
+	ret0, _ := ret[0].(selectors.KeysPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Scan indicates an expected call of Scan
+func (mr *MockTransportMockRecorder) Scan(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockTransport)(nil).Scan), arg0, arg1)
+}
+
+// ScanMembers mocks base method
+func (m *MockTransport) ScanMembers(arg0 selectors.Key, arg1 selectors.Cursor, arg2 selectors.ScanOptions) (selectors.FieldsPage, error) {
+	ret := m.ctrl.Call(m, "ScanMembers", arg0, arg1, arg2)
+	ret0, _ := ret[0].(selectors.FieldsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScanMembers indicates an expected call of ScanMembers
+func (mr *MockTransportMockRecorder) ScanMembers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanMembers", reflect.TypeOf((*MockTransport)(nil).ScanMembers), arg0, arg1, arg2)
+}
+
 // Score mocks base method
 func (m *MockTransport) Score(arg0 selectors.Key, arg1 selectors.Field) (selectors.Presence, error) {
 	ret := m.ctrl.Call(m, "Score", arg0, arg1)
@@ -61,3 +61,35 @@ func ChangeSetOutput(a selectors.ChangeSet) ChangeSet {
 		Failure: FieldsOutput(a.Failure),
 	}
 }
+
+// Event is an input for marshalling json input and out from the api
+type Event struct {
+	Offset uint64 `json:"offset"`
+	Type   string `json:"type"`
+	Key    Key    `json:"key"`
+	Field  Field  `json:"field"`
+	Value  []byte `json:"value"`
+	Score  int64  `json:"score"`
+}
+
+func EventOutput(a selectors.Event) Event {
+	return Event{
+		Offset: a.Offset,
+		Type:   a.Type.String(),
+		Key:    Key(a.Key.String()),
+		Field:  Field(a.Field.String()),
+		Value:  a.Value,
+		Score:  a.Score,
+	}
+}
+
+func EventInput(a Event) selectors.Event {
+	return selectors.Event{
+		Offset: a.Offset,
+		Type:   selectors.EventType(a.Type),
+		Key:    selectors.Key(a.Key),
+		Field:  selectors.Field(a.Field),
+		Value:  a.Value,
+		Score:  a.Score,
+	}
+}
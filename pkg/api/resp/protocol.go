@@ -0,0 +1,150 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxArgs    = 1024 * 1024
+	maxBulkLen = 512 * 1024 * 1024
+)
+
+// errProtocol is a client error that can't be recovered from, as the reader
+// is no longer aligned with the requests.
+type errProtocol struct {
+	err error
+}
+
+func (e errProtocol) Error() string {
+	return e.err.Error()
+}
+
+// readCommand reads either a RESP array of bulk strings or an inline command,
+// which is what redis-cli and telnet send.
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, nil
+	}
+
+	if line[0] != '*' {
+		fields := strings.Fields(string(line))
+		args := make([][]byte, len(fields))
+		for k, v := range fields {
+			args[k] = []byte(v)
+		}
+		return args, nil
+	}
+
+	amount, err := strconv.Atoi(string(line[1:]))
+	if err != nil || amount > maxArgs {
+		return nil, errProtocol{errors.New("invalid multibulk length")}
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	args := make([][]byte, 0, amount)
+	for i := 0; i < amount; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol{errors.Errorf("expected '$', got %q", line)}
+		}
+
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, errProtocol{errors.New("invalid bulk length")}
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, errProtocol{errors.New("invalid bulk terminator")}
+		}
+		args = append(args, buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errProtocol{errors.New("line too long")}
+	} else if err != nil {
+		return nil, err
+	}
+
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// writer encodes replies using RESP.
+type writer struct {
+	w *bufio.Writer
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{
+		w: bufio.NewWriter(w),
+	}
+}
+
+func (w *writer) SimpleString(s string) {
+	w.w.WriteByte('+')
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Error(err error) {
+	msg := strings.Replace(err.Error(), "\r\n", " ", -1)
+	if !strings.HasPrefix(msg, "ERR ") && !strings.HasPrefix(msg, "WRONGTYPE ") {
+		msg = "ERR " + msg
+	}
+	w.w.WriteByte('-')
+	w.w.WriteString(msg)
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Integer(i int64) {
+	w.w.WriteByte(':')
+	w.w.WriteString(strconv.FormatInt(i, 10))
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Bulk(b []byte) {
+	w.w.WriteByte('$')
+	w.w.WriteString(strconv.Itoa(len(b)))
+	w.w.WriteString("\r\n")
+	w.w.Write(b)
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Null() {
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *writer) Array(size int) {
+	w.w.WriteByte('*')
+	w.w.WriteString(strconv.Itoa(size))
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,383 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// Server maps the redis protocol on to the farm, so that existing redis
+// clients can talk to the cluster. Hashes map on to the members of a key and
+// sorted sets on to the scores of the members.
+type Server struct {
+	farm     farm.Farm
+	quorum   selectors.Quorum
+	logger   log.Logger
+	clients  metrics.Gauge
+	duration metrics.HistogramVec
+	commands map[string]command
+	now      func() int64
+	mutex    sync.Mutex
+	conns    map[net.Conn]struct{}
+}
+
+type command struct {
+	arity int
+	fn    func(*session, [][]byte) error
+}
+
+// session holds the state of a single connection.
+type session struct {
+	writer *writer
+	quorum selectors.Quorum
+	quit   bool
+}
+
+// NewServer creates a Server with the correct dependencies. The quorum is used
+// for every connection, until the connection chooses a different one with the
+// QUORUM command.
+func NewServer(farm farm.Farm,
+	quorum selectors.Quorum,
+	logger log.Logger,
+	clients metrics.Gauge,
+	duration metrics.HistogramVec,
+) *Server {
+	s := &Server{
+		farm:     farm,
+		quorum:   quorum,
+		logger:   logger,
+		clients:  clients,
+		duration: duration,
+		now: func() int64 {
+			return time.Now().UnixNano()
+		},
+		conns: make(map[net.Conn]struct{}),
+	}
+
+	// A positive arity is the exact number of arguments including the command
+	// name and a negative arity is the minimum number of arguments.
+	s.commands = map[string]command{
+		"ping":    {-1, s.ping},
+		"echo":    {2, s.echo},
+		"quit":    {1, s.quit},
+		"select":  {2, s.selectDB},
+		"command": {-1, s.command},
+		"quorum":  {-1, s.setQuorum},
+		"hset":    {-4, s.hset},
+		"hget":    {3, s.hget},
+		"hdel":    {-3, s.hdel},
+		"hkeys":   {2, s.hkeys},
+		"hlen":    {2, s.hlen},
+		"zadd":    {-4, s.zadd},
+		"zscore":  {3, s.zscore},
+		"keys":    {2, s.keys},
+	}
+
+	return s
+}
+
+// Serve accepts connections on the listener, until the listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// Close out the Server, along with any open connections.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	s.clients.Inc()
+	defer s.clients.Dec()
+
+	s.track(conn, true)
+	defer s.track(conn, false)
+	defer conn.Close()
+
+	var (
+		reader  = bufio.NewReader(conn)
+		session = &session{
+			writer: newWriter(conn),
+			quorum: s.quorum,
+		}
+	)
+	for !session.quit {
+		args, err := readCommand(reader)
+		if err != nil {
+			if e, ok := err.(errProtocol); ok {
+				session.writer.Error(errors.Errorf("Protocol error: %s", e.Error()))
+				session.writer.Flush()
+			} else if err != io.EOF {
+				level.Debug(s.logger).Log("err", err, "remote", conn.RemoteAddr())
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		s.execute(session, args)
+
+		// Only flush once all the pipelined commands have been handled.
+		if reader.Buffered() == 0 {
+			if err := session.writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+	session.writer.Flush()
+}
+
+func (s *Server) track(conn net.Conn, open bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if open {
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+}
+
+func (s *Server) execute(session *session, args [][]byte) {
+	var (
+		name   = strings.ToLower(string(args[0]))
+		status = "ok"
+		begin  = time.Now()
+	)
+
+	cmd, ok := s.commands[name]
+	if !ok {
+		name, status = "unknown", "error"
+		session.writer.Error(errors.Errorf("unknown command '%s'", args[0]))
+	} else if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		status = "error"
+		session.writer.Error(errors.Errorf("wrong number of arguments for '%s' command", name))
+	} else if err := cmd.fn(session, args[1:]); err != nil {
+		status = "error"
+		session.writer.Error(err)
+	}
+
+	s.duration.WithLabelValues(
+		"RESP",
+		name,
+		status,
+	).Observe(time.Since(begin).Seconds())
+}
+
+func (s *Server) ping(session *session, args [][]byte) error {
+	switch len(args) {
+	case 0:
+		session.writer.SimpleString("PONG")
+	case 1:
+		session.writer.Bulk(args[0])
+	default:
+		return errors.New("wrong number of arguments for 'ping' command")
+	}
+	return nil
+}
+
+func (s *Server) echo(session *session, args [][]byte) error {
+	session.writer.Bulk(args[0])
+	return nil
+}
+
+func (s *Server) quit(session *session, args [][]byte) error {
+	session.quit = true
+	session.writer.SimpleString("OK")
+	return nil
+}
+
+func (s *Server) selectDB(session *session, args [][]byte) error {
+	if string(args[0]) != "0" {
+		return errors.New("DB index is out of range")
+	}
+	session.writer.SimpleString("OK")
+	return nil
+}
+
+func (s *Server) command(session *session, args [][]byte) error {
+	// Clients ask for the command table when they connect, an empty table is
+	// enough for them to carry on.
+	session.writer.Array(0)
+	return nil
+}
+
+// setQuorum returns the quorum of the connection, or changes it if a quorum is
+// given.
+func (s *Server) setQuorum(session *session, args [][]byte) error {
+	switch len(args) {
+	case 0:
+		session.writer.Bulk([]byte(session.quorum.String()))
+	case 1:
+		quorum, err := selectors.ParseQuorum(strings.ToLower(string(args[0])))
+		if err != nil {
+			return err
+		}
+		session.quorum = quorum
+		session.writer.SimpleString("OK")
+	default:
+		return errors.New("wrong number of arguments for 'quorum' command")
+	}
+	return nil
+}
+
+func (s *Server) hset(session *session, args [][]byte) error {
+	if len(args)%2 != 1 {
+		return errors.New("wrong number of arguments for 'hset' command")
+	}
+
+	var (
+		score   = s.now()
+		members = make([]selectors.FieldValueScore, 0, len(args)/2)
+	)
+	for i := 1; i < len(args); i += 2 {
+		members = append(members, selectors.FieldValueScore{
+			Field: selectors.Field(args[i]),
+			Value: args[i+1],
+			Score: score,
+		})
+	}
+
+	changeSet, err := s.farm.Insert(selectors.Key(args[0]), members, session.quorum)
+	if err != nil {
+		return err
+	}
+	session.writer.Integer(int64(len(changeSet.Success)))
+	return nil
+}
+
+func (s *Server) hget(session *session, args [][]byte) error {
+	member, err := s.farm.Select(selectors.Key(args[0]), selectors.Field(args[1]), session.quorum)
+	if err != nil {
+		if selectors.NotFoundError(err) {
+			session.writer.Null()
+			return nil
+		}
+		return err
+	}
+	session.writer.Bulk(member.Value)
+	return nil
+}
+
+func (s *Server) hdel(session *session, args [][]byte) error {
+	var (
+		score   = s.now()
+		members = make([]selectors.FieldValueScore, 0, len(args)-1)
+	)
+	for _, field := range args[1:] {
+		members = append(members, selectors.FieldValueScore{
+			Field: selectors.Field(field),
+			Score: score,
+		})
+	}
+
+	changeSet, err := s.farm.Delete(selectors.Key(args[0]), members, session.quorum)
+	if err != nil {
+		return err
+	}
+	session.writer.Integer(int64(len(changeSet.Success)))
+	return nil
+}
+
+func (s *Server) hkeys(session *session, args [][]byte) error {
+	fields, err := s.farm.Members(selectors.Key(args[0]), session.quorum)
+	if err != nil && !selectors.NotFoundError(err) {
+		return err
+	}
+
+	session.writer.Array(len(fields))
+	for _, field := range fields {
+		session.writer.Bulk([]byte(field.String()))
+	}
+	return nil
+}
+
+func (s *Server) hlen(session *session, args [][]byte) error {
+	size, err := s.farm.Size(selectors.Key(args[0]), session.quorum)
+	if err != nil && !selectors.NotFoundError(err) {
+		return err
+	}
+	session.writer.Integer(size)
+	return nil
+}
+
+func (s *Server) zadd(session *session, args [][]byte) error {
+	if len(args)%2 != 1 {
+		return errors.New("syntax error")
+	}
+
+	members := make([]selectors.FieldValueScore, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		score, err := strconv.ParseInt(string(args[i]), 10, 64)
+		if err != nil {
+			return errors.New("score is not an integer or out of range")
+		}
+		members = append(members, selectors.FieldValueScore{
+			Field: selectors.Field(args[i+1]),
+			Score: score,
+		})
+	}
+
+	changeSet, err := s.farm.Insert(selectors.Key(args[0]), members, session.quorum)
+	if err != nil {
+		return err
+	}
+	session.writer.Integer(int64(len(changeSet.Success)))
+	return nil
+}
+
+func (s *Server) zscore(session *session, args [][]byte) error {
+	presence, err := s.farm.Score(selectors.Key(args[0]), selectors.Field(args[1]), session.quorum)
+	if err != nil {
+		if selectors.NotFoundError(err) {
+			session.writer.Null()
+			return nil
+		}
+		return err
+	}
+
+	// A deleted member still has a score, but it's not part of the set.
+	if !presence.Present || !presence.Inserted {
+		session.writer.Null()
+		return nil
+	}
+	session.writer.Bulk([]byte(strconv.FormatInt(presence.Score, 10)))
+	return nil
+}
+
+func (s *Server) keys(session *session, args [][]byte) error {
+	keys, err := s.farm.KeysMatching(string(args[0]), session.quorum)
+	if err != nil && !selectors.NotFoundError(err) {
+		return err
+	}
+
+	session.writer.Array(len(keys))
+	for _, key := range keys {
+		session.writer.Bulk([]byte(key.String()))
+	}
+	return nil
+}
@@ -0,0 +1,404 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+
+	farmMocks "github.com/SimonRichardson/coherence/pkg/cluster/farm/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ping", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client, closer := newClient(t, farmMocks.NewMockFarm(ctrl))
+		defer closer()
+
+		if expected, actual := "PONG", client.do("PING"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client, closer := newClient(t, farmMocks.NewMockFarm(ctrl))
+		defer closer()
+
+		if expected, actual := replyError("ERR unknown command 'BAD'"), client.do("BAD"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("wrong number of arguments", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client, closer := newClient(t, farmMocks.NewMockFarm(ctrl))
+		defer closer()
+
+		want := replyError("ERR wrong number of arguments for 'hget' command")
+		if expected, actual := want, client.do("HGET", "key"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("hset", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		members := []selectors.FieldValueScore{
+			{Field: "a", Value: []byte("1"), Score: 10},
+			{Field: "b", Value: []byte("2"), Score: 10},
+		}
+		farm.EXPECT().Insert(selectors.Key("key"), members, selectors.Strong).Return(selectors.ChangeSet{
+			Success: []selectors.Field{"a", "b"},
+		}, nil)
+
+		if expected, actual := int64(2), client.do("HSET", "key", "a", "1", "b", "2"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("hset with error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Insert(selectors.Key("key"), gomock.Any(), selectors.Strong).Return(selectors.ChangeSet{}, errors.New("bad"))
+
+		if expected, actual := replyError("ERR bad"), client.do("HSET", "key", "a", "1"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("hget", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Select(selectors.Key("key"), selectors.Field("a"), selectors.Strong).Return(selectors.FieldValueScore{
+			Field: "a",
+			Value: []byte("1"),
+			Score: 10,
+		}, nil)
+
+		if expected, actual := "1", client.do("HGET", "key", "a"); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("hget not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Select(selectors.Key("key"), selectors.Field("a"), selectors.Strong).Return(selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		if actual := client.do("HGET", "key", "a"); actual != nil {
+			t.Errorf("expected: %v, actual: %v", nil, actual)
+		}
+	})
+
+	t.Run("hdel", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		members := []selectors.FieldValueScore{
+			{Field: "a", Score: 10},
+		}
+		farm.EXPECT().Delete(selectors.Key("key"), members, selectors.Strong).Return(selectors.ChangeSet{
+			Success: []selectors.Field{"a"},
+		}, nil)
+
+		if expected, actual := int64(1), client.do("HDEL", "key", "a"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("hkeys and hlen", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Members(selectors.Key("key"), selectors.Strong).Return([]selectors.Field{"a", "b"}, nil)
+		farm.EXPECT().Size(selectors.Key("key"), selectors.Strong).Return(int64(2), nil)
+
+		if expected, actual := []interface{}{"a", "b"}, client.do("HKEYS", "key"); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := int64(2), client.do("HLEN", "key"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("zadd", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		members := []selectors.FieldValueScore{
+			{Field: "a", Score: 5},
+			{Field: "b", Score: 6},
+		}
+		farm.EXPECT().Insert(selectors.Key("key"), members, selectors.Strong).Return(selectors.ChangeSet{
+			Success: []selectors.Field{"a", "b"},
+		}, nil)
+
+		if expected, actual := int64(2), client.do("ZADD", "key", "5", "a", "6", "b"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("zadd with invalid score", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client, closer := newClient(t, farmMocks.NewMockFarm(ctrl))
+		defer closer()
+
+		if _, ok := client.do("ZADD", "key", "1.5", "a").(replyError); !ok {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("zscore", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Score(selectors.Key("key"), selectors.Field("a"), selectors.Strong).Return(selectors.Presence{
+			Present:  true,
+			Inserted: true,
+			Score:    5,
+		}, nil)
+		farm.EXPECT().Score(selectors.Key("key"), selectors.Field("b"), selectors.Strong).Return(selectors.Presence{
+			Present:  true,
+			Inserted: false,
+			Score:    6,
+		}, nil)
+
+		if expected, actual := "5", client.do("ZSCORE", "key", "a"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if actual := client.do("ZSCORE", "key", "b"); actual != nil {
+			t.Errorf("expected: %v, actual: %v", nil, actual)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().KeysMatching("user:*", selectors.Strong).Return([]selectors.Key{"user:1", "user:2"}, nil)
+
+		if expected, actual := []interface{}{"user:1", "user:2"}, client.do("KEYS", "user:*"); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("quorum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Select(selectors.Key("key"), selectors.Field("a"), selectors.One).Return(selectors.FieldValueScore{
+			Value: []byte("1"),
+		}, nil)
+
+		if expected, actual := "strong", client.do("QUORUM"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "OK", client.do("QUORUM", "one"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if _, ok := client.do("QUORUM", "bad").(replyError); !ok {
+			t.Errorf("expected error")
+		}
+		if expected, actual := "1", client.do("HGET", "key", "a"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("pipelined", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		farm := farmMocks.NewMockFarm(ctrl)
+		client, closer := newClient(t, farm)
+		defer closer()
+
+		farm.EXPECT().Size(gomock.Any(), selectors.Strong).Return(int64(1), nil).Times(10)
+
+		for i := 0; i < 10; i++ {
+			client.send("HLEN", fmt.Sprintf("key-%d", i))
+		}
+		for i := 0; i < 10; i++ {
+			if expected, actual := int64(1), client.reply(); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+	})
+
+	t.Run("inline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client, closer := newClient(t, farmMocks.NewMockFarm(ctrl))
+		defer closer()
+
+		if _, err := client.conn.Write([]byte("ECHO hello\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := "hello", client.reply(); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+type replyError string
+
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newClient(t *testing.T, farm *farmMocks.MockFarm) (*client, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(farm,
+		selectors.Strong,
+		log.NewNopLogger(),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+	)
+	server.now = func() int64 { return 10 }
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &client{
+		t:      t,
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}, func() {
+		conn.Close()
+		listener.Close()
+		server.Close()
+	}
+}
+
+func (c *client) do(args ...string) interface{} {
+	c.send(args...)
+	return c.reply()
+}
+
+func (c *client) send(args ...string) {
+	buf := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		buf += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, buf); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) reply() interface{} {
+	line, err := readLine(c.reader)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:])
+	case '-':
+		return replyError(line[1:])
+	case ':':
+		i, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		return i
+	case '$':
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if size < 0 {
+			return nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			c.t.Fatal(err)
+		}
+		return string(buf[:size])
+	case '*':
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		res := make([]interface{}, size)
+		for k := range res {
+			res[k] = c.reply()
+		}
+		return res
+	default:
+		c.t.Fatalf("unexpected reply %q", line)
+		return nil
+	}
+}
@@ -0,0 +1,148 @@
+package rpc
+
+import "github.com/SimonRichardson/coherence/pkg/selectors"
+
+// FromMembers converts the members in to their wire format.
+func FromMembers(members []selectors.FieldValueScore) []*FieldValueScore {
+	res := make([]*FieldValueScore, len(members))
+	for k, v := range members {
+		res[k] = FromMember(v)
+	}
+	return res
+}
+
+// FromMember converts the member in to its wire format.
+func FromMember(member selectors.FieldValueScore) *FieldValueScore {
+	return &FieldValueScore{
+		Field: member.Field.String(),
+		Value: member.Value,
+		Score: member.Score,
+	}
+}
+
+// ToMembers converts the members from their wire format.
+func ToMembers(members []*FieldValueScore) []selectors.FieldValueScore {
+	res := make([]selectors.FieldValueScore, len(members))
+	for k, v := range members {
+		res[k] = ToMember(v)
+	}
+	return res
+}
+
+// ToMember converts the member from its wire format.
+func ToMember(member *FieldValueScore) selectors.FieldValueScore {
+	return selectors.FieldValueScore{
+		Field: selectors.Field(member.GetField()),
+		Value: member.GetValue(),
+		Score: member.GetScore(),
+	}
+}
+
+// FromFields converts the fields in to their wire format.
+func FromFields(fields []selectors.Field) []string {
+	res := make([]string, len(fields))
+	for k, v := range fields {
+		res[k] = v.String()
+	}
+	return res
+}
+
+// ToFields converts the fields from their wire format.
+func ToFields(fields []string) []selectors.Field {
+	res := make([]selectors.Field, len(fields))
+	for k, v := range fields {
+		res[k] = selectors.Field(v)
+	}
+	return res
+}
+
+// FromKeys converts the keys in to their wire format.
+func FromKeys(keys []selectors.Key) []string {
+	res := make([]string, len(keys))
+	for k, v := range keys {
+		res[k] = v.String()
+	}
+	return res
+}
+
+// ToKeys converts the keys from their wire format.
+func ToKeys(keys []string) []selectors.Key {
+	res := make([]selectors.Key, len(keys))
+	for k, v := range keys {
+		res[k] = selectors.Key(v)
+	}
+	return res
+}
+
+// FromResume converts where a watch resumes from in to its wire format.
+func FromResume(resume selectors.Resume) (WatchRequest_Resume, int64) {
+	switch resume.Type {
+	case selectors.ResumeOffset:
+		return WatchRequest_OFFSET, resume.Value
+	case selectors.ResumeScore:
+		return WatchRequest_SCORE, resume.Value
+	default:
+		return WatchRequest_NONE, 0
+	}
+}
+
+// ToResume converts where a watch resumes from, from its wire format.
+func ToResume(resume WatchRequest_Resume, value int64) selectors.Resume {
+	switch resume {
+	case WatchRequest_OFFSET:
+		return selectors.Resume{Type: selectors.ResumeOffset, Value: value}
+	case WatchRequest_SCORE:
+		return selectors.Resume{Type: selectors.ResumeScore, Value: value}
+	default:
+		return selectors.Resume{}
+	}
+}
+
+// FromEvent converts the event in to its wire format.
+func FromEvent(event selectors.Event) *WatchEvent {
+	return &WatchEvent{
+		Offset: event.Offset,
+		Type:   event.Type.String(),
+		Key:    event.Key.String(),
+		Member: &FieldValueScore{
+			Field: event.Field.String(),
+			Value: event.Value,
+			Score: event.Score,
+		},
+	}
+}
+
+// ToEvent converts the event from its wire format.
+func ToEvent(event *WatchEvent) selectors.Event {
+	member := ToMember(event.GetMember())
+	return selectors.Event{
+		Offset: event.GetOffset(),
+		Type:   selectors.EventType(event.GetType()),
+		Key:    selectors.Key(event.GetKey()),
+		Field:  member.Field,
+		Value:  member.Value,
+		Score:  member.Score,
+	}
+}
+
+// FromScan converts the cursor and options of a scan in to their wire format.
+func FromScan(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) *ScanRequest {
+	return &ScanRequest{
+		Key:    key.String(),
+		Cursor: cursor.String(),
+		Match:  opts.Match,
+		Count:  int32(opts.Count),
+	}
+}
+
+// ToScan converts the cursor and options of a scan from their wire format.
+func ToScan(req *ScanRequest) (selectors.Cursor, selectors.ScanOptions, error) {
+	cursor := selectors.Cursor(req.GetCursor())
+	if _, _, err := cursor.After(); err != nil {
+		return "", selectors.ScanOptions{}, err
+	}
+	return cursor, selectors.ScanOptions{
+		Match: req.GetMatch(),
+		Count: int(req.GetCount()),
+	}, nil
+}
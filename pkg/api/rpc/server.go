@@ -0,0 +1,355 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/api/writes"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultPageSize is the number of keys or members that are sent in each
+	// message of a stream, when the request doesn't specify a page size.
+	DefaultPageSize = 1000
+)
+
+// Server serves the Store service with the local store.
+type Server struct {
+	store  store.Store
+	logger log.Logger
+	writes *writes.Queue
+}
+
+// NewServer creates a Server with the correct dependencies.
+func NewServer(store store.Store, logger log.Logger) *Server {
+	server := &Server{
+		store:  store,
+		logger: logger,
+		writes: writes.NewQueue(),
+	}
+
+	return server
+}
+
+// Register the Server with the gRPC server, along with interceptors to record
+// the same metrics as the HTTP API.
+func Register(server *Server, clients metrics.Gauge, duration metrics.HistogramVec) *grpc.Server {
+	res := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryInterceptor(clients, duration)),
+		grpc.StreamInterceptor(streamInterceptor(clients, duration)),
+	)
+	RegisterStoreServer(res, server)
+	return res
+}
+
+// Close out the Server
+func (s *Server) Close() {
+	s.writes.Close()
+}
+
+// Insert takes a key and members and stores them with in the store.
+func (s *Server) Insert(ctx context.Context, req *WriteRequest) (*ChangeSetResponse, error) {
+	return s.write(ctx, req, s.store.Insert)
+}
+
+// Delete removes the members associated with the key.
+func (s *Server) Delete(ctx context.Context, req *WriteRequest) (*ChangeSetResponse, error) {
+	return s.write(ctx, req, s.store.Delete)
+}
+
+// Swap inserts the member only if its field is inserted with the score of the
+// token.
+func (s *Server) Swap(ctx context.Context, req *SwapRequest) (*ChangeSetResponse, error) {
+	if req.GetMember() == nil {
+		return nil, status.Error(codes.InvalidArgument, "expected a member to swap")
+	}
+
+	write := &WriteRequest{
+		Key:     req.GetKey(),
+		Members: []*FieldValueScore{req.GetMember()},
+	}
+	return s.write(ctx, write, func(key selectors.Key, members []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+		return s.store.Swap(key, members[0], req.GetToken())
+	})
+}
+
+// Select retrieves a field, value and score associated with the key.
+func (s *Server) Select(ctx context.Context, req *FieldRequest) (*FieldValueScoreResponse, error) {
+	if req.GetKey() == "" {
+		return nil, errKey(req.GetKey())
+	}
+
+	member, err := s.store.Select(selectors.Key(req.GetKey()), selectors.Field(req.GetField()))
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &FieldValueScoreResponse{
+		Member: FromMember(member),
+	}, nil
+}
+
+// Keys returns all the keys that are stored with in the store, split in to
+// pages.
+func (s *Server) Keys(req *KeysRequest, stream Store_KeysServer) error {
+	keys, err := s.store.Keys()
+	if err != nil {
+		return s.error(err)
+	}
+
+	values := FromKeys(keys)
+	return page(len(values), pageSize(req.GetPageSize()), func(from, to int) error {
+		return stream.Send(&KeysResponse{
+			Keys: values[from:to],
+		})
+	})
+}
+
+// Size returns the number of members for the key.
+func (s *Server) Size(ctx context.Context, req *KeyRequest) (*SizeResponse, error) {
+	if req.GetKey() == "" {
+		return nil, errKey(req.GetKey())
+	}
+
+	size, err := s.store.Size(selectors.Key(req.GetKey()))
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &SizeResponse{
+		Size: size,
+	}, nil
+}
+
+// Members returns the members associated with the key, split in to pages.
+func (s *Server) Members(req *MembersRequest, stream Store_MembersServer) error {
+	if req.GetKey() == "" {
+		return errKey(req.GetKey())
+	}
+
+	members, err := s.store.Members(selectors.Key(req.GetKey()))
+	if err != nil {
+		return s.error(err)
+	}
+
+	values := FromFields(members)
+	return page(len(values), pageSize(req.GetPageSize()), func(from, to int) error {
+		return stream.Send(&MembersResponse{
+			Fields: values[from:to],
+		})
+	})
+}
+
+// Score returns the score and existence of a field with in the key.
+func (s *Server) Score(ctx context.Context, req *FieldRequest) (*PresenceResponse, error) {
+	if req.GetKey() == "" {
+		return nil, errKey(req.GetKey())
+	}
+
+	presence, err := s.store.Score(selectors.Key(req.GetKey()), selectors.Field(req.GetField()))
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &PresenceResponse{
+		Present:  presence.Present,
+		Inserted: presence.Inserted,
+		Score:    presence.Score,
+	}, nil
+}
+
+// Watch streams the changes of the key as they're applied to the store.
+func (s *Server) Watch(req *WatchRequest, stream Store_WatchServer) error {
+	if req.GetKey() == "" {
+		return errKey(req.GetKey())
+	}
+
+	resume := ToResume(req.GetResume(), req.GetResumeValue())
+	sub, err := s.store.Watch(selectors.Key(req.GetKey()), resume)
+	if err != nil {
+		if selectors.ExpiredError(err) {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+		return s.error(err)
+	}
+	defer sub.Close()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					return status.Error(codes.Aborted, err.Error())
+				}
+				return nil
+			}
+			if err := stream.Send(FromEvent(event)); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// Scan returns the next page of keys after the cursor.
+func (s *Server) Scan(ctx context.Context, req *ScanRequest) (*ScanResponse, error) {
+	cursor, opts, err := ToScan(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	page, err := s.store.Scan(cursor, opts)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &ScanResponse{
+		Values: FromKeys(page.Keys),
+		Cursor: page.Cursor.String(),
+	}, nil
+}
+
+// ScanMembers returns the next page of members for the key after the cursor.
+func (s *Server) ScanMembers(ctx context.Context, req *ScanRequest) (*ScanResponse, error) {
+	if req.GetKey() == "" {
+		return nil, errKey(req.GetKey())
+	}
+
+	cursor, opts, err := ToScan(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	page, err := s.store.ScanMembers(selectors.Key(req.GetKey()), cursor, opts)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return &ScanResponse{
+		Values: FromFields(page.Fields),
+		Cursor: page.Cursor.String(),
+	}, nil
+}
+
+func (s *Server) write(ctx context.Context,
+	req *WriteRequest,
+	fn func(selectors.Key, []selectors.FieldValueScore) (selectors.ChangeSet, error),
+) (*ChangeSetResponse, error) {
+	if req.GetKey() == "" {
+		return nil, errKey(req.GetKey())
+	}
+
+	var (
+		key     = selectors.Key(req.GetKey())
+		members = ToMembers(req.GetMembers())
+
+		internalError = make(chan error, 1)
+		result        = make(chan selectors.ChangeSet, 1)
+	)
+	if err := s.writes.Apply(key, func() {
+		changeSet, err := fn(key, members)
+		if err != nil {
+			internalError <- err
+			return
+		}
+		result <- changeSet
+	}); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	// The write is still applied if the caller goes away, as it's already
+	// been queued.
+	select {
+	case err := <-internalError:
+		return nil, s.error(err)
+	case changeSet := <-result:
+		return &ChangeSetResponse{
+			Success: FromFields(changeSet.Success),
+			Failure: FromFields(changeSet.Failure),
+		}, nil
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (s *Server) error(err error) error {
+	if selectors.NotFoundError(err) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	level.Error(s.logger).Log("err", err)
+	return status.Error(codes.Internal, err.Error())
+}
+
+func errKey(key string) error {
+	return status.Errorf(codes.InvalidArgument, "expected 'key' but got %q", key)
+}
+
+func pageSize(size int32) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	return int(size)
+}
+
+// page calls the function for each page of the total, an empty result still
+// sends a page, so that the receiver can tell the difference between no
+// results and a failure.
+func page(total, size int, fn func(from, to int) error) error {
+	if total == 0 {
+		return fn(0, 0)
+	}
+
+	for from := 0; from < total; from += size {
+		to := from + size
+		if to > total {
+			to = total
+		}
+		if err := fn(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unaryInterceptor(clients metrics.Gauge, duration metrics.HistogramVec) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		clients.Inc()
+		defer clients.Dec()
+
+		begin := time.Now()
+		resp, err := handler(ctx, req)
+		observe(duration, info.FullMethod, err, begin)
+		return resp, err
+	}
+}
+
+func streamInterceptor(clients metrics.Gauge, duration metrics.HistogramVec) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		clients.Inc()
+		defer clients.Dec()
+
+		begin := time.Now()
+		err := handler(srv, stream)
+		observe(duration, info.FullMethod, err, begin)
+		return err
+	}
+}
+
+func observe(duration metrics.HistogramVec, method string, err error, begin time.Time) {
+	duration.WithLabelValues(
+		"GRPC",
+		method,
+		status.Code(err).String(),
+	).Observe(time.Since(begin).Seconds())
+}
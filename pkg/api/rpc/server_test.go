@@ -0,0 +1,476 @@
+package rpc
+
+import (
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	storeImpl "github.com/SimonRichardson/coherence/pkg/store"
+	storeMocks "github.com/SimonRichardson/coherence/pkg/store/mocks"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServerInsert(t *testing.T) {
+	t.Parallel()
+
+	t.Run("insert with no key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store  = storeMocks.NewMockStore(ctrl)
+			server = NewServer(store, log.NewNopLogger())
+		)
+		defer server.Close()
+
+		_, err := server.Insert(context.Background(), &WriteRequest{})
+		if expected, actual := codes.InvalidArgument, status.Code(err); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("insert with error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			if key.String() == "" {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+			)
+			defer server.Close()
+
+			store.EXPECT().Insert(key, normalise(members)).Return(selectors.ChangeSet{}, errors.New("bad"))
+
+			_, err := server.Insert(context.Background(), &WriteRequest{
+				Key:     key.String(),
+				Members: FromMembers(members),
+			})
+			return status.Code(err) == codes.Internal
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			if key.String() == "" {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+
+				changeSet = selectors.ChangeSet{
+					Success: extractFields(members),
+					Failure: make([]selectors.Field, 0),
+				}
+			)
+			defer server.Close()
+
+			store.EXPECT().Insert(key, normalise(members)).Return(changeSet, nil)
+
+			res, err := server.Insert(context.Background(), &WriteRequest{
+				Key:     key.String(),
+				Members: FromMembers(members),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return reflect.DeepEqual(FromFields(changeSet.Success), res.GetSuccess())
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestServerSelect(t *testing.T) {
+	t.Parallel()
+
+	t.Run("select with not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, field selectors.Field) bool {
+			if key.String() == "" {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+			)
+			defer server.Close()
+
+			store.EXPECT().Select(key, field).Return(selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("bad")))
+
+			_, err := server.Select(context.Background(), &FieldRequest{
+				Key:   key.String(),
+				Field: field.String(),
+			})
+			return status.Code(err) == codes.NotFound
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("select", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, member selectors.FieldValueScore) bool {
+			if key.String() == "" {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+			)
+			defer server.Close()
+
+			store.EXPECT().Select(key, member.Field).Return(member, nil)
+
+			res, err := server.Select(context.Background(), &FieldRequest{
+				Key:   key.String(),
+				Field: member.Field.String(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return reflect.DeepEqual(member, ToMember(res.GetMember()))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestServerKeys(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keys with error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store  = storeMocks.NewMockStore(ctrl)
+			server = NewServer(store, log.NewNopLogger())
+			stream = &keysStream{}
+		)
+		defer server.Close()
+
+		store.EXPECT().Keys().Return(nil, errors.New("bad"))
+
+		err := server.Keys(&KeysRequest{}, stream)
+		if expected, actual := codes.Internal, status.Code(err); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("keys with no keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store  = storeMocks.NewMockStore(ctrl)
+			server = NewServer(store, log.NewNopLogger())
+			stream = &keysStream{}
+		)
+		defer server.Close()
+
+		store.EXPECT().Keys().Return(nil, nil)
+
+		if err := server.Keys(&KeysRequest{}, stream); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 1, len(stream.pages); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("keys in pages", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(keys []selectors.Key, size uint8) bool {
+			if len(keys) == 0 {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+				stream = &keysStream{}
+
+				pageSize = int(size%5) + 1
+			)
+			defer server.Close()
+
+			store.EXPECT().Keys().Return(keys, nil)
+
+			if err := server.Keys(&KeysRequest{PageSize: int32(pageSize)}, stream); err != nil {
+				t.Fatal(err)
+			}
+
+			var res []selectors.Key
+			for _, page := range stream.pages {
+				if len(page.GetKeys()) > pageSize {
+					t.Errorf("expected: %v, actual: %v", pageSize, len(page.GetKeys()))
+				}
+				res = append(res, ToKeys(page.GetKeys())...)
+			}
+
+			want := (len(keys) + pageSize - 1) / pageSize
+			return len(stream.pages) == want && reflect.DeepEqual(keys, res)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestServerMembers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("members with no key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store  = storeMocks.NewMockStore(ctrl)
+			server = NewServer(store, log.NewNopLogger())
+			stream = &membersStream{}
+		)
+		defer server.Close()
+
+		err := server.Members(&MembersRequest{}, stream)
+		if expected, actual := codes.InvalidArgument, status.Code(err); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("members in pages", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, fields []selectors.Field, size uint8) bool {
+			if key.String() == "" || len(fields) == 0 {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+				stream = &membersStream{}
+
+				pageSize = int(size%5) + 1
+			)
+			defer server.Close()
+
+			store.EXPECT().Members(key).Return(fields, nil)
+
+			if err := server.Members(&MembersRequest{
+				Key:      key.String(),
+				PageSize: int32(pageSize),
+			}, stream); err != nil {
+				t.Fatal(err)
+			}
+
+			var res []selectors.Field
+			for _, page := range stream.pages {
+				res = append(res, ToFields(page.GetFields())...)
+			}
+			return reflect.DeepEqual(fields, res)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestServerScore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("score", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, field selectors.Field, presence selectors.Presence) bool {
+			if key.String() == "" {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+			)
+			defer server.Close()
+
+			store.EXPECT().Score(key, field).Return(presence, nil)
+
+			res, err := server.Score(context.Background(), &FieldRequest{
+				Key:   key.String(),
+				Field: field.String(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return res.GetPresent() == presence.Present &&
+				res.GetInserted() == presence.Inserted &&
+				res.GetScore() == presence.Score
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestServerWatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("watch with no key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store  = storeMocks.NewMockStore(ctrl)
+			server = NewServer(store, log.NewNopLogger())
+		)
+		defer server.Close()
+
+		err := server.Watch(&WatchRequest{}, &watchStream{})
+		if expected, actual := codes.InvalidArgument, status.Code(err); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("watch events", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			if key.String() == "" || len(members) == 0 {
+				return true
+			}
+
+			var (
+				store  = storeMocks.NewMockStore(ctrl)
+				server = NewServer(store, log.NewNopLogger())
+				feed   = storeImpl.NewFeed(len(members))
+
+				ctx, cancel = context.WithCancel(context.Background())
+				stream      = &watchStream{ctx: ctx, cancel: cancel, amount: len(members)}
+			)
+			defer server.Close()
+
+			var expected []selectors.Event
+			for _, member := range normalise(members) {
+				expected = append(expected, feed.Publish(selectors.Event{
+					Type:  selectors.EventInsert,
+					Key:   key,
+					Field: member.Field,
+					Value: member.Value,
+					Score: member.Score,
+				}))
+			}
+			sub, err := feed.Subscribe(key, selectors.ResumeFromOffset(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store.EXPECT().Watch(key, selectors.ResumeFromOffset(0)).Return(sub, nil)
+
+			err = server.Watch(&WatchRequest{
+				Key:    key.String(),
+				Resume: WatchRequest_OFFSET,
+			}, stream)
+			if expected, actual := codes.Canceled, status.Code(err); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+
+			var res []selectors.Event
+			for _, event := range stream.events {
+				res = append(res, ToEvent(event))
+			}
+			return reflect.DeepEqual(expected, res)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+type keysStream struct {
+	grpc.ServerStream
+	pages []*KeysResponse
+}
+
+func (s *keysStream) Send(page *KeysResponse) error {
+	s.pages = append(s.pages, page)
+	return nil
+}
+
+type membersStream struct {
+	grpc.ServerStream
+	pages []*MembersResponse
+}
+
+func (s *membersStream) Send(page *MembersResponse) error {
+	s.pages = append(s.pages, page)
+	return nil
+}
+
+// watchStream cancels the context once the amount of events have been sent.
+type watchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	amount int
+	events []*WatchEvent
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(event *WatchEvent) error {
+	s.events = append(s.events, event)
+	if len(s.events) == s.amount {
+		s.cancel()
+	}
+	return nil
+}
+
+// normalise the members so that they match the members after they've been
+// through the wire format.
+func normalise(members []selectors.FieldValueScore) []selectors.FieldValueScore {
+	return ToMembers(FromMembers(members))
+}
+
+func extractFields(members []selectors.FieldValueScore) []selectors.Field {
+	res := make([]selectors.Field, len(members))
+	for k, v := range members {
+		res[k] = v.Field
+	}
+	return res
+}
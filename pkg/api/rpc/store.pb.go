@@ -0,0 +1,1088 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: store.proto
+
+/*
+Package rpc is a generated protocol buffer package.
+
+It is generated from these files:
+
+	store.proto
+
+It has these top-level messages:
+
+	FieldValueScore
+	WriteRequest
+	KeyRequest
+	FieldRequest
+	KeysRequest
+	MembersRequest
+	ChangeSetResponse
+	FieldValueScoreResponse
+	KeysResponse
+	SizeResponse
+	MembersResponse
+	PresenceResponse
+	WatchRequest
+	WatchEvent
+	ScanRequest
+	ScanResponse
+	SwapRequest
+*/
+package rpc
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type WatchRequest_Resume int32
+
+const (
+	WatchRequest_NONE   WatchRequest_Resume = 0
+	WatchRequest_OFFSET WatchRequest_Resume = 1
+	WatchRequest_SCORE  WatchRequest_Resume = 2
+)
+
+var WatchRequest_Resume_name = map[int32]string{
+	0: "NONE",
+	1: "OFFSET",
+	2: "SCORE",
+}
+var WatchRequest_Resume_value = map[string]int32{
+	"NONE":   0,
+	"OFFSET": 1,
+	"SCORE":  2,
+}
+
+func (x WatchRequest_Resume) String() string {
+	return proto.EnumName(WatchRequest_Resume_name, int32(x))
+}
+func (WatchRequest_Resume) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{12, 0} }
+
+type FieldValueScore struct {
+	Field string `protobuf:"bytes,1,opt,name=field" json:"field,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Score int64  `protobuf:"varint,3,opt,name=score" json:"score,omitempty"`
+}
+
+func (m *FieldValueScore) Reset()                    { *m = FieldValueScore{} }
+func (m *FieldValueScore) String() string            { return proto.CompactTextString(m) }
+func (*FieldValueScore) ProtoMessage()               {}
+func (*FieldValueScore) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *FieldValueScore) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *FieldValueScore) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *FieldValueScore) GetScore() int64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+type WriteRequest struct {
+	Key     string             `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Members []*FieldValueScore `protobuf:"bytes,2,rep,name=members" json:"members,omitempty"`
+}
+
+func (m *WriteRequest) Reset()                    { *m = WriteRequest{} }
+func (m *WriteRequest) String() string            { return proto.CompactTextString(m) }
+func (*WriteRequest) ProtoMessage()               {}
+func (*WriteRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *WriteRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *WriteRequest) GetMembers() []*FieldValueScore {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+type KeyRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *KeyRequest) Reset()                    { *m = KeyRequest{} }
+func (m *KeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*KeyRequest) ProtoMessage()               {}
+func (*KeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *KeyRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type FieldRequest struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Field string `protobuf:"bytes,2,opt,name=field" json:"field,omitempty"`
+}
+
+func (m *FieldRequest) Reset()                    { *m = FieldRequest{} }
+func (m *FieldRequest) String() string            { return proto.CompactTextString(m) }
+func (*FieldRequest) ProtoMessage()               {}
+func (*FieldRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *FieldRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *FieldRequest) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+type KeysRequest struct {
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+}
+
+func (m *KeysRequest) Reset()                    { *m = KeysRequest{} }
+func (m *KeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*KeysRequest) ProtoMessage()               {}
+func (*KeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *KeysRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+type MembersRequest struct {
+	Key      string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	PageSize int32  `protobuf:"varint,2,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+}
+
+func (m *MembersRequest) Reset()                    { *m = MembersRequest{} }
+func (m *MembersRequest) String() string            { return proto.CompactTextString(m) }
+func (*MembersRequest) ProtoMessage()               {}
+func (*MembersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *MembersRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *MembersRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+type ChangeSetResponse struct {
+	Success []string `protobuf:"bytes,1,rep,name=success" json:"success,omitempty"`
+	Failure []string `protobuf:"bytes,2,rep,name=failure" json:"failure,omitempty"`
+}
+
+func (m *ChangeSetResponse) Reset()                    { *m = ChangeSetResponse{} }
+func (m *ChangeSetResponse) String() string            { return proto.CompactTextString(m) }
+func (*ChangeSetResponse) ProtoMessage()               {}
+func (*ChangeSetResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ChangeSetResponse) GetSuccess() []string {
+	if m != nil {
+		return m.Success
+	}
+	return nil
+}
+
+func (m *ChangeSetResponse) GetFailure() []string {
+	if m != nil {
+		return m.Failure
+	}
+	return nil
+}
+
+type FieldValueScoreResponse struct {
+	Member *FieldValueScore `protobuf:"bytes,1,opt,name=member" json:"member,omitempty"`
+}
+
+func (m *FieldValueScoreResponse) Reset()                    { *m = FieldValueScoreResponse{} }
+func (m *FieldValueScoreResponse) String() string            { return proto.CompactTextString(m) }
+func (*FieldValueScoreResponse) ProtoMessage()               {}
+func (*FieldValueScoreResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *FieldValueScoreResponse) GetMember() *FieldValueScore {
+	if m != nil {
+		return m.Member
+	}
+	return nil
+}
+
+type KeysResponse struct {
+	Keys []string `protobuf:"bytes,1,rep,name=keys" json:"keys,omitempty"`
+}
+
+func (m *KeysResponse) Reset()                    { *m = KeysResponse{} }
+func (m *KeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*KeysResponse) ProtoMessage()               {}
+func (*KeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *KeysResponse) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type SizeResponse struct {
+	Size int64 `protobuf:"varint,1,opt,name=size" json:"size,omitempty"`
+}
+
+func (m *SizeResponse) Reset()                    { *m = SizeResponse{} }
+func (m *SizeResponse) String() string            { return proto.CompactTextString(m) }
+func (*SizeResponse) ProtoMessage()               {}
+func (*SizeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *SizeResponse) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type MembersResponse struct {
+	Fields []string `protobuf:"bytes,1,rep,name=fields" json:"fields,omitempty"`
+}
+
+func (m *MembersResponse) Reset()                    { *m = MembersResponse{} }
+func (m *MembersResponse) String() string            { return proto.CompactTextString(m) }
+func (*MembersResponse) ProtoMessage()               {}
+func (*MembersResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *MembersResponse) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type PresenceResponse struct {
+	Present  bool  `protobuf:"varint,1,opt,name=present" json:"present,omitempty"`
+	Inserted bool  `protobuf:"varint,2,opt,name=inserted" json:"inserted,omitempty"`
+	Score    int64 `protobuf:"varint,3,opt,name=score" json:"score,omitempty"`
+}
+
+func (m *PresenceResponse) Reset()                    { *m = PresenceResponse{} }
+func (m *PresenceResponse) String() string            { return proto.CompactTextString(m) }
+func (*PresenceResponse) ProtoMessage()               {}
+func (*PresenceResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *PresenceResponse) GetPresent() bool {
+	if m != nil {
+		return m.Present
+	}
+	return false
+}
+
+func (m *PresenceResponse) GetInserted() bool {
+	if m != nil {
+		return m.Inserted
+	}
+	return false
+}
+
+func (m *PresenceResponse) GetScore() int64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	Key         string              `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Resume      WatchRequest_Resume `protobuf:"varint,2,opt,name=resume,enum=rpc.WatchRequest_Resume" json:"resume,omitempty"`
+	ResumeValue int64               `protobuf:"varint,3,opt,name=resume_value,json=resumeValue" json:"resume_value,omitempty"`
+}
+
+func (m *WatchRequest) Reset()                    { *m = WatchRequest{} }
+func (m *WatchRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()               {}
+func (*WatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *WatchRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetResume() WatchRequest_Resume {
+	if m != nil {
+		return m.Resume
+	}
+	return WatchRequest_NONE
+}
+
+func (m *WatchRequest) GetResumeValue() int64 {
+	if m != nil {
+		return m.ResumeValue
+	}
+	return 0
+}
+
+type WatchEvent struct {
+	Offset uint64           `protobuf:"varint,1,opt,name=offset" json:"offset,omitempty"`
+	Type   string           `protobuf:"bytes,2,opt,name=type" json:"type,omitempty"`
+	Key    string           `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	Member *FieldValueScore `protobuf:"bytes,4,opt,name=member" json:"member,omitempty"`
+}
+
+func (m *WatchEvent) Reset()                    { *m = WatchEvent{} }
+func (m *WatchEvent) String() string            { return proto.CompactTextString(m) }
+func (*WatchEvent) ProtoMessage()               {}
+func (*WatchEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *WatchEvent) GetOffset() uint64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *WatchEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *WatchEvent) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *WatchEvent) GetMember() *FieldValueScore {
+	if m != nil {
+		return m.Member
+	}
+	return nil
+}
+
+type ScanRequest struct {
+	Key    string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Cursor string `protobuf:"bytes,2,opt,name=cursor" json:"cursor,omitempty"`
+	Match  string `protobuf:"bytes,3,opt,name=match" json:"match,omitempty"`
+	Count  int32  `protobuf:"varint,4,opt,name=count" json:"count,omitempty"`
+}
+
+func (m *ScanRequest) Reset()                    { *m = ScanRequest{} }
+func (m *ScanRequest) String() string            { return proto.CompactTextString(m) }
+func (*ScanRequest) ProtoMessage()               {}
+func (*ScanRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *ScanRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetMatch() string {
+	if m != nil {
+		return m.Match
+	}
+	return ""
+}
+
+func (m *ScanRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type ScanResponse struct {
+	Values []string `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
+	Cursor string   `protobuf:"bytes,2,opt,name=cursor" json:"cursor,omitempty"`
+}
+
+func (m *ScanResponse) Reset()                    { *m = ScanResponse{} }
+func (m *ScanResponse) String() string            { return proto.CompactTextString(m) }
+func (*ScanResponse) ProtoMessage()               {}
+func (*ScanResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ScanResponse) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *ScanResponse) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+type SwapRequest struct {
+	Key    string           `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Member *FieldValueScore `protobuf:"bytes,2,opt,name=member" json:"member,omitempty"`
+	Token  int64            `protobuf:"varint,3,opt,name=token" json:"token,omitempty"`
+}
+
+func (m *SwapRequest) Reset()                    { *m = SwapRequest{} }
+func (m *SwapRequest) String() string            { return proto.CompactTextString(m) }
+func (*SwapRequest) ProtoMessage()               {}
+func (*SwapRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *SwapRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SwapRequest) GetMember() *FieldValueScore {
+	if m != nil {
+		return m.Member
+	}
+	return nil
+}
+
+func (m *SwapRequest) GetToken() int64 {
+	if m != nil {
+		return m.Token
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*FieldValueScore)(nil), "rpc.FieldValueScore")
+	proto.RegisterType((*WriteRequest)(nil), "rpc.WriteRequest")
+	proto.RegisterType((*KeyRequest)(nil), "rpc.KeyRequest")
+	proto.RegisterType((*FieldRequest)(nil), "rpc.FieldRequest")
+	proto.RegisterType((*KeysRequest)(nil), "rpc.KeysRequest")
+	proto.RegisterType((*MembersRequest)(nil), "rpc.MembersRequest")
+	proto.RegisterType((*ChangeSetResponse)(nil), "rpc.ChangeSetResponse")
+	proto.RegisterType((*FieldValueScoreResponse)(nil), "rpc.FieldValueScoreResponse")
+	proto.RegisterType((*KeysResponse)(nil), "rpc.KeysResponse")
+	proto.RegisterType((*SizeResponse)(nil), "rpc.SizeResponse")
+	proto.RegisterType((*MembersResponse)(nil), "rpc.MembersResponse")
+	proto.RegisterType((*PresenceResponse)(nil), "rpc.PresenceResponse")
+	proto.RegisterType((*WatchRequest)(nil), "rpc.WatchRequest")
+	proto.RegisterType((*WatchEvent)(nil), "rpc.WatchEvent")
+	proto.RegisterType((*ScanRequest)(nil), "rpc.ScanRequest")
+	proto.RegisterType((*ScanResponse)(nil), "rpc.ScanResponse")
+	proto.RegisterType((*SwapRequest)(nil), "rpc.SwapRequest")
+	proto.RegisterEnum("rpc.WatchRequest_Resume", WatchRequest_Resume_name, WatchRequest_Resume_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Store service
+
+type StoreClient interface {
+	// Insert takes a key and members and stores them with in the store.
+	Insert(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*ChangeSetResponse, error)
+	// Delete removes the members associated with the key.
+	Delete(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*ChangeSetResponse, error)
+	// Select retrieves a field, value and score associated with the key.
+	Select(ctx context.Context, in *FieldRequest, opts ...grpc.CallOption) (*FieldValueScoreResponse, error)
+	// Keys returns all the keys that are stored with in the store, split in to
+	// pages of at most page_size keys.
+	Keys(ctx context.Context, in *KeysRequest, opts ...grpc.CallOption) (Store_KeysClient, error)
+	// Size returns the number of members for the key.
+	Size(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*SizeResponse, error)
+	// Members returns the members associated with the key, split in to pages of
+	// at most page_size members.
+	Members(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (Store_MembersClient, error)
+	// Score returns the score and existence of a field with in the key.
+	Score(ctx context.Context, in *FieldRequest, opts ...grpc.CallOption) (*PresenceResponse, error)
+	// Watch streams the changes of the key as they're applied to the store,
+	// resuming from either an offset or a score.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Store_WatchClient, error)
+	// Scan returns the next page of keys after the cursor.
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	// ScanMembers returns the next page of members for the key after the
+	// cursor.
+	ScanMembers(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	// Swap inserts the member only if its field is inserted with the score of
+	// the token.
+	Swap(ctx context.Context, in *SwapRequest, opts ...grpc.CallOption) (*ChangeSetResponse, error)
+}
+
+type storeClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewStoreClient(cc *grpc.ClientConn) StoreClient {
+	return &storeClient{cc}
+}
+
+func (c *storeClient) Insert(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*ChangeSetResponse, error) {
+	out := new(ChangeSetResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Insert", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Delete(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*ChangeSetResponse, error) {
+	out := new(ChangeSetResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Delete", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Select(ctx context.Context, in *FieldRequest, opts ...grpc.CallOption) (*FieldValueScoreResponse, error) {
+	out := new(FieldValueScoreResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Select", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Keys(ctx context.Context, in *KeysRequest, opts ...grpc.CallOption) (Store_KeysClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Store_serviceDesc.Streams[0], c.cc, "/rpc.Store/Keys", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeKeysClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Store_KeysClient interface {
+	Recv() (*KeysResponse, error)
+	grpc.ClientStream
+}
+
+type storeKeysClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeKeysClient) Recv() (*KeysResponse, error) {
+	m := new(KeysResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) Size(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*SizeResponse, error) {
+	out := new(SizeResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Size", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Members(ctx context.Context, in *MembersRequest, opts ...grpc.CallOption) (Store_MembersClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Store_serviceDesc.Streams[1], c.cc, "/rpc.Store/Members", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeMembersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Store_MembersClient interface {
+	Recv() (*MembersResponse, error)
+	grpc.ClientStream
+}
+
+type storeMembersClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeMembersClient) Recv() (*MembersResponse, error) {
+	m := new(MembersResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) Score(ctx context.Context, in *FieldRequest, opts ...grpc.CallOption) (*PresenceResponse, error) {
+	out := new(PresenceResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Score", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Store_WatchClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Store_serviceDesc.Streams[2], c.cc, "/rpc.Store/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storeWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Store_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type storeWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *storeWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storeClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	out := new(ScanResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Scan", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) ScanMembers(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	out := new(ScanResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/ScanMembers", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeClient) Swap(ctx context.Context, in *SwapRequest, opts ...grpc.CallOption) (*ChangeSetResponse, error) {
+	out := new(ChangeSetResponse)
+	err := grpc.Invoke(ctx, "/rpc.Store/Swap", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Store service
+
+type StoreServer interface {
+	// Insert takes a key and members and stores them with in the store.
+	Insert(context.Context, *WriteRequest) (*ChangeSetResponse, error)
+	// Delete removes the members associated with the key.
+	Delete(context.Context, *WriteRequest) (*ChangeSetResponse, error)
+	// Select retrieves a field, value and score associated with the key.
+	Select(context.Context, *FieldRequest) (*FieldValueScoreResponse, error)
+	// Keys returns all the keys that are stored with in the store, split in to
+	// pages of at most page_size keys.
+	Keys(*KeysRequest, Store_KeysServer) error
+	// Size returns the number of members for the key.
+	Size(context.Context, *KeyRequest) (*SizeResponse, error)
+	// Members returns the members associated with the key, split in to pages of
+	// at most page_size members.
+	Members(*MembersRequest, Store_MembersServer) error
+	// Score returns the score and existence of a field with in the key.
+	Score(context.Context, *FieldRequest) (*PresenceResponse, error)
+	// Watch streams the changes of the key as they're applied to the store,
+	// resuming from either an offset or a score.
+	Watch(*WatchRequest, Store_WatchServer) error
+	// Scan returns the next page of keys after the cursor.
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
+	// ScanMembers returns the next page of members for the key after the
+	// cursor.
+	ScanMembers(context.Context, *ScanRequest) (*ScanResponse, error)
+	// Swap inserts the member only if its field is inserted with the score of
+	// the token.
+	Swap(context.Context, *SwapRequest) (*ChangeSetResponse, error)
+}
+
+func RegisterStoreServer(s *grpc.Server, srv StoreServer) {
+	s.RegisterService(&_Store_serviceDesc, srv)
+}
+
+func _Store_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Insert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Insert(ctx, req.(*WriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Delete(ctx, req.(*WriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Select_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FieldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Select(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Select",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Select(ctx, req.(*FieldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Keys_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(KeysRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreServer).Keys(m, &storeKeysServer{stream})
+}
+
+type Store_KeysServer interface {
+	Send(*KeysResponse) error
+	grpc.ServerStream
+}
+
+type storeKeysServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeKeysServer) Send(m *KeysResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Store_Size_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Size(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Size",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Size(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Members_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MembersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreServer).Members(m, &storeMembersServer{stream})
+}
+
+type Store_MembersServer interface {
+	Send(*MembersResponse) error
+	grpc.ServerStream
+}
+
+type storeMembersServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeMembersServer) Send(m *MembersResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Store_Score_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FieldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Score(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Score",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Score(ctx, req.(*FieldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StoreServer).Watch(m, &storeWatchServer{stream})
+}
+
+type Store_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type storeWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *storeWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Store_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Scan",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_ScanMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).ScanMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/ScanMembers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).ScanMembers(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Store_Swap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreServer).Swap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.Store/Swap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreServer).Swap(ctx, req.(*SwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Store_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "rpc.Store",
+	HandlerType: (*StoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Insert",
+			Handler:    _Store_Insert_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Store_Delete_Handler,
+		},
+		{
+			MethodName: "Select",
+			Handler:    _Store_Select_Handler,
+		},
+		{
+			MethodName: "Size",
+			Handler:    _Store_Size_Handler,
+		},
+		{
+			MethodName: "Score",
+			Handler:    _Store_Score_Handler,
+		},
+		{
+			MethodName: "Scan",
+			Handler:    _Store_Scan_Handler,
+		},
+		{
+			MethodName: "ScanMembers",
+			Handler:    _Store_ScanMembers_Handler,
+		},
+		{
+			MethodName: "Swap",
+			Handler:    _Store_Swap_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Keys",
+			Handler:       _Store_Keys_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Members",
+			Handler:       _Store_Members_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Store_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "store.proto",
+}
+
+func init() { proto.RegisterFile("store.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 735 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0xdd, 0x6e, 0xda, 0x4a,
+	0x10, 0x8e, 0xb1, 0x71, 0x60, 0x40, 0x01, 0xf6, 0xe4, 0x70, 0x10, 0xe7, 0xe8, 0x88, 0xee, 0x15,
+	0xa9, 0x52, 0x1a, 0x91, 0xb6, 0xaa, 0x54, 0xa9, 0xbd, 0x48, 0x49, 0x54, 0x45, 0x4d, 0xa2, 0x75,
+	0xd5, 0xdc, 0x35, 0x22, 0xee, 0x90, 0xa0, 0x00, 0x76, 0xbd, 0x26, 0x2d, 0x79, 0x9a, 0x3e, 0x4f,
+	0x9f, 0xaa, 0xda, 0x59, 0x2f, 0x36, 0x34, 0x56, 0xd3, 0xbb, 0xfd, 0xc6, 0xf3, 0xf7, 0x7d, 0x3b,
+	0xb3, 0x86, 0x8a, 0x8c, 0x83, 0x08, 0x7b, 0x61, 0x14, 0xc4, 0x01, 0xb3, 0xa3, 0xd0, 0xe7, 0x1e,
+	0xd4, 0x0e, 0xc7, 0x38, 0xf9, 0xfc, 0x71, 0x38, 0x99, 0xa3, 0xe7, 0x07, 0x11, 0xb2, 0x6d, 0x28,
+	0x8e, 0x94, 0xa9, 0x65, 0x75, 0xac, 0x6e, 0x59, 0x68, 0xa0, 0xac, 0xb7, 0xca, 0xa7, 0x55, 0xe8,
+	0x58, 0xdd, 0xaa, 0xd0, 0x40, 0x59, 0xa5, 0x0a, 0x6a, 0xd9, 0x1d, 0xab, 0x6b, 0x0b, 0x0d, 0xf8,
+	0x19, 0x54, 0xcf, 0xa3, 0x71, 0x8c, 0x02, 0xbf, 0xcc, 0x51, 0xc6, 0xac, 0x0e, 0xf6, 0x0d, 0x2e,
+	0x92, 0x7c, 0xea, 0xc8, 0x7a, 0xb0, 0x39, 0xc5, 0xe9, 0x25, 0x46, 0xb2, 0x55, 0xe8, 0xd8, 0xdd,
+	0x4a, 0x7f, 0xbb, 0x17, 0x85, 0x7e, 0x6f, 0xad, 0x15, 0x61, 0x9c, 0xf8, 0xff, 0x00, 0xc7, 0xb8,
+	0xc8, 0xcd, 0xc7, 0x5f, 0x40, 0x95, 0x62, 0xf3, 0x2b, 0x2e, 0x59, 0x15, 0x32, 0xac, 0xf8, 0x63,
+	0xa8, 0x1c, 0xe3, 0x42, 0x9a, 0xb0, 0x7f, 0xa1, 0x1c, 0x0e, 0xaf, 0xf0, 0x42, 0x8e, 0xef, 0x90,
+	0x82, 0x8b, 0xa2, 0xa4, 0x0c, 0xde, 0xf8, 0x0e, 0xf9, 0x1b, 0xd8, 0x7a, 0xaf, 0xdb, 0xc9, 0xaf,
+	0xb2, 0x92, 0xa0, 0xb0, 0x96, 0xe0, 0x08, 0x1a, 0x07, 0xd7, 0xc3, 0xd9, 0x15, 0x7a, 0x18, 0x0b,
+	0x94, 0x61, 0x30, 0x93, 0xc8, 0x5a, 0xb0, 0x29, 0xe7, 0xbe, 0x8f, 0x52, 0xb6, 0xac, 0x8e, 0xdd,
+	0x2d, 0x0b, 0x03, 0xd5, 0x97, 0xd1, 0x70, 0x3c, 0x99, 0x47, 0x48, 0x1a, 0x95, 0x85, 0x81, 0xfc,
+	0x08, 0xfe, 0x59, 0x57, 0xca, 0xa4, 0xdb, 0x05, 0x57, 0x6b, 0x46, 0x5d, 0xe5, 0xe9, 0x9a, 0xf8,
+	0x70, 0x0e, 0x55, 0x4d, 0x3f, 0x89, 0x66, 0xe0, 0xdc, 0xe0, 0xc2, 0x74, 0x42, 0x67, 0xe5, 0xa3,
+	0xba, 0xcf, 0xfa, 0x2c, 0xe5, 0xb1, 0x05, 0x9d, 0xf9, 0x0e, 0xd4, 0x96, 0xd2, 0x24, 0x6e, 0x4d,
+	0x70, 0x49, 0x62, 0x93, 0x2c, 0x41, 0xfc, 0x13, 0xd4, 0xcf, 0x22, 0x94, 0x38, 0xf3, 0x31, 0xab,
+	0x41, 0x48, 0xb6, 0x98, 0xb2, 0x96, 0x84, 0x81, 0xac, 0x0d, 0xa5, 0xf1, 0x4c, 0x62, 0x14, 0xa3,
+	0xbe, 0xb8, 0x92, 0x58, 0xe2, 0x9c, 0xd9, 0xfb, 0x6e, 0x41, 0xf5, 0x7c, 0x18, 0xfb, 0xd7, 0xf9,
+	0x97, 0xb4, 0x07, 0x6e, 0x84, 0x72, 0x3e, 0xd5, 0x37, 0xb4, 0xd5, 0x6f, 0x91, 0x46, 0xd9, 0xa0,
+	0x9e, 0xa0, 0xef, 0x22, 0xf1, 0x63, 0x8f, 0xa0, 0xaa, 0x4f, 0x17, 0x7a, 0x07, 0x74, 0xc5, 0x8a,
+	0xb6, 0x91, 0xae, 0x7c, 0x07, 0x5c, 0x1d, 0xc4, 0x4a, 0xe0, 0x9c, 0x9c, 0x9e, 0x0c, 0xea, 0x1b,
+	0x0c, 0xc0, 0x3d, 0x3d, 0x3c, 0xf4, 0x06, 0x1f, 0xea, 0x16, 0x2b, 0x43, 0xd1, 0x3b, 0x38, 0x15,
+	0x83, 0x7a, 0x81, 0x7f, 0x03, 0xa0, 0x62, 0x83, 0x5b, 0x45, 0xb1, 0x09, 0x6e, 0x30, 0x1a, 0x49,
+	0xd4, 0xdc, 0x1d, 0x91, 0x20, 0xa5, 0x73, 0xbc, 0x08, 0x31, 0x99, 0x57, 0x3a, 0x1b, 0x2e, 0x76,
+	0xca, 0x25, 0xbd, 0x6f, 0xe7, 0x01, 0xf7, 0xed, 0x43, 0xc5, 0xf3, 0x87, 0xb3, 0x7c, 0x69, 0x9a,
+	0xe0, 0xfa, 0xf3, 0x48, 0x06, 0x51, 0x52, 0x36, 0x41, 0x4a, 0xeb, 0xa9, 0x6a, 0x39, 0x29, 0xad,
+	0x81, 0xb2, 0xfa, 0xc1, 0x7c, 0x16, 0x53, 0xed, 0xa2, 0xd0, 0x80, 0xbf, 0x86, 0xaa, 0x2e, 0x92,
+	0x4e, 0x02, 0xa9, 0xb6, 0x9c, 0x04, 0x8d, 0xf2, 0x6a, 0x51, 0x93, 0x5f, 0x87, 0x61, 0x7e, 0x93,
+	0x29, 0xe7, 0xc2, 0xef, 0x39, 0xab, 0x26, 0xe3, 0xe0, 0x06, 0x67, 0x66, 0x4c, 0x08, 0xf4, 0x7f,
+	0x38, 0x50, 0xf4, 0xd4, 0x63, 0xc8, 0x9e, 0x83, 0xfb, 0x8e, 0x46, 0x8a, 0x35, 0xf4, 0x1c, 0x64,
+	0x5e, 0xae, 0x76, 0x93, 0x4c, 0xbf, 0x6c, 0x2d, 0xdf, 0x50, 0x61, 0x6f, 0x71, 0x82, 0x31, 0xfe,
+	0x59, 0xd8, 0x2b, 0x70, 0x3d, 0x9c, 0xa0, 0x6f, 0xaa, 0x65, 0x5f, 0xad, 0xf6, 0x7f, 0xf7, 0x12,
+	0x49, 0x83, 0x9f, 0x82, 0xa3, 0xd6, 0x95, 0xd5, 0xc9, 0x2f, 0xf3, 0x70, 0xb5, 0x1b, 0x19, 0x8b,
+	0x71, 0xdf, 0xb3, 0xd8, 0x2e, 0x38, 0x6a, 0x77, 0x59, 0xcd, 0x7c, 0x5e, 0xf5, 0xcf, 0xee, 0x35,
+	0xdf, 0x60, 0x2f, 0x61, 0x33, 0xd9, 0x62, 0xf6, 0x17, 0x7d, 0x5f, 0x7d, 0xee, 0xda, 0xdb, 0xab,
+	0xc6, 0x4c, 0x9d, 0x7d, 0x28, 0xea, 0x7f, 0xc7, 0x3d, 0xa4, 0xfe, 0x26, 0xd3, 0xfa, 0xce, 0x13,
+	0x9b, 0x22, 0xad, 0x81, 0x11, 0x30, 0xb3, 0x7f, 0xed, 0x5a, 0x6a, 0xa2, 0x2d, 0xa1, 0x2a, 0x4f,
+	0xc0, 0x51, 0x83, 0x95, 0xd0, 0xcf, 0x0c, 0x72, 0xbb, 0x91, 0xb1, 0x2c, 0xf3, 0x3f, 0xd3, 0xc3,
+	0x6e, 0x28, 0x3d, 0x30, 0xaa, 0x0f, 0x8e, 0x9a, 0x3e, 0xe3, 0x9e, 0x0e, 0x62, 0xfe, 0xa5, 0x5e,
+	0xba, 0xf4, 0x43, 0xdd, 0xff, 0x39, 0x00, 0x8f, 0xda, 0xab, 0xd9, 0x5f, 0x07, 0x00, 0x00,
+}
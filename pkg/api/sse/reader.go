@@ -0,0 +1,67 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Event is a single server-sent event.
+type Event struct {
+	ID   string
+	Type string
+	Data []byte
+}
+
+// Reader reads server-sent events from a stream.
+type Reader struct {
+	scanner *bufio.Scanner
+}
+
+// NewReader creates a Reader from a stream.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		scanner: bufio.NewScanner(r),
+	}
+}
+
+// Read the next event from the stream, comments and events without any data
+// are skipped. Returns io.EOF when the stream has ended.
+func (r *Reader) Read() (Event, error) {
+	var (
+		event Event
+		data  [][]byte
+	)
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if len(data) == 0 {
+				event = Event{}
+				continue
+			}
+			event.Data = bytes.Join(data, []byte("\n"))
+			return event, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		name, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch name {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, []byte(value))
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
@@ -0,0 +1,64 @@
+package sse
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"testing/quick"
+)
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		fn := func(id, data string) bool {
+			// Line breaks with in the id aren't valid.
+			id = string(bytes.Map(func(r rune) rune {
+				if r == '\n' || r == '\r' {
+					return -1
+				}
+				return r
+			}, []byte(id)))
+			data = string(bytes.Replace([]byte(data), []byte("\r"), nil, -1))
+
+			rec := httptest.NewRecorder()
+			writer, err := NewWriter(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.KeepAlive(); err != nil {
+				t.Fatal(err)
+			}
+			expected := Event{ID: id, Type: "insert", Data: []byte(data)}
+			if err := writer.Write(expected); err != nil {
+				t.Fatal(err)
+			}
+
+			reader := NewReader(rec.Body)
+			actual, err := reader.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := reader.Read(); err != io.EOF {
+				t.Errorf("expected: %v, actual: %v", io.EOF, err)
+			}
+			return expected.ID == actual.ID &&
+				expected.Type == actual.Type &&
+				bytes.Equal(expected.Data, actual.Data)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		if _, err := NewWriter(rec); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := ContentType, rec.Header().Get("Content-Type"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
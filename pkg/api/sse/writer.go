@@ -0,0 +1,71 @@
+package sse
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ContentType is the content type of a stream of server-sent events.
+const ContentType = "text/event-stream"
+
+// Writer writes server-sent events to a http.ResponseWriter, flushing each
+// event so that it's sent straight away.
+type Writer struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewWriter creates a Writer, writing the headers of the stream. It errors if
+// the http.ResponseWriter can't be flushed.
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &Writer{
+		w:       w,
+		flusher: flusher,
+	}, nil
+}
+
+// Write an event with an id, so that the client can resume from it using the
+// Last-Event-ID header.
+func (w *Writer) Write(event Event) error {
+	buf := new(bytes.Buffer)
+	if event.ID != "" {
+		fmt.Fprintf(buf, "id: %s\n", event.ID)
+	}
+	if event.Type != "" {
+		fmt.Fprintf(buf, "event: %s\n", event.Type)
+	}
+	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	w.flusher.Flush()
+	return nil
+}
+
+// KeepAlive writes a comment, which is ignored by clients, so that idle
+// connections aren't closed.
+func (w *Writer) KeepAlive() error {
+	if _, err := fmt.Fprint(w.w, ":\n\n"); err != nil {
+		return err
+	}
+	w.flusher.Flush()
+	return nil
+}
@@ -10,7 +10,10 @@ import (
 
 	"github.com/SimonRichardson/coherence/pkg/api"
 	errs "github.com/SimonRichardson/coherence/pkg/api/http"
+	"github.com/SimonRichardson/coherence/pkg/api/sse"
+	"github.com/SimonRichardson/coherence/pkg/api/writes"
 	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/ratelimit"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/SimonRichardson/coherence/pkg/store"
 	"github.com/go-kit/kit/log"
@@ -18,29 +21,24 @@ import (
 	"github.com/pkg/errors"
 )
 
+// These are the API URL paths, they're shared with the cache API.
 const (
+	APIPathInsert      = errs.APIPathInsert
+	APIPathDelete      = errs.APIPathDelete
+	APIPathSelect      = errs.APIPathSelect
+	APIPathKeys        = errs.APIPathKeys
+	APIPathSize        = errs.APIPathSize
+	APIPathMembers     = errs.APIPathMembers
+	APIPathScore       = errs.APIPathScore
+	APIPathWatch       = errs.APIPathWatch
+	APIPathChanges     = errs.APIPathChanges
+	APIPathScan        = errs.APIPathScan
+	APIPathScanMembers = errs.APIPathScanMembers
+	APIPathSwap        = errs.APIPathSwap
+)
 
-	// APIPathInsert represents a way to insert a series or records.
-	APIPathInsert = "/insert"
-
-	// APIPathDelete represents a way to delete a series or records.
-	APIPathDelete = "/delete"
-
-	// APIPathSelect represents a way to select a record.
-	APIPathSelect = "/select"
-
-	// APIPathKeys represents a way to find all the keys with in the cache.
-	APIPathKeys = "/keys"
-
-	// APIPathSize represents a way to find the size of a key with in the cache.
-	APIPathSize = "/size"
-
-	// APIPathMembers represents a way to find all the members for a key with in
-	// the cache.
-	APIPathMembers = "/members"
-
-	// APIPathScore represents a way to find the score of a field with in a key.
-	APIPathScore = "/score"
+const (
+	defaultKeepAlive = time.Second * 15
 )
 
 // API serves the cache API
@@ -50,8 +48,22 @@ type API struct {
 	clients  metrics.Gauge
 	duration metrics.HistogramVec
 	errors   errs.Error
-	action   chan func()
-	stop     chan chan struct{}
+	router   *errs.Router
+	shedder  *ratelimit.Shedder
+	shed     metrics.Counter
+	writes   *writes.Queue
+}
+
+// Option defines a option for configuring the API
+type Option func(*API)
+
+// WithShedder sheds the inserts and deletes once too many of them are
+// waiting for the writes before them, counting each one that's shed.
+func WithShedder(shedder *ratelimit.Shedder, shed metrics.Counter) Option {
+	return func(a *API) {
+		a.shedder = shedder
+		a.shed = shed
+	}
 }
 
 // NewAPI creates a API with the correct dependencies.
@@ -59,6 +71,7 @@ func NewAPI(store store.Store,
 	logger log.Logger,
 	clients metrics.Gauge,
 	duration metrics.HistogramVec,
+	opts ...Option,
 ) *API {
 	api := &API{
 		store:    store,
@@ -66,11 +79,27 @@ func NewAPI(store store.Store,
 		clients:  clients,
 		duration: duration,
 		errors:   errs.NewError(logger),
-		action:   make(chan func()),
-		stop:     make(chan chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(api)
 	}
 
-	go api.run()
+	api.router = errs.NewRouter(errs.Handlers{
+		APIPathInsert:      api.handleInsertion,
+		APIPathDelete:      api.handleDeletion,
+		APIPathSelect:      api.handleSelect,
+		APIPathKeys:        api.handleKeys,
+		APIPathSize:        api.handleSize,
+		APIPathMembers:     api.handleMembers,
+		APIPathScore:       api.handleScore,
+		APIPathWatch:       api.handleWatch,
+		APIPathChanges:     api.handleChanges,
+		APIPathScan:        api.handleScan,
+		APIPathScanMembers: api.handleScanMembers,
+		APIPathSwap:        api.handleSwap,
+	}, api.errors)
+
+	api.writes = writes.NewQueue()
 
 	return api
 }
@@ -93,47 +122,34 @@ func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		).Observe(time.Since(begin).Seconds())
 	}(time.Now())
 
-	// Routing table
-	method, path := r.Method, r.URL.Path
-	switch {
-	case method == "POST" && path == APIPathInsert:
-		a.handleInsertion(w, r)
-	case method == "POST" && path == APIPathDelete:
-		a.handleDeletion(w, r)
-	case method == "GET" && path == APIPathSelect:
-		a.handleSelect(w, r)
-	case method == "GET" && path == APIPathKeys:
-		a.handleKeys(w, r)
-	case method == "GET" && path == APIPathSize:
-		a.handleSize(w, r)
-	case method == "GET" && path == APIPathMembers:
-		a.handleMembers(w, r)
-	case method == "GET" && path == APIPathScore:
-		a.handleScore(w, r)
-	default:
-		// Nothing found
-		a.errors.NotFound(w, r)
-	}
+	a.router.ServeHTTP(w, r)
 }
 
-func (a *API) run() {
-	for {
-		select {
-		case f := <-a.action:
-			f()
-
-		case c := <-a.stop:
-			close(c)
-			return
+// acquire sheds the write, replying to the request, if too many writes are
+// already waiting. Every write that's acquired has to be released.
+func (a *API) acquire(w http.ResponseWriter, r *http.Request) bool {
+	if a.shedder == nil {
+		return true
+	}
+	ok, retryAfter := a.shedder.Acquire()
+	if !ok {
+		if a.shed != nil {
+			a.shed.Inc()
 		}
+		a.errors.TooManyRequests(w, r, retryAfter)
+	}
+	return ok
+}
+
+func (a *API) release() {
+	if a.shedder != nil {
+		a.shedder.Release()
 	}
 }
 
 // Close out the API
 func (a *API) Close() {
-	c := make(chan struct{})
-	a.stop <- c
-	<-c
+	a.writes.Close()
 }
 
 func (a *API) handleInsertion(w http.ResponseWriter, r *http.Request) {
@@ -155,21 +171,33 @@ func (a *API) handleInsertion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.acquire(w, r) {
+		return
+	}
+	defer a.release()
+
 	var (
 		internalError = make(chan error)
 		result        = make(chan selectors.ChangeSet)
 	)
-	a.action <- func() {
+	if err := a.writes.Apply(qp.Key(), func() {
 		changeSet, err := a.store.Insert(qp.Key(), members)
 		if err != nil {
 			internalError <- err
 			return
 		}
 		result <- changeSet
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
 
 	select {
 	case err := <-internalError:
+		if selectors.QuotaError(err) {
+			a.errors.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		}
 		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
 	case changeSet := <-result:
 		// Make sure we collect the document for the result.
@@ -201,17 +229,25 @@ func (a *API) handleDeletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !a.acquire(w, r) {
+		return
+	}
+	defer a.release()
+
 	var (
 		internalError = make(chan error)
 		result        = make(chan selectors.ChangeSet)
 	)
-	a.action <- func() {
+	if err := a.writes.Apply(qp.Key(), func() {
 		changeSet, err := a.store.Delete(qp.Key(), members)
 		if err != nil {
 			internalError <- err
 			return
 		}
 		result <- changeSet
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
 
 	select {
@@ -228,6 +264,72 @@ func (a *API) handleDeletion(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *API) handleSwap(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+
+	// Validate user input.
+	var qp SwapQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryRequired); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	members, err := ingestMembers(r.Body)
+	if err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+	if len(members) != 1 {
+		a.errors.BadRequest(w, r, "expected one member to swap")
+		return
+	}
+
+	if !a.acquire(w, r) {
+		return
+	}
+	defer a.release()
+
+	var (
+		internalError = make(chan error)
+		result        = make(chan selectors.ChangeSet)
+	)
+	if err := a.writes.Apply(qp.Key(), func() {
+		changeSet, err := a.store.Swap(qp.Key(), members[0], qp.Token())
+		if err != nil {
+			internalError <- err
+			return
+		}
+		result <- changeSet
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case err := <-internalError:
+		if selectors.NotFoundError(err) {
+			a.errors.NotFound(w, r)
+			return
+		}
+		if selectors.QuotaError(err) {
+			a.errors.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		}
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	case changeSet := <-result:
+		// Make sure we collect the document for the result.
+		qr := ChangeSetQueryResult{Errors: a.errors, Params: qp.KeyQueryParams}
+		qr.ChangeSet = changeSet
+
+		// Finish
+		qr.Duration = time.Since(begin).String()
+		qr.EncodeTo(w)
+	}
+}
+
 func (a *API) handleSelect(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -266,7 +368,22 @@ func (a *API) handleKeys(w http.ResponseWriter, r *http.Request) {
 	// useful metrics
 	begin := time.Now()
 
-	keys, err := a.store.Keys()
+	// Validate user input.
+	var qp KeysQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	var (
+		keys []selectors.Key
+		err  error
+	)
+	if match := qp.Match(); match != "" {
+		keys, err = a.store.KeysMatching(match)
+	} else {
+		keys, err = a.store.Keys()
+	}
 	if err != nil {
 		a.errors.InternalServerError(w, r, err.Error())
 		return
@@ -316,20 +433,28 @@ func (a *API) handleMembers(w http.ResponseWriter, r *http.Request) {
 	begin := time.Now()
 
 	// Validate user input.
-	var qp KeyQueryParams
+	var qp MembersQueryParams
 	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
 		a.errors.BadRequest(w, r, err.Error())
 		return
 	}
 
-	members, err := a.store.Members(qp.Key())
+	var (
+		members []selectors.Field
+		err     error
+	)
+	if prefix := qp.Prefix(); prefix != "" {
+		members, err = a.store.MembersWithPrefix(qp.Key(), prefix)
+	} else {
+		members, err = a.store.Members(qp.Key())
+	}
 	if err != nil {
 		a.errors.InternalServerError(w, r, err.Error())
 		return
 	}
 
 	// Make sure we collect the document for the result.
-	qr := FieldsQueryResult{Errors: a.errors, Params: qp}
+	qr := FieldsQueryResult{Errors: a.errors, Params: qp.KeyQueryParams}
 	qr.Fields = members
 
 	// Finish
@@ -365,6 +490,160 @@ func (a *API) handleScore(w http.ResponseWriter, r *http.Request) {
 	qr.EncodeTo(w)
 }
 
+func (a *API) handleWatch(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// Validate user input.
+	var qp WatchQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	sub, err := a.store.Watch(qp.Key(), qp.Resume())
+	if err != nil {
+		if selectors.ExpiredError(err) {
+			a.errors.Error(w, err.Error(), http.StatusGone)
+		} else {
+			a.errors.InternalServerError(w, r, err.Error())
+		}
+		return
+	}
+	defer sub.Close()
+
+	writer, err := sse.NewWriter(w)
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	ticker := time.NewTicker(defaultKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					level.Warn(a.logger).Log("key", qp.Key(), "err", err)
+				}
+				return
+			}
+			if err := writeEvent(writer, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := writer.KeepAlive(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (a *API) handleChanges(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+
+	// Validate user input.
+	var qp ChangesQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	events, err := a.store.Changes(qp.Since(), qp.Limit())
+	if err != nil {
+		if selectors.ExpiredError(err) {
+			a.errors.Error(w, err.Error(), http.StatusGone)
+		} else {
+			a.errors.InternalServerError(w, r, err.Error())
+		}
+		return
+	}
+
+	// Make sure we collect the document for the result.
+	qr := ChangesQueryResult{Errors: a.errors, Params: qp}
+	qr.Events = events
+
+	// Finish
+	qr.Duration = time.Since(begin).String()
+	qr.EncodeTo(w)
+}
+
+func (a *API) handleScan(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+
+	// Validate user input.
+	var qp ScanQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	page, err := a.store.Scan(qp.Cursor(), qp.Options())
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	// Make sure we collect the document for the result.
+	qr := KeysPageQueryResult{Errors: a.errors}
+	qr.KeysPage = page
+
+	// Finish
+	qr.Duration = time.Since(begin).String()
+	qr.EncodeTo(w)
+}
+
+func (a *API) handleScanMembers(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	// useful metrics
+	begin := time.Now()
+
+	// Validate user input.
+	var qp KeyScanQueryParams
+	if err := qp.DecodeFrom(r.URL, r.Header, queryOptional); err != nil {
+		a.errors.BadRequest(w, r, err.Error())
+		return
+	}
+
+	page, err := a.store.ScanMembers(qp.Key(), qp.Cursor(), qp.Options())
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	// Make sure we collect the document for the result.
+	qr := FieldsPageQueryResult{Errors: a.errors, Params: qp}
+	qr.FieldsPage = page
+
+	// Finish
+	qr.Duration = time.Since(begin).String()
+	qr.EncodeTo(w)
+}
+
+// writeEvent writes the event using the offset as the id, so that clients can
+// resume from it.
+func writeEvent(writer *sse.Writer, event selectors.Event) error {
+	b, err := json.Marshal(api.EventOutput(event))
+	if err != nil {
+		return err
+	}
+	return writer.Write(sse.Event{
+		ID:   strconv.FormatUint(event.Offset, 10),
+		Type: event.Type.String(),
+		Data: b,
+	})
+}
+
 type interceptingWriter struct {
 	code int
 	http.ResponseWriter
@@ -375,6 +654,14 @@ func (iw *interceptingWriter) WriteHeader(code int) {
 	iw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush passes through to the underlying http.ResponseWriter, so that the
+// events of a watch are sent straight away.
+func (iw *interceptingWriter) Flush() {
+	if flusher, ok := iw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func ingestMembers(reader io.ReadCloser) ([]selectors.FieldValueScore, error) {
 	bytes, err := ioutil.ReadAll(reader)
 	if err != nil {
@@ -13,8 +13,10 @@ import (
 	"testing/quick"
 
 	objects "github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/sse"
 	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	storeImpl "github.com/SimonRichardson/coherence/pkg/store"
 	storeMocks "github.com/SimonRichardson/coherence/pkg/store/mocks"
 	"github.com/go-kit/kit/log"
 	"github.com/golang/mock/gomock"
@@ -197,6 +199,107 @@ func TestInsertAPI(t *testing.T) {
 	})
 }
 
+func TestSwapAPI(t *testing.T) {
+	t.Parallel()
+
+	swap := func(t *testing.T, store *storeMocks.MockStore, duration *metricMocks.MockHistogramVec, members []selectors.FieldValueScore) *http.Response {
+		var (
+			ctrl     = gomock.NewController(t)
+			clients  = metricMocks.NewMockGauge(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+
+			api    = NewAPI(store, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer server.Close()
+		defer api.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+		duration.EXPECT().WithLabelValues(gomock.Any(), gomock.Any(), gomock.Any()).Return(observer).AnyTimes()
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).AnyTimes()
+
+		b, err := json.Marshal(objects.MembersInput{
+			Members: convertToInput(members),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Post(fmt.Sprintf("%s/swap?key=a&token=1", server.URL), "application/json", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	t.Run("post", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store    = storeMocks.NewMockStore(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			member   = selectors.FieldValueScore{Field: "x", Value: []byte("v"), Score: 2}
+		)
+		store.EXPECT().Swap(selectors.Key("a"), member, int64(1)).Return(selectors.ChangeSet{
+			Success: []selectors.Field{"x"},
+			Failure: make([]selectors.Field, 0),
+		}, nil)
+
+		resp := swap(t, store, duration, []selectors.FieldValueScore{member})
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusOK, resp.StatusCode; expected != actual {
+			t.Fatalf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var cs struct {
+			Records selectors.ChangeSet `json:"records"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&cs); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"x"}, cs.Records.Success; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("post with not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			store    = storeMocks.NewMockStore(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			member   = selectors.FieldValueScore{Field: "x", Score: 2}
+		)
+		store.EXPECT().Swap(selectors.Key("a"), member, int64(1)).Return(selectors.ChangeSet{}, selectors.NewNotFoundError(errors.New("bad")))
+
+		resp := swap(t, store, duration, []selectors.FieldValueScore{member})
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusNotFound, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("post with more than one member", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		resp := swap(t, storeMocks.NewMockStore(ctrl), metricMocks.NewMockHistogramVec(ctrl), []selectors.FieldValueScore{
+			{Field: "x", Score: 2},
+			{Field: "y", Score: 2},
+		})
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+}
+
 func TestDeleteAPI(t *testing.T) {
 	t.Parallel()
 
@@ -409,3 +512,257 @@ func unique(a []selectors.Field) []selectors.Field {
 
 	return res
 }
+
+func TestWatchAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("get with no key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			store    = storeMocks.NewMockStore(ctrl)
+
+			api    = NewAPI(store, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/watch", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		resp, err := http.Get(fmt.Sprintf("%s/watch", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get events", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			store    = storeMocks.NewMockStore(ctrl)
+
+			api    = NewAPI(store, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+			feed   = storeImpl.NewFeed(10)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		feed.Publish(selectors.Event{Type: selectors.EventInsert, Key: "a", Field: "x", Score: 1})
+		feed.Publish(selectors.Event{Type: selectors.EventDelete, Key: "a", Field: "x", Score: 2})
+		sub, err := feed.Subscribe("a", selectors.ResumeFromOffset(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/watch", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		store.EXPECT().Watch(selectors.Key("a"), selectors.ResumeFromOffset(1)).Return(sub, nil)
+
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/watch?key=a&offset=0", server.URL), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Last-Event-ID", "1")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event, err := sse.NewReader(resp.Body).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Closing the subscription ends the stream.
+		sub.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if expected, actual := "2", event.ID; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "delete", event.Type; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		var output objects.Event
+		if err := json.Unmarshal(event.Data, &output); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := selectors.Field("x"), objects.EventInput(output).Field; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestChangesAPI(t *testing.T) {
+	t.Parallel()
+
+	t.Run("get with invalid since", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			store    = storeMocks.NewMockStore(ctrl)
+
+			api    = NewAPI(store, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/changes", "400").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		resp, err := http.Get(fmt.Sprintf("%s/changes?since=-1", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusBadRequest, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get expired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			store    = storeMocks.NewMockStore(ctrl)
+
+			api    = NewAPI(store, log.NewNopLogger(), clients, duration)
+			server = httptest.NewServer(api)
+		)
+		defer api.Close()
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/changes", "410").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		store.EXPECT().Changes(uint64(2), defaultChangesLimit).Return(nil, selectors.NewExpiredError(errors.New("bad")))
+
+		resp, err := http.Get(fmt.Sprintf("%s/changes?since=2", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if expected, actual := http.StatusGone, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fn := func(since uint64, members []selectors.FieldValueScore) bool {
+			since = since % (1 << 32)
+
+			var (
+				clients  = metricMocks.NewMockGauge(ctrl)
+				duration = metricMocks.NewMockHistogramVec(ctrl)
+				observer = metricMocks.NewMockObserver(ctrl)
+				store    = storeMocks.NewMockStore(ctrl)
+
+				api    = NewAPI(store, log.NewNopLogger(), clients, duration)
+				server = httptest.NewServer(api)
+			)
+			defer api.Close()
+			defer server.Close()
+
+			events := make([]selectors.Event, len(members))
+			for k, v := range members {
+				events[k] = selectors.Event{
+					Offset: since + uint64(k) + 1,
+					Type:   selectors.EventInsert,
+					Key:    "key",
+					Field:  v.Field,
+					Value:  v.Value,
+					Score:  v.Score,
+				}
+			}
+
+			clients.EXPECT().Inc().Times(1)
+			clients.EXPECT().Dec().Times(1)
+
+			duration.EXPECT().WithLabelValues("GET", "/changes", "200").Return(observer).Times(1)
+			observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+			store.EXPECT().Changes(since, 10).Return(events, nil)
+
+			resp, err := http.Get(fmt.Sprintf("%s/changes?since=%d&limit=10", server.URL, since))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body struct {
+				Records []objects.Event `json:"records"`
+				Next    uint64          `json:"next"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if expected, actual := since+uint64(len(members)), body.Next; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+			if expected, actual := fmt.Sprintf("%d", body.Next), resp.Header.Get("X-Offset"); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+			if expected, actual := len(events), len(body.Records); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+				return false
+			}
+			for k, v := range body.Records {
+				if expected, actual := events[k].Offset, objects.EventInput(v).Offset; expected != actual {
+					t.Errorf("expected: %v, actual: %v", expected, actual)
+				}
+			}
+			return true
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/SimonRichardson/coherence/pkg/selectors"
@@ -10,7 +11,9 @@ import (
 )
 
 const (
-	defaultContentType = "application/json"
+	defaultContentType  = "application/json"
+	defaultChangesLimit = 1000
+	maxChangesLimit     = 10000
 )
 
 // KeyParams represents a parameter that has a selectors.Key
@@ -89,6 +92,197 @@ func (qp *KeyFieldQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBeh
 	return nil
 }
 
+// SwapQueryParams defines all the dimensions of a swap query.
+type SwapQueryParams struct {
+	KeyQueryParams
+	token int64
+}
+
+// Token returns the score that the member has to still have to be swapped
+func (qp SwapQueryParams) Token() int64 {
+	return qp.token
+}
+
+// DecodeFrom populates a SwapQueryParams from a URL.
+func (qp *SwapQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	if err := qp.KeyQueryParams.DecodeFrom(u, h, rb); err != nil {
+		return err
+	}
+
+	token := u.Query().Get("token")
+	v, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return errors.Errorf("expected 'token' but got %q", token)
+	}
+	qp.token = v
+
+	return nil
+}
+
+// WatchQueryParams defines all the dimensions of a watch query.
+type WatchQueryParams struct {
+	key    selectors.Key
+	resume selectors.Resume
+}
+
+// Key returns the key value from the parameters
+func (qp WatchQueryParams) Key() selectors.Key {
+	return qp.key
+}
+
+// Resume returns where the watch resumes from
+func (qp WatchQueryParams) Resume() selectors.Resume {
+	return qp.resume
+}
+
+// DecodeFrom populates a WatchQueryParams from a URL. The Last-Event-ID header
+// is used as the offset, when a client reconnects.
+func (qp *WatchQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	key := u.Query().Get("key")
+	if key == "" {
+		return errors.Errorf("expected 'key' but got %q", key)
+	}
+	qp.key = selectors.Key(key)
+
+	offset := u.Query().Get("offset")
+	if id := h.Get("Last-Event-ID"); id != "" {
+		offset = id
+	}
+
+	var err error
+	qp.resume, err = selectors.ParseResume(offset, u.Query().Get("score"))
+	return err
+}
+
+// ChangesQueryParams defines all the dimensions of a changes query.
+type ChangesQueryParams struct {
+	since uint64
+	limit int
+}
+
+// Since returns the offset to read the changes after
+func (qp ChangesQueryParams) Since() uint64 {
+	return qp.since
+}
+
+// Limit returns the maximum number of changes to read
+func (qp ChangesQueryParams) Limit() int {
+	return qp.limit
+}
+
+// DecodeFrom populates a ChangesQueryParams from a URL.
+func (qp *ChangesQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	qp.since, qp.limit = 0, defaultChangesLimit
+
+	if since := u.Query().Get("since"); since != "" {
+		v, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			return errors.Errorf("expected 'since' but got %q", since)
+		}
+		qp.since = v
+	}
+
+	if limit := u.Query().Get("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil || v < 1 || v > maxChangesLimit {
+			return errors.Errorf("expected 'limit' between 1 and %d but got %q", maxChangesLimit, limit)
+		}
+		qp.limit = v
+	}
+
+	return nil
+}
+
+// KeysQueryParams defines all the dimensions of a keys query.
+type KeysQueryParams struct {
+	match string
+}
+
+// Match returns the glob style pattern that the keys have to match, the
+// empty pattern matches every key.
+func (qp KeysQueryParams) Match() string {
+	return qp.match
+}
+
+// DecodeFrom populates a KeysQueryParams from a URL.
+func (qp *KeysQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	qp.match = u.Query().Get("match")
+	return nil
+}
+
+// MembersQueryParams defines all the dimensions of a members query.
+type MembersQueryParams struct {
+	KeyQueryParams
+	prefix string
+}
+
+// Prefix returns the prefix that the members have to start with, the empty
+// prefix matches every member.
+func (qp MembersQueryParams) Prefix() string {
+	return qp.prefix
+}
+
+// DecodeFrom populates a MembersQueryParams from a URL.
+func (qp *MembersQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	if err := qp.KeyQueryParams.DecodeFrom(u, h, rb); err != nil {
+		return err
+	}
+	qp.prefix = u.Query().Get("prefix")
+	return nil
+}
+
+// ScanQueryParams defines all the dimensions of a scan query.
+type ScanQueryParams struct {
+	cursor selectors.Cursor
+	opts   selectors.ScanOptions
+}
+
+// Cursor returns the cursor to carry on the scan from
+func (qp ScanQueryParams) Cursor() selectors.Cursor {
+	return qp.cursor
+}
+
+// Options returns the options for the page of the scan
+func (qp ScanQueryParams) Options() selectors.ScanOptions {
+	return qp.opts
+}
+
+// DecodeFrom populates a ScanQueryParams from a URL.
+func (qp *ScanQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	cursor := selectors.Cursor(u.Query().Get("cursor"))
+	if _, _, err := cursor.After(); err != nil {
+		return err
+	}
+	qp.cursor = cursor
+
+	var err error
+	qp.opts, err = selectors.ParseScanOptions(u.Query().Get("match"), u.Query().Get("count"))
+	return err
+}
+
+// KeyScanQueryParams defines all the dimensions of a scan query with in a
+// key.
+type KeyScanQueryParams struct {
+	ScanQueryParams
+	key selectors.Key
+}
+
+// Key returns the key value from the parameters
+func (qp KeyScanQueryParams) Key() selectors.Key {
+	return qp.key
+}
+
+// DecodeFrom populates a KeyScanQueryParams from a URL.
+func (qp *KeyScanQueryParams) DecodeFrom(u *url.URL, h http.Header, rb queryBehavior) error {
+	key := u.Query().Get("key")
+	if key == "" {
+		return errors.Errorf("expected 'key' but got %q", key)
+	}
+	qp.key = selectors.Key(key)
+
+	return qp.ScanQueryParams.DecodeFrom(u, h, rb)
+}
+
 type queryBehavior int
 
 const (
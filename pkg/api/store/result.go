@@ -3,6 +3,7 @@ package store
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/SimonRichardson/coherence/pkg/api"
 	errs "github.com/SimonRichardson/coherence/pkg/api/http"
@@ -182,10 +183,100 @@ func (qr *FieldValueScoreQueryResult) EncodeTo(w http.ResponseWriter) {
 	}
 }
 
+// ChangesQueryResult contains statistics about the query.
+type ChangesQueryResult struct {
+	Errors   errs.Error
+	Params   ChangesQueryParams `json:"query"`
+	Duration string             `json:"duration"`
+	Events   []selectors.Event  `json:"events"`
+}
+
+// EncodeTo encodes the ChangesQueryResult to the HTTP response writer. The
+// next offset is the offset to read the following changes from.
+func (qr *ChangesQueryResult) EncodeTo(w http.ResponseWriter) {
+	next := qr.Params.Since()
+	if len(qr.Events) > 0 {
+		next = qr.Events[len(qr.Events)-1].Offset
+	}
+
+	w.Header().Set(httpHeaderContentType, defaultContentType)
+	w.Header().Set(httpHeaderDuration, qr.Duration)
+	w.Header().Set(httpHeaderOffset, strconv.FormatUint(next, 10))
+
+	records := make([]api.Event, len(qr.Events))
+	for k, v := range qr.Events {
+		records[k] = api.EventOutput(v)
+	}
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Records []api.Event `json:"records"`
+		Next    uint64      `json:"next"`
+	}{
+		Records: records,
+		Next:    next,
+	}); err != nil {
+		qr.Errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// KeysPageQueryResult contains statistics about the query.
+type KeysPageQueryResult struct {
+	Errors   errs.Error
+	Duration string             `json:"duration"`
+	KeysPage selectors.KeysPage `json:"page"`
+}
+
+// EncodeTo encodes the KeysPageQueryResult to the HTTP response writer. The
+// cursor is empty once the scan is finished.
+func (qr *KeysPageQueryResult) EncodeTo(w http.ResponseWriter) {
+	w.Header().Set(httpHeaderContentType, defaultContentType)
+	w.Header().Set(httpHeaderDuration, qr.Duration)
+	w.Header().Set(httpHeaderCursor, qr.KeysPage.Cursor.String())
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Records []api.Key `json:"records"`
+		Cursor  string    `json:"cursor"`
+	}{
+		Records: api.KeysOutput(qr.KeysPage.Keys),
+		Cursor:  qr.KeysPage.Cursor.String(),
+	}); err != nil {
+		qr.Errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// FieldsPageQueryResult contains statistics about the query.
+type FieldsPageQueryResult struct {
+	Errors     errs.Error
+	Params     KeyScanQueryParams   `json:"query"`
+	Duration   string               `json:"duration"`
+	FieldsPage selectors.FieldsPage `json:"page"`
+}
+
+// EncodeTo encodes the FieldsPageQueryResult to the HTTP response writer. The
+// cursor is empty once the scan is finished.
+func (qr *FieldsPageQueryResult) EncodeTo(w http.ResponseWriter) {
+	w.Header().Set(httpHeaderContentType, defaultContentType)
+	w.Header().Set(httpHeaderDuration, qr.Duration)
+	w.Header().Set(httpHeaderKey, qr.Params.Key().String())
+	w.Header().Set(httpHeaderCursor, qr.FieldsPage.Cursor.String())
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Records []api.Field `json:"records"`
+		Cursor  string      `json:"cursor"`
+	}{
+		Records: api.FieldsOutput(qr.FieldsPage.Fields),
+		Cursor:  qr.FieldsPage.Cursor.String(),
+	}); err != nil {
+		qr.Errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 const (
 	httpHeaderContentType = "Content-Type"
 	httpHeaderDuration    = "X-Duration"
 	httpHeaderKey         = "X-Key"
 	httpHeaderField       = "X-Field"
 	httpHeaderQuorum      = "X-Quorum"
+	httpHeaderOffset      = "X-Offset"
+	httpHeaderCursor      = "X-Cursor"
 )
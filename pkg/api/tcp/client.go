@@ -0,0 +1,252 @@
+package tcp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// Client sends requests to a host over a pool of persistent connections. Each
+// connection can have many requests in flight at once, the responses are
+// matched back to the requests as they arrive.
+type Client struct {
+	host    string
+	timeout time.Duration
+	tls     *tls.Config
+	next    uint32
+	mutex   sync.Mutex
+	conns   []*conn
+}
+
+// ClientOption defines a option for configuring the Client.
+type ClientOption func(*Client)
+
+// WithTLS dials the connections of the client over TLS with the config.
+func WithTLS(config *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.tls = config
+	}
+}
+
+// NewClient creates a Client for the host, with a pool of size connections,
+// which are dialed on demand.
+func NewClient(host string, size int, timeout time.Duration, opts ...ClientOption) *Client {
+	if size < 1 {
+		size = 1
+	}
+	c := &Client{
+		host:    host,
+		timeout: timeout,
+		conns:   make([]*conn, size),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Do sends the request body for the operation and waits for the response
+// body.
+func (c *Client) Do(op Op, body []byte) ([]byte, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := conn.do(op, body, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch Status(res.code) {
+	case StatusOK:
+		return res.body, nil
+	case StatusNotFound:
+		return nil, selectors.NewNotFoundError(errors.New(message(res.body)))
+	default:
+		return nil, errors.Errorf("invalid status: %s (%s)", Status(res.code), message(res.body))
+	}
+}
+
+// Host returns the host that the client talks to.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Close closes all the connections in the pool.
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for k, v := range c.conns {
+		if v != nil {
+			v.close(errors.New("client closed"))
+			c.conns[k] = nil
+		}
+	}
+	return nil
+}
+
+// conn picks the next connection from the pool in turn, dialing a new one if
+// it's not been dialed yet or if it's broken.
+func (c *Client) conn() (*conn, error) {
+	index := int(atomic.AddUint32(&c.next, 1) % uint32(len(c.conns)))
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if existing := c.conns[index]; existing != nil && !existing.broken() {
+		return existing, nil
+	}
+
+	nc, err := c.dial()
+	if err != nil {
+		return nil, errors.Wrap(err, "dial")
+	}
+	if _, err := nc.Write([]byte(Magic)); err != nil {
+		nc.Close()
+		return nil, errors.Wrap(err, "handshake")
+	}
+
+	res := newConn(nc)
+	c.conns[index] = res
+	return res, nil
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	if c.tls == nil {
+		return net.DialTimeout("tcp", c.host, c.timeout)
+	}
+	return tls.DialWithDialer(&net.Dialer{Timeout: c.timeout}, "tcp", c.host, c.tls)
+}
+
+type conn struct {
+	conn    net.Conn
+	writer  *bufio.Writer
+	wmutex  sync.Mutex
+	mutex   sync.Mutex
+	id      uint32
+	pending map[uint32]chan frame
+	err     error
+}
+
+func newConn(nc net.Conn) *conn {
+	c := &conn{
+		conn:    nc,
+		writer:  bufio.NewWriter(nc),
+		pending: make(map[uint32]chan frame),
+	}
+	go c.read()
+	return c
+}
+
+func (c *conn) do(op Op, body []byte, timeout time.Duration) (frame, error) {
+	ch := make(chan frame, 1)
+
+	c.mutex.Lock()
+	if c.err != nil {
+		c.mutex.Unlock()
+		return frame{}, c.err
+	}
+	c.id++
+	id := c.id
+	c.pending[id] = ch
+	c.mutex.Unlock()
+
+	// Requests are written without waiting for the previous response, so that
+	// they're pipelined over the connection.
+	c.wmutex.Lock()
+	err := writeFrame(c.writer, frame{
+		id:   id,
+		code: byte(op),
+		body: body,
+	})
+	if err == nil {
+		err = c.writer.Flush()
+	}
+	c.wmutex.Unlock()
+
+	if err != nil {
+		c.close(errors.Wrap(err, "write"))
+		return frame{}, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res, ok := <-ch:
+		if !ok {
+			return frame{}, c.failure()
+		}
+		return res, nil
+	case <-timer.C:
+		c.mutex.Lock()
+		delete(c.pending, id)
+		c.mutex.Unlock()
+		return frame{}, errors.Errorf("timed out after %s", timeout)
+	}
+}
+
+func (c *conn) read() {
+	reader := bufio.NewReader(c.conn)
+	for {
+		res, err := readFrame(reader)
+		if err != nil {
+			c.close(errors.Wrap(err, "read"))
+			return
+		}
+
+		c.mutex.Lock()
+		ch, ok := c.pending[res.id]
+		delete(c.pending, res.id)
+		c.mutex.Unlock()
+
+		// A missing request is one that has already timed out.
+		if ok {
+			ch <- res
+		}
+	}
+}
+
+// close the connection and fail all the requests that are waiting for a
+// response.
+func (c *conn) close(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.conn.Close()
+
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+func (c *conn) broken() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err != nil
+}
+
+func (c *conn) failure() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+func message(body []byte) string {
+	return NewDecoder(body).String()
+}
@@ -0,0 +1,232 @@
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	t.Run("insert and select", func(t *testing.T) {
+		client, closer := newClient(t)
+		defer closer()
+
+		member := selectors.FieldValueScore{
+			Field: "field",
+			Value: []byte("value"),
+			Score: 1,
+		}
+		body := NewEncoder().Key("key").Members([]selectors.FieldValueScore{member}).Bytes()
+		if _, err := client.Do(OpInsert, body); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(OpSelect, NewEncoder().Key("key").Field("field").Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dec := NewDecoder(res)
+		actual := dec.Member()
+		if err := dec.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if expected := member; expected.Field != actual.Field ||
+			string(expected.Value) != string(actual.Value) ||
+			expected.Score != actual.Score {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client, closer := newClient(t)
+		defer closer()
+
+		_, err := client.Do(OpSelect, NewEncoder().Key("key").Field("field").Bytes())
+		if expected, actual := true, selectors.NotFoundError(err); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		client, closer := newClient(t)
+		defer closer()
+
+		_, err := client.Do(OpInsert, []byte{0xff})
+		if err == nil {
+			t.Errorf("expected error")
+		}
+
+		// The connection should still be usable after a bad request.
+		if _, err := client.Do(OpKeys, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("pipelined", func(t *testing.T) {
+		client, closer := newClient(t)
+		defer closer()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 500; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				var (
+					key   = selectors.Key(fmt.Sprintf("key-%d", i))
+					field = selectors.Field(fmt.Sprintf("field-%d", i))
+				)
+				body := NewEncoder().Key(key).Members([]selectors.FieldValueScore{
+					{Field: field, Score: int64(i)},
+				}).Bytes()
+				if _, err := client.Do(OpInsert, body); err != nil {
+					t.Error(err)
+					return
+				}
+
+				res, err := client.Do(OpScore, NewEncoder().Key(key).Field(field).Bytes())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				dec := NewDecoder(res)
+				if expected, actual := int64(i), dec.Presence().Score; expected != actual {
+					t.Errorf("expected: %v, actual: %v", expected, actual)
+				}
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("reconnects", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		server := newServer(t)
+		go server.Serve(listener)
+
+		client := NewClient(listener.Addr().String(), 1, time.Second)
+		defer client.Close()
+
+		if _, err := client.Do(OpKeys, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Closing the server closes all of the connections, but the listener
+		// is still open, so a new connection can be made.
+		server.Close()
+		server = newServer(t)
+		defer server.Close()
+		go server.Serve(listener)
+
+		// The first request might fail before the client notices that the
+		// connection is broken.
+		client.Do(OpKeys, nil)
+		if _, err := client.Do(OpKeys, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		// Accept connections, but never respond.
+		go func() {
+			var conns []net.Conn
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					break
+				}
+				conns = append(conns, conn)
+			}
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		client := NewClient(listener.Addr().String(), 1, time.Millisecond*10)
+		defer client.Close()
+
+		if _, err := client.Do(OpKeys, nil); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestServerInvalidMagic(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	server := newServer(t)
+	defer server.Close()
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+}
+
+func newServer(t *testing.T) *Server {
+	persistence, err := store.New(fsys.NewNopFilesystem(), 1, 1000, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewServer(persistence,
+		log.NewNopLogger(),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+	)
+}
+
+func newClient(t *testing.T) (*Client, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newServer(t)
+	go server.Serve(listener)
+
+	client := NewClient(listener.Addr().String(), 2, time.Second*5)
+	return client, func() {
+		client.Close()
+		listener.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,318 @@
+package tcp
+
+import (
+	"encoding/binary"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// Encoder writes values in to a compact binary body. Strings and bytes are
+// prefixed with their length and integers are written as varints.
+type Encoder struct {
+	buf []byte
+	tmp [binary.MaxVarintLen64]byte
+}
+
+// NewEncoder creates an Encoder with an empty body.
+func NewEncoder() *Encoder {
+	return &Encoder{}
+}
+
+// Bytes returns the encoded body.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Uint writes an unsigned integer.
+func (e *Encoder) Uint(v uint64) *Encoder {
+	n := binary.PutUvarint(e.tmp[:], v)
+	e.buf = append(e.buf, e.tmp[:n]...)
+	return e
+}
+
+// Int writes a signed integer.
+func (e *Encoder) Int(v int64) *Encoder {
+	n := binary.PutVarint(e.tmp[:], v)
+	e.buf = append(e.buf, e.tmp[:n]...)
+	return e
+}
+
+// Bool writes a boolean.
+func (e *Encoder) Bool(v bool) *Encoder {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+	return e
+}
+
+// Blob writes a length prefixed slice of bytes.
+func (e *Encoder) Blob(v []byte) *Encoder {
+	e.Uint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+	return e
+}
+
+// String writes a length prefixed string.
+func (e *Encoder) String(v string) *Encoder {
+	e.Uint(uint64(len(v)))
+	e.buf = append(e.buf, v...)
+	return e
+}
+
+// Key writes a key.
+func (e *Encoder) Key(key selectors.Key) *Encoder {
+	return e.String(key.String())
+}
+
+// Field writes a field.
+func (e *Encoder) Field(field selectors.Field) *Encoder {
+	return e.String(field.String())
+}
+
+// Member writes a field, value and score.
+func (e *Encoder) Member(member selectors.FieldValueScore) *Encoder {
+	return e.Field(member.Field).Blob(member.Value).Int(member.Score)
+}
+
+// Members writes a slice of members.
+func (e *Encoder) Members(members []selectors.FieldValueScore) *Encoder {
+	e.Uint(uint64(len(members)))
+	for _, v := range members {
+		e.Member(v)
+	}
+	return e
+}
+
+// Keys writes a slice of keys.
+func (e *Encoder) Keys(keys []selectors.Key) *Encoder {
+	e.Uint(uint64(len(keys)))
+	for _, v := range keys {
+		e.Key(v)
+	}
+	return e
+}
+
+// Fields writes a slice of fields.
+func (e *Encoder) Fields(fields []selectors.Field) *Encoder {
+	e.Uint(uint64(len(fields)))
+	for _, v := range fields {
+		e.Field(v)
+	}
+	return e
+}
+
+// ChangeSet writes the success and failure fields.
+func (e *Encoder) ChangeSet(changeSet selectors.ChangeSet) *Encoder {
+	return e.Fields(changeSet.Success).Fields(changeSet.Failure)
+}
+
+// Presence writes the presence of a member.
+func (e *Encoder) Presence(presence selectors.Presence) *Encoder {
+	return e.Bool(presence.Present).Bool(presence.Inserted).Int(presence.Score)
+}
+
+// Scan writes the cursor and options of a scan.
+func (e *Encoder) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) *Encoder {
+	return e.String(cursor.String()).String(opts.Match).Uint(uint64(opts.Size()))
+}
+
+// KeysPage writes a page of keys and the cursor for the next page.
+func (e *Encoder) KeysPage(page selectors.KeysPage) *Encoder {
+	return e.Keys(page.Keys).String(page.Cursor.String())
+}
+
+// FieldsPage writes a page of fields and the cursor for the next page.
+func (e *Encoder) FieldsPage(page selectors.FieldsPage) *Encoder {
+	return e.Fields(page.Fields).String(page.Cursor.String())
+}
+
+// Decoder reads values that were written by the Encoder. The first error is
+// kept and every read after it returns the zero value, so that the error only
+// needs checking once all the values have been read.
+type Decoder struct {
+	buf []byte
+	err error
+}
+
+// NewDecoder creates a Decoder for the body.
+func NewDecoder(buf []byte) *Decoder {
+	return &Decoder{
+		buf: buf,
+	}
+}
+
+// Err returns the first error that was encountered whilst decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Uint reads an unsigned integer.
+func (d *Decoder) Uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errors.New("invalid unsigned integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// Int reads a signed integer.
+func (d *Decoder) Int() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errors.New("invalid integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// Bool reads a boolean.
+func (d *Decoder) Bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) < 1 {
+		d.err = errors.New("invalid boolean")
+		return false
+	}
+	v := d.buf[0]
+	d.buf = d.buf[1:]
+	return v == 1
+}
+
+// Blob reads a length prefixed slice of bytes.
+func (d *Decoder) Blob() []byte {
+	size := d.Uint()
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.buf)) < size {
+		d.err = errors.Errorf("invalid length: %d", size)
+		return nil
+	}
+	v := d.buf[:size:size]
+	d.buf = d.buf[size:]
+	return v
+}
+
+// String reads a length prefixed string.
+func (d *Decoder) String() string {
+	return string(d.Blob())
+}
+
+// Key reads a key.
+func (d *Decoder) Key() selectors.Key {
+	return selectors.Key(d.String())
+}
+
+// Field reads a field.
+func (d *Decoder) Field() selectors.Field {
+	return selectors.Field(d.String())
+}
+
+// Member reads a field, value and score.
+func (d *Decoder) Member() selectors.FieldValueScore {
+	return selectors.FieldValueScore{
+		Field: d.Field(),
+		Value: d.Blob(),
+		Score: d.Int(),
+	}
+}
+
+// Members reads a slice of members.
+func (d *Decoder) Members() []selectors.FieldValueScore {
+	size := d.length()
+	res := make([]selectors.FieldValueScore, size)
+	for k := range res {
+		res[k] = d.Member()
+	}
+	return res
+}
+
+// Keys reads a slice of keys.
+func (d *Decoder) Keys() []selectors.Key {
+	size := d.length()
+	res := make([]selectors.Key, size)
+	for k := range res {
+		res[k] = d.Key()
+	}
+	return res
+}
+
+// Fields reads a slice of fields.
+func (d *Decoder) Fields() []selectors.Field {
+	size := d.length()
+	res := make([]selectors.Field, size)
+	for k := range res {
+		res[k] = d.Field()
+	}
+	return res
+}
+
+// ChangeSet reads the success and failure fields.
+func (d *Decoder) ChangeSet() selectors.ChangeSet {
+	return selectors.ChangeSet{
+		Success: d.Fields(),
+		Failure: d.Fields(),
+	}
+}
+
+// Presence reads the presence of a member.
+func (d *Decoder) Presence() selectors.Presence {
+	return selectors.Presence{
+		Present:  d.Bool(),
+		Inserted: d.Bool(),
+		Score:    d.Int(),
+	}
+}
+
+// Scan reads the cursor and options of a scan.
+func (d *Decoder) Scan() (selectors.Cursor, selectors.ScanOptions) {
+	cursor, match, count := selectors.Cursor(d.String()), d.String(), d.Uint()
+	return cursor, selectors.ScanOptions{
+		Match: match,
+		Count: int(count),
+	}
+}
+
+// KeysPage reads a page of keys and the cursor for the next page.
+func (d *Decoder) KeysPage() selectors.KeysPage {
+	return selectors.KeysPage{
+		Keys:   d.Keys(),
+		Cursor: selectors.Cursor(d.String()),
+	}
+}
+
+// FieldsPage reads a page of fields and the cursor for the next page.
+func (d *Decoder) FieldsPage() selectors.FieldsPage {
+	return selectors.FieldsPage{
+		Fields: d.Fields(),
+		Cursor: selectors.Cursor(d.String()),
+	}
+}
+
+// length reads the number of items in a slice, every item takes at least a
+// byte, so anything larger than the remaining body is an error and prevents
+// allocating a huge slice from a bad length.
+func (d *Decoder) length() int {
+	size := d.Uint()
+	if d.err != nil {
+		return 0
+	}
+	if uint64(len(d.buf)) < size {
+		d.err = errors.Errorf("invalid length: %d", size)
+		return 0
+	}
+	return int(size)
+}
@@ -0,0 +1,106 @@
+package tcp
+
+import (
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	t.Run("members", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			dec := NewDecoder(NewEncoder().Key(key).Members(members).Bytes())
+
+			k, m := dec.Key(), dec.Members()
+			if err := dec.Err(); err != nil {
+				t.Fatal(err)
+			}
+			return k == key && reflect.DeepEqual(normalise(members), normalise(m))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		fn := func(keys []selectors.Key) bool {
+			dec := NewDecoder(NewEncoder().Keys(keys).Bytes())
+
+			res := dec.Keys()
+			if err := dec.Err(); err != nil {
+				t.Fatal(err)
+			}
+			return len(keys) == len(res) && (len(keys) == 0 || reflect.DeepEqual(keys, res))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("change set", func(t *testing.T) {
+		fn := func(success, failure []selectors.Field) bool {
+			changeSet := selectors.ChangeSet{
+				Success: success,
+				Failure: failure,
+			}
+			dec := NewDecoder(NewEncoder().ChangeSet(changeSet).Bytes())
+
+			res := dec.ChangeSet()
+			if err := dec.Err(); err != nil {
+				t.Fatal(err)
+			}
+			return len(success) == len(res.Success) &&
+				len(failure) == len(res.Failure) &&
+				(len(success) == 0 || reflect.DeepEqual(success, res.Success)) &&
+				(len(failure) == 0 || reflect.DeepEqual(failure, res.Failure))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("presence", func(t *testing.T) {
+		fn := func(presence selectors.Presence) bool {
+			dec := NewDecoder(NewEncoder().Presence(presence).Bytes())
+
+			res := dec.Presence()
+			if err := dec.Err(); err != nil {
+				t.Fatal(err)
+			}
+			return presence == res
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			body := NewEncoder().Key(key).Members(members).Bytes()
+
+			dec := NewDecoder(body[:len(body)-1])
+			dec.Key()
+			dec.Members()
+			return dec.Err() != nil
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// normalise the members so that an empty value matches a nil value.
+func normalise(members []selectors.FieldValueScore) []selectors.FieldValueScore {
+	res := make([]selectors.FieldValueScore, len(members))
+	for k, v := range members {
+		if len(v.Value) == 0 {
+			v.Value = nil
+		}
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,152 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Magic is sent by the client when it first connects, so that the server can
+// tell the binary protocol apart from HTTP on a shared listener.
+const Magic = "COH\x01"
+
+const (
+	// MaxFrameSize is the largest frame that will be read, anything larger is
+	// considered a protocol error.
+	MaxFrameSize = 64 << 20
+
+	headerSize = 4 + 4 + 1
+)
+
+// Op describes the operation of a request.
+type Op byte
+
+const (
+	// OpInsert inserts members for a key.
+	OpInsert Op = iota + 1
+	// OpDelete deletes members for a key.
+	OpDelete
+	// OpSelect selects a member for a key.
+	OpSelect
+	// OpKeys returns all the keys.
+	OpKeys
+	// OpSize returns the number of members for a key.
+	OpSize
+	// OpMembers returns the fields of the members for a key.
+	OpMembers
+	// OpScore returns the presence of a member for a key.
+	OpScore
+	// OpScan returns the next page of keys after a cursor.
+	OpScan
+	// OpScanMembers returns the next page of fields for a key after a cursor.
+	OpScanMembers
+	// OpSwap inserts a member for a key, only if its score is the token.
+	OpSwap
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpInsert:
+		return "insert"
+	case OpDelete:
+		return "delete"
+	case OpSelect:
+		return "select"
+	case OpKeys:
+		return "keys"
+	case OpSize:
+		return "size"
+	case OpMembers:
+		return "members"
+	case OpScore:
+		return "score"
+	case OpScan:
+		return "scan"
+	case OpScanMembers:
+		return "scan_members"
+	case OpSwap:
+		return "swap"
+	default:
+		return "unknown"
+	}
+}
+
+// Status describes the outcome of a request.
+type Status byte
+
+const (
+	// StatusOK is when the request was successful.
+	StatusOK Status = iota
+	// StatusNotFound is when the request couldn't find the key or field.
+	StatusNotFound
+	// StatusBadRequest is when the request couldn't be decoded.
+	StatusBadRequest
+	// StatusError is when the request failed.
+	StatusError
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusNotFound:
+		return "not_found"
+	case StatusBadRequest:
+		return "bad_request"
+	case StatusError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// frame is the unit that is sent over the wire in both directions. The id is
+// used to match responses to requests, so that many requests can be in flight
+// on the same connection. The code is either an Op or a Status, depending on
+// the direction.
+//
+//	uint32 length | uint32 id | uint8 code | body
+//
+// The length includes the id and code, but not itself.
+type frame struct {
+	id   uint32
+	code byte
+	body []byte
+}
+
+func writeFrame(w io.Writer, f frame) error {
+	var header [headerSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(f.body)+5))
+	binary.BigEndian.PutUint32(header[4:8], f.id)
+	header[8] = f.code
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(f.body)
+	return err
+}
+
+func readFrame(r io.Reader) (frame, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size < 5 || size > MaxFrameSize {
+		return frame{}, errors.Errorf("invalid frame size: %d", size)
+	}
+
+	body := make([]byte, size-5)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return frame{}, err
+	}
+
+	return frame{
+		id:   binary.BigEndian.Uint32(header[4:8]),
+		code: header[8],
+		body: body,
+	}, nil
+}
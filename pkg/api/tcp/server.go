@@ -0,0 +1,331 @@
+package tcp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/api/writes"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxInFlight is the number of requests that can be processed at once for
+	// each connection, before the server stops reading new requests.
+	maxInFlight = 128
+)
+
+// Server serves the store over the binary protocol.
+type Server struct {
+	store    store.Store
+	logger   log.Logger
+	clients  metrics.Gauge
+	duration metrics.HistogramVec
+	writes   *writes.Queue
+	mutex    sync.Mutex
+	conns    map[net.Conn]struct{}
+}
+
+// NewServer creates a Server with the correct dependencies.
+func NewServer(store store.Store,
+	logger log.Logger,
+	clients metrics.Gauge,
+	duration metrics.HistogramVec,
+) *Server {
+	server := &Server{
+		store:    store,
+		logger:   logger,
+		clients:  clients,
+		duration: duration,
+		writes:   writes.NewQueue(),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	return server
+}
+
+// Serve accepts connections on the listener, until the listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// Close out the Server, along with any open connections.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.writes.Close()
+}
+
+func (s *Server) serve(conn net.Conn) {
+	s.clients.Inc()
+	defer s.clients.Dec()
+
+	s.track(conn, true)
+	defer s.track(conn, false)
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != Magic {
+		level.Warn(s.logger).Log("err", "invalid magic", "remote", conn.RemoteAddr())
+		return
+	}
+
+	var (
+		writer = bufio.NewWriter(conn)
+		mutex  sync.Mutex
+		wg     sync.WaitGroup
+		limit  = make(chan struct{}, maxInFlight)
+	)
+	defer wg.Wait()
+
+	for {
+		req, err := readFrame(reader)
+		if err != nil {
+			if err != io.EOF {
+				level.Debug(s.logger).Log("err", err, "remote", conn.RemoteAddr())
+			}
+			return
+		}
+
+		limit <- struct{}{}
+		wg.Add(1)
+		go func(req frame) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			res := s.handle(req)
+
+			// Responses can be sent out of order, the id is used by the client
+			// to match them back up to the requests.
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			err := writeFrame(writer, res)
+			if err == nil {
+				err = writer.Flush()
+			}
+			if err != nil {
+				// The connection is broken, so closing it stops the reading of
+				// any more requests.
+				conn.Close()
+			}
+		}(req)
+	}
+}
+
+func (s *Server) track(conn net.Conn, open bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if open {
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+}
+
+func (s *Server) handle(req frame) frame {
+	var (
+		op    = Op(req.code)
+		begin = time.Now()
+	)
+
+	status, body := s.dispatch(op, NewDecoder(req.body))
+
+	s.duration.WithLabelValues(
+		"TCP",
+		op.String(),
+		status.String(),
+	).Observe(time.Since(begin).Seconds())
+
+	return frame{
+		id:   req.id,
+		code: byte(status),
+		body: body,
+	}
+}
+
+func (s *Server) dispatch(op Op, dec *Decoder) (Status, []byte) {
+	switch op {
+	case OpInsert:
+		key, members := dec.Key(), dec.Members()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		return s.write(key, members, s.store.Insert)
+
+	case OpDelete:
+		key, members := dec.Key(), dec.Members()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		return s.write(key, members, s.store.Delete)
+
+	case OpSwap:
+		key, member, token := dec.Key(), dec.Member(), dec.Int()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		return s.write(key, []selectors.FieldValueScore{member}, func(key selectors.Key, members []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+			return s.store.Swap(key, members[0], token)
+		})
+
+	case OpSelect:
+		key, field := dec.Key(), dec.Field()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		member, err := s.store.Select(key, field)
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().Member(member).Bytes()
+
+	case OpKeys:
+		keys, err := s.store.Keys()
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().Keys(keys).Bytes()
+
+	case OpSize:
+		key := dec.Key()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		size, err := s.store.Size(key)
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().Int(size).Bytes()
+
+	case OpMembers:
+		key := dec.Key()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		members, err := s.store.Members(key)
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().Fields(members).Bytes()
+
+	case OpScore:
+		key, field := dec.Key(), dec.Field()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		presence, err := s.store.Score(key, field)
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().Presence(presence).Bytes()
+
+	case OpScan:
+		cursor, opts := dec.Scan()
+		if status, body := s.validateScan(dec, cursor); status != StatusOK {
+			return status, body
+		}
+		page, err := s.store.Scan(cursor, opts)
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().KeysPage(page).Bytes()
+
+	case OpScanMembers:
+		key := dec.Key()
+		cursor, opts := dec.Scan()
+		if status, body := s.validate(dec, key); status != StatusOK {
+			return status, body
+		}
+		if status, body := s.validateScan(dec, cursor); status != StatusOK {
+			return status, body
+		}
+		page, err := s.store.ScanMembers(key, cursor, opts)
+		if err != nil {
+			return s.error(err)
+		}
+		return StatusOK, NewEncoder().FieldsPage(page).Bytes()
+
+	default:
+		return StatusBadRequest, NewEncoder().String(errors.Errorf("unexpected op: %d", op).Error()).Bytes()
+	}
+}
+
+func (s *Server) write(key selectors.Key,
+	members []selectors.FieldValueScore,
+	fn func(selectors.Key, []selectors.FieldValueScore) (selectors.ChangeSet, error),
+) (Status, []byte) {
+	var (
+		internalError = make(chan error, 1)
+		result        = make(chan selectors.ChangeSet, 1)
+	)
+	if err := s.writes.Apply(key, func() {
+		changeSet, err := fn(key, members)
+		if err != nil {
+			internalError <- err
+			return
+		}
+		result <- changeSet
+	}); err != nil {
+		return StatusError, NewEncoder().String(err.Error()).Bytes()
+	}
+
+	select {
+	case err := <-internalError:
+		return s.error(err)
+	case changeSet := <-result:
+		return StatusOK, NewEncoder().ChangeSet(changeSet).Bytes()
+	}
+}
+
+func (s *Server) validate(dec *Decoder, key selectors.Key) (Status, []byte) {
+	if err := dec.Err(); err != nil {
+		return StatusBadRequest, NewEncoder().String(err.Error()).Bytes()
+	}
+	if key.String() == "" {
+		return StatusBadRequest, NewEncoder().String("expected 'key' but got ''").Bytes()
+	}
+	return StatusOK, nil
+}
+
+func (s *Server) validateScan(dec *Decoder, cursor selectors.Cursor) (Status, []byte) {
+	if err := dec.Err(); err != nil {
+		return StatusBadRequest, NewEncoder().String(err.Error()).Bytes()
+	}
+	if _, _, err := cursor.After(); err != nil {
+		return StatusBadRequest, NewEncoder().String(err.Error()).Bytes()
+	}
+	return StatusOK, nil
+}
+
+func (s *Server) error(err error) (Status, []byte) {
+	if selectors.NotFoundError(err) {
+		return StatusNotFound, NewEncoder().String(err.Error()).Bytes()
+	}
+
+	level.Error(s.logger).Log("err", err)
+	return StatusError, NewEncoder().String(err.Error()).Bytes()
+}
@@ -1,6 +1,10 @@
 package api
 
-import "github.com/SimonRichardson/coherence/pkg/selectors"
+import (
+	"context"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
 
 // TransportStrategy defines a way to create a transport
 type TransportStrategy interface {
@@ -49,4 +53,55 @@ type Transport interface {
 
 	// Score returns the specific score for the field with in the key.
 	Score(selectors.Key, selectors.Field) (selectors.Presence, error)
+
+	// Scan returns the next page of keys after the cursor.
+	Scan(selectors.Cursor, selectors.ScanOptions) (selectors.KeysPage, error)
+
+	// ScanMembers returns the next page of members for a key after the cursor.
+	ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions) (selectors.FieldsPage, error)
+}
+
+// Watcher is implemented by the transports that can stream the changes of a
+// key from the store.
+type Watcher interface {
+
+	// Watch subscribes to the changes of a key, resuming from either an offset
+	// or a score.
+	Watch(selectors.Key, selectors.Resume) (selectors.Subscription, error)
+}
+
+// Swapper is implemented by the transports that can swap a member of the
+// store, only if its score is still the token.
+type Swapper interface {
+
+	// Swap inserts the member only if its field is inserted with the score
+	// of the token. The field is a failure of the ChangeSet if the score
+	// doesn't match.
+	Swap(selectors.Key, selectors.FieldValueScore, int64) (selectors.ChangeSet, error)
+}
+
+// Contextual is implemented by the transports that can send the trace of a
+// context along with their requests.
+type Contextual interface {
+
+	// WithContext returns a copy of the transport that sends the trace of the
+	// context.
+	WithContext(context.Context) Transport
+}
+
+// WithContext returns the transport sending the trace of the context, if it
+// can, otherwise the transport as it is.
+func WithContext(ctx context.Context, transport Transport) Transport {
+	if contextual, ok := transport.(Contextual); ok {
+		return contextual.WithContext(ctx)
+	}
+	return transport
+}
+
+// Circuit is implemented by the transports that stop sending requests, for a
+// while, after too many of them fail.
+type Circuit interface {
+
+	// CircuitOpen returns true if the transport is refusing to send requests.
+	CircuitOpen() bool
 }
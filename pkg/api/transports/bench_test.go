@@ -0,0 +1,140 @@
+package transports
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/client"
+	apiStore "github.com/SimonRichardson/coherence/pkg/api/store"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
+)
+
+var benchmarkTransports = []struct {
+	name string
+	fn   func(testing.TB) (api.Transport, func())
+}{
+	{"http", newHTTPTransport},
+	{"grpc", newGRPCTransport},
+	{"tcp", newTCPTransport},
+}
+
+func BenchmarkTransportInsert(b *testing.B) {
+	for _, v := range benchmarkTransports {
+		b.Run(v.name, func(b *testing.B) {
+			transport, closer := v.fn(b)
+			defer closer()
+
+			var (
+				id      uint64
+				members = benchmarkMembers(10)
+			)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := selectors.Key(fmt.Sprintf("key-%d", atomic.AddUint64(&id, 1)%1000))
+					if _, err := transport.Insert(key, members); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkTransportSelect(b *testing.B) {
+	for _, v := range benchmarkTransports {
+		b.Run(v.name, func(b *testing.B) {
+			transport, closer := v.fn(b)
+			defer closer()
+
+			members := benchmarkMembers(10)
+			if _, err := transport.Insert("key", members); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := transport.Select("key", members[0].Field); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkTransportMembers(b *testing.B) {
+	for _, v := range benchmarkTransports {
+		b.Run(v.name, func(b *testing.B) {
+			transport, closer := v.fn(b)
+			defer closer()
+
+			if _, err := transport.Insert("key", benchmarkMembers(1000)); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := transport.Members("key"); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func benchmarkMembers(n int) []selectors.FieldValueScore {
+	res := make([]selectors.FieldValueScore, n)
+	for k := range res {
+		res[k] = selectors.FieldValueScore{
+			Field: selectors.Field(fmt.Sprintf("field-%d", k)),
+			Value: []byte("value"),
+			Score: int64(k),
+		}
+	}
+	return res
+}
+
+func newHTTPTransport(t testing.TB) (api.Transport, func()) {
+	persistence, err := store.New(fsys.NewNopFilesystem(), 1, 10000, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storeAPI := apiStore.NewAPI(persistence,
+		log.NewNopLogger(),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/store/", http.StripPrefix("/store", storeAPI))
+	server := httptest.NewServer(mux)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHTTPTransport(client.New(cleanhttp.DefaultPooledClient(), "http", u.Host)), func() {
+		server.Close()
+		storeAPI.Close()
+	}
+}
@@ -0,0 +1,209 @@
+package transports
+
+import (
+	"io"
+
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/rpc"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+	"github.com/spaolacci/murmur3"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type grpcTransport struct {
+	hash   uint32
+	host   string
+	client rpc.StoreClient
+}
+
+// NewGRPCTransport creates a new Transport that uses the gRPC protocol
+func NewGRPCTransport(client rpc.StoreClient, host string) api.Transport {
+	return &grpcTransport{
+		hash:   murmur3.Sum32([]byte(host)),
+		host:   host,
+		client: client,
+	}
+}
+
+func (t *grpcTransport) Insert(key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+	res, err := t.client.Insert(context.Background(), &rpc.WriteRequest{
+		Key:     key.String(),
+		Members: rpc.FromMembers(fields),
+	})
+	if err != nil {
+		return selectors.ChangeSet{}, grpcError(err)
+	}
+	return changeSet(res), nil
+}
+
+func (t *grpcTransport) Delete(key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+	res, err := t.client.Delete(context.Background(), &rpc.WriteRequest{
+		Key:     key.String(),
+		Members: rpc.FromMembers(fields),
+	})
+	if err != nil {
+		return selectors.ChangeSet{}, grpcError(err)
+	}
+	return changeSet(res), nil
+}
+
+func (t *grpcTransport) Swap(key selectors.Key, field selectors.FieldValueScore, token int64) (selectors.ChangeSet, error) {
+	res, err := t.client.Swap(context.Background(), &rpc.SwapRequest{
+		Key:    key.String(),
+		Member: rpc.FromMember(field),
+		Token:  token,
+	})
+	if err != nil {
+		return selectors.ChangeSet{}, grpcError(err)
+	}
+	return changeSet(res), nil
+}
+
+func (t *grpcTransport) Select(key selectors.Key, field selectors.Field) (selectors.FieldValueScore, error) {
+	res, err := t.client.Select(context.Background(), &rpc.FieldRequest{
+		Key:   key.String(),
+		Field: field.String(),
+	})
+	if err != nil {
+		return selectors.FieldValueScore{}, grpcError(err)
+	}
+	if res.GetMember() == nil {
+		return selectors.FieldValueScore{}, errors.New("missing member")
+	}
+	return rpc.ToMember(res.GetMember()), nil
+}
+
+func (t *grpcTransport) Keys() ([]selectors.Key, error) {
+	stream, err := t.client.Keys(context.Background(), &rpc.KeysRequest{})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var res []selectors.Key
+	for {
+		page, err := stream.Recv()
+		if err == io.EOF {
+			return res, nil
+		} else if err != nil {
+			return nil, grpcError(err)
+		}
+		res = append(res, rpc.ToKeys(page.GetKeys())...)
+	}
+}
+
+func (t *grpcTransport) Size(key selectors.Key) (int64, error) {
+	res, err := t.client.Size(context.Background(), &rpc.KeyRequest{
+		Key: key.String(),
+	})
+	if err != nil {
+		return 0, grpcError(err)
+	}
+	return res.GetSize(), nil
+}
+
+func (t *grpcTransport) Members(key selectors.Key) ([]selectors.Field, error) {
+	stream, err := t.client.Members(context.Background(), &rpc.MembersRequest{
+		Key: key.String(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	var res []selectors.Field
+	for {
+		page, err := stream.Recv()
+		if err == io.EOF {
+			return res, nil
+		} else if err != nil {
+			return nil, grpcError(err)
+		}
+		res = append(res, rpc.ToFields(page.GetFields())...)
+	}
+}
+
+func (t *grpcTransport) Score(key selectors.Key, field selectors.Field) (selectors.Presence, error) {
+	res, err := t.client.Score(context.Background(), &rpc.FieldRequest{
+		Key:   key.String(),
+		Field: field.String(),
+	})
+	if err != nil {
+		return selectors.Presence{}, grpcError(err)
+	}
+	return selectors.Presence{
+		Present:  res.GetPresent(),
+		Inserted: res.GetInserted(),
+		Score:    res.GetScore(),
+	}, nil
+}
+
+func (t *grpcTransport) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.KeysPage, error) {
+	res, err := t.client.Scan(context.Background(), rpc.FromScan("", cursor, opts))
+	if err != nil {
+		return selectors.KeysPage{}, grpcError(err)
+	}
+	return selectors.KeysPage{
+		Keys:   rpc.ToKeys(res.GetValues()),
+		Cursor: selectors.Cursor(res.GetCursor()),
+	}, nil
+}
+
+func (t *grpcTransport) ScanMembers(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.FieldsPage, error) {
+	res, err := t.client.ScanMembers(context.Background(), rpc.FromScan(key, cursor, opts))
+	if err != nil {
+		return selectors.FieldsPage{}, grpcError(err)
+	}
+	return selectors.FieldsPage{
+		Fields: rpc.ToFields(res.GetValues()),
+		Cursor: selectors.Cursor(res.GetCursor()),
+	}, nil
+}
+
+func (t *grpcTransport) Watch(key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	kind, value := rpc.FromResume(resume)
+	stream, err := t.client.Watch(ctx, &rpc.WatchRequest{
+		Key:         key.String(),
+		Resume:      kind,
+		ResumeValue: value,
+	})
+	if err != nil {
+		cancel()
+		return nil, grpcError(err)
+	}
+
+	return newStreamSubscription(func() (selectors.Event, error) {
+		event, err := stream.Recv()
+		if err != nil {
+			return selectors.Event{}, grpcError(err)
+		}
+		return rpc.ToEvent(event), nil
+	}, cancel), nil
+}
+
+func (t *grpcTransport) Hash() uint32 {
+	return t.hash
+}
+
+func (t *grpcTransport) Host() string {
+	return t.host
+}
+
+func changeSet(res *rpc.ChangeSetResponse) selectors.ChangeSet {
+	return selectors.ChangeSet{
+		Success: rpc.ToFields(res.GetSuccess()),
+		Failure: rpc.ToFields(res.GetFailure()),
+	}
+}
+
+// grpcError converts the gRPC status in to the same errors that the HTTP
+// transport returns, so that a not found is still a not found.
+func grpcError(err error) error {
+	if status.Code(err) == codes.NotFound {
+		return selectors.NewNotFoundError(err)
+	}
+	return err
+}
@@ -0,0 +1,232 @@
+package transports
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/rpc"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
+	"google.golang.org/grpc"
+)
+
+func TestGRPCTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("insert", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			transport, closer := newGRPCTransport(t)
+			defer closer()
+
+			changeSet, err := transport.Insert(key, members)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return reflect.DeepEqual(sortFields(uniqueFields(members)), sortFields(changeSet.Success))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		transport, closer := newGRPCTransport(t)
+		defer closer()
+
+		if _, err := transport.Insert("a", []selectors.FieldValueScore{{Field: "x", Score: 1}}); err != nil {
+			t.Fatal(err)
+		}
+
+		swapper := transport.(api.Swapper)
+		changeSet, err := swapper.Swap("a", selectors.FieldValueScore{Field: "x", Value: []byte("v"), Score: 2}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"x"}, changeSet.Success; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		// The token has been swapped, so it doesn't match any more.
+		changeSet, err = swapper.Swap("a", selectors.FieldValueScore{Field: "x", Value: []byte("w"), Score: 3}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"x"}, changeSet.Failure; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		if _, err := swapper.Swap("a", selectors.FieldValueScore{Field: "y", Score: 2}, 1); !selectors.NotFoundError(err) {
+			t.Errorf("expected not found error, actual: %v", err)
+		}
+	})
+
+	t.Run("select not found", func(t *testing.T) {
+		fn := func(key selectors.Key, field selectors.Field) bool {
+			transport, closer := newGRPCTransport(t)
+			defer closer()
+
+			_, err := transport.Select(key, field)
+			return selectors.NotFoundError(err)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		fn := func(keys []selectors.Key) bool {
+			transport, closer := newGRPCTransport(t)
+			defer closer()
+
+			want := make(map[selectors.Key]struct{})
+			for _, key := range keys {
+				if _, err := transport.Insert(key, []selectors.FieldValueScore{
+					{Field: "a", Score: 1},
+				}); err != nil {
+					t.Fatal(err)
+				}
+				want[key] = struct{}{}
+			}
+
+			res, err := transport.Keys()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[selectors.Key]struct{})
+			for _, key := range res {
+				got[key] = struct{}{}
+			}
+			return reflect.DeepEqual(want, got)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("members over many pages", func(t *testing.T) {
+		transport, closer := newGRPCTransport(t)
+		defer closer()
+
+		var (
+			key     = selectors.Key("key")
+			members = make([]selectors.FieldValueScore, (rpc.DefaultPageSize*2)+1)
+		)
+		for k := range members {
+			members[k] = selectors.FieldValueScore{
+				Field: selectors.Field(string(rune('a'+(k%26))) + string(rune(k))),
+				Score: 1,
+			}
+		}
+		if _, err := transport.Insert(key, members); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := transport.Members(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := sortFields(uniqueFields(members)), sortFields(res); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", len(expected), len(actual))
+		}
+	})
+
+	t.Run("score", func(t *testing.T) {
+		fn := func(key selectors.Key, field selectors.Field, score int64) bool {
+			transport, closer := newGRPCTransport(t)
+			defer closer()
+
+			if _, err := transport.Insert(key, []selectors.FieldValueScore{
+				{Field: field, Score: score},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			presence, err := transport.Score(key, field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return presence.Present && presence.Inserted && presence.Score == score
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestParseGRPC(t *testing.T) {
+	t.Parallel()
+
+	strategy, err := Parse("grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := strategy.Apply("127.0.0.1:8080")
+	if expected, actual := "127.0.0.1:8080", transport.Host(); expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func newGRPCTransport(t testing.TB) (api.Transport, func()) {
+	persistence, err := store.New(fsys.NewNopFilesystem(), 1, 10000, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		storeServer = rpc.NewServer(persistence, log.NewNopLogger())
+		grpcServer  = rpc.Register(storeServer,
+			prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+			prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+		)
+	)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewGRPCTransport(rpc.NewStoreClient(conn), listener.Addr().String()), func() {
+		conn.Close()
+		grpcServer.Stop()
+		storeServer.Close()
+	}
+}
+
+func uniqueFields(members []selectors.FieldValueScore) []selectors.Field {
+	var (
+		res  []selectors.Field
+		seen = make(map[selectors.Field]struct{})
+	)
+	for _, v := range members {
+		if _, ok := seen[v.Field]; ok {
+			continue
+		}
+		seen[v.Field] = struct{}{}
+		res = append(res, v.Field)
+	}
+	return res
+}
+
+func sortFields(fields []selectors.Field) []string {
+	res := make([]string, len(fields))
+	for k, v := range fields {
+		res[k] = v.String()
+	}
+	sort.Strings(res)
+	return res
+}
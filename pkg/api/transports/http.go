@@ -1,11 +1,15 @@
 package transports
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 
 	"github.com/SimonRichardson/coherence/pkg/api"
 	"github.com/SimonRichardson/coherence/pkg/api/client"
+	"github.com/SimonRichardson/coherence/pkg/api/sse"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/spaolacci/murmur3"
 )
@@ -23,6 +27,21 @@ func NewHTTPTransport(client *client.Client) api.Transport {
 	}
 }
 
+// WithContext returns a copy of the transport that sends the trace of the
+// context along with its requests.
+func (t *httpTransport) WithContext(ctx context.Context) api.Transport {
+	return &httpTransport{
+		hash:   t.hash,
+		client: t.client.WithContext(ctx),
+	}
+}
+
+// CircuitOpen returns true if the client of the transport is refusing to send
+// requests.
+func (t *httpTransport) CircuitOpen() bool {
+	return t.client.CircuitOpen()
+}
+
 func (t *httpTransport) Insert(key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
 	return t.write("insert", key, fields)
 }
@@ -33,7 +52,7 @@ func (t *httpTransport) Delete(key selectors.Key, fields []selectors.FieldValueS
 
 func (t *httpTransport) Select(key selectors.Key, field selectors.Field) (record selectors.FieldValueScore, err error) {
 	var res []byte
-	res, err = t.client.Get(fmt.Sprintf("/store/select?key=%s&field=%s", key.String(), field.String()))
+	res, err = t.client.Get(fmt.Sprintf("/store/select?key=%s&field=%s", url.QueryEscape(key.String()), url.QueryEscape(field.String())))
 	if err != nil {
 		return
 	}
@@ -69,7 +88,7 @@ func (t *httpTransport) Keys() (record []selectors.Key, err error) {
 
 func (t *httpTransport) Size(key selectors.Key) (record int64, err error) {
 	var res []byte
-	res, err = t.client.Get(fmt.Sprintf("/store/size?key=%s", key.String()))
+	res, err = t.client.Get(fmt.Sprintf("/store/size?key=%s", url.QueryEscape(key.String())))
 	if err != nil {
 		return
 	}
@@ -87,7 +106,7 @@ func (t *httpTransport) Size(key selectors.Key) (record int64, err error) {
 
 func (t *httpTransport) Members(key selectors.Key) (record []selectors.Field, err error) {
 	var res []byte
-	res, err = t.client.Get(fmt.Sprintf("/store/members?key=%s", key.String()))
+	res, err = t.client.Get(fmt.Sprintf("/store/members?key=%s", url.QueryEscape(key.String())))
 	if err != nil {
 		return
 	}
@@ -105,7 +124,7 @@ func (t *httpTransport) Members(key selectors.Key) (record []selectors.Field, er
 
 func (t *httpTransport) Score(key selectors.Key, field selectors.Field) (record selectors.Presence, err error) {
 	var res []byte
-	res, err = t.client.Get(fmt.Sprintf("/store/score?key=%s&field=%s", key.String(), field.String()))
+	res, err = t.client.Get(fmt.Sprintf("/store/score?key=%s&field=%s", url.QueryEscape(key.String()), url.QueryEscape(field.String())))
 	if err != nil {
 		return
 	}
@@ -121,7 +140,85 @@ func (t *httpTransport) Score(key selectors.Key, field selectors.Field) (record
 	return
 }
 
-func (t *httpTransport) write(path string, key selectors.Key, fields []selectors.FieldValueScore) (record selectors.ChangeSet, err error) {
+func (t *httpTransport) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) (record selectors.KeysPage, err error) {
+	var res []byte
+	res, err = t.client.Get(fmt.Sprintf("/store/scan?%s", scanQuery(cursor, opts).Encode()))
+	if err != nil {
+		return
+	}
+
+	var page struct {
+		Records []selectors.Key  `json:"records"`
+		Cursor  selectors.Cursor `json:"cursor"`
+	}
+	if err = json.Unmarshal(res, &page); err != nil {
+		return
+	}
+
+	record = selectors.KeysPage{
+		Keys:   page.Records,
+		Cursor: page.Cursor,
+	}
+	return
+}
+
+func (t *httpTransport) ScanMembers(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) (record selectors.FieldsPage, err error) {
+	query := scanQuery(cursor, opts)
+	query.Set("key", key.String())
+
+	var res []byte
+	res, err = t.client.Get(fmt.Sprintf("/store/hscan?%s", query.Encode()))
+	if err != nil {
+		return
+	}
+
+	var page struct {
+		Records []selectors.Field `json:"records"`
+		Cursor  selectors.Cursor  `json:"cursor"`
+	}
+	if err = json.Unmarshal(res, &page); err != nil {
+		return
+	}
+
+	record = selectors.FieldsPage{
+		Fields: page.Records,
+		Cursor: page.Cursor,
+	}
+	return
+}
+
+func (t *httpTransport) Watch(key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	body, err := t.client.Stream(fmt.Sprintf("/store/watch?key=%s%s", url.QueryEscape(key.String()), resumeQuery(resume)))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sse.NewReader(body)
+	return newStreamSubscription(func() (selectors.Event, error) {
+		event, err := reader.Read()
+		if err != nil {
+			return selectors.Event{}, err
+		}
+
+		var output api.Event
+		if err := json.Unmarshal(event.Data, &output); err != nil {
+			return selectors.Event{}, err
+		}
+		return api.EventInput(output), nil
+	}, func() {
+		body.Close()
+	}), nil
+}
+
+func (t *httpTransport) Swap(key selectors.Key, field selectors.FieldValueScore, token int64) (selectors.ChangeSet, error) {
+	return t.post(fmt.Sprintf("/store/swap?key=%s&token=%d", url.QueryEscape(key.String()), token), []selectors.FieldValueScore{field})
+}
+
+func (t *httpTransport) write(path string, key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+	return t.post(fmt.Sprintf("/store/%s?key=%s", path, url.QueryEscape(key.String())), fields)
+}
+
+func (t *httpTransport) post(u string, fields []selectors.FieldValueScore) (record selectors.ChangeSet, err error) {
 	var b []byte
 	b, err = json.Marshal(struct {
 		Members []selectors.FieldValueScore `json:"members"`
@@ -133,7 +230,7 @@ func (t *httpTransport) write(path string, key selectors.Key, fields []selectors
 	}
 
 	var res []byte
-	res, err = t.client.Post(fmt.Sprintf("/store/%s?key=%s", path, key.String()), b)
+	res, err = t.client.Post(u, b)
 	if err != nil {
 		return
 	}
@@ -156,3 +253,19 @@ func (t *httpTransport) Hash() uint32 {
 func (t *httpTransport) Host() string {
 	return t.client.Host()
 }
+
+// scanQuery encodes the cursor and options of a scan, leaving out the values
+// that are the defaults.
+func scanQuery(cursor selectors.Cursor, opts selectors.ScanOptions) url.Values {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor.String())
+	}
+	if opts.Match != "" {
+		query.Set("match", opts.Match)
+	}
+	if opts.Count > 0 {
+		query.Set("count", strconv.Itoa(opts.Size()))
+	}
+	return query
+}
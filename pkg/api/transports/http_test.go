@@ -117,6 +117,89 @@ func TestRemoteInsert(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("insert with keyspace over quota", func(t *testing.T) {
+		key := selectors.Keyspace("team-a").Key("a&b")
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/store/insert", func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+			if expected, actual := key.String(), r.URL.Query().Get("key"); expected != actual {
+				t.Errorf("expected: %q, actual: %q", expected, actual)
+			}
+			w.WriteHeader(http.StatusInsufficientStorage)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		client := client.New(http.DefaultClient, "http", hostPort(server.URL))
+		node := NewHTTPTransport(client)
+		_, err := node.Insert(key, []selectors.FieldValueScore{{Field: "x", Score: 1}})
+		if expected, actual := true, selectors.QuotaError(err); expected != actual {
+			t.Errorf("expected: %t, actual: %t (%v)", expected, actual, err)
+		}
+	})
+}
+
+func TestRemoteSwap(t *testing.T) {
+	t.Parallel()
+
+	t.Run("swap", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/store/swap", func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+
+			if expected, actual := "a", r.URL.Query().Get("key"); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+			if expected, actual := "1", r.URL.Query().Get("token"); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+
+			if err := json.NewEncoder(w).Encode(struct {
+				Records selectors.ChangeSet `json:"records"`
+			}{
+				Records: selectors.ChangeSet{
+					Success: []selectors.Field{"x"},
+					Failure: make([]selectors.Field, 0),
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		client := client.New(http.DefaultClient, "http", hostPort(server.URL))
+		node := NewHTTPTransport(client).(api.Swapper)
+		got, err := node.Swap("a", selectors.FieldValueScore{Field: "x", Score: 2}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := selectors.ChangeSet{
+			Success: []selectors.Field{"x"},
+			Failure: make([]selectors.Field, 0),
+		}
+		if expected, actual := want, got; !expected.Equal(actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("swap not found", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/store/swap", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		client := client.New(http.DefaultClient, "http", hostPort(server.URL))
+		node := NewHTTPTransport(client).(api.Swapper)
+		if _, err := node.Swap("a", selectors.FieldValueScore{Field: "x", Score: 2}, 1); !selectors.NotFoundError(err) {
+			t.Errorf("expected not found error, actual: %v", err)
+		}
+	})
 }
 
 func TestRemoteDelete(t *testing.T) {
@@ -42,6 +42,16 @@ func (Nop) Score(selectors.Key, selectors.Field) (selectors.Presence, error) {
 	return selectors.Presence{}, nil
 }
 
+// Scan returns the next page of keys after the cursor.
+func (Nop) Scan(selectors.Cursor, selectors.ScanOptions) (selectors.KeysPage, error) {
+	return selectors.KeysPage{}, nil
+}
+
+// ScanMembers returns the next page of members for a key after the cursor.
+func (Nop) ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions) (selectors.FieldsPage, error) {
+	return selectors.FieldsPage{}, nil
+}
+
 // Hash returns the transport unique hash
 func (Nop) Hash() uint32 {
 	return 0
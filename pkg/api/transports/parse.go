@@ -1,10 +1,26 @@
 package transports
 
 import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/SimonRichardson/coherence/pkg/api"
 	"github.com/SimonRichardson/coherence/pkg/api/client"
+	"github.com/SimonRichardson/coherence/pkg/api/rpc"
+	"github.com/SimonRichardson/coherence/pkg/api/tcp"
+	"github.com/SimonRichardson/coherence/pkg/auth"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	defaultTCPConnections = 4
+	defaultTCPTimeout     = time.Second * 10
 )
 
 // Strategy wraps a Transport protocol layer for querying requests.
@@ -17,15 +33,119 @@ func (s Strategy) Apply(host string) api.Transport {
 	return s.fn(host)
 }
 
+// Option defines a option for configuring the transports of a Strategy.
+type Option func(*options)
+
+type options struct {
+	tls    *tls.Config
+	signer *auth.Signer
+	states metrics.GaugeVec
+}
+
+// WithTLS makes the grpc and the tcp transports talk over TLS with the
+// config, it's also the config that the https transport uses.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// WithSigner signs every request of the http and https transports, so that
+// the other nodes can authenticate them.
+func WithSigner(signer *auth.Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// WithCircuitStates reports the state of the circuit of the http and https
+// transports, labelled by the host they talk to.
+func WithCircuitStates(states metrics.GaugeVec) Option {
+	return func(o *options) {
+		o.states = states
+	}
+}
+
 // Parse a protocol transport config and return a Strategy for creating a
 // Transport on demand.
-func Parse(protocol string) (Strategy, error) {
+func Parse(protocol string, opts ...Option) (Strategy, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	switch protocol {
-	case "http":
+	case "http", "https":
+		if protocol == "http" && o.tls != nil {
+			return Strategy{}, errors.New("expected protocol \"https\" when using TLS")
+		}
 		pooledClient := cleanhttp.DefaultPooledClient()
+		if protocol == "https" {
+			pooledClient.Transport.(*http.Transport).TLSClientConfig = o.tls
+		}
+		if o.signer != nil {
+			pooledClient.Transport = o.signer.RoundTripper(pooledClient.Transport)
+		}
+		return Strategy{
+			fn: func(host string) api.Transport {
+				var clientOpts []client.Option
+				if o.states != nil {
+					clientOpts = append(clientOpts, client.WithCircuitState(o.states.WithLabelValues(host)))
+				}
+				return NewHTTPTransport(client.New(pooledClient, protocol, host, clientOpts...))
+			},
+		}, nil
+	case "grpc":
+		var (
+			mutex sync.Mutex
+			conns = make(map[string]*grpc.ClientConn)
+			creds = grpc.WithInsecure()
+		)
+		if o.tls != nil {
+			creds = grpc.WithTransportCredentials(credentials.NewTLS(o.tls))
+		}
 		return Strategy{
 			fn: func(host string) api.Transport {
-				return NewHTTPTransport(client.New(pooledClient, protocol, host))
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				// Connections are shared between transports of the same host, as
+				// gRPC multiplexes requests over a single connection.
+				conn, ok := conns[host]
+				if !ok {
+					var err error
+					// Dialing doesn't block, so the error is only for invalid
+					// options and never the connection itself.
+					if conn, err = grpc.Dial(host, creds); err != nil {
+						panic(err)
+					}
+					conns[host] = conn
+				}
+				return NewGRPCTransport(rpc.NewStoreClient(conn), host)
+			},
+		}, nil
+	case "tcp":
+		var (
+			mutex   sync.Mutex
+			clients = make(map[string]*tcp.Client)
+			tcpOpts []tcp.ClientOption
+		)
+		if o.tls != nil {
+			tcpOpts = append(tcpOpts, tcp.WithTLS(o.tls))
+		}
+		return Strategy{
+			fn: func(host string) api.Transport {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				// Clients are shared between transports of the same host, so that
+				// the pool of connections is shared as well.
+				c, ok := clients[host]
+				if !ok {
+					c = tcp.NewClient(host, defaultTCPConnections, defaultTCPTimeout, tcpOpts...)
+					clients[host] = c
+				}
+				return NewTCPTransport(c)
 			},
 		}, nil
 	case "nop":
@@ -0,0 +1,114 @@
+package transports
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestTransportScan(t *testing.T) {
+	t.Parallel()
+
+	for name, newTransport := range map[string]func(testing.TB) (api.Transport, func()){
+		"http": newHTTPTransport,
+		"grpc": newGRPCTransport,
+		"tcp":  newTCPTransport,
+	} {
+		newTransport := newTransport
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			transport, closer := newTransport(t)
+			defer closer()
+
+			var (
+				keys   []selectors.Key
+				fields []selectors.Field
+			)
+			for i := 0; i < 25; i++ {
+				key := selectors.Key(fmt.Sprintf("key:%02d", i))
+				field := selectors.Field(fmt.Sprintf("field:%02d", i))
+				if _, err := transport.Insert(key, []selectors.FieldValueScore{
+					{Field: field, Value: []byte("v"), Score: int64(i)},
+				}); err != nil {
+					t.Fatal(err)
+				}
+				keys = append(keys, key)
+				fields = append(fields, field)
+			}
+
+			t.Run("keys", func(t *testing.T) {
+				var (
+					res    []selectors.Key
+					pages  int
+					cursor selectors.Cursor
+				)
+				for {
+					page, err := transport.Scan(cursor, selectors.ScanOptions{Count: 10})
+					if err != nil {
+						t.Fatal(err)
+					}
+					res = append(res, page.Keys...)
+					pages++
+					if page.Cursor == "" {
+						break
+					}
+					cursor = page.Cursor
+				}
+
+				if expected, actual := keys, res; !reflect.DeepEqual(expected, actual) {
+					t.Errorf("expected: %v, actual: %v", expected, actual)
+				}
+				if expected, actual := 3, pages; expected != actual {
+					t.Errorf("expected: %d, actual: %d", expected, actual)
+				}
+			})
+
+			t.Run("keys with match", func(t *testing.T) {
+				page, err := transport.Scan("", selectors.ScanOptions{Match: "key:1?"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected, actual := keys[10:20], page.Keys; !reflect.DeepEqual(expected, actual) {
+					t.Errorf("expected: %v, actual: %v", expected, actual)
+				}
+				if expected, actual := selectors.Cursor(""), page.Cursor; expected != actual {
+					t.Errorf("expected: %q, actual: %q", expected, actual)
+				}
+			})
+
+			// The store only has a single bucket, so every key shares the same
+			// members.
+			t.Run("members", func(t *testing.T) {
+				page, err := transport.ScanMembers("key:00", "", selectors.ScanOptions{Count: 20})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected, actual := fields[:20], page.Fields; !reflect.DeepEqual(expected, actual) {
+					t.Errorf("expected: %v, actual: %v", expected, actual)
+				}
+
+				page, err = transport.ScanMembers("key:00", page.Cursor, selectors.ScanOptions{Count: 20})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected, actual := fields[20:], page.Fields; !reflect.DeepEqual(expected, actual) {
+					t.Errorf("expected: %v, actual: %v", expected, actual)
+				}
+				if expected, actual := selectors.Cursor(""), page.Cursor; expected != actual {
+					t.Errorf("expected: %q, actual: %q", expected, actual)
+				}
+			})
+
+			t.Run("invalid cursor", func(t *testing.T) {
+				if _, err := transport.Scan("bad", selectors.ScanOptions{}); err == nil {
+					t.Errorf("expected error")
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package transports
+
+import (
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/tcp"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/spaolacci/murmur3"
+)
+
+type tcpTransport struct {
+	hash   uint32
+	client *tcp.Client
+}
+
+// NewTCPTransport creates a new Transport that uses the binary TCP protocol
+func NewTCPTransport(client *tcp.Client) api.Transport {
+	return &tcpTransport{
+		hash:   murmur3.Sum32([]byte(client.Host())),
+		client: client,
+	}
+}
+
+func (t *tcpTransport) Insert(key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+	return t.write(tcp.OpInsert, key, fields)
+}
+
+func (t *tcpTransport) Delete(key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+	return t.write(tcp.OpDelete, key, fields)
+}
+
+func (t *tcpTransport) Select(key selectors.Key, field selectors.Field) (selectors.FieldValueScore, error) {
+	res, err := t.client.Do(tcp.OpSelect, tcp.NewEncoder().Key(key).Field(field).Bytes())
+	if err != nil {
+		return selectors.FieldValueScore{}, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	member := dec.Member()
+	return member, dec.Err()
+}
+
+func (t *tcpTransport) Keys() ([]selectors.Key, error) {
+	res, err := t.client.Do(tcp.OpKeys, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	keys := dec.Keys()
+	return keys, dec.Err()
+}
+
+func (t *tcpTransport) Size(key selectors.Key) (int64, error) {
+	res, err := t.client.Do(tcp.OpSize, tcp.NewEncoder().Key(key).Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	size := dec.Int()
+	return size, dec.Err()
+}
+
+func (t *tcpTransport) Members(key selectors.Key) ([]selectors.Field, error) {
+	res, err := t.client.Do(tcp.OpMembers, tcp.NewEncoder().Key(key).Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	fields := dec.Fields()
+	return fields, dec.Err()
+}
+
+func (t *tcpTransport) Score(key selectors.Key, field selectors.Field) (selectors.Presence, error) {
+	res, err := t.client.Do(tcp.OpScore, tcp.NewEncoder().Key(key).Field(field).Bytes())
+	if err != nil {
+		return selectors.Presence{}, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	presence := dec.Presence()
+	return presence, dec.Err()
+}
+
+func (t *tcpTransport) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.KeysPage, error) {
+	res, err := t.client.Do(tcp.OpScan, tcp.NewEncoder().Scan(cursor, opts).Bytes())
+	if err != nil {
+		return selectors.KeysPage{}, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	page := dec.KeysPage()
+	return page, dec.Err()
+}
+
+func (t *tcpTransport) ScanMembers(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.FieldsPage, error) {
+	res, err := t.client.Do(tcp.OpScanMembers, tcp.NewEncoder().Key(key).Scan(cursor, opts).Bytes())
+	if err != nil {
+		return selectors.FieldsPage{}, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	page := dec.FieldsPage()
+	return page, dec.Err()
+}
+
+func (t *tcpTransport) Swap(key selectors.Key, field selectors.FieldValueScore, token int64) (selectors.ChangeSet, error) {
+	res, err := t.client.Do(tcp.OpSwap, tcp.NewEncoder().Key(key).Member(field).Int(token).Bytes())
+	if err != nil {
+		return selectors.ChangeSet{}, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	changeSet := dec.ChangeSet()
+	return changeSet, dec.Err()
+}
+
+func (t *tcpTransport) write(op tcp.Op, key selectors.Key, fields []selectors.FieldValueScore) (selectors.ChangeSet, error) {
+	res, err := t.client.Do(op, tcp.NewEncoder().Key(key).Members(fields).Bytes())
+	if err != nil {
+		return selectors.ChangeSet{}, err
+	}
+
+	dec := tcp.NewDecoder(res)
+	changeSet := dec.ChangeSet()
+	return changeSet, dec.Err()
+}
+
+func (t *tcpTransport) Hash() uint32 {
+	return t.hash
+}
+
+func (t *tcpTransport) Host() string {
+	return t.client.Host()
+}
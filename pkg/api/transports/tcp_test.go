@@ -0,0 +1,208 @@
+package transports
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/api/tcp"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
+)
+
+func TestTCPTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("insert", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			transport, closer := newTCPTransport(t)
+			defer closer()
+
+			changeSet, err := transport.Insert(key, members)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return reflect.DeepEqual(sortFields(uniqueFields(members)), sortFields(changeSet.Success))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("insert with no key", func(t *testing.T) {
+		transport, closer := newTCPTransport(t)
+		defer closer()
+
+		if _, err := transport.Insert("", nil); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		transport, closer := newTCPTransport(t)
+		defer closer()
+
+		if _, err := transport.Insert("a", []selectors.FieldValueScore{{Field: "x", Score: 1}}); err != nil {
+			t.Fatal(err)
+		}
+
+		swapper := transport.(api.Swapper)
+		changeSet, err := swapper.Swap("a", selectors.FieldValueScore{Field: "x", Value: []byte("v"), Score: 2}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"x"}, changeSet.Success; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		// The token has been swapped, so it doesn't match any more.
+		changeSet, err = swapper.Swap("a", selectors.FieldValueScore{Field: "x", Value: []byte("w"), Score: 3}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"x"}, changeSet.Failure; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		if _, err := swapper.Swap("a", selectors.FieldValueScore{Field: "y", Score: 2}, 1); !selectors.NotFoundError(err) {
+			t.Errorf("expected not found error, actual: %v", err)
+		}
+	})
+
+	t.Run("select not found", func(t *testing.T) {
+		fn := func(key selectors.Key, field selectors.Field) bool {
+			transport, closer := newTCPTransport(t)
+			defer closer()
+
+			_, err := transport.Select(key, field)
+			return selectors.NotFoundError(err)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("select", func(t *testing.T) {
+		fn := func(key selectors.Key, member selectors.FieldValueScore) bool {
+			if len(member.Value) == 0 {
+				return true
+			}
+
+			transport, closer := newTCPTransport(t)
+			defer closer()
+
+			if _, err := transport.Insert(key, []selectors.FieldValueScore{member}); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := transport.Select(key, member.Field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return reflect.DeepEqual(member, res)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("size and members", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			transport, closer := newTCPTransport(t)
+			defer closer()
+
+			if _, err := transport.Insert(key, members); err != nil {
+				t.Fatal(err)
+			}
+
+			size, err := transport.Size(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields, err := transport.Members(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := uniqueFields(members)
+			return size == int64(len(want)) &&
+				reflect.DeepEqual(sortFields(want), sortFields(fields))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("delete and score", func(t *testing.T) {
+		fn := func(key selectors.Key, field selectors.Field, score uint16) bool {
+			transport, closer := newTCPTransport(t)
+			defer closer()
+
+			members := []selectors.FieldValueScore{
+				{Field: field, Score: int64(score)},
+			}
+			if _, err := transport.Insert(key, members); err != nil {
+				t.Fatal(err)
+			}
+			members[0].Score++
+			if _, err := transport.Delete(key, members); err != nil {
+				t.Fatal(err)
+			}
+
+			presence, err := transport.Score(key, field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return presence.Present && !presence.Inserted && presence.Score == int64(score)+1
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestParseTCP(t *testing.T) {
+	t.Parallel()
+
+	strategy, err := Parse("tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := strategy.Apply("127.0.0.1:8080")
+	if expected, actual := "127.0.0.1:8080", transport.Host(); expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func newTCPTransport(t testing.TB) (api.Transport, func()) {
+	persistence, err := store.New(fsys.NewNopFilesystem(), 1, 10000, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := tcp.NewServer(persistence,
+		log.NewNopLogger(),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+	)
+	go server.Serve(listener)
+
+	client := tcp.NewClient(listener.Addr().String(), defaultTCPConnections, time.Second*5)
+	return NewTCPTransport(client), func() {
+		client.Close()
+		listener.Close()
+		server.Close()
+	}
+}
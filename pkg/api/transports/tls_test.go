@@ -0,0 +1,129 @@
+package transports
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/api/rpc"
+	apiStore "github.com/SimonRichardson/coherence/pkg/api/store"
+	"github.com/SimonRichardson/coherence/pkg/api/tcp"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
+)
+
+func TestTLSTransport(t *testing.T) {
+	t.Parallel()
+
+	for _, protocol := range []string{"https", "grpc", "tcp"} {
+		protocol := protocol
+		t.Run(protocol, func(t *testing.T) {
+			serverConfig, clientConfig := newTLSConfigs()
+
+			host, closer := newTLSServer(t, protocol, serverConfig)
+			defer closer()
+
+			strategy, err := Parse(protocol, WithTLS(clientConfig))
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport := strategy.Apply(host)
+
+			members := []selectors.FieldValueScore{
+				{Field: "field", Value: []byte("value"), Score: 1},
+			}
+			if _, err := transport.Insert("key", members); err != nil {
+				t.Fatal(err)
+			}
+			member, err := transport.Select("key", "field")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected, actual := "value", string(member.Value); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		})
+	}
+
+	t.Run("untrusted", func(t *testing.T) {
+		serverConfig, _ := newTLSConfigs()
+
+		host, closer := newTLSServer(t, "tcp", serverConfig)
+		defer closer()
+
+		strategy, err := Parse("tcp", WithTLS(&tls.Config{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := strategy.Apply(host).Select("key", "field"); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestParseHTTPWithTLS(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Parse("http", WithTLS(&tls.Config{})); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+// newTLSConfigs borrows the certificate of a httptest server, which is valid
+// for 127.0.0.1, along with a client config that trusts it.
+func newTLSConfigs() (*tls.Config, *tls.Config) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	return &tls.Config{Certificates: server.TLS.Certificates},
+		server.Client().Transport.(*http.Transport).TLSClientConfig
+}
+
+func newTLSServer(t *testing.T, protocol string, config *tls.Config) (string, func()) {
+	persistence, err := store.New(fsys.NewNopFilesystem(), 1, 10000, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener = tls.NewListener(listener, config)
+
+	var (
+		clients  = prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"})
+		duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"})
+	)
+	switch protocol {
+	case "https":
+		storeAPI := apiStore.NewAPI(persistence, log.NewNopLogger(), clients, duration)
+		mux := http.NewServeMux()
+		mux.Handle("/store/", http.StripPrefix("/store", storeAPI))
+		go http.Serve(listener, mux)
+		return listener.Addr().String(), func() {
+			listener.Close()
+			storeAPI.Close()
+		}
+	case "grpc":
+		storeServer := rpc.NewServer(persistence, log.NewNopLogger())
+		grpcServer := rpc.Register(storeServer, clients, duration)
+		go grpcServer.Serve(listener)
+		return listener.Addr().String(), func() {
+			grpcServer.Stop()
+			storeServer.Close()
+		}
+	default:
+		server := tcp.NewServer(persistence, log.NewNopLogger(), clients, duration)
+		go server.Serve(listener)
+		return listener.Addr().String(), func() {
+			listener.Close()
+			server.Close()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package transports
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+// streamSubscription is a Subscription that receives the events from a
+// stream, such as the body of a response or a gRPC stream.
+type streamSubscription struct {
+	events chan selectors.Event
+	done   chan struct{}
+	once   sync.Once
+	closer func()
+	mutex  sync.Mutex
+	err    error
+}
+
+// newStreamSubscription creates a Subscription that calls recv until it errors,
+// the closer is called when the subscription is closed and must cause recv to
+// return.
+func newStreamSubscription(recv func() (selectors.Event, error), closer func()) *streamSubscription {
+	s := &streamSubscription{
+		events: make(chan selectors.Event),
+		done:   make(chan struct{}),
+		closer: closer,
+	}
+	go s.run(recv)
+	return s
+}
+
+func (s *streamSubscription) Events() <-chan selectors.Event {
+	return s.events
+}
+
+func (s *streamSubscription) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+func (s *streamSubscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.closer()
+	})
+}
+
+func (s *streamSubscription) run(recv func() (selectors.Event, error)) {
+	defer close(s.events)
+
+	for {
+		event, err := recv()
+		if err != nil {
+			select {
+			case <-s.done:
+				// Closing the stream causes the error.
+			default:
+				if err != io.EOF {
+					s.mutex.Lock()
+					s.err = err
+					s.mutex.Unlock()
+				}
+			}
+			return
+		}
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// resumeQuery returns the query parameters for where a watch resumes from.
+func resumeQuery(resume selectors.Resume) string {
+	switch resume.Type {
+	case selectors.ResumeOffset:
+		return fmt.Sprintf("&offset=%d", resume.Value)
+	case selectors.ResumeScore:
+		return fmt.Sprintf("&score=%d", resume.Value)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,80 @@
+package transports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/api"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestTransportWatch(t *testing.T) {
+	t.Parallel()
+
+	for name, newTransport := range map[string]func(testing.TB) (api.Transport, func()){
+		"http": newHTTPTransport,
+		"grpc": newGRPCTransport,
+	} {
+		newTransport := newTransport
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			transport, closer := newTransport(t)
+			defer closer()
+
+			watcher, ok := transport.(api.Watcher)
+			if !ok {
+				t.Fatal("expected watcher")
+			}
+
+			insert := func(field selectors.Field, score int64) {
+				if _, err := transport.Insert("a", []selectors.FieldValueScore{
+					{Field: field, Value: []byte("v"), Score: score},
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			insert("x", 1)
+			insert("y", 2)
+
+			sub, err := watcher.Watch("a", selectors.ResumeFromOffset(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+
+			insert("z", 3)
+
+			for _, expected := range []selectors.Event{
+				{Offset: 2, Type: selectors.EventInsert, Key: "a", Field: "y", Value: []byte("v"), Score: 2},
+				{Offset: 3, Type: selectors.EventInsert, Key: "a", Field: "z", Value: []byte("v"), Score: 3},
+			} {
+				select {
+				case actual, ok := <-sub.Events():
+					if !ok {
+						t.Fatalf("subscription ended: %v", sub.Err())
+					}
+					if expected.Offset != actual.Offset ||
+						expected.Type != actual.Type ||
+						expected.Key != actual.Key ||
+						expected.Field != actual.Field ||
+						string(expected.Value) != string(actual.Value) ||
+						expected.Score != actual.Score {
+						t.Errorf("expected: %v, actual: %v", expected, actual)
+					}
+				case <-time.After(time.Second * 5):
+					t.Fatal("timed out")
+				}
+			}
+
+			sub.Close()
+			if _, ok := <-sub.Events(); ok {
+				t.Errorf("expected closed")
+			}
+			if err := sub.Err(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package writes
+
+import (
+	"sync"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned when a write is sent to a Queue that's been closed.
+var ErrClosed = errors.New("write queue closed")
+
+// Queue applies the writes of different keys concurrently, whilst the writes
+// of the same key are applied one at a time, in the order they're sent.
+//
+// Each key that has writes to apply has a chain of them, which is applied in
+// turn by a goroutine of its own, so a slow key only holds up the writes of
+// that key. The chain goes away once its last write has been applied.
+type Queue struct {
+	mutex  sync.Mutex
+	chains map[selectors.Key][]func()
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a Queue.
+func NewQueue() *Queue {
+	return &Queue{
+		chains: make(map[selectors.Key][]func()),
+	}
+}
+
+// Apply queues the write of the key behind the writes of the key that
+// haven't been applied yet, it doesn't wait for the write to be applied.
+// ErrClosed is returned if the queue has been closed.
+func (q *Queue) Apply(key selectors.Key, write func()) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	if pending, ok := q.chains[key]; ok {
+		q.chains[key] = append(pending, write)
+		return nil
+	}
+
+	q.chains[key] = nil
+	q.wg.Add(1)
+	go q.run(key, write)
+
+	return nil
+}
+
+// Close stops the queue taking any more writes, then waits for the writes
+// that have already been queued to be applied.
+func (q *Queue) Close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.mutex.Unlock()
+
+	q.wg.Wait()
+}
+
+func (q *Queue) run(key selectors.Key, write func()) {
+	defer q.wg.Done()
+
+	for {
+		write()
+
+		q.mutex.Lock()
+		pending := q.chains[key]
+		if len(pending) == 0 {
+			delete(q.chains, key)
+			q.mutex.Unlock()
+			return
+		}
+		write, pending[0] = pending[0], nil
+		q.chains[key] = pending[1:]
+		q.mutex.Unlock()
+	}
+}
@@ -0,0 +1,168 @@
+package writes
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("same key in order", func(t *testing.T) {
+		queue := NewQueue()
+		defer queue.Close()
+
+		var (
+			mutex sync.Mutex
+			res   []int
+			done  = make(chan struct{})
+		)
+		for i := 0; i < 100; i++ {
+			i := i
+			if err := queue.Apply("a", func() {
+				mutex.Lock()
+				res = append(res, i)
+				mutex.Unlock()
+				if i == 99 {
+					close(done)
+				}
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		<-done
+
+		for k, v := range res {
+			if k != v {
+				t.Fatalf("expected: %d, actual: %d", k, v)
+			}
+		}
+	})
+
+	t.Run("slow key doesn't stall other keys", func(t *testing.T) {
+		queue := NewQueue()
+		defer queue.Close()
+
+		release := make(chan struct{})
+		if err := queue.Apply("a", func() {
+			<-release
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		// However many other keys there are, none of them wait on the slow
+		// one.
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			if err := queue.Apply(selectors.Key(fmt.Sprintf("b%d", i)), wg.Done); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the writes of other keys not to wait")
+		}
+		close(release)
+	})
+
+	t.Run("close applies the queued writes", func(t *testing.T) {
+		queue := NewQueue()
+
+		var applied int32
+		for i := 0; i < 10; i++ {
+			if err := queue.Apply("a", func() {
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&applied, 1)
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		queue.Close()
+
+		if expected, actual := int32(10), atomic.LoadInt32(&applied); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		queue := NewQueue()
+		queue.Close()
+
+		if expected, actual := ErrClosed, queue.Apply("a", func() {
+			t.Error("expected the write not to be applied")
+		}); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+// BenchmarkQueue applies writes that wait on the replicas, the throughput
+// scales with the number of writers, as the writes of different keys wait at
+// the same time.
+func BenchmarkQueue(b *testing.B) {
+	for _, writers := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("writers-%d", writers), func(b *testing.B) {
+			queue := NewQueue()
+			defer queue.Close()
+
+			var id uint64
+
+			b.SetParallelism(writers)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := selectors.Key(fmt.Sprintf("key-%d", atomic.AddUint64(&id, 1)))
+					apply(b, queue, key, func() {
+						time.Sleep(time.Millisecond)
+					})
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkQueueCPU applies writes that keep a CPU busy, the throughput
+// scales with the number of cores (run with -cpu 1,2,4,8).
+func BenchmarkQueueCPU(b *testing.B) {
+	queue := NewQueue()
+	defer queue.Close()
+
+	var id uint64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			key := selectors.Key(fmt.Sprintf("key-%d", atomic.AddUint64(&id, 1)))
+			apply(b, queue, key, func() {
+				for i := 0; i < 1000; i++ {
+					key = selectors.Key(fmt.Sprint(key.Hash()))
+				}
+			})
+		}
+	})
+}
+
+func apply(b *testing.B, queue *Queue, key selectors.Key, fn func()) {
+	done := make(chan struct{})
+	if err := queue.Apply(key, func() {
+		fn()
+		close(done)
+	}); err != nil {
+		b.Error(err)
+		return
+	}
+	<-done
+}
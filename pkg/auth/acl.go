@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// Access is what a principal is allowed to do with the keys.
+type Access int
+
+const (
+	// Read allows the keys and their members to be read.
+	Read Access = 1 << iota
+
+	// Write allows members to be inserted, deleted and repaired.
+	Write
+
+	// ReadWrite allows both reading and writing.
+	ReadWrite = Read | Write
+)
+
+// ParseAccess parses the access of a rule, which is either "read", "write"
+// or "readwrite".
+func ParseAccess(s string) (Access, error) {
+	switch strings.ToLower(s) {
+	case "read":
+		return Read, nil
+	case "write":
+		return Write, nil
+	case "readwrite":
+		return ReadWrite, nil
+	default:
+		return 0, errors.Errorf("invalid access %q", s)
+	}
+}
+
+func (a Access) String() string {
+	switch a {
+	case Read:
+		return "read"
+	case Write:
+		return "write"
+	case ReadWrite:
+		return "readwrite"
+	default:
+		return "none"
+	}
+}
+
+// AnyKeyspace is the keyspace of a rule that applies to every keyspace.
+const AnyKeyspace = "*"
+
+// Rule grants a principal access to every key with in the keyspace that
+// starts with the prefix, the empty prefix grants access to every key.
+type Rule struct {
+	Principal string
+	Keyspace  string
+	Prefix    string
+	Access    Access
+}
+
+// ACL holds the rules that authorize the requests, anything that isn't
+// granted by a rule is denied.
+type ACL struct {
+	rules map[string][]Rule
+}
+
+// NewACL creates an ACL from the rules.
+func NewACL(rules []Rule) *ACL {
+	m := make(map[string][]Rule)
+	for _, v := range rules {
+		m[v.Principal] = append(m[v.Principal], v)
+	}
+	return &ACL{m}
+}
+
+// Allowed returns true if the principal has the access to the key with in the
+// keyspace. Peers are always allowed, as they need to replicate every key.
+func (a *ACL) Allowed(principal Principal, keyspace selectors.Keyspace, key string, access Access) bool {
+	if principal.Peer {
+		return true
+	}
+
+	var granted Access
+	for _, v := range a.rules[principal.Name] {
+		if v.Keyspace != AnyKeyspace && v.Keyspace != keyspace.String() {
+			continue
+		}
+		if strings.HasPrefix(key, v.Prefix) {
+			granted |= v.Access
+		}
+	}
+	return granted&access == access
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/SimonRichardson/coherence/pkg/certs"
+	"github.com/pkg/errors"
+)
+
+// Principal is the identity that a request was authenticated as.
+type Principal struct {
+	Name string
+
+	// Peer is set if the request came from another node in the cluster.
+	Peer bool
+
+	// Admin is set if the principal may use the admin API of the nodes.
+	Admin bool
+}
+
+// PeerPrincipal is the principal of the other nodes in the cluster.
+var PeerPrincipal = Principal{Name: "peer", Peer: true}
+
+// Authenticator works out the Principal of a request.
+type Authenticator interface {
+
+	// Authenticate returns the Principal of the request. If the request
+	// doesn't carry any credentials the authenticator understands, then it
+	// returns ErrNoCredentials, so that another authenticator can be tried.
+	Authenticate(*http.Request) (Principal, error)
+}
+
+// ErrNoCredentials is returned when the request doesn't carry any
+// credentials for the Authenticator.
+var ErrNoCredentials = errors.New("no credentials")
+
+type chain []Authenticator
+
+// Chain tries each Authenticator in turn, until one of them finds the
+// credentials of the request.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+func (c chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, v := range c {
+		principal, err := v.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return principal, err
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+type tokens struct {
+	tokens map[string]Principal
+}
+
+// NewTokens creates an Authenticator for static bearer tokens, the tokens
+// map each token to the name of the principal.
+func NewTokens(t map[string]string) Authenticator {
+	principals := make(map[string]Principal, len(t))
+	for k, v := range t {
+		principals[k] = Principal{Name: v}
+	}
+	return tokens{principals}
+}
+
+func (t tokens) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := authorization(r, "Bearer")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	// Every token is compared, so that the time taken doesn't give away how
+	// close the token was.
+	var (
+		principal Principal
+		found     bool
+	)
+	for k, v := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(token)) == 1 {
+			principal, found = v, true
+		}
+	}
+	if !found {
+		return Principal{}, errors.New("invalid token")
+	}
+	return principal, nil
+}
+
+type peerCertificates struct{}
+
+// NewPeerCertificates creates an Authenticator that treats any request over a
+// connection with a verified client certificate as a peer. It needs the
+// connection to be put on the context with ConnContext.
+func NewPeerCertificates() Authenticator {
+	return peerCertificates{}
+}
+
+func (peerCertificates) Authenticate(r *http.Request) (Principal, error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return PeerPrincipal, nil
+	}
+	if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok && certs.Verified(conn) {
+		return PeerPrincipal, nil
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+type connKey struct{}
+
+// ConnContext puts the connection on the context of the requests, so that
+// the client certificate can be checked, even when the connection has been
+// wrapped by a cmux. It's used as the ConnContext of a http.Server.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+type principalKey struct{}
+
+// NewContext returns a context that carries the principal of the request.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal that the request was authenticated as,
+// if it was authenticated.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// authorization returns the credentials of the Authorization header for the
+// scheme.
+func authorization(r *http.Request, scheme string) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) || header[len(scheme)] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(scheme)+1:]), true
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	authenticator := NewTokens(map[string]string{
+		"secret": "team-a",
+	})
+
+	for _, testcase := range []struct {
+		name          string
+		authorization string
+		principal     string
+		err           error
+	}{
+		{"valid", "Bearer secret", "team-a", nil},
+		{"case insensitive scheme", "bearer secret", "team-a", nil},
+		{"invalid", "Bearer bad", "", errAny},
+		{"missing", "", "", ErrNoCredentials},
+		{"other scheme", "HMAC id:sig", "", ErrNoCredentials},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/select?key=a", nil)
+			if testcase.authorization != "" {
+				r.Header.Set("Authorization", testcase.authorization)
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if !matchErr(testcase.err, err) {
+				t.Fatalf("expected: %v, actual: %v", testcase.err, err)
+			}
+			if expected, actual := testcase.principal, principal.Name; expected != actual {
+				t.Errorf("expected: %q, actual: %q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	authenticator := Chain(
+		NewTokens(map[string]string{"a": "team-a"}),
+		NewTokens(map[string]string{"b": "team-b"}),
+	)
+
+	t.Run("first", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer a")
+
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := "team-a", principal.Name; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("error stops the chain", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer b")
+
+		if _, err := authenticator.Authenticate(r); err == nil || err == ErrNoCredentials {
+			t.Errorf("expected invalid token error, actual: %v", err)
+		}
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+
+		if _, err := authenticator.Authenticate(r); err != ErrNoCredentials {
+			t.Errorf("expected: %v, actual: %v", ErrNoCredentials, err)
+		}
+	})
+}
+
+func TestPeerCertificates(t *testing.T) {
+	t.Parallel()
+
+	t.Run("verified", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{}}},
+		}
+
+		principal, err := NewPeerCertificates().Authenticate(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := true, principal.Peer; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("not verified", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.TLS = &tls.ConnectionState{}
+
+		if _, err := NewPeerCertificates().Authenticate(r); err != ErrNoCredentials {
+			t.Errorf("expected: %v, actual: %v", ErrNoCredentials, err)
+		}
+	})
+}
+
+func TestACL(t *testing.T) {
+	t.Parallel()
+
+	acl := NewACL([]Rule{
+		{Principal: "team-a", Prefix: "a/", Access: ReadWrite},
+		{Principal: "team-a", Prefix: "shared/", Access: Read},
+		{Principal: "team-a", Keyspace: "team-a", Prefix: "", Access: ReadWrite},
+		{Principal: "admin", Keyspace: AnyKeyspace, Prefix: "", Access: Read},
+	})
+
+	for _, testcase := range []struct {
+		principal Principal
+		keyspace  selectors.Keyspace
+		key       string
+		access    Access
+		expected  bool
+	}{
+		{Principal{Name: "team-a"}, "", "a/1", Read, true},
+		{Principal{Name: "team-a"}, "", "a/1", Write, true},
+		{Principal{Name: "team-a"}, "", "shared/1", Read, true},
+		{Principal{Name: "team-a"}, "", "shared/1", Write, false},
+		{Principal{Name: "team-a"}, "", "b/1", Read, false},
+		{Principal{Name: "team-a"}, "", "", Read, false},
+		{Principal{Name: "team-a"}, "team-a", "b/1", Write, true},
+		{Principal{Name: "team-a"}, "team-b", "a/1", Read, false},
+		{Principal{Name: "admin"}, "", "b/1", Read, true},
+		{Principal{Name: "admin"}, "team-b", "", Read, true},
+		{Principal{Name: "admin"}, "", "b/1", Write, false},
+		{Principal{Name: "unknown"}, "", "a/1", Read, false},
+		{PeerPrincipal, "team-b", "b/1", Write, true},
+	} {
+		if expected, actual := testcase.expected, acl.Allowed(testcase.principal, testcase.keyspace, testcase.key, testcase.access); expected != actual {
+			t.Errorf("%s %s %q %q: expected: %t, actual: %t", testcase.principal.Name, testcase.access, testcase.keyspace, testcase.key, expected, actual)
+		}
+	}
+}
+
+func TestParseAccess(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []Access{Read, Write, ReadWrite} {
+		access, err := ParseAccess(strings.ToUpper(v.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := v, access; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+	}
+
+	if _, err := ParseAccess("all"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+// errAny matches any error that isn't ErrNoCredentials.
+var errAny = &struct{ error }{}
+
+func matchErr(expected, actual error) bool {
+	if expected == errAny {
+		return actual != nil && actual != ErrNoCredentials
+	}
+	return expected == actual
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// Config is what's loaded from the auth config file, which looks like:
+//
+//	{
+//	  "tokens": [{"principal": "team-a", "token": "..."}, {"principal": "ops", "token": "...", "admin": true}],
+//	  "keys": [{"principal": "team-b", "id": "key-1", "secret": "<base64>"}],
+//	  "peer_key": {"id": "cluster", "secret": "<base64>"},
+//	  "acl": [{"principal": "team-a", "keyspace": "a", "prefix": "", "access": "readwrite"}]
+//	}
+//
+// A token with admin set may use the admin API of the nodes, which is
+// otherwise only open to the peers.
+//
+// A rule without a keyspace applies to the default keyspace, the keyspace "*"
+// applies to every keyspace.
+//
+// The peer key is shared by every node, it signs the requests to the store
+// API of the other nodes.
+type Config struct {
+	Authenticator Authenticator
+	ACL           *ACL
+
+	// Signer signs the requests to other nodes, it's nil if there isn't a
+	// peer key.
+	Signer *Signer
+}
+
+type fileConfig struct {
+	Tokens []struct {
+		Principal string `json:"principal"`
+		Token     string `json:"token"`
+		Admin     bool   `json:"admin"`
+	} `json:"tokens"`
+	Keys    []fileKey `json:"keys"`
+	PeerKey *fileKey  `json:"peer_key"`
+	ACL     []struct {
+		Principal string `json:"principal"`
+		Keyspace  string `json:"keyspace"`
+		Prefix    string `json:"prefix"`
+		Access    string `json:"access"`
+	} `json:"acl"`
+}
+
+type fileKey struct {
+	Principal string `json:"principal"`
+	ID        string `json:"id"`
+	Secret    string `json:"secret"`
+}
+
+// Load reads the Config from the file. When mutual is set, the requests over
+// a connection with a verified client certificate are from peers.
+func Load(path string, mutual bool) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "open auth config")
+	}
+	defer file.Close()
+
+	return Parse(file, mutual)
+}
+
+// Parse reads the Config from the reader.
+func Parse(r io.Reader, mutual bool) (Config, error) {
+	var file fileConfig
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return Config{}, errors.Wrap(err, "decode auth config")
+	}
+
+	var authenticators []Authenticator
+	if mutual {
+		authenticators = append(authenticators, NewPeerCertificates())
+	}
+
+	principals := make(map[string]Principal, len(file.Tokens))
+	for _, v := range file.Tokens {
+		if v.Token == "" || v.Principal == "" {
+			return Config{}, errors.New("expected a token and a principal")
+		}
+		principals[v.Token] = Principal{Name: v.Principal, Admin: v.Admin}
+	}
+	authenticators = append(authenticators, tokens{principals})
+
+	var (
+		keys   []Key
+		signer *Signer
+	)
+	for _, v := range file.Keys {
+		if v.Principal == "" {
+			return Config{}, errors.Errorf("expected a principal for key %q", v.ID)
+		}
+		key, err := v.decode(Principal{Name: v.Principal})
+		if err != nil {
+			return Config{}, err
+		}
+		keys = append(keys, key)
+	}
+	if file.PeerKey != nil {
+		key, err := file.PeerKey.decode(PeerPrincipal)
+		if err != nil {
+			return Config{}, err
+		}
+		keys = append(keys, key)
+		signer = NewSigner(key.ID, key.Secret)
+	}
+	authenticators = append(authenticators, NewHMAC(keys, DefaultMaxSkew))
+
+	rules := make([]Rule, len(file.ACL))
+	for k, v := range file.ACL {
+		access, err := ParseAccess(v.Access)
+		if err != nil {
+			return Config{}, errors.Wrapf(err, "rule %d", k)
+		}
+		if v.Keyspace != AnyKeyspace {
+			if _, err := selectors.ParseKeyspace(v.Keyspace); err != nil {
+				return Config{}, errors.Wrapf(err, "rule %d", k)
+			}
+		}
+		rules[k] = Rule{
+			Principal: v.Principal,
+			Keyspace:  v.Keyspace,
+			Prefix:    v.Prefix,
+			Access:    access,
+		}
+	}
+
+	return Config{
+		Authenticator: Chain(authenticators...),
+		ACL:           NewACL(rules),
+		Signer:        signer,
+	}, nil
+}
+
+func (k fileKey) decode(principal Principal) (Key, error) {
+	if k.ID == "" {
+		return Key{}, errors.New("expected a key id")
+	}
+	secret, err := base64.StdEncoding.DecodeString(k.Secret)
+	if err != nil {
+		return Key{}, errors.Wrapf(err, "decode secret of key %q", k.ID)
+	}
+	if len(secret) < 16 {
+		return Key{}, errors.Errorf("secret of key %q is too short, expected at least 16 bytes", k.ID)
+	}
+	return Key{
+		ID:        k.ID,
+		Secret:    secret,
+		Principal: principal,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"net/http"
+
+	errs "github.com/SimonRichardson/coherence/pkg/api/http"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Handler authenticates and authorizes the requests of an API before passing
+// them on.
+type Handler struct {
+	next          http.Handler
+	authenticator Authenticator
+	acl           *ACL
+	peers         bool
+	admins        bool
+	logger        log.Logger
+	errors        errs.Error
+}
+
+// NewHandler creates a Handler that checks every request against the ACL.
+// The requests that read are GET requests and the ones that write are POST
+// requests, the key is taken from the "key" query or, when there isn't one,
+// from the literal prefix of the "match" query. The keyspace is the one that
+// the request names.
+func NewHandler(next http.Handler, authenticator Authenticator, acl *ACL, logger log.Logger) *Handler {
+	return &Handler{
+		next:          next,
+		authenticator: authenticator,
+		acl:           acl,
+		logger:        logger,
+		errors:        errs.NewError(logger),
+	}
+}
+
+// NewPeerHandler creates a Handler that only lets through the requests of
+// the other nodes in the cluster.
+func NewPeerHandler(next http.Handler, authenticator Authenticator, logger log.Logger) *Handler {
+	return &Handler{
+		next:          next,
+		authenticator: authenticator,
+		peers:         true,
+		logger:        logger,
+		errors:        errs.NewError(logger),
+	}
+}
+
+// NewAdminHandler creates a Handler that only lets through the requests of
+// the other nodes in the cluster and of the principals with admin set.
+func NewAdminHandler(next http.Handler, authenticator Authenticator, logger log.Logger) *Handler {
+	return &Handler{
+		next:          next,
+		authenticator: authenticator,
+		peers:         true,
+		admins:        true,
+		logger:        logger,
+		errors:        errs.NewError(logger),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	principal, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		level.Debug(h.logger).Log("method", r.Method, "path", r.URL.Path, "err", err)
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="coherence"`)
+		h.errors.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	allowed, err := h.allowed(principal, r)
+	if err != nil {
+		h.errors.BadRequest(w, r, err.Error())
+		return
+	}
+	if !allowed {
+		level.Debug(h.logger).Log("method", r.Method, "path", r.URL.Path, "principal", principal.Name, "err", "forbidden")
+
+		h.errors.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	h.next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+}
+
+func (h *Handler) allowed(principal Principal, r *http.Request) (bool, error) {
+	if h.peers {
+		return principal.Peer || (h.admins && principal.Admin), nil
+	}
+
+	keyspace, _, err := errs.Keyspace(r)
+	if err != nil {
+		return false, err
+	}
+
+	access := Read
+	if r.Method != "GET" && r.Method != "HEAD" {
+		access = Write
+	}
+
+	return h.acl.Allowed(principal, keyspace, errs.KeyPrefix(r), access), nil
+}
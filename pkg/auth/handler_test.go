@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+const testConfig = `{
+	"tokens": [
+		{"principal": "team-a", "token": "token-a"},
+		{"principal": "reader", "token": "token-r"},
+		{"principal": "ops", "token": "token-o", "admin": true}
+	],
+	"peer_key": {"id": "cluster", "secret": "MDEyMzQ1Njc4OWFiY2RlZg=="},
+	"acl": [
+		{"principal": "team-a", "prefix": "a/", "access": "readwrite"},
+		{"principal": "team-a", "keyspace": "team-a", "prefix": "", "access": "readwrite"},
+		{"principal": "reader", "keyspace": "*", "prefix": "", "access": "read"}
+	]
+}`
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	config, err := Parse(strings.NewReader(testConfig), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The principal is passed on, so that it can be rate limited.
+		if _, ok := FromContext(r.Context()); !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	var (
+		cache = NewHandler(next, config.Authenticator, config.ACL, log.NewNopLogger())
+		store = NewPeerHandler(next, config.Authenticator, log.NewNopLogger())
+		admin = NewAdminHandler(next, config.Authenticator, log.NewNopLogger())
+	)
+
+	for _, testcase := range []struct {
+		name    string
+		handler http.Handler
+		method  string
+		url     string
+		token   string
+		peer    bool
+		code    int
+	}{
+		{"no credentials", cache, "GET", "/select?key=a/1", "", false, http.StatusUnauthorized},
+		{"invalid token", cache, "GET", "/select?key=a/1", "bad", false, http.StatusUnauthorized},
+		{"read own prefix", cache, "GET", "/select?key=a/1", "token-a", false, http.StatusOK},
+		{"write own prefix", cache, "POST", "/insert?key=a/1", "token-a", false, http.StatusOK},
+		{"read other prefix", cache, "GET", "/select?key=b/1", "token-a", false, http.StatusForbidden},
+		{"match own prefix", cache, "GET", "/keys?match=a/*", "token-a", false, http.StatusOK},
+		{"match everything", cache, "GET", "/keys", "token-a", false, http.StatusForbidden},
+		{"reader reads", cache, "GET", "/keys", "token-r", false, http.StatusOK},
+		{"reader writes", cache, "POST", "/delete?key=a/1", "token-r", false, http.StatusForbidden},
+		{"own keyspace", cache, "POST", "/keyspaces/team-a/insert?key=b/1", "token-a", false, http.StatusOK},
+		{"other keyspace", cache, "GET", "/keyspaces/team-b/select?key=a/1", "token-a", false, http.StatusForbidden},
+		{"reader reads keyspace", cache, "GET", "/keyspaces/team-b/keys", "token-r", false, http.StatusOK},
+		{"invalid keyspace", cache, "GET", "/keyspaces/Team/keys", "token-r", false, http.StatusBadRequest},
+		{"peer writes", cache, "POST", "/insert?key=b/1", "", true, http.StatusOK},
+		{"store token", store, "POST", "/insert?key=a/1", "token-a", false, http.StatusForbidden},
+		{"store peer", store, "POST", "/insert?key=a/1", "", true, http.StatusOK},
+		{"store no credentials", store, "GET", "/keys", "", false, http.StatusUnauthorized},
+		{"store admin token", store, "POST", "/insert?key=a/1", "token-o", false, http.StatusForbidden},
+		{"admin no credentials", admin, "POST", "/decommission", "", false, http.StatusUnauthorized},
+		{"admin token", admin, "POST", "/decommission", "token-a", false, http.StatusForbidden},
+		{"admin admin token", admin, "POST", "/keyring/install", "token-o", false, http.StatusOK},
+		{"admin peer", admin, "POST", "/decommission", "", true, http.StatusOK},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			r := httptest.NewRequest(testcase.method, testcase.url, nil)
+			if testcase.token != "" {
+				r.Header.Set("Authorization", "Bearer "+testcase.token)
+			}
+			if testcase.peer {
+				if err := config.Signer.Sign(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			testcase.handler.ServeHTTP(w, r)
+
+			if expected, actual := testcase.code, w.Code; expected != actual {
+				t.Errorf("expected: %d, actual: %d", expected, actual)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		name   string
+		config string
+	}{
+		{"invalid json", `{`},
+		{"missing token", `{"tokens": [{"principal": "a"}]}`},
+		{"missing key principal", `{"keys": [{"id": "a", "secret": "MDEyMzQ1Njc4OWFiY2RlZg=="}]}`},
+		{"short secret", `{"peer_key": {"id": "a", "secret": "YWJj"}}`},
+		{"invalid access", `{"acl": [{"principal": "a", "prefix": "", "access": "all"}]}`},
+		{"invalid keyspace", `{"acl": [{"principal": "a", "keyspace": "A", "prefix": "", "access": "read"}]}`},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(testcase.config), false); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+
+	t.Run("no peer key", func(t *testing.T) {
+		config, err := Parse(strings.NewReader(`{}`), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Signer != nil {
+			t.Errorf("expected no signer")
+		}
+	})
+}
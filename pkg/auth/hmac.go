@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// HeaderDate is the header that holds the unix time a request was signed
+	// at, it's part of the signature.
+	HeaderDate = "X-Coherence-Date"
+
+	// HeaderNonce is the header that holds a random value that's unique to
+	// each request, it's part of the signature, so that a request can't be
+	// replayed.
+	HeaderNonce = "X-Coherence-Nonce"
+
+	// DefaultMaxSkew is how old, or how far in the future, a signed request
+	// can be before it's rejected.
+	DefaultMaxSkew = time.Minute * 5
+
+	// maxBody is the largest body of a signed request that will be read.
+	maxBody = 32 << 20
+)
+
+// Key is a secret that requests are signed with, the ID is sent with the
+// request so that the secret can be found.
+type Key struct {
+	ID        string
+	Secret    []byte
+	Principal Principal
+}
+
+type signatures struct {
+	keys    map[string]Key
+	maxSkew time.Duration
+	nonces  *nonces
+	now     func() time.Time
+}
+
+// NewHMAC creates an Authenticator for requests that are signed with one of
+// the keys. The Authorization header holds "HMAC <id>:<signature>", where the
+// signature covers the method, the URI, the date, the nonce and the body of
+// the request. Each nonce is only accepted once whilst its date is within the
+// skew.
+func NewHMAC(keys []Key, maxSkew time.Duration) Authenticator {
+	m := make(map[string]Key, len(keys))
+	for _, v := range keys {
+		m[v.ID] = v
+	}
+	return signatures{
+		keys:    m,
+		maxSkew: maxSkew,
+		nonces:  newNonces(),
+		now:     time.Now,
+	}
+}
+
+func (s signatures) Authenticate(r *http.Request) (Principal, error) {
+	credentials, ok := authorization(r, "HMAC")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	parts := strings.SplitN(credentials, ":", 2)
+	if len(parts) != 2 {
+		return Principal{}, errors.New("invalid signature")
+	}
+	key, ok := s.keys[parts[0]]
+	if !ok {
+		return Principal{}, errors.Errorf("unknown key %q", parts[0])
+	}
+	signature, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Principal{}, errors.New("invalid signature")
+	}
+
+	date := r.Header.Get(HeaderDate)
+	unix, err := strconv.ParseInt(date, 10, 64)
+	if err != nil {
+		return Principal{}, errors.Errorf("invalid %s header", HeaderDate)
+	}
+	now := s.now()
+	if skew := now.Sub(time.Unix(unix, 0)); skew > s.maxSkew || skew < -s.maxSkew {
+		return Principal{}, errors.New("signature expired")
+	}
+
+	nonce := r.Header.Get(HeaderNonce)
+	if nonce == "" {
+		return Principal{}, errors.Errorf("missing %s header", HeaderNonce)
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(nil, r.Body, maxBody)
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	expected := sign(key.Secret, r.Method, r.RequestURI, date, nonce, body)
+	if !hmac.Equal(expected, signature) {
+		return Principal{}, errors.New("invalid signature")
+	}
+
+	// The nonce is only remembered once the signature is known to be good,
+	// until the date of the request is outside of the skew.
+	if !s.nonces.add(key.ID+":"+nonce, time.Unix(unix, 0).Add(s.maxSkew), now) {
+		return Principal{}, errors.New("replayed signature")
+	}
+	return key.Principal, nil
+}
+
+// nonces remembers the nonces of the signed requests until they expire.
+type nonces struct {
+	mutex sync.Mutex
+	seen  map[string]time.Time
+	sweep time.Time
+}
+
+func newNonces() *nonces {
+	return &nonces{
+		seen: make(map[string]time.Time),
+	}
+}
+
+// add returns false if the nonce has already been seen and hasn't expired.
+func (n *nonces) add(nonce string, expires, now time.Time) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if expiry, ok := n.seen[nonce]; ok && now.Before(expiry) {
+		return false
+	}
+	n.seen[nonce] = expires
+
+	// The expired nonces are swept at most once a second, so that a busy
+	// node doesn't walk them on every request.
+	if now.After(n.sweep) {
+		for k, v := range n.seen {
+			if !now.Before(v) {
+				delete(n.seen, k)
+			}
+		}
+		n.sweep = now.Add(time.Second)
+	}
+	return true
+}
+
+// Signer signs the requests with a Key, so that they can be authenticated
+// by NewHMAC.
+type Signer struct {
+	id     string
+	secret []byte
+	now    func() time.Time
+}
+
+// NewSigner creates a Signer for the key.
+func NewSigner(id string, secret []byte) *Signer {
+	return &Signer{
+		id:     id,
+		secret: secret,
+		now:    time.Now,
+	}
+}
+
+// Sign adds the signature of the request to it, each call signs it with a new
+// nonce.
+func (s *Signer) Sign(r *http.Request) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	var random [16]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return errors.Wrap(err, "read nonce")
+	}
+
+	var (
+		date      = strconv.FormatInt(s.now().Unix(), 10)
+		nonce     = hex.EncodeToString(random[:])
+		signature = sign(s.secret, r.Method, r.URL.RequestURI(), date, nonce, body)
+	)
+
+	r.Header.Set(HeaderDate, date)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set("Authorization", "HMAC "+s.id+":"+base64.StdEncoding.EncodeToString(signature))
+	return nil
+}
+
+// RoundTripper signs every request before passing it on to the next
+// RoundTripper.
+func (s *Signer) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		// A RoundTripper mustn't modify the request it's given.
+		r = r.Clone(r.Context())
+		if err := s.Sign(r); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func sign(secret []byte, method, uri, date, nonce string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{
+		method,
+		uri,
+		date,
+		nonce,
+		hex.EncodeToString(sum[:]),
+	}, "\n")))
+	return mac.Sum(nil)
+}
+
+// readBody reads the body of the request, putting it back so that it can be
+// read again.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "read body")
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHMAC(t *testing.T) {
+	t.Parallel()
+
+	var (
+		secret = []byte("0123456789abcdef")
+		keys   = []Key{{ID: "key-1", Secret: secret, Principal: Principal{Name: "team-b"}}}
+	)
+
+	// newSignedRequest signs a request the way the client would send it, then
+	// turns it into a request the way the server would receive it.
+	newSignedRequest := func(t *testing.T, signer *Signer, body string) *http.Request {
+		r := httptest.NewRequest("POST", "/cache/insert?key=b%2F1", bytes.NewBufferString(body))
+		if err := signer.Sign(r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", secret), "body")
+
+		principal, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := "team-b", principal.Name; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+
+		// The body must still be readable.
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := "body", string(body); expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", secret), "body")
+		r.Body = ioutil.NopCloser(bytes.NewBufferString("other"))
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("tampered query", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", secret), "body")
+		r.RequestURI = "/cache/insert?key=a%2F1"
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", []byte("fedcba9876543210")), "body")
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-2", secret), "body")
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		signer := NewSigner("key-1", secret)
+		signer.now = func() time.Time { return time.Now().Add(-time.Hour) }
+		r := newSignedRequest(t, signer, "body")
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		var (
+			authenticator = NewHMAC(keys, DefaultMaxSkew)
+			r             = newSignedRequest(t, NewSigner("key-1", secret), "body")
+			replay        = r.Clone(r.Context())
+		)
+		replay.Body = ioutil.NopCloser(bytes.NewBufferString("body"))
+
+		if _, err := authenticator.Authenticate(r); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := authenticator.Authenticate(replay); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("missing nonce", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", secret), "body")
+		r.Header.Del(HeaderNonce)
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("tampered nonce", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", secret), "body")
+		r.Header.Set(HeaderNonce, "other")
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		r := newSignedRequest(t, NewSigner("key-1", secret), "body")
+		r.Body = ioutil.NopCloser(bytes.NewReader(make([]byte, maxBody+1)))
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+
+		if _, err := NewHMAC(keys, DefaultMaxSkew).Authenticate(r); err != ErrNoCredentials {
+			t.Errorf("expected: %v, actual: %v", ErrNoCredentials, err)
+		}
+	})
+
+	t.Run("round tripper", func(t *testing.T) {
+		authenticator := NewHMAC(keys, DefaultMaxSkew)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := authenticator.Authenticate(r); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := &http.Client{
+			Transport: NewSigner("key-1", secret).RoundTripper(http.DefaultTransport),
+		}
+		resp, err := client.Post(server.URL+"/store/insert?key=a", "application/json", bytes.NewBufferString("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if expected, actual := http.StatusOK, resp.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+}
+
+func TestNonces(t *testing.T) {
+	t.Parallel()
+
+	var (
+		n       = newNonces()
+		now     = time.Now()
+		expires = now.Add(time.Minute)
+	)
+
+	if !n.add("a", expires, now) {
+		t.Errorf("expected the nonce to be added")
+	}
+	if n.add("a", expires, now.Add(time.Second)) {
+		t.Errorf("expected the nonce to be seen")
+	}
+	if !n.add("b", expires, now) {
+		t.Errorf("expected the nonce to be added")
+	}
+
+	// Once it's expired, it's swept.
+	if !n.add("c", now.Add(time.Hour), expires) {
+		t.Errorf("expected the nonce to be added")
+	}
+	if expected, actual := 1, len(n.seen); expected != actual {
+		t.Errorf("expected: %d, actual: %d", expected, actual)
+	}
+}
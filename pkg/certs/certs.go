@@ -0,0 +1,228 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/soheilhy/cmux"
+)
+
+// Config holds the files that the certificates are loaded from. The CAFile is
+// optional, when it's set the peers are expected to present a certificate
+// signed by it (mutual TLS).
+type Config struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// Enabled returns true if the config has a certificate to load.
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Reloader holds the certificates loaded from the Config, which can be
+// reloaded at any time without having to recreate the listeners or the
+// clients that use them.
+type Reloader struct {
+	config Config
+	mutex  sync.RWMutex
+	cert   *tls.Certificate
+	pool   *x509.CertPool
+}
+
+// NewReloader creates a Reloader and loads the certificates for the first
+// time.
+func NewReloader(config Config) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("expected both a certificate and a key file")
+	}
+	r := &Reloader{
+		config: config,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewClientReloader creates a Reloader for clients that don't have to present
+// a certificate, so either the certificate and key or only the CA file can be
+// given. Without a CA, the certificates of the servers are verified against
+// the roots of the system.
+func NewClientReloader(config Config) (*Reloader, error) {
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("expected both a certificate and a key file")
+	}
+	if config.CertFile == "" && config.CAFile == "" {
+		return nil, errors.New("expected either a certificate or a ca file")
+	}
+	r := &Reloader{
+		config: config,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificates from the files again. If any of the files
+// can't be loaded, the existing certificates are kept.
+func (r *Reloader) Reload() error {
+	// Clients without a certificate send an empty one, which tells the
+	// server that there isn't one.
+	cert := tls.Certificate{}
+	if r.config.CertFile != "" {
+		var err error
+		if cert, err = tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile); err != nil {
+			return errors.Wrap(err, "load key pair")
+		}
+	}
+
+	var pool *x509.CertPool
+	if r.config.CAFile != "" {
+		bytes, err := ioutil.ReadFile(r.config.CAFile)
+		if err != nil {
+			return errors.Wrap(err, "read ca")
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bytes) {
+			return errors.Errorf("no certificates found in %q", r.config.CAFile)
+		}
+	}
+
+	r.mutex.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.mutex.Unlock()
+	return nil
+}
+
+// Mutual returns true if the peers have to present a certificate.
+func (r *Reloader) Mutual() bool {
+	return r.config.CAFile != ""
+}
+
+// ServerConfig returns a tls.Config for the listeners, the certificates are
+// read on every handshake so that a reload is picked up straight away.
+// Clients that present a certificate have it verified against the CA, the
+// clients that don't are only allowed on the public API (see PeerListener).
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if pool != nil {
+				config.ClientCAs = pool
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return config, nil
+		},
+	}
+}
+
+// ClientConfig returns a tls.Config for talking to the other nodes. Nodes are
+// addressed by their advertised address and not by name, so when there is a
+// CA the chain of the peer is verified against it, without checking the host
+// name.
+func (r *Reloader) ClientConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}
+	if r.Mutual() {
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+			_, pool := r.current()
+			return verify(raw, pool)
+		}
+	}
+	return config
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, r.pool
+}
+
+func verify(raw [][]byte, pool *x509.CertPool) error {
+	if len(raw) == 0 {
+		return errors.New("no peer certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(raw))
+	for k, v := range raw {
+		cert, err := x509.ParseCertificate(v)
+		if err != nil {
+			return errors.Wrap(err, "parse certificate")
+		}
+		certs[k] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, v := range certs[1:] {
+		intermediates.AddCert(v)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return errors.Wrap(err, "verify certificate")
+}
+
+// PeerListener wraps a listener that is only for the other nodes, any
+// connection that doesn't present a verified client certificate is closed.
+type PeerListener struct {
+	net.Listener
+}
+
+// NewPeerListener creates a PeerListener from a listener that accepts TLS
+// connections, either directly or through a cmux.
+func NewPeerListener(listener net.Listener) PeerListener {
+	return PeerListener{listener}
+}
+
+// Accept waits for the next connection that has a verified client
+// certificate.
+func (l PeerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if Verified(conn) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
+
+// Verified returns true if the connection is a TLS connection, where the peer
+// presented a certificate that was verified.
+func Verified(conn net.Conn) bool {
+	if mux, ok := conn.(*cmux.MuxConn); ok {
+		conn = mux.Conn
+	}
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return false
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return false
+	}
+	return len(tlsConn.ConnectionState().VerifiedChains) > 0
+}
@@ -0,0 +1,288 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing files", func(t *testing.T) {
+		if _, err := NewReloader(Config{}); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		_, err := NewReloader(Config{
+			CertFile: filepath.Join(dir, "cert.pem"),
+			KeyFile:  filepath.Join(dir, "key.pem"),
+		})
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		ca := newAuthority(t)
+		config := ca.write(t, dir, "node")
+
+		reloader, err := NewReloader(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, _ := reloader.current()
+
+		ca.write(t, dir, "node")
+		if err := reloader.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		after, _ := reloader.current()
+
+		if string(before.Certificate[0]) == string(after.Certificate[0]) {
+			t.Errorf("expected certificate to be reloaded")
+		}
+	})
+
+	t.Run("reload keeps existing", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		ca := newAuthority(t)
+		config := ca.write(t, dir, "node")
+
+		reloader, err := NewReloader(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, _ := reloader.current()
+
+		if err := ioutil.WriteFile(config.CertFile, []byte("bad"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := reloader.Reload(); err == nil {
+			t.Errorf("expected error")
+		}
+		if after, _ := reloader.current(); after != before {
+			t.Errorf("expected certificate to be kept")
+		}
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("verified peer", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		ca := newAuthority(t)
+		server, client := newReloader(t, ca.write(t, dir, "server")), newReloader(t, ca.write(t, dir, "client"))
+
+		verified, err := handshake(t, server, client.ClientConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := true, verified; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		ca := newAuthority(t)
+		server := newReloader(t, ca.write(t, dir, "server"))
+
+		verified, err := handshake(t, server, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := false, verified; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("client with only a ca", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		ca := newAuthority(t)
+		server := newReloader(t, ca.write(t, dir, "server"))
+
+		client, err := NewClientReloader(Config{CAFile: filepath.Join(dir, "ca.pem")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verified, err := handshake(t, server, client.ClientConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := false, verified; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("unknown authority", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		server := newReloader(t, newAuthority(t).write(t, filepath.Join(dir, "a"), "server"))
+		client := newReloader(t, newAuthority(t).write(t, filepath.Join(dir, "b"), "client"))
+
+		if _, err := handshake(t, server, client.ClientConfig()); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func handshake(t *testing.T, server *Reloader, config *tls.Config) (bool, error) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server.ServerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	result := make(chan bool, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- false
+			return
+		}
+		defer conn.Close()
+		result <- Verified(conn)
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), config)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	select {
+	case verified := <-result:
+		return verified, nil
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return false, nil
+}
+
+func newReloader(t *testing.T, config Config) *Reloader {
+	reloader, err := NewReloader(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reloader
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authority{cert, key}
+}
+
+// write a certificate signed by the authority to the directory, along with the
+// authority itself.
+func (a authority) write(t *testing.T, dir, name string) Config {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+"-key.pem"),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+	}
+	writePEM(t, config.CertFile, "CERTIFICATE", der)
+	writePEM(t, config.KeyFile, "EC PRIVATE KEY", keyDER)
+	writePEM(t, config.CAFile, "CERTIFICATE", a.cert.Raw)
+	return config
+}
+
+func writePEM(t *testing.T, path, kind string, bytes []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: bytes})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
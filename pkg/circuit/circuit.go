@@ -0,0 +1,52 @@
+package circuit
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/resilience/breaker"
+)
+
+// Circuit wraps a circuit breaker, so that its state can be observed. The
+// state is only known as requests are made, the circuit is open whilst it
+// refuses to run them.
+type Circuit struct {
+	breaker *breaker.CircuitBreaker
+	open    int32
+	state   metrics.Gauge
+}
+
+// New creates a Circuit that opens after the rate of failures, for the
+// timeout. The state is reported to the gauge, if there is one, 1 whilst the
+// circuit is open, otherwise 0.
+func New(rate int, timeout time.Duration, state metrics.Gauge) *Circuit {
+	return &Circuit{
+		breaker: breaker.New(rate, timeout),
+		state:   state,
+	}
+}
+
+// Run runs the function with in the circuit.
+func (c *Circuit) Run(fn func() error) error {
+	var ran bool
+	err := c.breaker.Run(func() error {
+		ran = true
+		return fn()
+	})
+
+	var open int32
+	if !ran {
+		open = 1
+	}
+	atomic.StoreInt32(&c.open, open)
+	if c.state != nil {
+		c.state.Set(float64(open))
+	}
+	return err
+}
+
+// Open returns if the circuit refused to run the last function.
+func (c *Circuit) Open() bool {
+	return atomic.LoadInt32(&c.open) == 1
+}
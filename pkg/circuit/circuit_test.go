@@ -0,0 +1,55 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func TestCircuit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("closed", func(t *testing.T) {
+		circuit := New(1, time.Minute, nil)
+		if err := circuit.Run(func() error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := false, circuit.Open(); expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("open", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		state := metricMocks.NewMockGauge(ctrl)
+		gomock.InOrder(
+			state.EXPECT().Set(float64(0)).Times(2),
+			state.EXPECT().Set(float64(1)),
+		)
+
+		var (
+			circuit = New(2, time.Minute, state)
+			ran     int
+		)
+		for i := 0; i < 3; i++ {
+			if err := circuit.Run(func() error {
+				ran++
+				return errors.New("bad")
+			}); err == nil {
+				t.Fatal("expected error")
+			}
+		}
+
+		if expected, actual := 2, ran; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+		if expected, actual := true, circuit.Open(); expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+}
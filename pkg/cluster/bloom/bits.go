@@ -71,6 +71,15 @@ func (b *Bits) Len() uint {
 	return b.len
 }
 
+// Count returns the number of bits that are set
+func (b *Bits) Count() uint {
+	var res int
+	for _, v := range b.b {
+		res += bits.OnesCount64(v)
+	}
+	return uint(res)
+}
+
 func (b *Bits) Read(r io.Reader) (int, error) {
 	var len uint64
 	if err := binary.Read(r, binary.LittleEndian, &len); err != nil {
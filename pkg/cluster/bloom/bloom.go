@@ -77,6 +77,15 @@ func (b *Bloom) Cap() uint {
 	return b.cap
 }
 
+// FillRatio returns the ratio of the bits of the Bloom that are set, the
+// higher it is, the more likely Contains is to return a false positive.
+func (b *Bloom) FillRatio() float64 {
+	if b.cap == 0 {
+		return 0
+	}
+	return float64(b.b.Count()) / float64(b.cap)
+}
+
 // Write a series of underlying bytes to the bloom. This wipes out any previous
 // additions and starts from scratch.
 func (b *Bloom) Write(w io.Writer) (int, error) {
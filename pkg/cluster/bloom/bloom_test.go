@@ -55,6 +55,24 @@ func TestBloom(t *testing.T) {
 		}
 	})
 
+	t.Run("fill ratio", func(t *testing.T) {
+		fn := func(a uuid.UUID) bool {
+			bloom := New(256*2, 4)
+			if bloom.FillRatio() != 0 {
+				t.Fatal("expected empty bloom")
+			}
+			if err := bloom.Add(a.String()); err != nil {
+				t.Fatal(err)
+			}
+			// Each recursion sets at most one bit.
+			ratio := bloom.FillRatio()
+			return ratio > 0 && ratio <= 4.0/512
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
 	t.Run("write and read", func(t *testing.T) {
 		fn := func(a uuid.UUID) bool {
 			bloom := New(256*2, 4)
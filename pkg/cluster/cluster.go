@@ -22,14 +22,40 @@ type Peer interface {
 	// perspective.
 	ClusterSize() int
 
-	// State returns a JSON-serializable dump of cluster state.
-	// Useful for debug.
-	State() map[string]interface{}
+	// State returns the state of the cluster, as the peer sees it.
+	State() (State, error)
 
 	// Current API host:ports for the given type of node.
 	// Bool defines if you want to include the current local node.
 	Current(peerType members.PeerType, includeLocal bool) ([]string, error)
 
+	// Weights returns the advertised weight for each API host:port for the
+	// given type of node.
+	// Bool defines if you want to include the current local node.
+	Weights(peerType members.PeerType, includeLocal bool) (map[string]int, error)
+
+	// Leaving returns the API host:ports for the given type of node, that are
+	// being decommissioned and are leaving the cluster.
+	Leaving(peerType members.PeerType) ([]string, error)
+
+	// SetStatus advertises the status of the local node to the cluster.
+	SetStatus(status members.PeerStatus) error
+
 	// Close and shutdown the peer
 	Close()
 }
+
+// State is the state of the cluster, as a peer sees it.
+type State struct {
+	Self       string        `json:"self"`
+	NumMembers int           `json:"num_members"`
+	Members    []MemberState `json:"members"`
+}
+
+// MemberState is a member of the cluster, along with the tags that it
+// advertises. Tags is nil if the member isn't alive.
+type MemberState struct {
+	Name    string            `json:"name"`
+	Address string            `json:"address"`
+	Tags    *members.PeerInfo `json:"tags,omitempty"`
+}
@@ -1,6 +1,8 @@
 package farm
 
 import (
+	"context"
+
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 )
 
@@ -20,17 +22,76 @@ type Farm interface {
 	Select(selectors.Key, selectors.Field, selectors.Quorum) (selectors.FieldValueScore, error)
 
 	// Keys returns all the potential keys that are stored with in the farm.
-	Keys() ([]selectors.Key, error)
+	// The keys from each replica are merged according to the quorum, rather
+	// than failing when the replicas disagree. The members of any key that's
+	// missing from some of the replicas are read and repaired.
+	Keys(selectors.Quorum) ([]selectors.Key, error)
+
+	// KeysMatching returns the keys that match the glob style pattern, merged
+	// from every replica.
+	KeysMatching(string, selectors.Quorum) ([]selectors.Key, error)
 
 	// Size returns the number of members for the key are stored in the farm.
-	Size(selectors.Key) (int64, error)
+	// The size is the largest that enough replicas agree on for the quorum,
+	// the members of a key whose size diverges are read and repaired.
+	Size(selectors.Key, selectors.Quorum) (int64, error)
 
-	// Members returns the members associated for a key
-	Members(selectors.Key) ([]selectors.Field, error)
+	// Members returns the members associated for a key, merged from the
+	// replicas according to the quorum. Any members that are missing from
+	// some of the replicas are repaired.
+	Members(selectors.Key, selectors.Quorum) ([]selectors.Field, error)
+
+	// MembersWithPrefix returns the members associated for a key that start
+	// with the prefix, merged from every replica.
+	MembersWithPrefix(selectors.Key, string, selectors.Quorum) ([]selectors.Field, error)
 
 	// Score returns the specific score for the field with in the key.
-	Score(selectors.Key, selectors.Field) (selectors.Presence, error)
+	Score(selectors.Key, selectors.Field, selectors.Quorum) (selectors.Presence, error)
+
+	// Scan returns the next page of keys after the cursor, merged from every
+	// replica. The quorum decides how many replicas have to answer.
+	Scan(selectors.Cursor, selectors.ScanOptions, selectors.Quorum) (selectors.KeysPage, error)
+
+	// ScanMembers returns the next page of members for a key after the
+	// cursor, merged from the replicas of the quorum.
+	ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions, selectors.Quorum) (selectors.FieldsPage, error)
+
+	// Watch subscribes to the changes of a key from the replicas of the
+	// quorum, resuming from a score.
+	Watch(selectors.Key, selectors.Resume, selectors.Quorum) (selectors.Subscription, error)
 
 	// Repair attempts to repair the store depending on the elements
 	Repair([]selectors.KeyFieldValue) error
 }
+
+// Swapper is implemented by the farms that can compare and swap a member on
+// the replicas of the key.
+type Swapper interface {
+
+	// Swap inserts the member on each replica only if the field is still
+	// inserted with the score of the token there. The field is a success of
+	// the ChangeSet if the quorum swapped it, and a failure if the score
+	// didn't match. A swap that doesn't meet the quorum may still have been
+	// applied to some of the replicas, like any other write, but they're not
+	// repaired, as that would swap the others regardless of their score.
+	Swap(selectors.Key, selectors.FieldValueScore, int64, selectors.Quorum) (selectors.ChangeSet, error)
+}
+
+// Contextual is implemented by the farms that can trace their reads and
+// writes as part of the trace of a context, and give up on their reads once
+// the context is done.
+type Contextual interface {
+
+	// WithContext returns a copy of the farm that traces with in the context
+	// and reads until the context is done.
+	WithContext(context.Context) Farm
+}
+
+// WithContext returns the farm tracing and reading with in the context, if it
+// can, otherwise the farm as it is.
+func WithContext(ctx context.Context, farm Farm) Farm {
+	if contextual, ok := farm.(Contextual); ok {
+		return contextual.WithContext(ctx)
+	}
+	return farm
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/SimonRichardson/coherence/pkg/cluster/farm (interfaces: Farm)
+// Source: github.com/SimonRichardson/coherence/pkg/cluster/farm (interfaces: Farm,Swapper)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -60,29 +60,55 @@ func (mr *MockFarmMockRecorder) Insert(arg0, arg1, arg2 interface{}) *gomock.Cal
 }
 
 // Keys mocks base method
-func (m *MockFarm) Keys() ([]selectors.Key, error) {
-	ret := m.ctrl.Call(m, "Keys")
+func (m *MockFarm) Keys(arg0 selectors.Quorum) ([]selectors.Key, error) {
+	ret := m.ctrl.Call(m, "Keys", arg0)
 	ret0, _ := ret[0].([]selectors.Key)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Keys indicates an expected call of Keys
-func (mr *MockFarmMockRecorder) Keys() *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockFarm)(nil).Keys))
+func (mr *MockFarmMockRecorder) Keys(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockFarm)(nil).Keys), arg0)
+}
+
+// KeysMatching mocks base method
+func (m *MockFarm) KeysMatching(arg0 string, arg1 selectors.Quorum) ([]selectors.Key, error) {
+	ret := m.ctrl.Call(m, "KeysMatching", arg0, arg1)
+	ret0, _ := ret[0].([]selectors.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// KeysMatching indicates an expected call of KeysMatching
+func (mr *MockFarmMockRecorder) KeysMatching(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeysMatching", reflect.TypeOf((*MockFarm)(nil).KeysMatching), arg0, arg1)
 }
 
 // Members mocks base method
-func (m *MockFarm) Members(arg0 selectors.Key) ([]selectors.Field, error) {
-	ret := m.ctrl.Call(m, "Members", arg0)
+func (m *MockFarm) Members(arg0 selectors.Key, arg1 selectors.Quorum) ([]selectors.Field, error) {
+	ret := m.ctrl.Call(m, "Members", arg0, arg1)
 	ret0, _ := ret[0].([]selectors.Field)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Members indicates an expected call of Members
-func (mr *MockFarmMockRecorder) Members(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockFarm)(nil).Members), arg0)
+func (mr *MockFarmMockRecorder) Members(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockFarm)(nil).Members), arg0, arg1)
+}
+
+// MembersWithPrefix mocks base method
+func (m *MockFarm) MembersWithPrefix(arg0 selectors.Key, arg1 string, arg2 selectors.Quorum) ([]selectors.Field, error) {
+	ret := m.ctrl.Call(m, "MembersWithPrefix", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]selectors.Field)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MembersWithPrefix indicates an expected call of MembersWithPrefix
+func (mr *MockFarmMockRecorder) MembersWithPrefix(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MembersWithPrefix", reflect.TypeOf((*MockFarm)(nil).MembersWithPrefix), arg0, arg1, arg2)
 }
 
 // Repair mocks base method
@@ -97,17 +123,43 @@ func (mr *MockFarmMockRecorder) Repair(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Repair", reflect.TypeOf((*MockFarm)(nil).Repair), arg0)
 }
 
+// Scan mocks base method
+func (m *MockFarm) Scan(arg0 selectors.Cursor, arg1 selectors.ScanOptions, arg2 selectors.Quorum) (selectors.KeysPage, error) {
+	ret := m.ctrl.Call(m, "Scan", arg0, arg1, arg2)
+	ret0, _ := ret[0].(selectors.KeysPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Scan indicates an expected call of Scan
+func (mr *MockFarmMockRecorder) Scan(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockFarm)(nil).Scan), arg0, arg1, arg2)
+}
+
+// ScanMembers mocks base method
+func (m *MockFarm) ScanMembers(arg0 selectors.Key, arg1 selectors.Cursor, arg2 selectors.ScanOptions, arg3 selectors.Quorum) (selectors.FieldsPage, error) {
+	ret := m.ctrl.Call(m, "ScanMembers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(selectors.FieldsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScanMembers indicates an expected call of ScanMembers
+func (mr *MockFarmMockRecorder) ScanMembers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanMembers", reflect.TypeOf((*MockFarm)(nil).ScanMembers), arg0, arg1, arg2, arg3)
+}
+
 // Score mocks base method
-func (m *MockFarm) Score(arg0 selectors.Key, arg1 selectors.Field) (selectors.Presence, error) {
-	ret := m.ctrl.Call(m, "Score", arg0, arg1)
+func (m *MockFarm) Score(arg0 selectors.Key, arg1 selectors.Field, arg2 selectors.Quorum) (selectors.Presence, error) {
+	ret := m.ctrl.Call(m, "Score", arg0, arg1, arg2)
 	ret0, _ := ret[0].(selectors.Presence)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Score indicates an expected call of Score
-func (mr *MockFarmMockRecorder) Score(arg0, arg1 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Score", reflect.TypeOf((*MockFarm)(nil).Score), arg0, arg1)
+func (mr *MockFarmMockRecorder) Score(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Score", reflect.TypeOf((*MockFarm)(nil).Score), arg0, arg1, arg2)
 }
 
 // Select mocks base method
@@ -124,14 +176,63 @@ func (mr *MockFarmMockRecorder) Select(arg0, arg1, arg2 interface{}) *gomock.Cal
 }
 
 // Size mocks base method
-func (m *MockFarm) Size(arg0 selectors.Key) (int64, error) {
-	ret := m.ctrl.Call(m, "Size", arg0)
+func (m *MockFarm) Size(arg0 selectors.Key, arg1 selectors.Quorum) (int64, error) {
+	ret := m.ctrl.Call(m, "Size", arg0, arg1)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Size indicates an expected call of Size
-func (mr *MockFarmMockRecorder) Size(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Size", reflect.TypeOf((*MockFarm)(nil).Size), arg0)
+func (mr *MockFarmMockRecorder) Size(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Size", reflect.TypeOf((*MockFarm)(nil).Size), arg0, arg1)
+}
+
+// Watch mocks base method
+func (m *MockFarm) Watch(arg0 selectors.Key, arg1 selectors.Resume, arg2 selectors.Quorum) (selectors.Subscription, error) {
+	ret := m.ctrl.Call(m, "Watch", arg0, arg1, arg2)
+	ret0, _ := ret[0].(selectors.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch
+func (mr *MockFarmMockRecorder) Watch(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockFarm)(nil).Watch), arg0, arg1, arg2)
+}
+
+// MockSwapper is a mock of Swapper interface
+type MockSwapper struct {
+	ctrl     *gomock.Controller
+	recorder *MockSwapperMockRecorder
+}
+
+// MockSwapperMockRecorder is the mock recorder for MockSwapper
+type MockSwapperMockRecorder struct {
+	mock *MockSwapper
+}
+
+// NewMockSwapper creates a new mock instance
+func NewMockSwapper(ctrl *gomock.Controller) *MockSwapper {
+	mock := &MockSwapper{ctrl: ctrl}
+	mock.recorder = &MockSwapperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockSwapper) EXPECT() *MockSwapperMockRecorder {
+	return m.recorder
+}
+
+// Swap mocks base method
+func (m *MockSwapper) Swap(arg0 selectors.Key, arg1 selectors.FieldValueScore, arg2 int64, arg3 selectors.Quorum) (selectors.ChangeSet, error) {
+	ret := m.ctrl.Call(m, "Swap", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(selectors.ChangeSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Swap indicates an expected call of Swap
+func (mr *MockSwapperMockRecorder) Swap(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Swap", reflect.TypeOf((*MockSwapper)(nil).Swap), arg0, arg1, arg2, arg3)
 }
@@ -33,12 +33,25 @@ func (nop) Delete(key selectors.Key,
 func (nop) Select(selectors.Key, selectors.Field, selectors.Quorum) (selectors.FieldValueScore, error) {
 	return selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("not found"))
 }
-func (nop) Keys() ([]selectors.Key, error)                   { return nil, nil }
-func (nop) Size(selectors.Key) (int64, error)                { return -1, nil }
-func (nop) Members(selectors.Key) ([]selectors.Field, error) { return nil, nil }
-func (nop) Score(selectors.Key, selectors.Field) (selectors.Presence, error) {
+func (nop) Keys(selectors.Quorum) ([]selectors.Key, error)                     { return nil, nil }
+func (nop) KeysMatching(string, selectors.Quorum) ([]selectors.Key, error)     { return nil, nil }
+func (nop) Size(selectors.Key, selectors.Quorum) (int64, error)                { return -1, nil }
+func (nop) Members(selectors.Key, selectors.Quorum) ([]selectors.Field, error) { return nil, nil }
+func (nop) MembersWithPrefix(selectors.Key, string, selectors.Quorum) ([]selectors.Field, error) {
+	return nil, nil
+}
+func (nop) Score(selectors.Key, selectors.Field, selectors.Quorum) (selectors.Presence, error) {
 	return selectors.Presence{}, nil
 }
+func (nop) Scan(selectors.Cursor, selectors.ScanOptions, selectors.Quorum) (selectors.KeysPage, error) {
+	return selectors.KeysPage{}, nil
+}
+func (nop) ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions, selectors.Quorum) (selectors.FieldsPage, error) {
+	return selectors.FieldsPage{}, nil
+}
+func (nop) Watch(selectors.Key, selectors.Resume, selectors.Quorum) (selectors.Subscription, error) {
+	return nil, errors.New("nothing to watch")
+}
 func (nop) Repair([]selectors.KeyFieldValue) error { return nil }
 
 func extractFields(members []selectors.FieldValueScore) []selectors.Field {
@@ -84,7 +84,7 @@ func TestNopKeys(t *testing.T) {
 	t.Run("keys", func(t *testing.T) {
 		fn := func() bool {
 			farm := NewNop()
-			keys, err := farm.Keys()
+			keys, err := farm.Keys(selectors.Strong)
 			return len(keys) == 0 && err == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -99,7 +99,7 @@ func TestNopSize(t *testing.T) {
 	t.Run("size", func(t *testing.T) {
 		fn := func(key selectors.Key) bool {
 			farm := NewNop()
-			size, err := farm.Size(key)
+			size, err := farm.Size(key, selectors.Strong)
 			return size == -1 && err == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -114,7 +114,7 @@ func TestNopMembers(t *testing.T) {
 	t.Run("members", func(t *testing.T) {
 		fn := func(key selectors.Key) bool {
 			farm := NewNop()
-			members, err := farm.Members(key)
+			members, err := farm.Members(key, selectors.Strong)
 			return len(members) == 0 && err == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -129,7 +129,7 @@ func TestNopScore(t *testing.T) {
 	t.Run("presence", func(t *testing.T) {
 		fn := func(key selectors.Key, field selectors.Field) bool {
 			farm := NewNop()
-			presence, err := farm.Score(key, field)
+			presence, err := farm.Score(key, field, selectors.Strong)
 			return selectors.Presence{}.Equal(presence) && err == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
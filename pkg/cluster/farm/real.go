@@ -1,50 +1,204 @@
 package farm
 
 import (
+	"context"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/SimonRichardson/coherence/pkg/circuit"
 	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/ratelimit"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
-	"github.com/SimonRichardson/resilience/breaker"
+	"github.com/SimonRichardson/coherence/pkg/trace"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
 	defaultAllKey         = selectors.Key("all")
 	defaultFailureRate    = 3
 	defaultFailureTimeout = time.Second
+
+	// defaultKeyRepairs is the number of keys a second that are read to be
+	// repaired, once a read of the keys or the size finds them diverging.
+	defaultKeyRepairs = 10
 )
 
 type real struct {
+	*instruments
 	nodes          hashring.Snapshot
 	repairStrategy *repairStrategy
-	circuit        *breaker.CircuitBreaker
+	circuit        *circuit.Circuit
+	state          metrics.Gauge
+	divergence     metrics.CounterVec
+	outcomes       metrics.CounterVec
+	keyRepairs     *ratelimit.Limiter
+	ctx            context.Context
+}
+
+// instruments trace and measure the requests to the replicas, they're shared
+// by the reads and the writes of the farm and its repairs. Each of them is
+// optional, the host of a replica is only asked for when there's something
+// to label with it.
+type instruments struct {
+	tracer   *trace.Tracer
+	duration metrics.HistogramVec
+	errors   metrics.CounterVec
+	repairs  metrics.CounterVec
+}
+
+// Option defines a option for configuring the real Farm
+type Option func(*real)
+
+// WithDivergence counts the reads where the replicas returned different
+// results, labelled by the method that was read.
+func WithDivergence(divergence metrics.CounterVec) Option {
+	return func(r *real) {
+		r.divergence = divergence
+	}
+}
+
+// WithTracer traces the reads, the writes and the repairs of the farm, along
+// with the request to each node.
+func WithTracer(tracer *trace.Tracer) Option {
+	return func(r *real) {
+		r.tracer = tracer
+	}
+}
+
+// WithQuorumOutcomes counts the outcome of the quorum of every read and write,
+// labelled by the method and the outcome. The quorum is met when every replica
+// answers, partial when enough of them answer and failed otherwise.
+func WithQuorumOutcomes(outcomes metrics.CounterVec) Option {
+	return func(r *real) {
+		r.outcomes = outcomes
+	}
+}
+
+// WithReplicaMetrics observes the duration of the requests to each replica and
+// counts the requests that fail, both labelled by the host and the method.
+func WithReplicaMetrics(duration metrics.HistogramVec, errors metrics.CounterVec) Option {
+	return func(r *real) {
+		r.duration = duration
+		r.errors = errors
+	}
+}
+
+// WithRepairs counts the members that are repaired, skipped or fail to be
+// repaired, labelled by the outcome.
+func WithRepairs(repairs metrics.CounterVec) Option {
+	return func(r *real) {
+		r.repairs = repairs
+	}
+}
+
+// WithKeyRepairs limits the keys that are read to be repaired, once a read of
+// the keys or the size finds them diverging. Only the members of a key say
+// which of them are missing, so each key costs a read of its members from
+// every replica.
+func WithKeyRepairs(limit ratelimit.Limit) Option {
+	return func(r *real) {
+		r.keyRepairs = ratelimit.NewLimiter(limit)
+	}
+}
+
+// WithCircuitState reports the state of the circuit of the farm, 1 whilst it's
+// open and refusing the writes, otherwise 0.
+func WithCircuitState(state metrics.Gauge) Option {
+	return func(r *real) {
+		r.state = state
+	}
 }
 
 // NewReal creates a farm that talks to various nodes
-func NewReal(nodes hashring.Snapshot) Farm {
-	return &real{
-		nodes:          nodes,
-		repairStrategy: &repairStrategy{nodes},
-		circuit:        breaker.New(defaultFailureRate, defaultFailureTimeout),
+func NewReal(nodes hashring.Snapshot, opts ...Option) Farm {
+	r := &real{
+		instruments: &instruments{},
+		nodes:       nodes,
+		divergence: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "divergence",
+		}, []string{"method"}),
+		outcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "outcomes",
+		}, []string{"method", "outcome"}),
+		keyRepairs: ratelimit.NewLimiter(ratelimit.Limit{
+			Rate:  defaultKeyRepairs,
+			Burst: defaultKeyRepairs,
+		}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.circuit = circuit.New(defaultFailureRate, defaultFailureTimeout, r.state)
+	r.repairStrategy = &repairStrategy{
+		instruments: r.instruments,
+		nodes:       nodes,
+	}
+	return r
+}
+
+// WithContext returns a copy of the farm whose spans are children of the span
+// of the context, the requests to the nodes send the trace along with them.
+// Once the context is done, the reads stop waiting on the nodes. The writes and
+// the repairs carry on, as the nodes might have already applied them. The copy
+// shares everything else with the farm.
+func (r *real) WithContext(ctx context.Context) Farm {
+	res := *r
+	res.ctx = ctx
+	return &res
+}
+
+func (r *real) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
 	}
+	return r.ctx
+}
+
+// detached returns the context without its deadline or cancellation, for the
+// work that has to carry on once the caller has given up.
+func (r *real) detached() context.Context {
+	return context.WithoutCancel(r.context())
+}
+
+// start starts the span of a read or a write of the farm.
+func (r *real) start(name string, quorum selectors.Quorum, attrs ...trace.Attribute) (context.Context, *trace.Span) {
+	return r.tracer.Start(r.context(), trace.KindInternal, name, append(attrs, trace.String("quorum", quorum.String()))...)
+}
+
+// CircuitOpen returns if the circuit of the farm is open, refusing the
+// writes.
+func (r *real) CircuitOpen() bool {
+	return r.circuit.Open()
 }
 
 func (r *real) Insert(key selectors.Key,
 	members []selectors.FieldValueScore,
 	quorum selectors.Quorum,
 ) (selectors.ChangeSet, error) {
-	var changeSet selectors.ChangeSet
+	var (
+		changeSet selectors.ChangeSet
+		quotaErr  error
+	)
 	err := r.circuit.Run(func() error {
 		var err error
-		changeSet, err = r.write(key, quorum, func(n nodes.Node) <-chan selectors.Element {
+		changeSet, err = r.write("insert", key, quorum, func(n nodes.Node) <-chan selectors.Element {
 			return n.Insert(key, members)
 		})
+		// A keyspace going over its quota isn't a failure of the cluster, so
+		// it's kept out of the circuit.
+		if selectors.QuotaError(err) {
+			quotaErr = err
+			return nil
+		}
 		return err
 	})
+	if quotaErr != nil {
+		return changeSet, quotaErr
+	}
 	if PartialError(err) {
 		go r.Repair(mergeKeyFieldMembers(key, changeSet.Failure, members))
 	}
@@ -58,7 +212,7 @@ func (r *real) Delete(key selectors.Key,
 	var changeSet selectors.ChangeSet
 	err := r.circuit.Run(func() error {
 		var err error
-		changeSet, err = r.write(key, quorum, func(n nodes.Node) <-chan selectors.Element {
+		changeSet, err = r.write("delete", key, quorum, func(n nodes.Node) <-chan selectors.Element {
 			return n.Delete(key, members)
 		})
 		return err
@@ -69,6 +223,41 @@ func (r *real) Delete(key selectors.Key,
 	return changeSet, err
 }
 
+func (r *real) Swap(key selectors.Key,
+	member selectors.FieldValueScore,
+	token int64,
+	quorum selectors.Quorum,
+) (selectors.ChangeSet, error) {
+	var (
+		changeSet selectors.ChangeSet
+		quotaErr  error
+	)
+	err := r.circuit.Run(func() error {
+		var err error
+		changeSet, err = r.write("swap", key, quorum, func(n nodes.Node) <-chan selectors.Element {
+			swapper, ok := n.(nodes.Swapper)
+			if !ok {
+				ch := make(chan selectors.Element, 1)
+				ch <- selectors.NewErrorElement(n.Hash(), errors.Errorf("node %s doesn't support swapping", n.Host()))
+				close(ch)
+				return ch
+			}
+			return swapper.Swap(key, member, token)
+		})
+		// The same as an insert, going over the quota isn't a failure of the
+		// cluster.
+		if selectors.QuotaError(err) {
+			quotaErr = err
+			return nil
+		}
+		return err
+	})
+	if quotaErr != nil {
+		return changeSet, quotaErr
+	}
+	return changeSet, err
+}
+
 func (r *real) Select(key selectors.Key,
 	field selectors.Field,
 	quorum selectors.Quorum,
@@ -78,38 +267,89 @@ func (r *real) Select(key selectors.Key,
 	})
 }
 
-func (r *real) Keys() ([]selectors.Key, error) {
-	return r.readKeys(defaultAllKey, func(n nodes.Node) <-chan selectors.Element {
+func (r *real) Keys(quorum selectors.Quorum) ([]selectors.Key, error) {
+	return r.readKeys(defaultAllKey, quorum, func(n nodes.Node) <-chan selectors.Element {
 		return n.Keys()
 	})
 }
 
-func (r *real) Size(key selectors.Key) (int64, error) {
-	return r.readSize(key, func(n nodes.Node) <-chan selectors.Element {
+func (r *real) Size(key selectors.Key, quorum selectors.Quorum) (int64, error) {
+	return r.readSize(key, quorum, func(n nodes.Node) <-chan selectors.Element {
 		return n.Size(key)
 	})
 }
 
-func (r *real) Members(key selectors.Key) ([]selectors.Field, error) {
-	return r.readMembers(key, func(n nodes.Node) <-chan selectors.Element {
+func (r *real) Members(key selectors.Key, quorum selectors.Quorum) ([]selectors.Field, error) {
+	fields, missing, err := r.readMembers(key, quorum, func(n nodes.Node) <-chan selectors.Element {
 		return n.Members(key)
 	})
+	if len(missing) > 0 && r.context().Err() == nil {
+		go r.repairStrategy.RepairMembers(r.detached(), key, missing)
+	}
+	return fields, err
 }
 
-func (r *real) Score(key selectors.Key, field selectors.Field) (selectors.Presence, error) {
-	return r.readScore(key, func(n nodes.Node) <-chan selectors.Element {
+func (r *real) Score(key selectors.Key, field selectors.Field, quorum selectors.Quorum) (selectors.Presence, error) {
+	return r.readScore(key, quorum, func(n nodes.Node) <-chan selectors.Element {
 		return n.Score(key, field)
 	})
 }
 
+func (r *real) Scan(cursor selectors.Cursor, opts selectors.ScanOptions, quorum selectors.Quorum) (selectors.KeysPage, error) {
+	// The keys are spread across the whole of the ring, so every node has to
+	// be scanned, the quorum is how many of them have to answer.
+	values, next, err := r.readScan("scan", r.nodes.Read(defaultAllKey, selectors.Strong), quorum, opts, func(n nodes.Node) <-chan selectors.Element {
+		return n.Scan(cursor, opts)
+	}, keysPage)
+	if err != nil {
+		return selectors.KeysPage{}, err
+	}
+
+	keys := make([]selectors.Key, len(values))
+	for k, v := range values {
+		keys[k] = selectors.Key(v)
+	}
+	return selectors.KeysPage{Keys: keys, Cursor: next}, nil
+}
+
+func (r *real) ScanMembers(key selectors.Key,
+	cursor selectors.Cursor,
+	opts selectors.ScanOptions,
+	quorum selectors.Quorum,
+) (selectors.FieldsPage, error) {
+	values, next, err := r.readScan("scan_members", r.nodes.Read(key, quorum), quorum, opts, func(n nodes.Node) <-chan selectors.Element {
+		return n.ScanMembers(key, cursor, opts)
+	}, fieldsPage)
+	if err != nil {
+		return selectors.FieldsPage{}, err
+	}
+
+	fields := make([]selectors.Field, len(values))
+	for k, v := range values {
+		fields[k] = selectors.Field(v)
+	}
+	return selectors.FieldsPage{Fields: fields, Cursor: next}, nil
+}
+
+func (r *real) Watch(key selectors.Key, resume selectors.Resume, quorum selectors.Quorum) (selectors.Subscription, error) {
+	return watch(r.nodes.Read(key, quorum), key, resume)
+}
+
 func (r *real) Repair(members []selectors.KeyFieldValue) error {
-	return r.repairStrategy.Repair(members)
+	return r.repairStrategy.Repair(r.detached(), members)
 }
 
-func (r *real) write(key selectors.Key,
+func (r *real) write(method string,
+	key selectors.Key,
 	quorum selectors.Quorum,
 	fn func(nodes.Node) <-chan selectors.Element,
-) (selectors.ChangeSet, error) {
+) (_ selectors.ChangeSet, err error) {
+	ctx, span := r.start("farm.write", quorum, trace.String("key", key.String()))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	var (
 		retrieved = 0
 		returned  = 0
@@ -126,7 +366,9 @@ func (r *real) write(key selectors.Key,
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	// A write isn't abandoned once it's been sent, otherwise the nodes that
+	// applied it would never be added to the snapshot.
+	if err := r.scatterRequests(context.WithoutCancel(ctx), method, nodes, fn, wg, elements); err != nil {
 		return selectors.ChangeSet{}, err
 	}
 
@@ -145,6 +387,8 @@ func (r *real) write(key selectors.Key,
 		hashes = append(hashes, element.Hash())
 	}
 
+	r.observe(method, quorum, len(nodes), errs)
+
 	// Finish and close the snapshot back to the node set
 	go finish(hashes)
 
@@ -171,7 +415,13 @@ func (r *real) write(key selectors.Key,
 func (r *real) read(key selectors.Key,
 	quorum selectors.Quorum,
 	fn func(nodes.Node) <-chan selectors.Element,
-) (selectors.FieldValueScore, error) {
+) (_ selectors.FieldValueScore, err error) {
+	ctx, span := r.start("farm.read", quorum, trace.String("key", key.String()))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	var (
 		retrieved = 0
 		returned  = 0
@@ -187,7 +437,7 @@ func (r *real) read(key selectors.Key,
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	if err := r.scatterRequests(ctx, "select", nodes, fn, wg, elements); err != nil {
 		return selectors.FieldValueScore{}, err
 	}
 
@@ -205,9 +455,15 @@ func (r *real) read(key selectors.Key,
 			result,
 		}))
 	}
+	r.observe("select", quorum, len(nodes), errs)
+
 	union, difference := UnionDifference(results, quorum)
 
-	go r.Repair(FieldValueScoresToKeyField(key, difference))
+	// A read that was given up on doesn't have all of the results, so it
+	// can't tell what needs repairing.
+	if ctx.Err() == nil {
+		go r.Repair(FieldValueScoresToKeyField(key, difference))
+	}
 
 	if len(errs) > 0 {
 		return selectors.FieldValueScore{}, mapErrors(errs)
@@ -217,11 +473,22 @@ func (r *real) read(key selectors.Key,
 	return selectors.FieldValueScore{}, errors.New("invalid results")
 }
 
-func (r *real) readKeys(key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) ([]selectors.Key, error) {
+func (r *real) readKeys(key selectors.Key,
+	quorum selectors.Quorum,
+	fn func(nodes.Node) <-chan selectors.Element,
+) (_ []selectors.Key, err error) {
+	ctx, span := r.start("farm.read_keys", quorum)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	var (
 		retrieved = 0
 		returned  = 0
 
+		// The keys are spread across the whole of the ring, so every node has
+		// to be read, the quorum is used to merge the keys together instead.
 		nodes    = r.nodes.Read(key, selectors.Strong)
 		elements = make(chan selectors.Element, len(nodes))
 
@@ -233,7 +500,7 @@ func (r *real) readKeys(key selectors.Key, fn func(nodes.Node) <-chan selectors.
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	if err := r.scatterRequests(ctx, "keys", nodes, fn, wg, elements); err != nil {
 		return nil, err
 	}
 
@@ -248,25 +515,41 @@ func (r *real) readKeys(key selectors.Key, fn func(nodes.Node) <-chan selectors.
 		returned++
 		keys := selectors.KeysFromElement(element)
 		records.Add(keys)
-
-		// Bail out, if there is an error
-		if err := records.Err(); err != nil {
-			return nil, errPartial{err}
-		}
 	}
 
-	if len(errs) > 0 {
+	r.observe("keys", quorum, len(nodes), errs)
+
+	if len(errs) > 0 && !consensus(quorum, len(nodes), returned) {
 		return nil, mapErrors(errs)
 	}
-	return records.Keys(), nil
+
+	// The keys don't say which of their members are missing, so each key
+	// that's missing from some of the nodes has its members read to repair
+	// them.
+	if missing := records.Missing(returned); len(missing) > 0 {
+		r.divergence.WithLabelValues("keys").Inc()
+		if ctx.Err() == nil {
+			go r.repairKeys(missing)
+		}
+	}
+	return records.Keys(quorum, len(nodes)), nil
 }
 
-func (r *real) readSize(key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) (int64, error) {
+func (r *real) readSize(key selectors.Key,
+	quorum selectors.Quorum,
+	fn func(nodes.Node) <-chan selectors.Element,
+) (_ int64, err error) {
+	ctx, span := r.start("farm.read_size", quorum, trace.String("key", key.String()))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	var (
 		retrieved = 0
 		returned  = 0
 
-		nodes    = r.nodes.Read(key, selectors.Strong)
+		nodes    = r.nodes.Read(key, quorum)
 		elements = make(chan selectors.Element, len(nodes))
 
 		errs    []error
@@ -277,7 +560,7 @@ func (r *real) readSize(key selectors.Key, fn func(nodes.Node) <-chan selectors.
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	if err := r.scatterRequests(ctx, "size", nodes, fn, wg, elements); err != nil {
 		return -1, err
 	}
 
@@ -292,25 +575,64 @@ func (r *real) readSize(key selectors.Key, fn func(nodes.Node) <-chan selectors.
 		returned++
 		size := selectors.Int64FromElement(element)
 		records.Add(size)
+	}
 
-		// Bail out, if there is an error
-		if err := records.Err(); err != nil {
-			return -1, errPartial{err}
+	r.observe("size", quorum, len(nodes), errs)
+
+	if len(errs) > 0 && !consensus(quorum, len(nodes), returned) {
+		return -1, mapErrors(errs)
+	}
+
+	// The size doesn't say which members are missing, so the members are read
+	// to repair them.
+	if records.Divergent() {
+		r.divergence.WithLabelValues("size").Inc()
+		if ctx.Err() == nil {
+			go r.repairKeys([]selectors.Key{key})
 		}
 	}
+	return records.Int64(quorum, len(nodes)), nil
+}
 
-	if len(errs) > 0 {
-		return -1, mapErrors(errs)
+// repairKeys reads the members of each key from every replica, repairing the
+// members that are missing from some of them. The keys over the limit of the
+// repairs are left for a later read to find again.
+func (r *real) repairKeys(keys []selectors.Key) {
+	detached := r.WithContext(r.detached()).(*real)
+
+	for _, key := range keys {
+		if ok, _ := r.keyRepairs.Take("repair"); !ok {
+			return
+		}
+
+		_, missing, err := detached.readMembers(key, selectors.Strong, func(n nodes.Node) <-chan selectors.Element {
+			return n.Members(key)
+		})
+		if err != nil || len(missing) == 0 {
+			continue
+		}
+		detached.repairStrategy.RepairMembers(detached.context(), key, missing)
 	}
-	return records.Int64(), nil
 }
 
-func (r *real) readMembers(key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) ([]selectors.Field, error) {
+// readMembers returns the members that satisfy the quorum, along with the
+// members that are missing from some of the replicas, so that they can be
+// repaired.
+func (r *real) readMembers(key selectors.Key,
+	quorum selectors.Quorum,
+	fn func(nodes.Node) <-chan selectors.Element,
+) (_, _ []selectors.Field, err error) {
+	ctx, span := r.start("farm.read_members", quorum, trace.String("key", key.String()))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	var (
 		retrieved = 0
 		returned  = 0
 
-		nodes    = r.nodes.Read(key, selectors.Strong)
+		nodes    = r.nodes.Read(key, quorum)
 		elements = make(chan selectors.Element, len(nodes))
 
 		errs    []error
@@ -321,8 +643,8 @@ func (r *real) readMembers(key selectors.Key, fn func(nodes.Node) <-chan selecto
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
-		return nil, err
+	if err := r.scatterRequests(ctx, "members", nodes, fn, wg, elements); err != nil {
+		return nil, nil, err
 	}
 
 	for element := range elements {
@@ -334,27 +656,38 @@ func (r *real) readMembers(key selectors.Key, fn func(nodes.Node) <-chan selecto
 		}
 
 		returned++
-		keys := selectors.FieldsFromElement(element)
-		records.Add(keys)
+		fields := selectors.FieldsFromElement(element)
+		records.Add(fields)
+	}
 
-		// Bail out, if there is an error
-		if err := records.Err(); err != nil {
-			return nil, errPartial{err}
-		}
+	r.observe("members", quorum, len(nodes), errs)
+
+	if len(errs) > 0 && !consensus(quorum, len(nodes), returned) {
+		return nil, nil, mapErrors(errs)
 	}
 
-	if len(errs) > 0 {
-		return nil, mapErrors(errs)
+	missing := records.Missing(returned)
+	if len(missing) > 0 {
+		r.divergence.WithLabelValues("members").Inc()
 	}
-	return records.Fields(), nil
+	return records.Fields(quorum, len(nodes)), missing, nil
 }
 
-func (r *real) readScore(key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) (selectors.Presence, error) {
+func (r *real) readScore(key selectors.Key,
+	quorum selectors.Quorum,
+	fn func(nodes.Node) <-chan selectors.Element,
+) (_ selectors.Presence, err error) {
+	ctx, span := r.start("farm.read_score", quorum, trace.String("key", key.String()))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	var (
 		retrieved = 0
 		returned  = 0
 
-		nodes    = r.nodes.Read(key, selectors.Strong)
+		nodes    = r.nodes.Read(key, quorum)
 		elements = make(chan selectors.Element, len(nodes))
 
 		errs    []error
@@ -365,7 +698,7 @@ func (r *real) readScore(key selectors.Key, fn func(nodes.Node) <-chan selectors
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	if err := r.scatterRequests(ctx, "score", nodes, fn, wg, elements); err != nil {
 		return selectors.Presence{}, err
 	}
 
@@ -383,17 +716,48 @@ func (r *real) readScore(key selectors.Key, fn func(nodes.Node) <-chan selectors
 
 		// Bail out, if there is an error
 		if err := records.Err(); err != nil {
+			r.observe("score", quorum, len(nodes), errs)
 			return selectors.Presence{}, errPartial{err}
 		}
 	}
 
+	r.observe("score", quorum, len(nodes), errs)
+
 	if len(errs) > 0 {
 		return selectors.Presence{}, mapErrors(errs)
 	}
 	return records.Presence(), nil
 }
 
-func scatterRequests(n []nodes.Node,
+// observe counts the outcome of the quorum of a read or a write, from the
+// replicas that failed to answer. A replica that answers that the member isn't
+// found has still answered.
+func (r *real) observe(method string, quorum selectors.Quorum, total int, errs []error) {
+	var failed int
+	for _, err := range errs {
+		if replicaError(err) {
+			failed++
+		}
+	}
+
+	outcome := "failed"
+	if answered := total - failed; total > 0 && answered == total {
+		outcome = "met"
+	} else if total > 0 && consensus(quorum, total, answered) {
+		outcome = "partial"
+	}
+	r.outcomes.WithLabelValues(method, outcome).Inc()
+}
+
+// scatterRequests sends the request to every node, each request has its own
+// span, which sends the trace along with the request if the node can. The
+// duration of each request is observed once every element has been received.
+// Once the context is done, the nodes that haven't been asked aren't and the
+// nodes that haven't answered aren't waited on, each of them gets the error of
+// the context instead.
+func (i *instruments) scatterRequests(ctx context.Context,
+	method string,
+	n []nodes.Node,
 	fn func(nodes.Node) <-chan selectors.Element,
 	wg *sync.WaitGroup,
 	dst chan selectors.Element,
@@ -401,12 +765,69 @@ func scatterRequests(n []nodes.Node,
 	return tactic(n, func(k int, n nodes.Node) {
 		defer wg.Done()
 
-		for e := range fn(n) {
-			dst <- e
+		if err := ctx.Err(); err != nil {
+			dst <- selectors.NewErrorElement(n.Hash(), err)
+			return
+		}
+
+		var host string
+		if i.tracer != nil || i.duration != nil || i.errors != nil {
+			host = n.Host()
+		}
+
+		var (
+			span    *trace.Span
+			nodeCtx = ctx
+		)
+		if i.tracer != nil {
+			nodeCtx, span = i.tracer.Start(ctx, trace.KindClient, "farm.node",
+				trace.String("node.host", host),
+				trace.String("method", method),
+			)
+		}
+		defer span.End()
+
+		var (
+			begin    = time.Now()
+			elements = fn(nodes.WithContext(nodeCtx, n))
+		)
+		for {
+			select {
+			case e, ok := <-elements:
+				if !ok {
+					if i.duration != nil {
+						i.duration.WithLabelValues(host, method).Observe(time.Since(begin).Seconds())
+					}
+					return
+				}
+				err := selectors.ErrorFromElement(e)
+				span.SetError(err)
+				if i.errors != nil && replicaError(err) {
+					i.errors.WithLabelValues(host, method).Inc()
+				}
+				dst <- e
+
+			case <-ctx.Done():
+				// Whatever the node still sends is dropped, so that it isn't
+				// left blocked on sending it.
+				go func() {
+					for range elements {
+					}
+				}()
+				span.SetError(ctx.Err())
+				dst <- selectors.NewErrorElement(n.Hash(), ctx.Err())
+				return
+			}
 		}
 	})
 }
 
+// replicaError returns if the error is a failure of the replica, rather than
+// an answer from it, such as the member not being found.
+func replicaError(err error) bool {
+	return err != nil && !selectors.NotFoundError(err) && !selectors.QuotaError(err)
+}
+
 func tactic(n []nodes.Node, fn func(k int, n nodes.Node)) error {
 	for k, v := range n {
 		go func(k int, v nodes.Node) {
@@ -417,13 +838,17 @@ func tactic(n []nodes.Node, fn func(k int, n nodes.Node)) error {
 }
 
 func mapErrors(errs []error) error {
-	notFound := true
+	notFound, quota := true, true
 	for _, v := range errs {
 		notFound = notFound && selectors.NotFoundError(v)
+		quota = quota && selectors.QuotaError(v)
 	}
 	if notFound {
 		return selectors.NewNotFoundError(errors.New("not found"))
 	}
+	if quota {
+		return selectors.NewQuotaError(errors.Wrap(joinErrors(errs), "quota exceeded"))
+	}
 	return errors.Wrapf(joinErrors(errs), "partial error")
 }
 
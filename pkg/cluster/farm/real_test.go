@@ -1,16 +1,26 @@
 package farm
 
 import (
+	"context"
 	"reflect"
+	"sync"
 	"testing"
 	"testing/quick"
+	"time"
 
 	hashringMocks "github.com/SimonRichardson/coherence/pkg/cluster/hashring/mocks"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes/mocks"
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/SimonRichardson/coherence/pkg/ratelimit"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/SimonRichardson/coherence/pkg/trace"
+	"github.com/go-kit/kit/log"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
 )
 
 func TestRealInsert(t *testing.T) {
@@ -175,6 +185,80 @@ func TestRealInsert(t *testing.T) {
 	})
 }
 
+func TestRealSwap(t *testing.T) {
+	t.Parallel()
+
+	replicas := func(t *testing.T, n int) []nodes.Node {
+		var res []nodes.Node
+		for i := 0; i < n; i++ {
+			s, err := store.New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Insert("a", []selectors.FieldValueScore{{Field: "x", Score: 1}}); err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, nodes.NewVirtual(uint32(i), "", s))
+		}
+		return res
+	}
+
+	t.Run("concurrent swaps of a token", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Write(selectors.Key("a"), selectors.Strong).Return(replicas(t, 3), func([]uint32) error { return nil }).AnyTimes()
+
+		var (
+			farm    = NewReal(nodeSet)
+			wg      sync.WaitGroup
+			mutex   sync.Mutex
+			swapped []int64
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(score int64) {
+				defer wg.Done()
+				changeSet, err := farm.(Swapper).Swap("a", selectors.FieldValueScore{Field: "x", Score: score}, 1, selectors.Strong)
+				if err != nil {
+					return
+				}
+				if len(changeSet.Success) > 0 {
+					mutex.Lock()
+					swapped = append(swapped, score)
+					mutex.Unlock()
+				}
+			}(int64(2 + i))
+		}
+		wg.Wait()
+
+		// Every replica swaps the token once, so at most one swap is stored.
+		if len(swapped) > 1 {
+			t.Errorf("expected at most one swap, actual: %v", swapped)
+		}
+	})
+
+	t.Run("swap without swapping nodes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		node := mocks.NewMockNode(ctrl)
+		node.EXPECT().Hash().Return(uint32(0)).AnyTimes()
+		node.EXPECT().Host().Return("a").AnyTimes()
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Write(selectors.Key("a"), selectors.Strong).Return([]nodes.Node{
+			node,
+		}, func([]uint32) error { return nil })
+
+		farm := NewReal(nodeSet)
+		if _, err := farm.(Swapper).Swap("a", selectors.FieldValueScore{Field: "x", Score: 2}, 1, selectors.Strong); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
 func TestRealDelete(t *testing.T) {
 	t.Parallel()
 
@@ -408,6 +492,88 @@ func TestRealSelect(t *testing.T) {
 	})
 }
 
+func TestRealSelectContext(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key   = selectors.Key("key")
+		field = selectors.Field("field")
+	)
+
+	t.Run("done before the read", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// The nodes aren't asked at all, once the caller has given up.
+		node := mocks.NewMockNode(ctrl)
+		node.EXPECT().Hash().Return(uint32(1)).AnyTimes()
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(key, selectors.Strong).Return([]nodes.Node{
+			node,
+		})
+
+		farm := NewReal(nodeSet)
+		_, err := WithContext(ctx, farm).Select(key, field, selectors.Strong)
+		if err == nil {
+			t.Errorf("expected: error, actual: %v", err)
+		}
+	})
+
+	t.Run("timed out during the read", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+
+		// The slow node answers long after the read has timed out, the fast
+		// node answers straight away. Neither gets repaired.
+		var (
+			release = make(chan struct{})
+			slow    = make(chan selectors.Element)
+			fast    = make(chan selectors.Element, 1)
+		)
+		go func() {
+			defer close(slow)
+			<-release
+			slow <- selectors.NewFieldValueScoreElement(1, selectors.FieldValueScore{Field: field, Score: 1})
+		}()
+		fast <- selectors.NewFieldValueScoreElement(2, selectors.FieldValueScore{Field: field, Score: 2})
+		close(fast)
+
+		slowNode := mocks.NewMockNode(ctrl)
+		slowNode.EXPECT().Hash().Return(uint32(1)).AnyTimes()
+		slowNode.EXPECT().Select(key, field).Return(slow)
+
+		fastNode := mocks.NewMockNode(ctrl)
+		fastNode.EXPECT().Select(key, field).Return(fast)
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(key, selectors.Strong).Return([]nodes.Node{
+			slowNode,
+			fastNode,
+		})
+
+		begin := time.Now()
+
+		farm := NewReal(nodeSet)
+		_, err := WithContext(ctx, farm).Select(key, field, selectors.Strong)
+		if err == nil {
+			t.Errorf("expected: error, actual: %v", err)
+		}
+		if elapsed := time.Since(begin); elapsed > time.Second {
+			t.Errorf("expected: the read to stop at the timeout, actual: %v", elapsed)
+		}
+
+		// The slow node can still send its answer, without anyone reading it.
+		close(release)
+	})
+}
+
 func TestRealKeys(t *testing.T) {
 	t.Parallel()
 
@@ -434,7 +600,7 @@ func TestRealKeys(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			_, err := farm.Keys()
+			_, err := farm.Keys(selectors.Strong)
 			return err != nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -465,12 +631,12 @@ func TestRealKeys(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			value, err := farm.Keys()
+			value, err := farm.Keys(selectors.Strong)
 			if err != nil {
 				t.Error(err)
 			}
 
-			if expected, actual := keys, value; !reflect.DeepEqual(expected, actual) {
+			if expected, actual := uniqueKeys(keys), value; !keysEqual(expected, actual) {
 				t.Errorf("expected: %v, actual: %v", expected, actual)
 			}
 
@@ -482,6 +648,199 @@ func TestRealKeys(t *testing.T) {
 	})
 }
 
+func TestRealKeysDivergence(t *testing.T) {
+	t.Parallel()
+
+	keys := func(keys ...selectors.Key) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewKeysElement(defaultAllKey.Hash(), keys)
+		close(ch)
+		return ch
+	}
+
+	for _, test := range []struct {
+		quorum selectors.Quorum
+		want   []selectors.Key
+	}{
+		{selectors.One, []selectors.Key{"a", "b", "c"}},
+		{selectors.Consensus, []selectors.Key{"a", "b"}},
+		{selectors.Strong, []selectors.Key{"a"}},
+	} {
+		t.Run(test.quorum.String(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var (
+				first  = mocks.NewMockNode(ctrl)
+				second = mocks.NewMockNode(ctrl)
+				third  = mocks.NewMockNode(ctrl)
+			)
+			first.EXPECT().Keys().Return(keys("a", "b"))
+			second.EXPECT().Keys().Return(keys("a", "b", "c"))
+			third.EXPECT().Keys().Return(keys("a"))
+
+			nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+			nodeSet.EXPECT().Read(defaultAllKey, selectors.Strong).Return([]nodes.Node{
+				first, second, third,
+			})
+
+			// The members of the keys that are missing from some of the nodes
+			// are read to be repaired.
+			wg := &sync.WaitGroup{}
+			wg.Add(2)
+			for _, key := range []selectors.Key{"b", "c"} {
+				nodeSet.EXPECT().Read(key, selectors.Strong).DoAndReturn(func(selectors.Key, selectors.Quorum) []nodes.Node {
+					defer wg.Done()
+					return nil
+				})
+			}
+
+			divergence := metricMocks.NewMockCounterVec(ctrl)
+			divergence.EXPECT().WithLabelValues("keys").Return(prometheus.NewCounter(prometheus.CounterOpts{
+				Name: "divergence",
+			}))
+
+			farm := NewReal(nodeSet, WithDivergence(divergence))
+			value, err := farm.Keys(test.quorum)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if expected, actual := test.want, value; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+
+			wg.Wait()
+		})
+	}
+
+	t.Run("partial errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewErrorElement(defaultAllKey.Hash(), errors.New("bad"))
+		close(ch)
+
+		var (
+			first  = mocks.NewMockNode(ctrl)
+			second = mocks.NewMockNode(ctrl)
+			third  = mocks.NewMockNode(ctrl)
+		)
+		first.EXPECT().Keys().Return(keys("a", "b"))
+		second.EXPECT().Keys().Return(keys("a", "b"))
+		third.EXPECT().Keys().Return(ch)
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(defaultAllKey, selectors.Strong).Return([]nodes.Node{
+			first, second, third,
+		})
+
+		farm := NewReal(nodeSet)
+		value, err := farm.Keys(selectors.Consensus)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []selectors.Key{"a", "b"}, value; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestRealKeysRepair(t *testing.T) {
+	t.Parallel()
+
+	replicas := func(t *testing.T) ([]nodes.Node, []store.Store) {
+		var (
+			res    []nodes.Node
+			stores []store.Store
+		)
+		for i := 0; i < 3; i++ {
+			s, err := store.New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The last replica is missing the member.
+			if i < 2 {
+				if _, err := s.Insert("a", []selectors.FieldValueScore{{Field: "x", Value: []byte("v"), Score: 1}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res = append(res, nodes.NewVirtual(uint32(i), "", s))
+			stores = append(stores, s)
+		}
+		return res, stores
+	}
+
+	repaired := func(s store.Store) bool {
+		for i := 0; i < 100; i++ {
+			if member, err := s.Select("a", "x"); err == nil && string(member.Value) == "v" {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		replicas, stores := replicas(t)
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(gomock.Any(), selectors.Strong).Return(replicas).AnyTimes()
+		nodeSet.EXPECT().Write(selectors.Key("a"), selectors.Strong).Return(replicas, func([]uint32) error { return nil }).AnyTimes()
+
+		farm := NewReal(nodeSet)
+		if _, err := farm.Keys(selectors.One); err != nil {
+			t.Fatal(err)
+		}
+
+		if !repaired(stores[2]) {
+			t.Error("expected the missing member to be repaired")
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		replicas, stores := replicas(t)
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(selectors.Key("a"), selectors.Strong).Return(replicas).AnyTimes()
+		nodeSet.EXPECT().Write(selectors.Key("a"), selectors.Strong).Return(replicas, func([]uint32) error { return nil }).AnyTimes()
+
+		farm := NewReal(nodeSet)
+		if _, err := farm.Size("a", selectors.Strong); err != nil {
+			t.Fatal(err)
+		}
+
+		if !repaired(stores[2]) {
+			t.Error("expected the missing member to be repaired")
+		}
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// Only the first key is read, the rest are over the limit.
+		done := make(chan struct{})
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(gomock.Any(), selectors.Strong).DoAndReturn(func(selectors.Key, selectors.Quorum) []nodes.Node {
+			defer close(done)
+			return nil
+		})
+
+		farm := NewReal(nodeSet, WithKeyRepairs(ratelimit.Limit{Rate: 0.001, Burst: 1})).(*real)
+		farm.repairKeys([]selectors.Key{"a", "b", "c"})
+		<-done
+	})
+}
+
 func TestRealSize(t *testing.T) {
 	t.Parallel()
 
@@ -508,7 +867,7 @@ func TestRealSize(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			_, err := farm.Size(key)
+			_, err := farm.Size(key, selectors.Strong)
 			return err != nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -539,7 +898,7 @@ func TestRealSize(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			value, err := farm.Size(key)
+			value, err := farm.Size(key, selectors.Strong)
 			if err != nil {
 				t.Error(err)
 			}
@@ -556,6 +915,73 @@ func TestRealSize(t *testing.T) {
 	})
 }
 
+func TestRealSizeDivergence(t *testing.T) {
+	t.Parallel()
+
+	key := selectors.Key("key")
+
+	size := func(size int64) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewInt64Element(key.Hash(), size)
+		close(ch)
+		return ch
+	}
+
+	for _, test := range []struct {
+		quorum selectors.Quorum
+		want   int64
+	}{
+		{selectors.One, 3},
+		{selectors.Consensus, 2},
+		{selectors.Strong, 1},
+	} {
+		t.Run(test.quorum.String(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var (
+				first  = mocks.NewMockNode(ctrl)
+				second = mocks.NewMockNode(ctrl)
+				third  = mocks.NewMockNode(ctrl)
+			)
+			first.EXPECT().Size(key).Return(size(1))
+			second.EXPECT().Size(key).Return(size(3))
+			third.EXPECT().Size(key).Return(size(2))
+
+			nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+			nodeSet.EXPECT().Read(key, test.quorum).Return([]nodes.Node{
+				first, second, third,
+			})
+
+			// The members of the key are read from every replica to be
+			// repaired.
+			wg := &sync.WaitGroup{}
+			wg.Add(1)
+			nodeSet.EXPECT().Read(key, selectors.Strong).DoAndReturn(func(selectors.Key, selectors.Quorum) []nodes.Node {
+				defer wg.Done()
+				return nil
+			})
+
+			divergence := metricMocks.NewMockCounterVec(ctrl)
+			divergence.EXPECT().WithLabelValues("size").Return(prometheus.NewCounter(prometheus.CounterOpts{
+				Name: "divergence",
+			}))
+
+			farm := NewReal(nodeSet, WithDivergence(divergence))
+			value, err := farm.Size(key, test.quorum)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if expected, actual := test.want, value; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
 func TestRealMembers(t *testing.T) {
 	t.Parallel()
 
@@ -582,7 +1008,7 @@ func TestRealMembers(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			_, err := farm.Members(key)
+			_, err := farm.Members(key, selectors.Strong)
 			return err != nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -613,12 +1039,12 @@ func TestRealMembers(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			value, err := farm.Members(key)
+			value, err := farm.Members(key, selectors.Strong)
 			if err != nil {
 				t.Error(err)
 			}
 
-			if expected, actual := members, value; !reflect.DeepEqual(expected, actual) {
+			if expected, actual := uniqueFields(members), value; !fieldsEqual(expected, actual) {
 				t.Errorf("expected: %v, actual: %v", expected, actual)
 			}
 
@@ -656,7 +1082,7 @@ func TestRealScore(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			_, err := farm.Score(key, field)
+			_, err := farm.Score(key, field, selectors.Strong)
 			return err != nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -693,7 +1119,7 @@ func TestRealScore(t *testing.T) {
 			})
 
 			farm := NewReal(nodeSet)
-			value, err := farm.Score(key, field)
+			value, err := farm.Score(key, field, selectors.Strong)
 			if err != nil {
 				t.Error(err)
 			}
@@ -709,3 +1135,247 @@ func TestRealScore(t *testing.T) {
 		}
 	})
 }
+
+func TestRealMembersDivergence(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key    = selectors.Key("key")
+		member = selectors.FieldValueScore{
+			Field: "b",
+			Value: []byte("value"),
+			Score: 5,
+		}
+	)
+
+	element := func(element selectors.Element) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- element
+		close(ch)
+		return ch
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		first  = mocks.NewMockNode(ctrl)
+		second = mocks.NewMockNode(ctrl)
+		third  = mocks.NewMockNode(ctrl)
+
+		present = selectors.Presence{Inserted: true, Present: true, Score: member.Score}
+		missing = selectors.Presence{Score: -1}
+		repair  = []selectors.FieldValueScore{{
+			Field: member.Field,
+			Value: member.Value,
+			Score: member.Score + 1,
+		}}
+
+		wg = &sync.WaitGroup{}
+	)
+
+	first.EXPECT().Members(key).Return(element(selectors.NewFieldsElement(key.Hash(), []selectors.Field{"a", "b"})))
+	second.EXPECT().Members(key).Return(element(selectors.NewFieldsElement(key.Hash(), []selectors.Field{"b", "a"})))
+	third.EXPECT().Members(key).Return(element(selectors.NewFieldsElement(key.Hash(), []selectors.Field{"a"})))
+
+	// The missing member is read from the replicas that hold it, before being
+	// repaired on to all the replicas.
+	first.EXPECT().Select(key, member.Field).Return(element(selectors.NewFieldValueScoreElement(key.Hash(), member)))
+	second.EXPECT().Select(key, member.Field).Return(element(selectors.NewFieldValueScoreElement(key.Hash(), member)))
+	third.EXPECT().Select(key, member.Field).Return(element(selectors.NewErrorElement(key.Hash(), selectors.NewNotFoundError(errors.New("not found")))))
+
+	first.EXPECT().Score(key, member.Field).Return(element(selectors.NewPresenceElement(key.Hash(), present)))
+	second.EXPECT().Score(key, member.Field).Return(element(selectors.NewPresenceElement(key.Hash(), present)))
+	third.EXPECT().Score(key, member.Field).Return(element(selectors.NewPresenceElement(key.Hash(), missing)))
+
+	wg.Add(3)
+	for _, node := range []*mocks.MockNode{first, second, third} {
+		node.EXPECT().Insert(key, repair).DoAndReturn(func(selectors.Key, []selectors.FieldValueScore) <-chan selectors.Element {
+			defer wg.Done()
+			return element(selectors.NewChangeSetElement(key.Hash(), selectors.ChangeSet{
+				Success: []selectors.Field{member.Field},
+				Failure: make([]selectors.Field, 0),
+			}))
+		})
+	}
+
+	nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+	nodeSet.EXPECT().Read(key, selectors.Strong).Return([]nodes.Node{
+		first, second, third,
+	}).Times(3)
+	nodeSet.EXPECT().Write(key, selectors.Strong).Return([]nodes.Node{
+		first, second, third,
+	}, func([]uint32) error { return nil })
+
+	divergence := metricMocks.NewMockCounterVec(ctrl)
+	divergence.EXPECT().WithLabelValues("members").Return(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "divergence",
+	}))
+
+	farm := NewReal(nodeSet, WithDivergence(divergence))
+	value, err := farm.Members(key, selectors.Strong)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := []selectors.Field{"a"}, value; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+
+	wg.Wait()
+}
+
+func TestRealTracing(t *testing.T) {
+	t.Parallel()
+
+	element := func(fn func() selectors.Element) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- fn()
+		close(ch)
+		return ch
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		key    = selectors.Key("key")
+		member = selectors.FieldValueScore{Field: "field", Value: []byte("value"), Score: 1}
+	)
+
+	a := mocks.NewMockNode(ctrl)
+	a.EXPECT().Host().Return("a").AnyTimes()
+	a.EXPECT().Select(key, member.Field).Return(element(func() selectors.Element {
+		return selectors.NewFieldValueScoreElement(1, member)
+	}))
+	a.EXPECT().Size(key).Return(element(func() selectors.Element {
+		return selectors.NewInt64Element(1, 1)
+	}))
+
+	b := mocks.NewMockNode(ctrl)
+	b.EXPECT().Host().Return("b").AnyTimes()
+	b.EXPECT().Select(key, member.Field).Return(element(func() selectors.Element {
+		return selectors.NewFieldValueScoreElement(2, member)
+	}))
+	b.EXPECT().Size(key).Return(element(func() selectors.Element {
+		return selectors.NewErrorElement(2, errors.New("bad"))
+	}))
+
+	nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+	nodeSet.EXPECT().Read(key, gomock.Any()).Return([]nodes.Node{a, b}).Times(2)
+
+	exporter := trace.NewInMemoryExporter()
+	tracer := trace.NewTracer(exporter, log.NewNopLogger())
+	defer tracer.Close()
+
+	ctx, parent := tracer.Start(context.Background(), trace.KindServer, "request")
+	farm := WithContext(ctx, NewReal(nodeSet, WithTracer(tracer)))
+
+	if _, err := farm.Select(key, member.Field, selectors.Strong); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := farm.Size(key, selectors.One); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+	tracer.Flush()
+
+	read := exporter.Named("farm.read")
+	if expected, actual := 1, len(read); expected != actual {
+		t.Fatalf("expected: %d, actual: %d", expected, actual)
+	}
+	if expected, actual := parent.Context().SpanID, read[0].Parent; expected != actual {
+		t.Errorf("expected: %s, actual: %s", expected, actual)
+	}
+
+	var (
+		hosts  = make(map[string]int)
+		failed []string
+	)
+	for _, v := range exporter.Named("farm.node") {
+		if expected, actual := parent.Context().TraceID, v.Context.TraceID; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		hosts[v.Attribute("node.host").(string)]++
+		if v.Err != "" {
+			failed = append(failed, v.Attribute("node.host").(string))
+		}
+	}
+	if expected, actual := map[string]int{"a": 2, "b": 2}, hosts; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if expected, actual := []string{"b"}, failed; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestRealMetrics(t *testing.T) {
+	t.Parallel()
+
+	element := func(fn func() selectors.Element) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- fn()
+		close(ch)
+		return ch
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		key   = selectors.Key("key")
+		field = selectors.Field("field")
+	)
+
+	a := mocks.NewMockNode(ctrl)
+	a.EXPECT().Host().Return("a").AnyTimes()
+	a.EXPECT().Select(key, field).Return(element(func() selectors.Element {
+		return selectors.NewErrorElement(1, selectors.NewNotFoundError(errors.New("not found")))
+	}))
+	a.EXPECT().Size(key).Return(element(func() selectors.Element {
+		return selectors.NewInt64Element(1, 1)
+	}))
+
+	b := mocks.NewMockNode(ctrl)
+	b.EXPECT().Host().Return("b").AnyTimes()
+	b.EXPECT().Select(key, field).Return(element(func() selectors.Element {
+		return selectors.NewErrorElement(2, selectors.NewNotFoundError(errors.New("not found")))
+	}))
+	b.EXPECT().Size(key).Return(element(func() selectors.Element {
+		return selectors.NewErrorElement(2, errors.New("bad"))
+	}))
+
+	nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+	nodeSet.EXPECT().Read(key, gomock.Any()).Return([]nodes.Node{a, b}).Times(2)
+
+	var (
+		outcomes = metricMocks.NewMockCounterVec(ctrl)
+		duration = metricMocks.NewMockHistogramVec(ctrl)
+		failures = metricMocks.NewMockCounterVec(ctrl)
+		observer = metricMocks.NewMockObserver(ctrl)
+		counter  = prometheus.NewCounter(prometheus.CounterOpts{Name: "counter"})
+	)
+
+	// Both replicas answer that the member isn't found, which is still an
+	// answer, but only one of them answers the size.
+	outcomes.EXPECT().WithLabelValues("select", "met").Return(counter)
+	outcomes.EXPECT().WithLabelValues("size", "partial").Return(counter)
+	failures.EXPECT().WithLabelValues("b", "size").Return(counter)
+	for _, host := range []string{"a", "b"} {
+		duration.EXPECT().WithLabelValues(host, "select").Return(observer)
+		duration.EXPECT().WithLabelValues(host, "size").Return(observer)
+	}
+	observer.EXPECT().Observe(gomock.Any()).Times(4)
+
+	farm := NewReal(nodeSet,
+		WithQuorumOutcomes(outcomes),
+		WithReplicaMetrics(duration, failures),
+	)
+
+	if _, err := farm.Select(key, field, selectors.Strong); !selectors.NotFoundError(err) {
+		t.Errorf("expected not found error, actual: %v", err)
+	}
+	if _, err := farm.Size(key, selectors.One); err != nil {
+		t.Fatal(err)
+	}
+}
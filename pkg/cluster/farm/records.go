@@ -30,113 +30,133 @@ func (r *changeSetRecords) ChangeSet() selectors.ChangeSet {
 	return r.changeSet
 }
 
-type keysRecords struct {
-	keys []selectors.Key
-	set  bool
-	err  error
+// countRecords counts how many replicas returned each value, so that the
+// values can be merged according to a quorum, rather than expecting every
+// replica to agree.
+type countRecords struct {
+	counts map[string]int
 }
 
-func (r *keysRecords) Add(v []selectors.Key) {
-	match := func(a, b []selectors.Key) bool {
-		if len(a) != len(b) {
-			return false
-		}
+func (r *countRecords) add(values []string) {
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
 
-		sort.Slice(a, func(i, j int) bool {
-			return a[i].String() < a[j].String()
-		})
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		r.counts[v]++
+	}
+}
 
-		sort.Slice(b, func(i, j int) bool {
-			return b[i].String() < b[j].String()
-		})
+// quorum returns the values that enough of the replicas returned to satisfy
+// the quorum, out of the total amount of replicas, in sorted order.
+func (r *countRecords) quorum(quorum selectors.Quorum, total int) []string {
+	res := make([]string, 0, len(r.counts))
+	for v, count := range r.counts {
+		if consensus(quorum, total, count) {
+			res = append(res, v)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
 
-		for k, v := range a {
-			if v != b[k] {
-				return false
-			}
+// missing returns the values that some, but not all of the replicas returned,
+// in sorted order.
+func (r *countRecords) missing(total int) []string {
+	var res []string
+	for v, count := range r.counts {
+		if count < total {
+			res = append(res, v)
 		}
-		return true
 	}
-	if r.set && !match(r.keys, v) {
-		r.err = errors.New("variance detected from replication")
-		return
+	sort.Strings(res)
+	return res
+}
+
+type keysRecords struct {
+	records countRecords
+}
+
+func (r *keysRecords) Add(v []selectors.Key) {
+	values := make([]string, len(v))
+	for k, key := range v {
+		values[k] = key.String()
 	}
-	r.keys = v
-	r.set = true
+	r.records.add(values)
 }
 
-func (r *keysRecords) Err() error {
-	return r.err
+// Keys returns the union or the intersection of the keys, depending on the
+// quorum.
+func (r *keysRecords) Keys(quorum selectors.Quorum, total int) []selectors.Key {
+	return toKeys(r.records.quorum(quorum, total))
 }
 
-func (r *keysRecords) Keys() []selectors.Key {
-	return r.keys
+// Missing returns the keys that are missing from some of the replicas.
+func (r *keysRecords) Missing(total int) []selectors.Key {
+	return toKeys(r.records.missing(total))
 }
 
 type fieldsRecords struct {
-	fields []selectors.Field
-	set    bool
-	err    error
+	records countRecords
 }
 
 func (r *fieldsRecords) Add(v []selectors.Field) {
-	match := func(a, b []selectors.Field) bool {
-		if len(a) != len(b) {
-			return false
-		}
-
-		sort.Slice(a, func(i, j int) bool {
-			return a[i].String() < a[j].String()
-		})
-
-		sort.Slice(b, func(i, j int) bool {
-			return b[i].String() < b[j].String()
-		})
-
-		for k, v := range a {
-			if v != b[k] {
-				return false
-			}
-		}
-		return true
-	}
-	if r.set && !match(r.fields, v) {
-		r.err = errors.New("variance detected from replication")
-		return
+	values := make([]string, len(v))
+	for k, field := range v {
+		values[k] = field.String()
 	}
-	r.fields = v
-	r.set = true
+	r.records.add(values)
 }
 
-func (r *fieldsRecords) Err() error {
-	return r.err
+// Fields returns the union or the intersection of the fields, depending on
+// the quorum.
+func (r *fieldsRecords) Fields(quorum selectors.Quorum, total int) []selectors.Field {
+	return toFields(r.records.quorum(quorum, total))
 }
 
-func (r *fieldsRecords) Fields() []selectors.Field {
-	return r.fields
+// Missing returns the fields that are missing from some of the replicas.
+func (r *fieldsRecords) Missing(total int) []selectors.Field {
+	return toFields(r.records.missing(total))
 }
 
 type int64Records struct {
-	integer int64
-	set     bool
-	err     error
+	integers []int64
 }
 
 func (r *int64Records) Add(v int64) {
-	if r.set && r.integer != v {
-		r.err = errors.New("variance detected from replication")
-		return
-	}
-	r.integer = v
-	r.set = true
+	r.integers = append(r.integers, v)
 }
 
-func (r *int64Records) Err() error {
-	return r.err
+// Divergent returns if any of the replicas returned a different integer.
+func (r *int64Records) Divergent() bool {
+	for _, v := range r.integers {
+		if v != r.integers[0] {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *int64Records) Int64() int64 {
-	return r.integer
+// Int64 returns the largest integer that enough of the replicas have reached
+// to satisfy the quorum, out of the total amount of replicas.
+func (r *int64Records) Int64(quorum selectors.Quorum, total int) int64 {
+	integers := make([]int64, len(r.integers))
+	copy(integers, r.integers)
+	sort.Slice(integers, func(i, j int) bool {
+		return integers[i] > integers[j]
+	})
+
+	for k, v := range integers {
+		if consensus(quorum, total, k+1) {
+			return v
+		}
+	}
+	return -1
 }
 
 type presenceRecords struct {
@@ -161,3 +181,19 @@ func (r *presenceRecords) Err() error {
 func (r *presenceRecords) Presence() selectors.Presence {
 	return r.presence
 }
+
+func toKeys(values []string) []selectors.Key {
+	res := make([]selectors.Key, len(values))
+	for k, v := range values {
+		res[k] = selectors.Key(v)
+	}
+	return res
+}
+
+func toFields(values []string) []selectors.Field {
+	res := make([]selectors.Field, len(values))
+	for k, v := range values {
+		res[k] = selectors.Field(v)
+	}
+	return res
+}
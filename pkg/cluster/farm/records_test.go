@@ -2,6 +2,7 @@ package farm
 
 import (
 	"reflect"
+	"sort"
 	"testing"
 	"testing/quick"
 
@@ -45,24 +46,56 @@ func TestKeysRecords(t *testing.T) {
 			records.Add(values)
 			records.Add(values)
 
-			return records.Err() == nil && ((len(values) == 0 && len(records.Keys()) == 0) || reflect.DeepEqual(values, records.Keys()))
+			return len(records.Missing(2)) == 0 &&
+				keysEqual(uniqueKeys(values), records.Keys(selectors.Strong, 2))
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
 		}
 	})
 
-	t.Run("variance", func(t *testing.T) {
+	t.Run("union", func(t *testing.T) {
 		fn := func(value0, value1 []selectors.Key) bool {
 			records := keysRecords{}
 			records.Add(value0)
 			records.Add(value1)
-			return records.Err() != nil && ((len(value0) == 0 && len(records.Keys()) == 0) || reflect.DeepEqual(value0, records.Keys()))
+
+			return keysEqual(uniqueKeys(append(value0, value1...)), records.Keys(selectors.One, 2))
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
 		}
 	})
+
+	t.Run("intersection", func(t *testing.T) {
+		records := keysRecords{}
+		records.Add([]selectors.Key{"a", "b", "c"})
+		records.Add([]selectors.Key{"b", "c", "d"})
+		records.Add([]selectors.Key{"c", "d", "a"})
+
+		if expected, actual := []selectors.Key{"c"}, records.Keys(selectors.Strong, 3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []selectors.Key{"a", "b", "c", "d"}, records.Keys(selectors.Consensus, 3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []selectors.Key{"a", "b", "d"}, records.Missing(3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("quorum with missing replicas", func(t *testing.T) {
+		records := keysRecords{}
+		records.Add([]selectors.Key{"a", "b"})
+		records.Add([]selectors.Key{"b"})
+
+		if expected, actual := []selectors.Key{}, records.Keys(selectors.Strong, 3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []selectors.Key{"b"}, records.Keys(selectors.Consensus, 3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
 }
 
 func TestFieldsRecords(t *testing.T) {
@@ -73,24 +106,44 @@ func TestFieldsRecords(t *testing.T) {
 			records := fieldsRecords{}
 			records.Add(values)
 			records.Add(values)
-			return records.Err() == nil && ((len(values) == 0 && len(records.Fields()) == 0) || reflect.DeepEqual(values, records.Fields()))
+
+			return len(records.Missing(2)) == 0 &&
+				fieldsEqual(uniqueFields(values), records.Fields(selectors.Strong, 2))
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
 		}
 	})
 
-	t.Run("variance", func(t *testing.T) {
+	t.Run("union", func(t *testing.T) {
 		fn := func(value0, value1 []selectors.Field) bool {
 			records := fieldsRecords{}
 			records.Add(value0)
 			records.Add(value1)
-			return records.Err() != nil && ((len(value0) == 0 && len(records.Fields()) == 0) || reflect.DeepEqual(value0, records.Fields()))
+
+			return fieldsEqual(uniqueFields(append(value0, value1...)), records.Fields(selectors.One, 2))
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
 		}
 	})
+
+	t.Run("intersection", func(t *testing.T) {
+		records := fieldsRecords{}
+		records.Add([]selectors.Field{"a", "b", "c"})
+		records.Add([]selectors.Field{"b", "c", "d"})
+		records.Add([]selectors.Field{"c", "d", "a"})
+
+		if expected, actual := []selectors.Field{"c"}, records.Fields(selectors.Strong, 3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []selectors.Field{"a", "b", "c", "d"}, records.Fields(selectors.Consensus, 3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []selectors.Field{"a", "b", "d"}, records.Missing(3); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
 }
 
 func TestInt64Records(t *testing.T) {
@@ -101,7 +154,7 @@ func TestInt64Records(t *testing.T) {
 			records := int64Records{}
 			records.Add(values)
 			records.Add(values)
-			return records.Err() == nil && reflect.DeepEqual(values, records.Int64())
+			return !records.Divergent() && values == records.Int64(selectors.Strong, 2)
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
@@ -109,14 +162,22 @@ func TestInt64Records(t *testing.T) {
 	})
 
 	t.Run("variance", func(t *testing.T) {
-		fn := func(value0, value1 int64) bool {
-			records := int64Records{}
-			records.Add(value0)
-			records.Add(value1)
-			return records.Err() != nil && reflect.DeepEqual(value0, records.Int64())
+		records := int64Records{}
+		records.Add(1)
+		records.Add(3)
+		records.Add(2)
+
+		if expected, actual := true, records.Divergent(); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
 		}
-		if err := quick.Check(fn, nil); err != nil {
-			t.Error(err)
+		if expected, actual := int64(3), records.Int64(selectors.One, 3); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := int64(2), records.Int64(selectors.Consensus, 3); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := int64(1), records.Int64(selectors.Strong, 3); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
 		}
 	})
 }
@@ -148,3 +209,43 @@ func TestPresenceRecords(t *testing.T) {
 		}
 	})
 }
+
+func uniqueKeys(values []selectors.Key) []selectors.Key {
+	seen := make(map[selectors.Key]struct{})
+	res := make([]selectors.Key, 0)
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res
+}
+
+func keysEqual(a, b []selectors.Key) bool {
+	return (len(a) == 0 && len(b) == 0) || reflect.DeepEqual(a, b)
+}
+
+func uniqueFields(values []selectors.Field) []selectors.Field {
+	seen := make(map[selectors.Field]struct{})
+	res := make([]selectors.Field, 0)
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+	return res
+}
+
+func fieldsEqual(a, b []selectors.Field) bool {
+	return (len(a) == 0 && len(b) == 0) || reflect.DeepEqual(a, b)
+}
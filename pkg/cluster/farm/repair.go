@@ -1,31 +1,48 @@
 package farm
 
 import (
+	"context"
 	"sync"
 
 	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/trace"
 	"github.com/pkg/errors"
 )
 
 type repairStrategy struct {
+	*instruments
 	nodes hashring.Snapshot
 }
 
-func (r *repairStrategy) Repair(members []selectors.KeyFieldValue) error {
+func (r *repairStrategy) Repair(ctx context.Context, members []selectors.KeyFieldValue) (err error) {
+	if len(members) == 0 {
+		return nil
+	}
+
+	// Repairs happen in the background, so the span can outlive the span of
+	// the request that found them.
+	ctx, span := r.tracer.Start(ctx, trace.KindInternal, "farm.repair", trace.Int("members", len(members)))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	clues := make([]selectors.Clue, 0)
 	for _, v := range members {
 		// This can be optimised to send all of them at once, but could flood the
 		// hosts
-		clue, err := r.readScoreRepair(v.Key, func(n nodes.Node) <-chan selectors.Element {
+		clue, err := r.readScoreRepair(ctx, v.Key, func(n nodes.Node) <-chan selectors.Element {
 			return n.Score(v.Key, v.Field)
 		})
 		if err != nil {
+			r.count("failed", 1)
 			continue
 		}
 		// Ignore the clue, we don't want to perform any read repairs.
 		if clue.Ignore {
+			r.count("skipped", 1)
 			continue
 		}
 		clues = append(clues, clue.SetKeyFieldValue(v.Key, v.Field, v.Value))
@@ -38,6 +55,7 @@ func (r *repairStrategy) Repair(members []selectors.KeyFieldValue) error {
 	for _, v := range clues {
 		// If we've not met quorum then we shouldn't be doing anything.
 		if v.Ignore || !v.Quorum {
+			r.count("skipped", 1)
 			continue
 		}
 
@@ -58,18 +76,24 @@ func (r *repairStrategy) Repair(members []selectors.KeyFieldValue) error {
 
 	var errs []error
 	for key, members := range inserts {
-		if err := r.write(key, func(n nodes.Node) <-chan selectors.Element {
+		if err := r.write(ctx, "repair_insert", key, func(n nodes.Node) <-chan selectors.Element {
 			return n.Insert(key, members)
 		}); err != nil {
+			r.count("failed", len(members))
 			errs = append(errs, err)
+			continue
 		}
+		r.count("repaired", len(members))
 	}
 	for key, members := range deletes {
-		if err := r.write(key, func(n nodes.Node) <-chan selectors.Element {
+		if err := r.write(ctx, "repair_delete", key, func(n nodes.Node) <-chan selectors.Element {
 			return n.Delete(key, members)
 		}); err != nil {
+			r.count("failed", len(members))
 			errs = append(errs, err)
+			continue
 		}
+		r.count("repaired", len(members))
 	}
 
 	if len(errs) > 0 {
@@ -78,7 +102,35 @@ func (r *repairStrategy) Repair(members []selectors.KeyFieldValue) error {
 	return nil
 }
 
-func (r *repairStrategy) readScoreRepair(key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) (selectors.Clue, error) {
+// RepairMembers repairs the members of a key that are missing from some of the
+// replicas, the value is read from the replicas that still hold the members.
+func (r *repairStrategy) RepairMembers(ctx context.Context, key selectors.Key, fields []selectors.Field) error {
+	members := make([]selectors.KeyFieldValue, 0, len(fields))
+	for _, field := range fields {
+		member, err := r.readValueRepair(ctx, key, func(n nodes.Node) <-chan selectors.Element {
+			return n.Select(key, field)
+		})
+		if err != nil {
+			r.count("failed", 1)
+			continue
+		}
+		members = append(members, selectors.KeyFieldValue{
+			Key:   key,
+			Field: field,
+			Value: member.Value,
+		})
+	}
+	return r.Repair(ctx, members)
+}
+
+// count counts the members of a repair by their outcome.
+func (r *repairStrategy) count(outcome string, members int) {
+	if r.repairs != nil && members > 0 {
+		r.repairs.WithLabelValues(outcome).Add(float64(members))
+	}
+}
+
+func (r *repairStrategy) readScoreRepair(ctx context.Context, key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) (selectors.Clue, error) {
 	var (
 		retrieved = 0
 		returned  = 0
@@ -94,7 +146,7 @@ func (r *repairStrategy) readScoreRepair(key selectors.Key, fn func(nodes.Node)
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	if err := r.scatterRequests(ctx, "repair_score", nodes, fn, wg, elements); err != nil {
 		return selectors.Clue{}, err
 	}
 
@@ -143,7 +195,43 @@ func (r *repairStrategy) readScoreRepair(key selectors.Key, fn func(nodes.Node)
 	}, nil
 }
 
-func (r *repairStrategy) write(key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) error {
+func (r *repairStrategy) readValueRepair(ctx context.Context, key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) (selectors.FieldValueScore, error) {
+	var (
+		nodes    = r.nodes.Read(key, selectors.Strong)
+		elements = make(chan selectors.Element, len(nodes))
+
+		found  = false
+		member selectors.FieldValueScore
+		wg     = &sync.WaitGroup{}
+	)
+
+	wg.Add(len(nodes))
+	go func() { wg.Wait(); close(elements) }()
+
+	if err := r.scatterRequests(ctx, "repair_select", nodes, fn, wg, elements); err != nil {
+		return selectors.FieldValueScore{}, err
+	}
+
+	// Any replica that doesn't hold the member will return an error, so only
+	// the one with the highest score is of any interest.
+	for element := range elements {
+		if err := selectors.ErrorFromElement(element); err != nil {
+			continue
+		}
+
+		if value := selectors.FieldValueScoreFromElement(element); !found || value.Score > member.Score {
+			found = true
+			member = value
+		}
+	}
+
+	if !found {
+		return selectors.FieldValueScore{}, errors.Errorf("unable to perform repair")
+	}
+	return member, nil
+}
+
+func (r *repairStrategy) write(ctx context.Context, method string, key selectors.Key, fn func(nodes.Node) <-chan selectors.Element) error {
 	var (
 		retrieved = 0
 		returned  = 0
@@ -160,7 +248,7 @@ func (r *repairStrategy) write(key selectors.Key, fn func(nodes.Node) <-chan sel
 	wg.Add(len(nodes))
 	go func() { wg.Wait(); close(elements) }()
 
-	if err := scatterRequests(nodes, fn, wg, elements); err != nil {
+	if err := r.scatterRequests(ctx, method, nodes, fn, wg, elements); err != nil {
 		return err
 	}
 
@@ -1,14 +1,17 @@
 package farm
 
 import (
+	"context"
 	"testing"
 	"testing/quick"
 
 	hashringMocks "github.com/SimonRichardson/coherence/pkg/cluster/hashring/mocks"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes/mocks"
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestRepair(t *testing.T) {
@@ -66,8 +69,8 @@ func TestRepair(t *testing.T) {
 				node,
 			}).AnyTimes()
 
-			strategy := repairStrategy{nodeSet}
-			err := strategy.Repair(members)
+			strategy := repairStrategy{instruments: &instruments{}, nodes: nodeSet}
+			err := strategy.Repair(context.Background(), members)
 			return err == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -127,12 +130,75 @@ func TestRepair(t *testing.T) {
 				node,
 			}).AnyTimes()
 
-			strategy := repairStrategy{nodeSet}
-			err := strategy.Repair(members)
+			strategy := repairStrategy{instruments: &instruments{}, nodes: nodeSet}
+			err := strategy.Repair(context.Background(), members)
 			return err == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
 		}
 	})
+
+	t.Run("repair counts the members", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			key    = selectors.Key("key")
+			member = selectors.KeyFieldValue{Key: key, Field: "field", Value: []byte("value")}
+		)
+
+		node := mocks.NewMockNode(ctrl)
+		node.EXPECT().Score(key, member.Field).Return(singleElement(selectors.NewPresenceElement(key.Hash(), selectors.Presence{
+			Inserted: true,
+			Present:  true,
+			Score:    2,
+		})))
+		node.EXPECT().Insert(key, []selectors.FieldValueScore{
+			{Field: member.Field, Value: member.Value, Score: 3},
+		}).Return(singleElement(selectors.NewChangeSetElement(key.Hash(), selectors.ChangeSet{
+			Success: []selectors.Field{member.Field},
+			Failure: make([]selectors.Field, 0),
+		})))
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Write(key, selectors.Strong).Return([]nodes.Node{
+			node,
+		}, func([]uint32) error { return nil })
+		nodeSet.EXPECT().Read(key, selectors.Strong).Return([]nodes.Node{
+			node,
+		})
+
+		var (
+			repaired = &counterValue{Counter: prometheus.NewCounter(prometheus.CounterOpts{Name: "repaired"})}
+			repairs  = metricMocks.NewMockCounterVec(ctrl)
+		)
+		repairs.EXPECT().WithLabelValues("repaired").Return(repaired)
+
+		strategy := repairStrategy{instruments: &instruments{repairs: repairs}, nodes: nodeSet}
+		if err := strategy.Repair(context.Background(), []selectors.KeyFieldValue{member}); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := float64(1), repaired.value; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+// singleElement returns a closed channel with just the element.
+func singleElement(e selectors.Element) <-chan selectors.Element {
+	ch := make(chan selectors.Element, 1)
+	ch <- e
+	close(ch)
+	return ch
+}
+
+// counterValue records the values that are added to the counter.
+type counterValue struct {
+	prometheus.Counter
+	value float64
+}
+
+func (c *counterValue) Add(value float64) {
+	c.value += value
 }
@@ -0,0 +1,125 @@
+package farm
+
+import (
+	"sync"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/trace"
+)
+
+// KeysMatching scans every page of the keys that match the pattern, so that
+// only the matching keys are sent by each replica.
+func (r *real) KeysMatching(pattern string, quorum selectors.Quorum) ([]selectors.Key, error) {
+	var (
+		res    = make([]selectors.Key, 0)
+		cursor selectors.Cursor
+		opts   = selectors.ScanOptions{Match: pattern, Count: selectors.MaxScanCount}
+	)
+	for {
+		page, err := r.Scan(cursor, opts, quorum)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page.Keys...)
+		if page.Cursor == "" {
+			return res, nil
+		}
+		cursor = page.Cursor
+	}
+}
+
+// MembersWithPrefix scans every page of the members of the key that start
+// with the prefix.
+func (r *real) MembersWithPrefix(key selectors.Key, prefix string, quorum selectors.Quorum) ([]selectors.Field, error) {
+	var (
+		res    = make([]selectors.Field, 0)
+		cursor selectors.Cursor
+		opts   = selectors.ScanOptions{Match: selectors.PrefixPattern(prefix), Count: selectors.MaxScanCount}
+	)
+	for {
+		page, err := r.ScanMembers(key, cursor, opts, quorum)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page.Fields...)
+		if page.Cursor == "" {
+			return res, nil
+		}
+		cursor = page.Cursor
+	}
+}
+
+// scanPage extracts the values and the cursor of a page from an element.
+type scanPage func(selectors.Element) ([]string, selectors.Cursor)
+
+// readScan scatters the scan to the nodes and merges the pages together.
+// Replicas are allowed to disagree, as each page is the first values after the
+// same cursor, so the union of them is still in order.
+func (r *real) readScan(method string,
+	nodes []nodes.Node,
+	quorum selectors.Quorum,
+	opts selectors.ScanOptions,
+	fn func(nodes.Node) <-chan selectors.Element,
+	extract scanPage,
+) (_ []string, _ selectors.Cursor, err error) {
+	ctx, span := r.start("farm.read_scan", quorum, trace.String("match", opts.Match))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	var (
+		elements = make(chan selectors.Element, len(nodes))
+
+		errs    []error
+		pages   [][]string
+		cursors []selectors.Cursor
+		wg      = &sync.WaitGroup{}
+	)
+
+	wg.Add(len(nodes))
+	go func() { wg.Wait(); close(elements) }()
+
+	if err := r.scatterRequests(ctx, method, nodes, fn, wg, elements); err != nil {
+		return nil, "", err
+	}
+
+	for element := range elements {
+		if err := selectors.ErrorFromElement(element); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		values, cursor := extract(element)
+		pages = append(pages, values)
+		cursors = append(cursors, cursor)
+	}
+
+	r.observe(method, quorum, len(nodes), errs)
+
+	if len(errs) > 0 && !consensus(quorum, len(nodes), len(pages)) {
+		return nil, "", mapErrors(errs)
+	}
+
+	values, cursor := selectors.MergeSorted(pages, cursors, opts)
+	return values, cursor, nil
+}
+
+func keysPage(element selectors.Element) ([]string, selectors.Cursor) {
+	page := selectors.KeysPageFromElement(element)
+	res := make([]string, len(page.Keys))
+	for k, v := range page.Keys {
+		res[k] = v.String()
+	}
+	return res, page.Cursor
+}
+
+func fieldsPage(element selectors.Element) ([]string, selectors.Cursor) {
+	page := selectors.FieldsPageFromElement(element)
+	res := make([]string, len(page.Fields))
+	for k, v := range page.Fields {
+		res[k] = v.String()
+	}
+	return res, page.Cursor
+}
@@ -0,0 +1,172 @@
+package farm
+
+import (
+	"reflect"
+	"testing"
+
+	hashringMocks "github.com/SimonRichardson/coherence/pkg/cluster/hashring/mocks"
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func TestRealScan(t *testing.T) {
+	t.Parallel()
+
+	page := func(page selectors.KeysPage) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewKeysPageElement(defaultAllKey.Hash(), page)
+		close(ch)
+		return ch
+	}
+
+	t.Run("merges replicas", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			opts   = selectors.ScanOptions{Count: 2}
+			first  = mocks.NewMockNode(ctrl)
+			second = mocks.NewMockNode(ctrl)
+		)
+		first.EXPECT().Scan(selectors.Cursor(""), opts).Return(page(selectors.KeysPage{
+			Keys:   []selectors.Key{"a", "c"},
+			Cursor: selectors.NewCursor("c"),
+		}))
+		second.EXPECT().Scan(selectors.Cursor(""), opts).Return(page(selectors.KeysPage{
+			Keys: []selectors.Key{"b"},
+		}))
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(defaultAllKey, selectors.Strong).Return([]nodes.Node{
+			first, second,
+		})
+
+		farm := NewReal(nodeSet)
+		value, err := farm.Scan("", opts, selectors.Strong)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := []selectors.Key{"a", "b"}, value.Keys; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := selectors.NewCursor("b"), value.Cursor; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("scan with errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewErrorElement(defaultAllKey.Hash(), errors.New("bad"))
+		close(ch)
+
+		node := mocks.NewMockNode(ctrl)
+		node.EXPECT().Scan(selectors.Cursor(""), selectors.ScanOptions{}).Return(ch)
+
+		nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+		nodeSet.EXPECT().Read(defaultAllKey, selectors.Strong).Return([]nodes.Node{
+			node,
+		})
+
+		farm := NewReal(nodeSet)
+		if _, err := farm.Scan("", selectors.ScanOptions{}, selectors.Strong); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestRealScanMembers(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		key  = selectors.Key("key")
+		opts = selectors.ScanOptions{Count: 10}
+	)
+
+	page := func(fields ...selectors.Field) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewFieldsPageElement(key.Hash(), selectors.FieldsPage{
+			Fields: fields,
+		})
+		close(ch)
+		return ch
+	}
+
+	first := mocks.NewMockNode(ctrl)
+	first.EXPECT().ScanMembers(key, selectors.Cursor(""), opts).Return(page("a", "b"))
+
+	second := mocks.NewMockNode(ctrl)
+	second.EXPECT().ScanMembers(key, selectors.Cursor(""), opts).Return(page("b", "c"))
+
+	nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+	nodeSet.EXPECT().Read(key, selectors.Strong).Return([]nodes.Node{
+		first, second,
+	})
+
+	farm := NewReal(nodeSet)
+	value, err := farm.ScanMembers(key, "", opts, selectors.Strong)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := []selectors.Field{"a", "b", "c"}, value.Fields; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if expected, actual := selectors.Cursor(""), value.Cursor; expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestRealKeysMatching(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		opts = selectors.ScanOptions{Match: "user:*", Count: selectors.MaxScanCount}
+		next = selectors.NewCursor("user:1")
+		node = mocks.NewMockNode(ctrl)
+	)
+
+	page := func(page selectors.KeysPage) <-chan selectors.Element {
+		ch := make(chan selectors.Element, 1)
+		ch <- selectors.NewKeysPageElement(defaultAllKey.Hash(), page)
+		close(ch)
+		return ch
+	}
+
+	gomock.InOrder(
+		node.EXPECT().Scan(selectors.Cursor(""), opts).Return(page(selectors.KeysPage{
+			Keys:   []selectors.Key{"user:1"},
+			Cursor: next,
+		})),
+		node.EXPECT().Scan(next, opts).Return(page(selectors.KeysPage{
+			Keys: []selectors.Key{"user:2"},
+		})),
+	)
+
+	nodeSet := hashringMocks.NewMockSnapshot(ctrl)
+	nodeSet.EXPECT().Read(defaultAllKey, selectors.Strong).Return([]nodes.Node{
+		node,
+	}).Times(2)
+
+	farm := NewReal(nodeSet)
+	keys, err := farm.KeysMatching("user:*", selectors.Strong)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := []selectors.Key{"user:1", "user:2"}, keys; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
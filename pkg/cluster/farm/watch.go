@@ -0,0 +1,131 @@
+package farm
+
+import (
+	"sync"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// mergedSubscription merges the subscriptions of each replica of a key, each
+// change is applied to every replica, so only the first event for each score
+// of a field is sent.
+type mergedSubscription struct {
+	subs   []selectors.Subscription
+	events chan selectors.Event
+	done   chan struct{}
+	once   sync.Once
+	mutex  sync.Mutex
+	scores map[selectors.Field]int64
+	err    error
+}
+
+func newMergedSubscription(subs []selectors.Subscription) *mergedSubscription {
+	s := &mergedSubscription{
+		subs:   subs,
+		events: make(chan selectors.Event),
+		done:   make(chan struct{}),
+		scores: make(map[selectors.Field]int64),
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(subs))
+	go func() { wg.Wait(); close(s.events) }()
+
+	for _, sub := range subs {
+		go s.run(sub, wg)
+	}
+	return s
+}
+
+func (s *mergedSubscription) Events() <-chan selectors.Event {
+	return s.events
+}
+
+func (s *mergedSubscription) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+func (s *mergedSubscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		for _, sub := range s.subs {
+			sub.Close()
+		}
+	})
+}
+
+func (s *mergedSubscription) run(sub selectors.Subscription, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for event := range sub.Events() {
+		if !s.accept(event) {
+			continue
+		}
+
+		// Offsets are only meaningful for a single node.
+		event.Offset = 0
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+
+	if err := sub.Err(); err != nil {
+		s.mutex.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// accept returns if the event hasn't already been sent by another replica.
+func (s *mergedSubscription) accept(event selectors.Event) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if score, ok := s.scores[event.Field]; ok && score >= event.Score {
+		return false
+	}
+	s.scores[event.Field] = event.Score
+	return true
+}
+
+// watch subscribes to every replica of the key that can be watched.
+func watch(replicas []nodes.Node, key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	if resume.Type == selectors.ResumeOffset {
+		return nil, errors.New("offsets are only valid for a single node, resume from a score instead")
+	}
+
+	var (
+		subs []selectors.Subscription
+		errs []error
+	)
+	for _, node := range replicas {
+		watcher, ok := node.(nodes.Watcher)
+		if !ok {
+			continue
+		}
+		sub, err := watcher.Watch(key, resume)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		subs = append(subs, sub)
+	}
+
+	if len(subs) == 0 {
+		if len(errs) > 0 {
+			return nil, joinErrors(errs)
+		}
+		return nil, errors.Errorf("no replicas of %s can be watched", key)
+	}
+	return newMergedSubscription(subs), nil
+}
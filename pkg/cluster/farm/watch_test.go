@@ -0,0 +1,93 @@
+package farm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/trussle/fsys"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("watch with offset", func(t *testing.T) {
+		_, err := watch(nil, "a", selectors.ResumeFromOffset(1))
+		if err == nil {
+			t.Errorf("expected err")
+		}
+	})
+
+	t.Run("watch with no watchers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := watch([]nodes.Node{mocks.NewMockNode(ctrl)}, "a", selectors.Resume{})
+		if err == nil {
+			t.Errorf("expected err")
+		}
+	})
+
+	t.Run("watch merges replicas", func(t *testing.T) {
+		var (
+			stores   []store.Store
+			replicas []nodes.Node
+		)
+		for i := 0; i < 3; i++ {
+			s, err := store.New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			stores = append(stores, s)
+			replicas = append(replicas, nodes.NewVirtual(uint32(i), "", s))
+		}
+
+		sub, err := watch(replicas, "a", selectors.Resume{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		for _, member := range []selectors.FieldValueScore{
+			{Field: "x", Score: 1},
+			{Field: "x", Score: 2},
+		} {
+			for _, s := range stores {
+				if _, err := s.Insert("a", []selectors.FieldValueScore{member}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		var events []selectors.Event
+		for len(events) < 2 {
+			select {
+			case event := <-sub.Events():
+				events = append(events, event)
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		}
+
+		// Every replica has sent the events, so no more are expected.
+		select {
+		case event := <-sub.Events():
+			t.Errorf("unexpected event: %v", event)
+		case <-time.After(time.Millisecond * 50):
+		}
+
+		for k, event := range events {
+			if expected, actual := int64(k+1), event.Score; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+			if expected, actual := uint64(0), event.Offset; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+	})
+}
@@ -3,6 +3,7 @@ package hashring
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"sync"
 	"text/tabwriter"
 
@@ -57,6 +58,12 @@ func (n *Actor) Time() clock.Time {
 	return n.clock.Now()
 }
 
+// FillRatio returns the ratio of the bits of the bloom of the actor that are
+// set.
+func (n *Actor) FillRatio() float64 {
+	return n.bloom.FillRatio()
+}
+
 // Add adds a known piece of data to the actor to improve the potential of
 // finding the data with in the store. Consider this as a Hint to improve
 // various consensus algorithms.
@@ -156,6 +163,51 @@ func (n *Actors) Update(hash uint32, payload []byte) error {
 	return nil
 }
 
+// ActorStatus describes what an Actor knows of the store of a host.
+type ActorStatus struct {
+	Host      string  `json:"host"`
+	Hash      uint32  `json:"hash"`
+	Clock     uint64  `json:"clock"`
+	BloomFill float64 `json:"bloom_fill"`
+}
+
+// Status returns the status of each of the actors, ordered by host.
+func (n *Actors) Status() []ActorStatus {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	res := make([]ActorStatus, 0, len(n.hashes))
+	for _, v := range n.hashes {
+		res = append(res, ActorStatus{
+			Host:      v.Host(),
+			Hash:      v.Hash(),
+			Clock:     v.Time().Value(),
+			BloomFill: v.FillRatio(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Host < res[j].Host
+	})
+	return res
+}
+
+// Circuits returns if the circuit in front of each host is open, keyed by
+// host. The hosts without a circuit are left out.
+func (n *Actors) Circuits() map[string]bool {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	res := make(map[string]bool, len(n.hashes))
+	for _, v := range n.hashes {
+		if circuit, ok := v.node.(nodes.Circuit); ok {
+			if open, ok := circuit.CircuitOpen(); ok {
+				res[v.Host()] = open
+			}
+		}
+	}
+	return res
+}
+
 // String returns a table view of the internal actor nodes
 func (n *Actors) String() string {
 	buf := new(bytes.Buffer)
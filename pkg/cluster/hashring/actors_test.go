@@ -295,4 +295,82 @@ func TestActors(t *testing.T) {
 			t.Errorf("expected: %s, actual: %s", expected, actual)
 		}
 	})
+
+	t.Run("status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		actors := NewActors()
+		for k, host := range []string{"b", "a"} {
+			node := mocks.NewMockNode(ctrl)
+			node.EXPECT().Host().Return(host).AnyTimes()
+			node.EXPECT().Hash().Return(uint32(k)).AnyTimes()
+
+			actor := NewActor(func() nodes.Node {
+				return node
+			})
+			if host == "a" {
+				if err := actor.Add("key"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			actors.Set(actor)
+		}
+
+		status := actors.Status()
+		if expected, actual := 2, len(status); expected != actual {
+			t.Fatalf("expected: %d, actual: %d", expected, actual)
+		}
+		if expected, actual := "a", status[0].Host; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := uint64(1), status[0].Clock; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+		if status[0].BloomFill <= 0 {
+			t.Errorf("expected: bloom fill, actual: %f", status[0].BloomFill)
+		}
+		if expected, actual := (ActorStatus{Host: "b", Hash: 0}), status[1]; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("circuits", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			node    = mocks.NewMockNode(ctrl)
+			circuit = mocks.NewMockNode(ctrl)
+		)
+		node.EXPECT().Hash().Return(uint32(1)).AnyTimes()
+		circuit.EXPECT().Hash().Return(uint32(2)).AnyTimes()
+
+		actors := NewActors()
+		actors.Set(NewActor(func() nodes.Node {
+			return node
+		}))
+		actors.Set(NewActor(func() nodes.Node {
+			return circuitNode{MockNode: circuit, host: "a", open: true}
+		}))
+
+		if expected, actual := map[string]bool{"a": true}, actors.Circuits(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+// circuitNode is a node with a circuit in front of it.
+type circuitNode struct {
+	*mocks.MockNode
+	host string
+	open bool
+}
+
+func (n circuitNode) Host() string {
+	return n.host
+}
+
+func (n circuitNode) CircuitOpen() (bool, bool) {
+	return n.open, true
 }
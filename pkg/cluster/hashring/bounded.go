@@ -0,0 +1,235 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/spaolacci/murmur3"
+)
+
+const (
+	defaultPartitionCount = 271
+	defaultLoadFactor     = 1.25
+)
+
+// boundedPlacement implements consistent hashing with bounded loads. The key
+// space is split into a fixed number of partitions, and each partition is
+// placed on the first host on the ring that hasn't exceeded its capacity. The
+// capacity of a host is its weighted share of the partitions multiplied by the
+// load factor, so no host can own more than its bounded share of the keys.
+type boundedPlacement struct {
+	replicationFactor int
+	partitionCount    int
+	loadFactor        float64
+	hosts             map[string]int
+	points            []point
+	partitions        []string
+}
+
+type point struct {
+	hash uint32
+	host string
+}
+
+// NewBoundedPlacement creates a Placement that uses consistent hashing with
+// bounded loads. The load factor must be greater than 1, the closer to 1 the
+// more uniform the distribution, at the cost of moving more partitions when
+// the hosts change.
+func NewBoundedPlacement(replicationFactor, partitionCount int, loadFactor float64) Placement {
+	if loadFactor <= 1 {
+		loadFactor = defaultLoadFactor
+	}
+	return &boundedPlacement{
+		replicationFactor: replicationFactor,
+		partitionCount:    partitionCount,
+		loadFactor:        loadFactor,
+		hosts:             make(map[string]int),
+		partitions:        make([]string, partitionCount),
+	}
+}
+
+func (r *boundedPlacement) Add(host string, weight int) bool {
+	if _, ok := r.hosts[host]; ok {
+		return false
+	}
+
+	weight = normaliseWeight(weight)
+	r.hosts[host] = weight
+
+	for i := 0; i < r.replicationFactor*weight; i++ {
+		key := fmt.Sprintf("%s%d", host, i)
+		r.points = append(r.points, point{
+			hash: murmur3.Sum32([]byte(key)),
+			host: host,
+		})
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].host < r.points[j].host
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+
+	r.distribute()
+	return true
+}
+
+func (r *boundedPlacement) Remove(host string) bool {
+	if _, ok := r.hosts[host]; !ok {
+		return false
+	}
+
+	delete(r.hosts, host)
+
+	points := r.points[:0]
+	for _, v := range r.points {
+		if v.host != host {
+			points = append(points, v)
+		}
+	}
+	r.points = points
+
+	r.distribute()
+	return true
+}
+
+func (r *boundedPlacement) LookupN(key string, n int) []string {
+	if len(r.points) == 0 || n < 1 {
+		return []string{}
+	}
+
+	if n > len(r.hosts) {
+		n = len(r.hosts)
+	}
+
+	var (
+		partition = int(murmur3.Sum32([]byte(key)) % uint32(r.partitionCount))
+		owner     = r.partitions[partition]
+		res       = append(make([]string, 0, n), owner)
+		unique    = map[string]struct{}{owner: struct{}{}}
+	)
+
+	// Any subsequent replicas follow the owner around the ring.
+	idx := r.search(partitionHash(partition))
+	for i := 0; i < len(r.points) && len(res) < n; i++ {
+		host := r.points[(idx+i)%len(r.points)].host
+		if _, ok := unique[host]; ok {
+			continue
+		}
+		unique[host] = struct{}{}
+		res = append(res, host)
+	}
+	return res
+}
+
+func (r *boundedPlacement) Contains(host string) bool {
+	_, ok := r.hosts[host]
+	return ok
+}
+
+func (r *boundedPlacement) Weight(host string) int {
+	return r.hosts[host]
+}
+
+func (r *boundedPlacement) Hosts() []string {
+	res := make([]string, 0, len(r.hosts))
+	for k := range r.hosts {
+		res = append(res, k)
+	}
+	return res
+}
+
+func (r *boundedPlacement) Len() int {
+	return len(r.hosts)
+}
+
+// Ownership of a host is the number of partitions it owns.
+func (r *boundedPlacement) Ownership() []Ownership {
+	hosts := make(map[string]*Ownership, len(r.hosts))
+	for host, weight := range r.hosts {
+		hosts[host] = &Ownership{
+			Host:   host,
+			Weight: weight,
+		}
+	}
+
+	for _, v := range r.partitions {
+		if o, ok := hosts[v]; ok {
+			o.Points++
+		}
+	}
+	for _, v := range hosts {
+		v.Percentage = (float64(v.Points) / float64(r.partitionCount)) * 100
+	}
+
+	return sortOwnership(hosts)
+}
+
+func (r *boundedPlacement) Clone() Placement {
+	res := &boundedPlacement{
+		replicationFactor: r.replicationFactor,
+		partitionCount:    r.partitionCount,
+		loadFactor:        r.loadFactor,
+		hosts:             make(map[string]int, len(r.hosts)),
+		points:            make([]point, len(r.points)),
+		partitions:        make([]string, len(r.partitions)),
+	}
+	for host, weight := range r.hosts {
+		res.hosts[host] = weight
+	}
+	copy(res.points, r.points)
+	copy(res.partitions, r.partitions)
+	return res
+}
+
+// distribute places every partition on to the first host, walking around the
+// ring, that has capacity for it.
+func (r *boundedPlacement) distribute() {
+	if len(r.points) == 0 {
+		for k := range r.partitions {
+			r.partitions[k] = ""
+		}
+		return
+	}
+
+	var total int
+	for _, v := range r.hosts {
+		total += v
+	}
+
+	loads := make(map[string]int, len(r.hosts))
+	for partition := range r.partitions {
+		idx := r.search(partitionHash(partition))
+		for i := 0; i < len(r.points); i++ {
+			host := r.points[(idx+i)%len(r.points)].host
+			if loads[host] < r.capacity(host, total) {
+				loads[host]++
+				r.partitions[partition] = host
+				break
+			}
+		}
+	}
+}
+
+func (r *boundedPlacement) capacity(host string, total int) int {
+	share := float64(r.partitionCount) * float64(r.hosts[host]) / float64(total)
+	return int(math.Ceil(share * r.loadFactor))
+}
+
+// search returns the index of the first point that is greater or equal to the
+// hash, wrapping around the ring if required.
+func (r *boundedPlacement) search(hash uint32) int {
+	idx := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if idx >= len(r.points) {
+		return 0
+	}
+	return idx
+}
+
+func partitionHash(partition int) uint32 {
+	return murmur3.Sum32([]byte(strconv.Itoa(partition)))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,7 +12,9 @@ import (
 	"github.com/SimonRichardson/coherence/pkg/cluster"
 	"github.com/SimonRichardson/coherence/pkg/cluster/members"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
 	"github.com/SimonRichardson/resilience/clock"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -34,34 +37,69 @@ type Cluster struct {
 	mutex        sync.RWMutex
 	peer         cluster.Peer
 	transport    api.TransportStrategy
+	store        store.Store
 	localAPIAddr string
 	localAPIHash uint32
 	ring         *HashRing
+	leaving      map[string]struct{}
 	actors       *Actors
 	times        map[uint32]clock.Time
 	timesMutex   sync.RWMutex
+	topology     chan Topology
+	changes      metrics.CounterVec
+	fill         metrics.GaugeVec
 	stop         chan chan struct{}
 	logger       log.Logger
 }
 
-// NewCluster creates a Cluster with the correct dependencies
+// Option defines a option for configuring the Cluster
+type Option func(*Cluster)
+
+// WithMembershipChanges counts the hosts that join or leave the hash ring, or
+// change their weight, labelled by the change.
+func WithMembershipChanges(changes metrics.CounterVec) Option {
+	return func(n *Cluster) {
+		n.changes = changes
+	}
+}
+
+// WithBloomFill reports the fill ratio of the bloom of each host, labelled by
+// the host, every time the blooms are broadcast.
+func WithBloomFill(fill metrics.GaugeVec) Option {
+	return func(n *Cluster) {
+		n.fill = fill
+	}
+}
+
+// NewCluster creates a Cluster with the correct dependencies. The placement
+// decides which hosts own a key with in the cluster. The local node is served
+// directly from the store, rather than through the transport.
 func NewCluster(peer cluster.Peer,
 	transport api.TransportStrategy,
-	replicationFactor int,
+	placement Placement,
+	store store.Store,
 	localAPIAddr string,
 	logger log.Logger,
+	opts ...Option,
 ) *Cluster {
-	return &Cluster{
+	n := &Cluster{
 		peer:         peer,
 		transport:    transport,
+		store:        store,
 		localAPIAddr: localAPIAddr,
 		localAPIHash: murmur3.Sum32([]byte(localAPIAddr)),
-		ring:         NewHashRing(replicationFactor),
+		ring:         NewHashRingWithPlacement(placement),
+		leaving:      make(map[string]struct{}),
 		actors:       NewActors(),
 		times:        make(map[uint32]clock.Time),
+		topology:     make(chan Topology, 1),
 		stop:         make(chan chan struct{}),
 		logger:       logger,
 	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
 }
 
 // Run the Cluster snapshot selection process, this is required to get a valid
@@ -85,7 +123,7 @@ func (n *Cluster) Run() error {
 	for {
 		select {
 		case <-discoveryTicker.C:
-			hosts, err := n.peer.Current(cluster.PeerTypeStore, true)
+			hosts, err := n.peer.Weights(cluster.PeerTypeStore, true)
 			if err != nil {
 				continue
 			}
@@ -93,10 +131,17 @@ func (n *Cluster) Run() error {
 				return err
 			}
 
+			leaving, err := n.peer.Leaving(cluster.PeerTypeStore)
+			if err != nil {
+				continue
+			}
+			n.updateLeaving(leaving)
+
 		case <-broadcastTicker.C:
 			for _, v := range n.actors.Hashes() {
 				n.dispatchBloomEvent(v)
 			}
+			n.observeBloomFill()
 
 		case c := <-n.stop:
 			close(c)
@@ -122,8 +167,13 @@ func (n *Cluster) DeregisterEventHandler(fn members.EventHandler) error {
 	return n.peer.DeregisterEventHandler(fn)
 }
 
+// Write returns a set of nodes that the key should be written to. Any nodes
+// that are leaving the cluster are not written to, so that their data can be
+// drained to the remaining nodes.
 func (n *Cluster) Write(key selectors.Key, quorum selectors.Quorum) ([]nodes.Node, func([]uint32) error) {
-	res := n.Read(key, quorum)
+	n.mutex.RLock()
+	res := n.lookup(key, quorum, n.leaving)
+	n.mutex.RUnlock()
 
 	// Once finished, we commit the key to the bloom.
 	return res, func(h []uint32) error {
@@ -144,34 +194,44 @@ func (n *Cluster) Write(key selectors.Key, quorum selectors.Quorum) ([]nodes.Nod
 // the Read are not guaranteed to succeed for longer than their purpose.
 // It is not recommended to store the nodes locally as they may not be the same
 // nodes over time.
-func (n *Cluster) Read(key selectors.Key, quorum selectors.Quorum) (nodes []nodes.Node) {
+func (n *Cluster) Read(key selectors.Key, quorum selectors.Quorum) []nodes.Node {
 	n.mutex.RLock()
 	defer n.mutex.RUnlock()
 
+	// Nodes that are leaving the cluster can still be read from, as they might
+	// be the only nodes that hold the key until they've been drained.
+	return n.lookup(key, quorum, nil)
+}
+
+func (n *Cluster) lookup(key selectors.Key, quorum selectors.Quorum, exclude map[string]struct{}) (nodes []nodes.Node) {
 	var (
 		hosts []string
 		k     = key.String()
 	)
 	switch quorum {
 	case selectors.One:
-		hosts = n.filter(n.shuffle(), k)
+		hosts = n.filter(without(n.shuffle(), exclude), k)
 		if len(hosts) > 0 {
 			hosts = hosts[:1]
 		}
 
 	case selectors.Strong:
-		hosts = n.shuffle()
+		hosts = without(n.shuffle(), exclude)
 
 	case selectors.Consensus:
 		// For consensus, we would like to attempt to get hosts that have at least
-		// a chance of knowing the key exists.
+		// a chance of knowing the key exists. Look past any excluded hosts, so
+		// that the next owners on the ring are used instead.
 		min := (n.ring.Len() / 2) + 1
-		hosts = n.ring.LookupN(k, min)
+		hosts = without(n.ring.LookupN(k, min+len(exclude)), exclude)
+		if len(hosts) > min {
+			hosts = hosts[:min]
+		}
 		hosts = n.filter(hosts, k)
 
 		// If there isn't enough hosts, attempt to brute force a get.
 		if len(hosts) < min {
-			for _, v := range n.ring.Hosts() {
+			for _, v := range without(n.ring.Hosts(), exclude) {
 				if contains(hosts, v) {
 					continue
 				}
@@ -214,30 +274,116 @@ func (n *Cluster) shuffle() (res []string) {
 	return
 }
 
-func (n *Cluster) updateRemoteActors(hosts []string) error {
+// Ownership returns the percentage of the hashring that each host owns.
+func (n *Cluster) Ownership() []Ownership {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.ring.Ownership()
+}
+
+// Leaving returns the hosts that are leaving the cluster.
+func (n *Cluster) Leaving() []string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	res := make([]string, 0, len(n.leaving))
+	for k := range n.leaving {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Actors returns the status of the actor of each host, ordered by host.
+func (n *Cluster) Actors() []ActorStatus {
+	return n.actors.Status()
+}
+
+// Circuits returns if the circuit in front of each host is open, keyed by
+// host.
+func (n *Cluster) Circuits() map[string]bool {
+	return n.actors.Circuits()
+}
+
+// DrainTopology returns the topology of the cluster once the local node has
+// left the cluster.
+func (n *Cluster) DrainTopology() Topology {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	after := n.ring.placement.Clone()
+	after.Remove(n.localAPIAddr)
+
+	return Topology{
+		Before: n.ring.placement.Clone(),
+		After:  after,
+	}
+}
+
+// LocalHost returns the API host of the local node.
+func (n *Cluster) LocalHost() string {
+	return n.localAPIAddr
+}
+
+// Node returns the node for the API host, if it exists with in the cluster.
+func (n *Cluster) Node(host string) (nodes.Node, bool) {
+	actor, ok := n.actors.Get(hash(host))
+	if !ok {
+		return nil, false
+	}
+	return actor.node, true
+}
+
+// Topology returns a channel that's notified when hosts are added or removed
+// from the hashring.
+func (n *Cluster) Topology() <-chan Topology {
+	return n.topology
+}
+
+func (n *Cluster) updateRemoteActors(hosts map[string]int) error {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
 
+	// Keep a copy of the current topology, so any changes to the ownership of
+	// keys can be calculated.
+	var before Placement
+	if n.topologyChanged(hosts) {
+		before = n.ring.placement.Clone()
+	}
+
 	// Remove any dropped nodes
-	for k := range n.ring.hosts {
-		if !contains(hosts, k) {
+	for _, k := range n.ring.Hosts() {
+		if _, ok := hosts[k]; !ok {
 			n.ring.Remove(k)
 			n.actors.Remove(hash(k))
+			n.changed("left")
+			if n.fill != nil {
+				n.fill.DeleteLabelValues(k)
+			}
 		}
 	}
 
 	// Add if it doesn't already exist
 	var addition bool
-	for _, v := range hosts {
+	for v, weight := range hosts {
+		weight = normaliseWeight(weight)
+
 		if n.ring.Contains(v) {
+			// If the weight of a host has changed, then move the points on the
+			// ring, but keep the actor as the node is still the same.
+			if n.ring.Weight(v) != weight {
+				n.ring.Remove(v)
+				n.ring.AddWeighted(v, weight)
+				n.changed("reweighted")
+			}
 			continue
 		}
 
-		if ok := n.ring.Add(v); ok {
+		if ok := n.ring.AddWeighted(v, weight); ok {
 			addition = true
-			n.actors.Set(NewActor(func() nodes.Node {
-				return nodes.NewRemote(n.transport.Apply(v))
-			}))
+			n.actors.Set(NewActor(n.nodeFor(v)))
+			n.changed("joined")
 		}
 	}
 
@@ -246,10 +392,95 @@ func (n *Cluster) updateRemoteActors(hosts []string) error {
 		n.dispatchBloomEvent(n.localAPIHash)
 	}
 
+	if before != nil {
+		n.notifyTopology(Topology{
+			Before: before,
+			After:  n.ring.placement.Clone(),
+		})
+	}
+
 	// Go through and make sure that we have all the nodes in the ring.
 	return nil
 }
 
+// changed counts a change to the membership of the hash ring.
+func (n *Cluster) changed(change string) {
+	if n.changes != nil {
+		n.changes.WithLabelValues(change).Inc()
+	}
+}
+
+// observeBloomFill reports the fill ratio of the bloom of each host, the more
+// it fills, the more often the lookups are sent to hosts without the key.
+func (n *Cluster) observeBloomFill() {
+	if n.fill == nil {
+		return
+	}
+	for _, v := range n.actors.Hashes() {
+		if actor, ok := n.actors.Get(v); ok {
+			n.fill.WithLabelValues(actor.Host()).Set(actor.FillRatio())
+		}
+	}
+}
+
+// nodeFor returns a constructor for the node of a host. The local host uses a
+// virtual node, so that the local replica doesn't talk to itself over the
+// transport.
+func (n *Cluster) nodeFor(host string) NodeStrategy {
+	if hash(host) == n.localAPIHash {
+		return func() nodes.Node {
+			return nodes.NewVirtual(n.localAPIHash, n.localAPIAddr, n.store)
+		}
+	}
+	return func() nodes.Node {
+		return nodes.NewRemote(n.transport.Apply(host))
+	}
+}
+
+func (n *Cluster) updateLeaving(hosts []string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	leaving := make(map[string]struct{}, len(hosts))
+	for _, v := range hosts {
+		if n.ring.Contains(v) {
+			leaving[v] = struct{}{}
+		}
+	}
+	n.leaving = leaving
+}
+
+func (n *Cluster) topologyChanged(hosts map[string]int) bool {
+	if len(hosts) != n.ring.Len() {
+		return true
+	}
+	for k, v := range hosts {
+		if n.ring.Weight(k) != normaliseWeight(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyTopology sends the topology without blocking. If there is already a
+// pending topology that hasn't been received, then both are merged so that
+// none of the changes are lost.
+func (n *Cluster) notifyTopology(topology Topology) {
+	for {
+		select {
+		case n.topology <- topology:
+			return
+		default:
+		}
+
+		select {
+		case pending := <-n.topology:
+			topology.Before = pending.Before
+		default:
+		}
+	}
+}
+
 func (n *Cluster) dispatchBloomEvent(hash uint32) {
 	// Every new addition to the actor ring, send an bloom filter event.
 	// Note: under network issues we should throttle this so it doesn't become
@@ -354,6 +585,20 @@ func contains(a []string, b string) bool {
 	return false
 }
 
+func without(a []string, exclude map[string]struct{}) []string {
+	if len(exclude) == 0 {
+		return a
+	}
+
+	res := make([]string, 0, len(a))
+	for _, v := range a {
+		if _, ok := exclude[v]; !ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
 func hash(data string) uint32 {
 	return murmur3.Sum32([]byte(data))
 }
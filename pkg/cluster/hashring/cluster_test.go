@@ -15,9 +15,12 @@ import (
 	"github.com/SimonRichardson/coherence/pkg/cluster/mocks"
 	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
 	nodeMocks "github.com/SimonRichardson/coherence/pkg/cluster/nodes/mocks"
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	storeMocks "github.com/SimonRichardson/coherence/pkg/store/mocks"
 	"github.com/go-kit/kit/log"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spaolacci/murmur3"
 )
 
@@ -31,7 +34,7 @@ func TestClusterRead(t *testing.T) {
 		peer := mocks.NewMockPeer(ctrl)
 		strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-		cluster := NewCluster(peer, strategy, 3, "0.0.0.0:9090", log.NewNopLogger())
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:9090", log.NewNopLogger())
 		nodes := cluster.Read(selectors.Key("a"), selectors.Strong)
 
 		if expected, actual := 0, len(nodes); expected != actual {
@@ -53,11 +56,11 @@ func TestClusterRead(t *testing.T) {
 		strategy.EXPECT().Apply("0.0.0.0:8080").Return(transport)
 		strategy.EXPECT().Apply("0.0.0.0:8081").Return(transport)
 
-		cluster := NewCluster(peer, strategy, 3, "0.0.0.0:9090", log.NewNopLogger())
-		cluster.updateRemoteActors([]string{
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:9090", log.NewNopLogger())
+		cluster.updateRemoteActors(uniform([]string{
 			"0.0.0.0:8080",
 			"0.0.0.0:8081",
-		})
+		}))
 
 		nodes := cluster.Read(selectors.Key("a"), selectors.Strong)
 		if expected, actual := []uint32{
@@ -82,15 +85,15 @@ func TestClusterRead(t *testing.T) {
 		strategy.EXPECT().Apply("0.0.0.0:8080").Return(transport)
 		strategy.EXPECT().Apply("0.0.0.0:8081").Return(transport)
 
-		cluster := NewCluster(peer, strategy, 3, "0.0.0.0:9090", log.NewNopLogger())
-		cluster.updateRemoteActors([]string{
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:9090", log.NewNopLogger())
+		cluster.updateRemoteActors(uniform([]string{
 			"0.0.0.0:8080",
 			"0.0.0.0:8081",
-		})
-		cluster.updateRemoteActors([]string{
+		}))
+		cluster.updateRemoteActors(uniform([]string{
 			"0.0.0.0:8080",
 			"0.0.0.0:8081",
-		})
+		}))
 
 		nodes := cluster.Read(selectors.Key("a"), selectors.Strong)
 		if expected, actual := []uint32{
@@ -112,7 +115,7 @@ func TestClusterWrite(t *testing.T) {
 		peer := mocks.NewMockPeer(ctrl)
 		strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-		cluster := NewCluster(peer, strategy, 3, "0.0.0.0:9090", log.NewNopLogger())
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:9090", log.NewNopLogger())
 		nodes, _ := cluster.Write(selectors.Key("a"), selectors.Strong)
 
 		if expected, actual := 0, len(nodes); expected != actual {
@@ -136,11 +139,11 @@ func TestClusterWrite(t *testing.T) {
 		strategy.EXPECT().Apply("0.0.0.0:8080").Return(transport)
 		strategy.EXPECT().Apply("0.0.0.0:8081").Return(transport)
 
-		cluster := NewCluster(peer, strategy, 3, "0.0.0.0:9090", log.NewNopLogger())
-		cluster.updateRemoteActors([]string{
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:9090", log.NewNopLogger())
+		cluster.updateRemoteActors(uniform([]string{
 			"0.0.0.0:8080",
 			"0.0.0.0:8081",
-		})
+		}))
 
 		want := []uint32{
 			a,
@@ -173,15 +176,15 @@ func TestClusterWrite(t *testing.T) {
 		strategy.EXPECT().Apply("0.0.0.0:8080").Return(transport)
 		strategy.EXPECT().Apply("0.0.0.0:8081").Return(transport)
 
-		cluster := NewCluster(peer, strategy, 3, "0.0.0.0:9090", log.NewNopLogger())
-		cluster.updateRemoteActors([]string{
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:9090", log.NewNopLogger())
+		cluster.updateRemoteActors(uniform([]string{
 			"0.0.0.0:8080",
 			"0.0.0.0:8081",
-		})
-		cluster.updateRemoteActors([]string{
+		}))
+		cluster.updateRemoteActors(uniform([]string{
 			"0.0.0.0:8080",
 			"0.0.0.0:8081",
-		})
+		}))
 
 		want := []uint32{
 			a,
@@ -218,7 +221,7 @@ func TestDispatchBloomEvent(t *testing.T) {
 
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.actors.Set(actor)
 			cluster.times[hash] = actor.clock.Now()
 
@@ -263,7 +266,7 @@ func TestDispatchBloomEvent(t *testing.T) {
 
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.actors.Set(actor)
 
 			cluster.dispatchBloomEvent(hash)
@@ -283,7 +286,7 @@ func TestDispatchBloomEvent(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.dispatchBloomEvent(hash)
 
 			return true
@@ -294,9 +297,159 @@ func TestDispatchBloomEvent(t *testing.T) {
 	})
 }
 
+func TestClusterLeaving(t *testing.T) {
+	t.Parallel()
+
+	hosts := []string{
+		"0.0.0.0:8080",
+		"0.0.0.0:8081",
+		"0.0.0.0:8082",
+	}
+
+	newCluster := func(ctrl *gomock.Controller) *Cluster {
+		// The local node is part of the ring, so it'll broadcast its bloom.
+		peer := mocks.NewMockPeer(ctrl)
+		peer.EXPECT().Name().Return("local").AnyTimes()
+		peer.EXPECT().DispatchEvent(gomock.Any()).Return(nil).AnyTimes()
+
+		// The local node doesn't use the transport.
+		strategy := apiMocks.NewMockTransportStrategy(ctrl)
+		for _, v := range hosts[:2] {
+			transport := apiMocks.NewMockTransport(ctrl)
+			transport.EXPECT().Hash().Return(murmur3.Sum32([]byte(v))).AnyTimes()
+			strategy.EXPECT().Apply(v).Return(transport)
+		}
+
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), nil, "0.0.0.0:8082", log.NewNopLogger())
+		cluster.updateRemoteActors(uniform(hosts))
+		cluster.updateLeaving([]string{"0.0.0.0:8082", "0.0.0.0:9999"})
+		return cluster
+	}
+
+	t.Run("leaving", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cluster := newCluster(ctrl)
+
+		// Hosts that aren't in the ring are ignored.
+		if expected, actual := []string{"0.0.0.0:8082"}, cluster.Leaving(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("write strong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cluster := newCluster(ctrl)
+
+		nodes, _ := cluster.Write(selectors.Key("a"), selectors.Strong)
+		if expected, actual := []uint32{
+			murmur3.Sum32([]byte("0.0.0.0:8080")),
+			murmur3.Sum32([]byte("0.0.0.0:8081")),
+		}, extractAddresses(nodes); !match(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("write consensus", func(t *testing.T) {
+		fn := func(key selectors.Key) bool {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cluster := newCluster(ctrl)
+
+			nodes, _ := cluster.Write(key, selectors.Consensus)
+			for _, v := range extractAddresses(nodes) {
+				if v == murmur3.Sum32([]byte("0.0.0.0:8082")) {
+					return false
+				}
+			}
+			return true
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("read strong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cluster := newCluster(ctrl)
+
+		nodes := cluster.Read(selectors.Key("a"), selectors.Strong)
+		if expected, actual := []uint32{
+			murmur3.Sum32([]byte("0.0.0.0:8080")),
+			murmur3.Sum32([]byte("0.0.0.0:8081")),
+			murmur3.Sum32([]byte("0.0.0.0:8082")),
+		}, extractAddresses(nodes); !match(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("drain topology", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cluster := newCluster(ctrl)
+
+		topology := cluster.DrainTopology()
+		if expected, actual := []string{"0.0.0.0:8082"}, topology.Removed(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := 3, cluster.ring.Len(); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
 func TestUpdateRemoteActors(t *testing.T) {
 	t.Parallel()
 
+	t.Run("update - local node is virtual", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			local  = "0.0.0.0:8080"
+			keys   = []selectors.Key{"a", "b"}
+			peer   = mocks.NewMockPeer(ctrl)
+			store  = storeMocks.NewMockStore(ctrl)
+			remote = apiMocks.NewMockTransport(ctrl)
+		)
+		peer.EXPECT().Name().Return("local").AnyTimes()
+		peer.EXPECT().DispatchEvent(gomock.Any()).Return(nil).AnyTimes()
+
+		remote.EXPECT().Hash().Return(murmur3.Sum32([]byte("0.0.0.0:8081")))
+
+		strategy := apiMocks.NewMockTransportStrategy(ctrl)
+		strategy.EXPECT().Apply("0.0.0.0:8081").Return(remote)
+
+		store.EXPECT().Keys().Return(keys, nil)
+
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(3), store, local, log.NewNopLogger())
+		cluster.updateRemoteActors(uniform([]string{local, "0.0.0.0:8081"}))
+
+		node, ok := cluster.Node(local)
+		if !ok {
+			t.Fatal("expected local node")
+		}
+
+		for element := range node.Keys() {
+			if err := selectors.ErrorFromElement(element); err != nil {
+				t.Fatal(err)
+			}
+			if expected, actual := murmur3.Sum32([]byte(local)), element.Hash(); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+			if expected, actual := keys, selectors.KeysFromElement(element); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+	})
+
 	t.Run("update", func(t *testing.T) {
 		fn := func(host string) bool {
 			hosts := []string{host}
@@ -307,13 +460,13 @@ func TestUpdateRemoteActors(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			for _, v := range hosts {
 				if ok := cluster.ring.Add(v); !ok {
 					t.Fatalf("expected valid %v %s", cluster.ring.Hosts(), v)
 				}
 			}
-			return cluster.updateRemoteActors(hosts) == nil
+			return cluster.updateRemoteActors(uniform(hosts)) == nil
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
@@ -334,14 +487,119 @@ func TestUpdateRemoteActors(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.ring.Add(old)
 			for _, v := range hosts {
 				if ok := cluster.ring.Add(v); !ok {
 					t.Fatalf("expected valid %v %s", cluster.ring.Hosts(), v)
 				}
 			}
-			return cluster.updateRemoteActors(hosts) == nil
+			return cluster.updateRemoteActors(uniform(hosts)) == nil
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("update - notifies topology", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		peer := mocks.NewMockPeer(ctrl)
+		transport := apiMocks.NewMockTransport(ctrl)
+		transport.EXPECT().Hash().Return(murmur3.Sum32([]byte("0.0.0.0:8080"))).AnyTimes()
+
+		strategy := apiMocks.NewMockTransportStrategy(ctrl)
+		strategy.EXPECT().Apply(gomock.Any()).Return(transport).AnyTimes()
+
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
+		cluster.updateRemoteActors(uniform([]string{"0.0.0.0:8080"}))
+		cluster.updateRemoteActors(uniform([]string{"0.0.0.0:8080", "0.0.0.0:8081"}))
+
+		// Both of the changes should be merged in to one topology.
+		topology := <-cluster.Topology()
+		if expected, actual := []string{"0.0.0.0:8080", "0.0.0.0:8081"}, topology.Added(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		// No changes, shouldn't notify.
+		cluster.updateRemoteActors(uniform([]string{"0.0.0.0:8080", "0.0.0.0:8081"}))
+		select {
+		case <-cluster.Topology():
+			t.Errorf("expected no topology change")
+		default:
+		}
+	})
+
+	t.Run("update - membership metrics", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		peer := mocks.NewMockPeer(ctrl)
+		strategy := apiMocks.NewMockTransportStrategy(ctrl)
+		for _, host := range []string{"0.0.0.0:8080", "0.0.0.0:8081"} {
+			transport := apiMocks.NewMockTransport(ctrl)
+			transport.EXPECT().Hash().Return(murmur3.Sum32([]byte(host))).AnyTimes()
+			transport.EXPECT().Host().Return(host).AnyTimes()
+			strategy.EXPECT().Apply(host).Return(transport)
+		}
+
+		var (
+			joined = prometheus.NewCounter(prometheus.CounterOpts{Name: "joined"})
+			left   = prometheus.NewCounter(prometheus.CounterOpts{Name: "left"})
+			gauge  = &gaugeValue{Gauge: prometheus.NewGauge(prometheus.GaugeOpts{Name: "fill"})}
+
+			changes = metricMocks.NewMockCounterVec(ctrl)
+			fill    = metricMocks.NewMockGaugeVec(ctrl)
+		)
+		changes.EXPECT().WithLabelValues("joined").Return(joined).Times(2)
+		changes.EXPECT().WithLabelValues("left").Return(left)
+		fill.EXPECT().DeleteLabelValues("0.0.0.0:8081").Return(true)
+		fill.EXPECT().WithLabelValues("0.0.0.0:8080").Return(gauge)
+
+		cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger(),
+			WithMembershipChanges(changes),
+			WithBloomFill(fill),
+		)
+		cluster.updateRemoteActors(uniform([]string{"0.0.0.0:8080", "0.0.0.0:8081"}))
+		cluster.updateRemoteActors(uniform([]string{"0.0.0.0:8080"}))
+
+		actor, ok := cluster.actors.Get(murmur3.Sum32([]byte("0.0.0.0:8080")))
+		if !ok {
+			t.Fatal("expected actor")
+		}
+		if err := actor.Add("a"); err != nil {
+			t.Fatal(err)
+		}
+		cluster.observeBloomFill()
+
+		if expected, actual := actor.FillRatio(), gauge.value; expected != actual || actual == 0 {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("update - change weight", func(t *testing.T) {
+		fn := func(host string, weight uint8) bool {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			peer := mocks.NewMockPeer(ctrl)
+			strategy := apiMocks.NewMockTransportStrategy(ctrl)
+
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
+			cluster.ring.Add(host)
+
+			w := int(weight%8) + 1
+			if err := cluster.updateRemoteActors(map[string]int{
+				host: w,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			ownership := cluster.Ownership()
+			return len(ownership) == 1 &&
+				ownership[0].Weight == w &&
+				ownership[0].Points == 4*w
 		}
 		if err := quick.Check(fn, nil); err != nil {
 			t.Error(err)
@@ -373,7 +631,7 @@ func TestFilter(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.actors = actors
 			return len(cluster.filter(hosts, hosts[0])) == 1
 		}
@@ -390,7 +648,7 @@ func TestFilter(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			return len(cluster.filter(hosts, key)) == 0
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -417,7 +675,7 @@ func TestActorTimeIsIncremented(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			return cluster.actorTimeIncremented(actor)
 		}
 		if err := quick.Check(fn, nil); err != nil {
@@ -440,7 +698,7 @@ func TestActorTimeIsIncremented(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.times[hash] = actor.clock.Now()
 			actor.clock.Increment()
 			return cluster.actorTimeIncremented(actor)
@@ -465,7 +723,7 @@ func TestActorTimeIsIncremented(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.times[hash] = actor.clock.Now()
 			return !cluster.actorTimeIncremented(actor)
 		}
@@ -496,7 +754,7 @@ func TestStoreActorTime(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.actors = actors
 
 			cluster.storeActorTime(hash)
@@ -517,7 +775,7 @@ func TestStoreActorTime(t *testing.T) {
 			peer := mocks.NewMockPeer(ctrl)
 			strategy := apiMocks.NewMockTransportStrategy(ctrl)
 
-			cluster := NewCluster(peer, strategy, 4, "", log.NewNopLogger())
+			cluster := NewCluster(peer, strategy, NewConsistentPlacement(4), nil, "", log.NewNopLogger())
 			cluster.actors = actors
 
 			cluster.storeActorTime(hash)
@@ -529,6 +787,24 @@ func TestStoreActorTime(t *testing.T) {
 	})
 }
 
+// gaugeValue records the value that the gauge is set to.
+type gaugeValue struct {
+	prometheus.Gauge
+	value float64
+}
+
+func (g *gaugeValue) Set(value float64) {
+	g.value = value
+}
+
+func uniform(hosts []string) map[string]int {
+	res := make(map[string]int, len(hosts))
+	for _, v := range hosts {
+		res[v] = DefaultWeight
+	}
+	return res
+}
+
 func extractAddresses(nodes []nodes.Node) []uint32 {
 	res := make([]uint32, 0)
 	for _, v := range nodes {
@@ -0,0 +1,170 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/rbtree"
+	"github.com/spaolacci/murmur3"
+)
+
+// consistentPlacement stores strings on a consistent hash ring. It internally
+// uses Red-Black Tree to achieve O(log N) lookup and insertion time.
+type consistentPlacement struct {
+	replicationFactor int
+	hosts             map[string]int
+	tree              *rbtree.RBTree
+}
+
+// NewConsistentPlacement creates a Placement that uses consistent hashing,
+// where each host is replicated around the ring according to the replication
+// factor scaled by the weight of the host.
+func NewConsistentPlacement(replicationFactor int) Placement {
+	return &consistentPlacement{
+		replicationFactor: replicationFactor,
+		hosts:             make(map[string]int, 0),
+		tree:              rbtree.NewRBTree(),
+	}
+}
+
+// Add a host and replicates it around the hashring according to the
+// replication factor and the weight. A host with a weight of 2 will own roughly
+// twice as many keys as a host with a weight of 1.
+// Returns true if an insertion happens for all replicated points
+func (r *consistentPlacement) Add(host string, weight int) bool {
+	if _, ok := r.hosts[host]; ok {
+		return false
+	}
+
+	weight = normaliseWeight(weight)
+	r.hosts[host] = weight
+
+	added := true
+	for i := 0; i < r.replicationFactor*weight; i++ {
+		var (
+			key  = fmt.Sprintf("%s%d", host, i)
+			hash = murmur3.Sum32([]byte(key))
+		)
+		added = added && r.tree.Insert(int(hash), host)
+	}
+
+	return added
+}
+
+// Remove a host from the hashring including all the subsequent replicated
+// hosts.
+// Returns true if a deletion happens to all the replicated points
+func (r *consistentPlacement) Remove(host string) bool {
+	weight, ok := r.hosts[host]
+	if !ok {
+		return false
+	}
+
+	removed := true
+	for i := 0; i < r.replicationFactor*weight; i++ {
+		var (
+			key  = fmt.Sprintf("%s%d", host, i)
+			hash = murmur3.Sum32([]byte(key))
+		)
+		removed = removed && r.tree.Delete(int(hash))
+	}
+
+	delete(r.hosts, host)
+
+	return removed
+}
+
+// LookupN returns the N servers that own the given key. Duplicates in the form
+// of virtual nodes are skipped to maintain a list of unique servers. If there
+// are less servers than N, we simply return all existing servers.
+func (r *consistentPlacement) LookupN(key string, n int) []string {
+	hash := murmur3.Sum32([]byte(key))
+	return r.tree.LookupNUniqueAt(n, int(hash))
+}
+
+// Contains checks to see if a key is already in the ring.
+// Returns true if a key is found with in the ring.
+func (r *consistentPlacement) Contains(key string) bool {
+	if _, ok := r.hosts[key]; ok {
+		return true
+	}
+
+	hash := murmur3.Sum32([]byte(key))
+	_, ok := r.tree.Search(int(hash))
+	return ok
+}
+
+// Weight returns the weight of the host with in the ring. If the host isn't
+// found, then 0 is returned.
+func (r *consistentPlacement) Weight(host string) int {
+	return r.hosts[host]
+}
+
+// Hosts returns the hosts in a slice.
+func (r *consistentPlacement) Hosts() []string {
+	var (
+		idx int
+		res = make([]string, len(r.hosts))
+	)
+	for k := range r.hosts {
+		res[idx] = k
+		idx++
+	}
+	return res
+}
+
+// Len returns the number of unique hosts
+func (r *consistentPlacement) Len() int {
+	return len(r.hosts)
+}
+
+// Ownership returns the percentage of the hashring that each host owns, sorted
+// by host. A key is owned by the first point that is greater or equal to the
+// hash of the key, so each point owns the range between itself and the point
+// before it.
+func (r *consistentPlacement) Ownership() []Ownership {
+	var (
+		hosts  = make(map[string]*Ownership, len(r.hosts))
+		values []string
+		keys   []int
+	)
+	for host, weight := range r.hosts {
+		hosts[host] = &Ownership{
+			Host:   host,
+			Weight: weight,
+		}
+	}
+
+	r.tree.Walk(func(key int, value string) {
+		keys = append(keys, key)
+		values = append(values, value)
+	})
+
+	const total = float64(math.MaxUint32) + 1
+	for k, v := range keys {
+		var size float64
+		if k == 0 {
+			// The first point also owns the range that wraps around the ring.
+			size = float64(v) + (total - float64(keys[len(keys)-1]))
+		} else {
+			size = float64(v - keys[k-1])
+		}
+
+		if o, ok := hosts[values[k]]; ok {
+			o.Points++
+			o.Percentage += (size / total) * 100
+		}
+	}
+
+	return sortOwnership(hosts)
+}
+
+// Clone returns a copy of the hashring, by replaying the hosts on to a new
+// ring.
+func (r *consistentPlacement) Clone() Placement {
+	res := NewConsistentPlacement(r.replicationFactor)
+	for host, weight := range r.hosts {
+		res.Add(host, weight)
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+package hashring
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster"
+	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// DecommissionState describes the state of the decommissioning of the local
+// node.
+type DecommissionState string
+
+const (
+	// DecommissionIdle is when the node hasn't been decommissioned.
+	DecommissionIdle DecommissionState = "idle"
+
+	// DecommissionLeaving is when the node has advertised that it's leaving and
+	// is waiting for the rest of the cluster to stop writing to it.
+	DecommissionLeaving DecommissionState = "leaving"
+
+	// DecommissionDraining is when the keys of the node are being streamed to
+	// the remaining nodes.
+	DecommissionDraining DecommissionState = "draining"
+
+	// DecommissionLeft is when the node has drained and left the cluster.
+	DecommissionLeft DecommissionState = "left"
+
+	// DecommissionFailed is when the node couldn't be decommissioned.
+	DecommissionFailed DecommissionState = "failed"
+)
+
+// DecommissionStatus describes the status of the decommissioning of the local
+// node.
+type DecommissionStatus struct {
+	State    DecommissionState `json:"state"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// DrainCluster describes the topology of a cluster, once the local node has
+// left it.
+type DrainCluster interface {
+	// DrainTopology returns the topology of the cluster once the local node has
+	// left the cluster.
+	DrainTopology() Topology
+}
+
+// Drainer streams all the keys of the local node to the remaining nodes.
+type Drainer interface {
+	// Drain streams every key to the owners with in the new topology.
+	Drain(Topology) error
+}
+
+// Decommissioner gracefully removes the local node from the cluster. The node
+// is first marked as leaving, so that no more writes are routed to it, then
+// all of its keys are streamed to the remaining replicas before it finally
+// leaves the cluster.
+type Decommissioner struct {
+	peer    cluster.Peer
+	cluster DrainCluster
+	drainer Drainer
+	grace   time.Duration
+	mutex   sync.RWMutex
+	status  DecommissionStatus
+	logger  log.Logger
+}
+
+// NewDecommissioner creates a Decommissioner, which waits for the grace period
+// after marking the node as leaving before it starts draining the keys, so
+// that the rest of the cluster has time to stop writing to it.
+func NewDecommissioner(peer cluster.Peer,
+	cluster DrainCluster,
+	drainer Drainer,
+	grace time.Duration,
+	logger log.Logger,
+) *Decommissioner {
+	return &Decommissioner{
+		peer:    peer,
+		cluster: cluster,
+		drainer: drainer,
+		grace:   grace,
+		status: DecommissionStatus{
+			State: DecommissionIdle,
+		},
+		logger: logger,
+	}
+}
+
+// Decommission starts decommissioning the local node in the background. It
+// errors if the node is already being decommissioned or has left.
+func (d *Decommissioner) Decommission() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch d.status.State {
+	case DecommissionIdle, DecommissionFailed:
+	default:
+		return errors.Errorf("node is already %s", d.status.State)
+	}
+
+	if err := d.peer.SetStatus(members.PeerStatusLeaving); err != nil {
+		return errors.Wrap(err, "set status")
+	}
+
+	d.status = DecommissionStatus{
+		State:   DecommissionLeaving,
+		Started: time.Now(),
+	}
+
+	go d.decommission()
+
+	return nil
+}
+
+// Status returns the status of the decommissioning of the local node.
+func (d *Decommissioner) Status() DecommissionStatus {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.status
+}
+
+func (d *Decommissioner) decommission() {
+	time.Sleep(d.grace)
+
+	d.update(DecommissionDraining, nil)
+
+	if err := d.drainer.Drain(d.cluster.DrainTopology()); err != nil {
+		d.fail(errors.Wrap(err, "drain"))
+		return
+	}
+
+	if err := d.peer.Leave(); err != nil {
+		d.fail(errors.Wrap(err, "leave"))
+		return
+	}
+
+	d.update(DecommissionLeft, nil)
+}
+
+func (d *Decommissioner) fail(err error) {
+	level.Error(d.logger).Log("err", err)
+
+	// Advertise that the node is alive again, so that writes are routed back to
+	// it, as it's still part of the cluster.
+	if e := d.peer.SetStatus(members.PeerStatusAlive); e != nil {
+		level.Error(d.logger).Log("err", e)
+	}
+
+	d.update(DecommissionFailed, err)
+}
+
+func (d *Decommissioner) update(state DecommissionState, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.status.State = state
+	if state == DecommissionLeft || state == DecommissionFailed {
+		d.status.Finished = time.Now()
+	}
+	if err != nil {
+		d.status.Error = err.Error()
+	}
+}
@@ -0,0 +1,137 @@
+package hashring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	"github.com/SimonRichardson/coherence/pkg/cluster/mocks"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func TestDecommissioner(t *testing.T) {
+	t.Parallel()
+
+	t.Run("idle", func(t *testing.T) {
+		decommissioner := NewDecommissioner(nil, nil, nil, 0, log.NewNopLogger())
+
+		if expected, actual := DecommissionIdle, decommissioner.Status().State; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("decommission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		topology := Topology{
+			Before: NewConsistentPlacement(1),
+			After:  NewConsistentPlacement(1),
+		}
+
+		peer := mocks.NewMockPeer(ctrl)
+		peer.EXPECT().SetStatus(members.PeerStatusLeaving).Return(nil)
+		peer.EXPECT().Leave().Return(nil)
+
+		drainer := &fakeDrainer{}
+
+		decommissioner := NewDecommissioner(peer, fakeDrainCluster{topology}, drainer, 0, log.NewNopLogger())
+		if err := decommissioner.Decommission(); err != nil {
+			t.Fatal(err)
+		}
+
+		status := waitForDecommission(t, decommissioner, DecommissionLeft)
+		if expected, actual := "", status.Error; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := 1, drainer.calls; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		// Decommissioning twice should fail.
+		err := decommissioner.Decommission()
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("set status failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		peer := mocks.NewMockPeer(ctrl)
+		peer.EXPECT().SetStatus(members.PeerStatusLeaving).Return(errors.New("bad"))
+
+		decommissioner := NewDecommissioner(peer, nil, nil, 0, log.NewNopLogger())
+
+		err := decommissioner.Decommission()
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+		if expected, actual := DecommissionIdle, decommissioner.Status().State; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("drain failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		topology := Topology{
+			Before: NewConsistentPlacement(1),
+			After:  NewConsistentPlacement(1),
+		}
+
+		peer := mocks.NewMockPeer(ctrl)
+		peer.EXPECT().SetStatus(members.PeerStatusLeaving).Return(nil)
+		peer.EXPECT().SetStatus(members.PeerStatusAlive).Return(nil)
+
+		drainer := &fakeDrainer{err: errors.New("bad")}
+
+		decommissioner := NewDecommissioner(peer, fakeDrainCluster{topology}, drainer, 0, log.NewNopLogger())
+		if err := decommissioner.Decommission(); err != nil {
+			t.Fatal(err)
+		}
+
+		status := waitForDecommission(t, decommissioner, DecommissionFailed)
+		if expected, actual := "drain: bad", status.Error; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+type fakeDrainCluster struct {
+	topology Topology
+}
+
+func (f fakeDrainCluster) DrainTopology() Topology {
+	return f.topology
+}
+
+type fakeDrainer struct {
+	calls int
+	err   error
+}
+
+func (f *fakeDrainer) Drain(Topology) error {
+	f.calls++
+	return f.err
+}
+
+func waitForDecommission(t *testing.T, decommissioner *Decommissioner, state DecommissionState) DecommissionStatus {
+	timeout := time.After(time.Second * 5)
+	for {
+		status := decommissioner.Status()
+		if status.State == state {
+			return status
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("expected: %v, actual: %v", state, status.State)
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
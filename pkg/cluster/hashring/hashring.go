@@ -1,26 +1,26 @@
 package hashring
 
-import (
-	"fmt"
+// DefaultWeight is the weight given to a host that doesn't advertise one.
+const DefaultWeight = 1
 
-	"github.com/SimonRichardson/coherence/pkg/cluster/rbtree"
-	"github.com/spaolacci/murmur3"
-)
-
-// HashRing stores strings on a consistent hash ring. HashRing internally uses
-// Red-Black Tree to achieve O(log N) lookup and insertion time.
+// HashRing stores hosts and decides which of them own a key. The placement of
+// the keys is delegated to the Placement algorithm, which by default uses a
+// consistent hash ring.
 type HashRing struct {
-	replicationFactor int
-	hosts             map[string]struct{}
-	tree              *rbtree.RBTree
+	placement Placement
 }
 
-// NewHashRing creates a new HashRing with a replication factor
+// NewHashRing creates a new HashRing with a replication factor, using
+// consistent hashing.
 func NewHashRing(replicationFactor int) *HashRing {
+	return NewHashRingWithPlacement(NewConsistentPlacement(replicationFactor))
+}
+
+// NewHashRingWithPlacement creates a new HashRing that uses the placement
+// algorithm to assign keys to hosts.
+func NewHashRingWithPlacement(placement Placement) *HashRing {
 	return &HashRing{
-		replicationFactor: replicationFactor,
-		hosts:             make(map[string]struct{}, 0),
-		tree:              rbtree.NewRBTree(),
+		placement: placement,
 	}
 }
 
@@ -28,80 +28,55 @@ func NewHashRing(replicationFactor int) *HashRing {
 // replication factor.
 // Returns true if an insertion happens for all replicated points
 func (r *HashRing) Add(host string) bool {
-	if _, ok := r.hosts[host]; ok {
-		return false
-	}
-
-	r.hosts[host] = struct{}{}
-
-	added := true
-	for i := 0; i < r.replicationFactor; i++ {
-		var (
-			key  = fmt.Sprintf("%s%d", host, i)
-			hash = murmur3.Sum32([]byte(key))
-		)
-		added = added && r.tree.Insert(int(hash), host)
-	}
+	return r.AddWeighted(host, DefaultWeight)
+}
 
-	return added
+// AddWeighted adds a host and replicates it around the hashring according to
+// the replication factor scaled by the weight of the host. A host with a
+// weight of 2 will own roughly twice as many keys as a host with a weight of 1.
+// Returns true if an insertion happens for all replicated points
+func (r *HashRing) AddWeighted(host string, weight int) bool {
+	return r.placement.Add(host, weight)
 }
 
 // Remove a host from the hashring including all the subsequent replicated
 // hosts.
 // Returns true if a deletion happens to all the replicated points
 func (r *HashRing) Remove(host string) bool {
-	if _, ok := r.hosts[host]; !ok {
-		return false
-	}
-
-	removed := true
-	for i := 0; i < r.replicationFactor; i++ {
-		var (
-			key  = fmt.Sprintf("%s%d", host, i)
-			hash = murmur3.Sum32([]byte(key))
-		)
-		removed = removed && r.tree.Delete(int(hash))
-	}
-
-	delete(r.hosts, host)
-
-	return removed
+	return r.placement.Remove(host)
 }
 
 // LookupN returns the N servers that own the given key. Duplicates in the form
 // of virtual nodes are skipped to maintain a list of unique servers. If there
 // are less servers than N, we simply return all existing servers.
 func (r *HashRing) LookupN(key string, n int) []string {
-	hash := murmur3.Sum32([]byte(key))
-	return r.tree.LookupNUniqueAt(n, int(hash))
+	return r.placement.LookupN(key, n)
 }
 
 // Contains checks to see if a key is already in the ring.
 // Returns true if a key is found with in the ring.
 func (r *HashRing) Contains(key string) bool {
-	if _, ok := r.hosts[key]; ok {
-		return true
-	}
-
-	hash := murmur3.Sum32([]byte(key))
-	_, ok := r.tree.Search(int(hash))
-	return ok
+	return r.placement.Contains(key)
 }
 
 // Hosts returns the hosts in a slice.
 func (r *HashRing) Hosts() []string {
-	var (
-		idx int
-		res = make([]string, len(r.hosts))
-	)
-	for k := range r.hosts {
-		res[idx] = k
-		idx++
-	}
-	return res
+	return r.placement.Hosts()
 }
 
 // Len returns the number of unique hosts
 func (r *HashRing) Len() int {
-	return len(r.hosts)
+	return r.placement.Len()
+}
+
+// Weight returns the weight of the host with in the ring. If the host isn't
+// found, then 0 is returned.
+func (r *HashRing) Weight(host string) int {
+	return r.placement.Weight(host)
+}
+
+// Ownership returns the percentage of the hashring that each host owns, sorted
+// by host.
+func (r *HashRing) Ownership() []Ownership {
+	return r.placement.Ownership()
 }
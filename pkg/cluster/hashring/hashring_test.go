@@ -1,6 +1,8 @@
 package hashring
 
 import (
+	"fmt"
+	"math"
 	"reflect"
 	"testing"
 	"testing/quick"
@@ -52,6 +54,108 @@ func TestHashRingAddRemove(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("add weighted then remove", func(t *testing.T) {
+		fn := func(a generators.ASCII, weight uint8) bool {
+			ring := NewHashRing(2)
+			ring.AddWeighted(a.String(), int(weight%8)+1)
+			return ring.Remove(a.String()) && len(ring.Ownership()) == 0
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("add weighted", func(t *testing.T) {
+		fn := func(a generators.ASCII, weight uint8) bool {
+			ring := NewHashRing(2)
+			w := int(weight%8) + 1
+			ring.AddWeighted(a.String(), w)
+			ownership := ring.Ownership()
+			return ring.Weight(a.String()) == w &&
+				len(ownership) == 1 &&
+				ownership[0].Points == 2*w
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("add weighted with invalid weight", func(t *testing.T) {
+		fn := func(a generators.ASCII) bool {
+			ring := NewHashRing(2)
+			ring.AddWeighted(a.String(), 0)
+			return ring.Weight(a.String()) == DefaultWeight
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestHashRingOwnership(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty", func(t *testing.T) {
+		ring := NewHashRing(10)
+		if expected, actual := 0, len(ring.Ownership()); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("single host owns everything", func(t *testing.T) {
+		fn := func(a generators.ASCII) bool {
+			ring := NewHashRing(10)
+			ring.Add(a.String())
+
+			ownership := ring.Ownership()
+			return len(ownership) == 1 &&
+				math.Abs(ownership[0].Percentage-100) < 0.0001
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("total", func(t *testing.T) {
+		fn := func(a []generators.ASCII) bool {
+			ring := NewHashRing(10)
+			for _, v := range a {
+				ring.Add(v.String())
+			}
+
+			var total float64
+			for _, v := range ring.Ownership() {
+				total += v.Percentage
+			}
+			return ring.Len() == 0 || math.Abs(total-100) < 0.0001
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		ring := NewHashRing(100)
+		for i := 0; i < 4; i++ {
+			ring.Add(fmt.Sprintf("10.0.0.%d:8080", i))
+		}
+		ring.AddWeighted("10.0.0.4:8080", 4)
+
+		var heavy, light float64
+		for _, v := range ring.Ownership() {
+			if v.Host == "10.0.0.4:8080" {
+				heavy = v.Percentage
+			} else {
+				light += v.Percentage / 4
+			}
+		}
+
+		// The weighted host should own roughly four times as much as the others.
+		if ratio := heavy / light; ratio < 3 || ratio > 5 {
+			t.Errorf("expected ratio to be roughly 4, actual: %f", ratio)
+		}
+	})
 }
 
 func TestHashRingLookup(t *testing.T) {
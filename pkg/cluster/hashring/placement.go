@@ -0,0 +1,97 @@
+package hashring
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PlacementConsistent places hosts on a consistent hash ring.
+	PlacementConsistent = "consistent"
+
+	// PlacementRendezvous places keys using rendezvous (highest random weight)
+	// hashing.
+	PlacementRendezvous = "rendezvous"
+
+	// PlacementBounded places keys on a consistent hash ring, where no host
+	// can own more than its bounded share of the keys.
+	PlacementBounded = "bounded"
+)
+
+// Placement describes how keys are assigned to hosts with in the cluster.
+// Implementations are not thread safe, so it's up to the caller to guard any
+// concurrent access.
+type Placement interface {
+	// Add a host with a weight to the placement.
+	// Returns true if the host was added.
+	Add(host string, weight int) bool
+
+	// Remove a host from the placement.
+	// Returns true if the host was removed.
+	Remove(host string) bool
+
+	// LookupN returns the N unique hosts that own the given key. If there are
+	// less hosts than N, then all the hosts are returned.
+	LookupN(key string, n int) []string
+
+	// Contains checks to see if a host is with in the placement.
+	Contains(host string) bool
+
+	// Weight returns the weight of a host, or 0 if the host isn't found.
+	Weight(host string) int
+
+	// Hosts returns all the hosts with in the placement.
+	Hosts() []string
+
+	// Len returns the number of unique hosts.
+	Len() int
+
+	// Ownership returns the percentage of keys each host owns.
+	Ownership() []Ownership
+
+	// Clone returns a copy of the placement, so that changes to the copy
+	// don't affect the original.
+	Clone() Placement
+}
+
+// ParsePlacement parses a placement algorithm and returns a Placement using
+// the replication factor, or an error if the algorithm isn't known.
+func ParsePlacement(algorithm string, replicationFactor int) (Placement, error) {
+	switch algorithm {
+	case PlacementConsistent:
+		return NewConsistentPlacement(replicationFactor), nil
+	case PlacementRendezvous:
+		return NewRendezvousPlacement(), nil
+	case PlacementBounded:
+		return NewBoundedPlacement(replicationFactor, defaultPartitionCount, defaultLoadFactor), nil
+	default:
+		return nil, errors.Errorf("unexpected placement algorithm: %q", algorithm)
+	}
+}
+
+// Ownership describes how much of the hashring a host is responsible for.
+type Ownership struct {
+	Host       string  `json:"host"`
+	Weight     int     `json:"weight"`
+	Points     int     `json:"points"`
+	Percentage float64 `json:"percentage"`
+}
+
+func sortOwnership(hosts map[string]*Ownership) []Ownership {
+	res := make([]Ownership, 0, len(hosts))
+	for _, v := range hosts {
+		res = append(res, *v)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Host < res[j].Host
+	})
+	return res
+}
+
+func normaliseWeight(weight int) int {
+	if weight < 1 {
+		return DefaultWeight
+	}
+	return weight
+}
@@ -0,0 +1,325 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/trussle/harness/generators"
+)
+
+var placements = []struct {
+	name string
+	fn   func() Placement
+}{
+	{PlacementConsistent, func() Placement { return NewConsistentPlacement(100) }},
+	{PlacementRendezvous, func() Placement { return NewRendezvousPlacement() }},
+	{PlacementBounded, func() Placement { return NewBoundedPlacement(100, defaultPartitionCount, defaultLoadFactor) }},
+}
+
+func TestParsePlacement(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range placements {
+		t.Run(v.name, func(t *testing.T) {
+			placement, err := ParsePlacement(v.name, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if expected, actual := reflect.TypeOf(v.fn()), reflect.TypeOf(placement); expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParsePlacement("bad", 10)
+
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+}
+
+func TestPlacement(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range placements {
+		v := v
+
+		t.Run(v.name, func(t *testing.T) {
+			t.Run("add duplicate", func(t *testing.T) {
+				fn := func(a generators.ASCII) bool {
+					placement := v.fn()
+					placement.Add(a.String(), 1)
+					return !placement.Add(a.String(), 1)
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("remove", func(t *testing.T) {
+				fn := func(a generators.ASCII) bool {
+					placement := v.fn()
+					return !placement.Remove(a.String())
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("add then remove", func(t *testing.T) {
+				fn := func(a generators.ASCII) bool {
+					placement := v.fn()
+					placement.Add(a.String(), 1)
+					return placement.Remove(a.String()) &&
+						placement.Len() == 0 &&
+						len(placement.LookupN(a.String(), 1)) == 0
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("weight", func(t *testing.T) {
+				fn := func(a generators.ASCII, weight uint8) bool {
+					placement := v.fn()
+					w := int(weight%4) + 1
+					placement.Add(a.String(), w)
+					return placement.Contains(a.String()) &&
+						placement.Weight(a.String()) == w
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("lookup unique hosts", func(t *testing.T) {
+				fn := func(a []generators.ASCII, key string) bool {
+					placement := v.fn()
+					for _, h := range a {
+						placement.Add(h.String(), 1)
+					}
+
+					n := placement.Len()
+					got := placement.LookupN(key, n+1)
+					if len(got) != n {
+						return false
+					}
+
+					sort.Strings(got)
+					want := placement.Hosts()
+					sort.Strings(want)
+					return n == 0 || reflect.DeepEqual(want, got)
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("lookup is stable", func(t *testing.T) {
+				fn := func(a []generators.ASCII, key string) bool {
+					placement := v.fn()
+					for _, h := range a {
+						placement.Add(h.String(), 1)
+					}
+
+					return reflect.DeepEqual(placement.LookupN(key, 2), placement.LookupN(key, 2))
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("clone", func(t *testing.T) {
+				fn := func(a []generators.ASCII, key string) bool {
+					placement := v.fn()
+					for _, h := range a {
+						placement.Add(h.String(), 1)
+					}
+
+					clone := placement.Clone()
+					if !reflect.DeepEqual(placement.LookupN(key, 2), clone.LookupN(key, 2)) {
+						return false
+					}
+
+					// Changes to the clone shouldn't change the original.
+					clone.Add(key+"-clone", 1)
+					return placement.Len() != clone.Len()
+				}
+				if err := quick.Check(fn, nil); err != nil {
+					t.Error(err)
+				}
+			})
+
+			t.Run("ownership total", func(t *testing.T) {
+				placement := v.fn()
+				for i := 0; i < 10; i++ {
+					placement.Add(fmt.Sprintf("10.0.0.%d:8080", i), 1)
+				}
+
+				var total float64
+				for _, o := range placement.Ownership() {
+					total += o.Percentage
+				}
+				if math.Abs(total-100) > 0.0001 {
+					t.Errorf("expected: 100, actual: %f", total)
+				}
+			})
+		})
+	}
+}
+
+func TestPlacementDistribution(t *testing.T) {
+	t.Parallel()
+
+	const (
+		numHosts = 20
+		numKeys  = 100000
+	)
+
+	// Maximum coefficient of variation (stddev / mean) of the keys owned by
+	// each host.
+	thresholds := map[string]float64{
+		PlacementConsistent: 0.2,
+		PlacementRendezvous: 0.05,
+		PlacementBounded:    0.2,
+	}
+
+	for _, v := range placements {
+		v := v
+
+		t.Run(v.name, func(t *testing.T) {
+			placement := v.fn()
+			for i := 0; i < numHosts; i++ {
+				placement.Add(fmt.Sprintf("10.0.0.%d:8080", i), 1)
+			}
+
+			counts := make(map[string]int, numHosts)
+			for i := 0; i < numKeys; i++ {
+				for _, h := range placement.LookupN(fmt.Sprintf("key-%d", i), 1) {
+					counts[h]++
+				}
+			}
+
+			mean, stddev, max := distribution(counts, numHosts)
+			cv := stddev / mean
+			t.Logf("%s: mean=%.1f stddev=%.1f cv=%.4f max/mean=%.4f", v.name, mean, stddev, cv, float64(max)/mean)
+
+			if threshold := thresholds[v.name]; cv > threshold {
+				t.Errorf("expected variation less than %f, actual: %f", threshold, cv)
+			}
+		})
+	}
+
+	t.Run("bounded load", func(t *testing.T) {
+		placement := NewBoundedPlacement(100, defaultPartitionCount, defaultLoadFactor)
+		for i := 0; i < numHosts; i++ {
+			placement.Add(fmt.Sprintf("10.0.0.%d:8080", i), 1)
+		}
+
+		limit := int(math.Ceil(float64(defaultPartitionCount) / numHosts * defaultLoadFactor))
+		for _, o := range placement.Ownership() {
+			if o.Points > limit {
+				t.Errorf("expected %s to own at most %d partitions, actual: %d", o.Host, limit, o.Points)
+			}
+		}
+	})
+}
+
+func TestPlacementWeighted(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range placements {
+		v := v
+
+		t.Run(v.name, func(t *testing.T) {
+			placement := v.fn()
+			for i := 0; i < 4; i++ {
+				placement.Add(fmt.Sprintf("10.0.0.%d:8080", i), 1)
+			}
+			placement.Add("10.0.0.4:8080", 4)
+
+			counts := make(map[string]int)
+			for i := 0; i < 50000; i++ {
+				for _, h := range placement.LookupN(fmt.Sprintf("key-%d", i), 1) {
+					counts[h]++
+				}
+			}
+
+			var light float64
+			for i := 0; i < 4; i++ {
+				light += float64(counts[fmt.Sprintf("10.0.0.%d:8080", i)]) / 4
+			}
+
+			// The weighted host should own roughly four times as much as the
+			// others.
+			if ratio := float64(counts["10.0.0.4:8080"]) / light; ratio < 3 || ratio > 5 {
+				t.Errorf("expected ratio to be roughly 4, actual: %f", ratio)
+			}
+		})
+	}
+}
+
+func BenchmarkPlacementLookupN(b *testing.B) {
+	for _, v := range placements {
+		v := v
+
+		b.Run(v.name, func(b *testing.B) {
+			placement := v.fn()
+			for i := 0; i < 16; i++ {
+				placement.Add(fmt.Sprintf("10.0.0.%d:8080", i), 1)
+			}
+
+			keys := make([]string, 1024)
+			for k := range keys {
+				keys[k] = fmt.Sprintf("key-%d", k)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				placement.LookupN(keys[i%len(keys)], 3)
+			}
+		})
+	}
+}
+
+func BenchmarkPlacementAdd(b *testing.B) {
+	for _, v := range placements {
+		v := v
+
+		b.Run(v.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				placement := v.fn()
+				for j := 0; j < 16; j++ {
+					placement.Add(fmt.Sprintf("10.0.0.%d:8080", j), 1)
+				}
+			}
+		})
+	}
+}
+
+func distribution(counts map[string]int, n int) (mean, stddev float64, max int) {
+	var total int
+	for _, v := range counts {
+		total += v
+		if v > max {
+			max = v
+		}
+	}
+	mean = float64(total) / float64(n)
+
+	var variance float64
+	for _, v := range counts {
+		variance += math.Pow(float64(v)-mean, 2)
+	}
+	// Hosts with no keys at all still count towards the variance.
+	variance += float64(n-len(counts)) * math.Pow(mean, 2)
+
+	return mean, math.Sqrt(variance / float64(n)), max
+}
@@ -0,0 +1,496 @@
+package hashring
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// RebalanceState describes the state of the rebalancer.
+type RebalanceState string
+
+const (
+	// RebalanceIdle is when no rebalancing has happened.
+	RebalanceIdle RebalanceState = "idle"
+
+	// RebalanceRunning is when keys are being streamed to new owners.
+	RebalanceRunning RebalanceState = "running"
+
+	// RebalanceComplete is when all the keys have been streamed to their new
+	// owners.
+	RebalanceComplete RebalanceState = "complete"
+
+	// RebalanceCancelled is when the rebalance was stopped before it completed.
+	RebalanceCancelled RebalanceState = "cancelled"
+
+	// RebalanceFailed is when the rebalance couldn't start.
+	RebalanceFailed RebalanceState = "failed"
+)
+
+// Topology describes the placement of the hosts before and after a change in
+// the cluster.
+type Topology struct {
+	Before, After Placement
+}
+
+// Added returns the hosts that are in the new topology, but weren't in the old
+// topology.
+func (t Topology) Added() []string {
+	return difference(t.After.Hosts(), t.Before)
+}
+
+// Removed returns the hosts that were in the old topology, but aren't in the
+// new topology.
+func (t Topology) Removed() []string {
+	return difference(t.Before.Hosts(), t.After)
+}
+
+// ClusterTopology gives access to the nodes with in a cluster, along with a
+// way to be notified when the topology of the cluster changes.
+type ClusterTopology interface {
+	// LocalHost returns the host of the local node.
+	LocalHost() string
+
+	// Node returns the node for the host.
+	Node(host string) (nodes.Node, bool)
+
+	// Topology returns a channel that's notified when hosts are added or
+	// removed from the cluster.
+	Topology() <-chan Topology
+}
+
+// Progress describes the progress of the current or last rebalance.
+type Progress struct {
+	State    RebalanceState `json:"state"`
+	Added    []string       `json:"added"`
+	Removed  []string       `json:"removed"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Keys     int            `json:"keys"`
+	Scanned  int            `json:"scanned"`
+	Streamed int            `json:"streamed"`
+	Members  int            `json:"members"`
+	Failed   int            `json:"failed"`
+	Drain    bool           `json:"drain"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Rebalancer streams keys that the local node holds, to any new owners of the
+// key when the topology of the cluster changes. Only keys that change owners
+// are streamed and the rate at which they're streamed is throttled, so that
+// the cluster isn't saturated during the rebalance.
+type Rebalancer struct {
+	cluster  ClusterTopology
+	rate     int
+	mutex    sync.RWMutex
+	progress Progress
+	drain    chan drainRequest
+	stop     chan chan struct{}
+	logger   log.Logger
+}
+
+// NewRebalancer creates a Rebalancer that streams at most rate keys a second
+// to their new owners.
+func NewRebalancer(cluster ClusterTopology, rate int, logger log.Logger) *Rebalancer {
+	return &Rebalancer{
+		cluster: cluster,
+		rate:    rate,
+		progress: Progress{
+			State: RebalanceIdle,
+		},
+		drain:  make(chan drainRequest),
+		stop:   make(chan chan struct{}),
+		logger: logger,
+	}
+}
+
+// Run the Rebalancer, any change in topology whilst a rebalance is running
+// cancels the current rebalance and starts a new one, which includes any keys
+// that weren't streamed. Changes in topology whilst draining are ignored, as
+// the local node is leaving the cluster.
+func (r *Rebalancer) Run() error {
+	var (
+		current  Topology
+		draining bool
+		cancel   chan struct{}
+		done     chan struct{}
+	)
+
+	wait := func() {
+		if cancel != nil {
+			close(cancel)
+			<-done
+			cancel, done = nil, nil
+		}
+	}
+
+	for {
+		select {
+		case topology := <-r.cluster.Topology():
+			if cancel != nil {
+				select {
+				case <-done:
+					draining = false
+				default:
+					if draining {
+						continue
+					}
+					// The current rebalance hasn't completed, so rebalance from
+					// the original topology.
+					topology.Before = current.Before
+				}
+			}
+			wait()
+
+			current = topology
+			cancel, done = make(chan struct{}), make(chan struct{})
+			go func(topology Topology, cancel <-chan struct{}, done chan<- struct{}) {
+				defer close(done)
+				r.rebalance(topology, cancel, rebalanceTargets, false)
+			}(current, cancel, done)
+
+		case req := <-r.drain:
+			wait()
+
+			draining = true
+			current = req.topology
+			cancel, done = make(chan struct{}), make(chan struct{})
+			go func(req drainRequest, cancel <-chan struct{}, done chan<- struct{}) {
+				defer close(done)
+				req.result <- r.rebalance(req.topology, cancel, drainTargets, true)
+			}(req, cancel, done)
+
+		case c := <-r.stop:
+			wait()
+			close(c)
+			return nil
+		}
+	}
+}
+
+// Stop the Rebalancer
+func (r *Rebalancer) Stop() {
+	c := make(chan struct{})
+	r.stop <- c
+	<-c
+}
+
+// Drain streams every key that the local node holds to the owners of the key
+// in the new topology, regardless of which node would usually stream the key.
+// Drain blocks until all the keys have been streamed and returns an error if
+// any of the keys couldn't be streamed.
+func (r *Rebalancer) Drain(topology Topology) error {
+	result := make(chan error, 1)
+	r.drain <- drainRequest{
+		topology: topology,
+		result:   result,
+	}
+	return <-result
+}
+
+// Progress returns the progress of the current or last rebalance.
+func (r *Rebalancer) Progress() Progress {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.progress
+}
+
+func (r *Rebalancer) rebalance(topology Topology,
+	cancel <-chan struct{},
+	targetsFn func(Topology, string, selectors.Key) []string,
+	drain bool,
+) error {
+	r.update(func(p *Progress) {
+		*p = Progress{
+			State:   RebalanceRunning,
+			Added:   topology.Added(),
+			Removed: topology.Removed(),
+			Started: time.Now(),
+			Drain:   drain,
+		}
+	})
+
+	local, ok := r.cluster.Node(r.cluster.LocalHost())
+	if !ok {
+		return r.finish(RebalanceFailed, errors.New("no local node"))
+	}
+
+	keys, err := nodeKeys(local)
+	if err != nil {
+		return r.finish(RebalanceFailed, err)
+	}
+
+	r.update(func(p *Progress) {
+		p.Keys = len(keys)
+	})
+
+	var throttle <-chan time.Time
+	if r.rate > 0 {
+		ticker := time.NewTicker(interval(r.rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var failed int
+	for _, key := range keys {
+		select {
+		case <-cancel:
+			return r.finish(RebalanceCancelled, errors.New("cancelled"))
+		default:
+		}
+
+		targets := targetsFn(topology, r.cluster.LocalHost(), key)
+		if len(targets) == 0 {
+			r.update(func(p *Progress) {
+				p.Scanned++
+			})
+			continue
+		}
+
+		select {
+		case <-throttle:
+		case <-cancel:
+			return r.finish(RebalanceCancelled, errors.New("cancelled"))
+		}
+
+		streamed, members, err := r.stream(local, key, targets)
+		if err != nil {
+			failed++
+			level.Warn(r.logger).Log("key", key, "err", err)
+		}
+
+		r.update(func(p *Progress) {
+			p.Scanned++
+			p.Streamed += streamed
+			p.Members += members
+			if err != nil {
+				p.Failed++
+			}
+		})
+	}
+
+	r.finish(RebalanceComplete, nil)
+	if failed > 0 {
+		return errors.Errorf("failed to stream %d of %d keys", failed, len(keys))
+	}
+	return nil
+}
+
+// stream the members of the key from the local node to all of the targets.
+// Members that are deleted from the local node whilst they're being streamed
+// are then deleted from the targets, otherwise the targets would hold on to
+// them, as the delete only went to the owners from before the change.
+func (r *Rebalancer) stream(local nodes.Node, key selectors.Key, targets []string) (int, int, error) {
+	members, err := nodeMembers(local, key)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(members) == 0 {
+		return 0, 0, nil
+	}
+
+	var (
+		streamed int
+		errs     []error
+	)
+	for _, host := range targets {
+		node, ok := r.cluster.Node(host)
+		if !ok {
+			errs = append(errs, errors.Errorf("no node for %s", host))
+			continue
+		}
+
+		for element := range node.Insert(key, members) {
+			if err := selectors.ErrorFromElement(element); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			streamed++
+		}
+	}
+
+	deleted, err := nodeDeleted(local, key, members)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(deleted) > 0 {
+		for _, host := range targets {
+			node, ok := r.cluster.Node(host)
+			if !ok {
+				continue
+			}
+
+			for element := range node.Delete(key, deleted) {
+				if err := selectors.ErrorFromElement(element); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return streamed, len(members), errors.Errorf("failed to stream to %d of %d nodes: %v", len(errs), len(targets), errs[0])
+	}
+	return streamed, len(members), nil
+}
+
+func (r *Rebalancer) update(fn func(*Progress)) {
+	r.mutex.Lock()
+	fn(&r.progress)
+	r.mutex.Unlock()
+}
+
+func (r *Rebalancer) finish(state RebalanceState, err error) error {
+	r.update(func(p *Progress) {
+		p.State = state
+		p.Finished = time.Now()
+		if err != nil && state != RebalanceCancelled {
+			p.Error = err.Error()
+		}
+	})
+	return err
+}
+
+type drainRequest struct {
+	topology Topology
+	result   chan<- error
+}
+
+// rebalanceTargets returns the hosts that a key needs to be streamed to from
+// the local host. Only one of the previous owners, that's still with in the
+// cluster, streams the key to prevent every replica streaming the same key.
+func rebalanceTargets(topology Topology, local string, key selectors.Key) []string {
+	var (
+		k      = key.String()
+		before = topology.Before.LookupN(k, owners(topology.Before))
+		after  = topology.After.LookupN(k, owners(topology.After))
+	)
+
+	// Find the first owner that survived the change in topology, if there are
+	// none, then the local node is responsible for streaming the key.
+	var source string
+	for _, v := range before {
+		if topology.After.Contains(v) {
+			source = v
+			break
+		}
+	}
+	if source != "" && source != local {
+		return nil
+	}
+
+	var res []string
+	for _, v := range after {
+		if v == local || contains(before, v) {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// drainTargets returns every owner of the key in the new topology, except for
+// the local host. Unlike rebalancing, every owner is targeted as the remaining
+// replicas might not hold all of the members that the local node does.
+func drainTargets(topology Topology, local string, key selectors.Key) []string {
+	var res []string
+	for _, v := range topology.After.LookupN(key.String(), owners(topology.After)) {
+		if v != local {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// interval returns the time between each key that's streamed, so that at most
+// rate keys are streamed a second. Rates of more than a key a nanosecond are
+// clamped to a nanosecond, as a ticker can't tick any faster.
+func interval(rate int) time.Duration {
+	if d := time.Second / time.Duration(rate); d > 0 {
+		return d
+	}
+	return time.Nanosecond
+}
+
+// owners returns the number of hosts that own a key, which matches the number
+// of hosts that are required to reach consensus.
+func owners(placement Placement) int {
+	return (placement.Len() / 2) + 1
+}
+
+func difference(hosts []string, placement Placement) []string {
+	res := make([]string, 0)
+	for _, v := range hosts {
+		if !placement.Contains(v) {
+			res = append(res, v)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func nodeKeys(node nodes.Node) ([]selectors.Key, error) {
+	var keys []selectors.Key
+	for element := range node.Keys() {
+		if err := selectors.ErrorFromElement(element); err != nil {
+			return nil, err
+		}
+		keys = append(keys, selectors.KeysFromElement(element)...)
+	}
+	return keys, nil
+}
+
+func nodeMembers(node nodes.Node, key selectors.Key) ([]selectors.FieldValueScore, error) {
+	var fields []selectors.Field
+	for element := range node.Members(key) {
+		if err := selectors.ErrorFromElement(element); err != nil {
+			return nil, err
+		}
+		fields = append(fields, selectors.FieldsFromElement(element)...)
+	}
+
+	members := make([]selectors.FieldValueScore, 0, len(fields))
+	for _, field := range fields {
+		for element := range node.Select(key, field) {
+			if err := selectors.ErrorFromElement(element); err != nil {
+				if selectors.NotFoundError(err) {
+					continue
+				}
+				return nil, err
+			}
+			members = append(members, selectors.FieldValueScoreFromElement(element))
+		}
+	}
+	return members, nil
+}
+
+// nodeDeleted returns the members that have been deleted from the node since
+// they were read, along with the score of the delete.
+func nodeDeleted(node nodes.Node, key selectors.Key, members []selectors.FieldValueScore) ([]selectors.FieldValueScore, error) {
+	var deleted []selectors.FieldValueScore
+	for _, member := range members {
+		for element := range node.Score(key, member.Field) {
+			if err := selectors.ErrorFromElement(element); err != nil {
+				if selectors.NotFoundError(err) {
+					continue
+				}
+				return nil, err
+			}
+			presence := selectors.PresenceFromElement(element)
+			if presence.Present && !presence.Inserted && presence.Score > member.Score {
+				deleted = append(deleted, selectors.FieldValueScore{
+					Field: member.Field,
+					Score: presence.Score,
+				})
+			}
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,469 @@
+package hashring
+
+import (
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes"
+	"github.com/SimonRichardson/coherence/pkg/cluster/nodes/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+)
+
+func TestTopology(t *testing.T) {
+	t.Parallel()
+
+	t.Run("added and removed", func(t *testing.T) {
+		before := NewConsistentPlacement(10)
+		before.Add("a", 1)
+		before.Add("b", 1)
+
+		after := NewConsistentPlacement(10)
+		after.Add("b", 1)
+		after.Add("c", 1)
+
+		topology := Topology{Before: before, After: after}
+
+		if expected, actual := []string{"c"}, topology.Added(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []string{"a"}, topology.Removed(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestRebalanceTargets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no change", func(t *testing.T) {
+		fn := func(key selectors.Key) bool {
+			placement := NewConsistentPlacement(10)
+			placement.Add("a", 1)
+			placement.Add("b", 1)
+
+			targets := rebalanceTargets(Topology{
+				Before: placement,
+				After:  placement.Clone(),
+			}, "a", key)
+			return len(targets) == 0
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("added host", func(t *testing.T) {
+		fn := func(key selectors.Key) bool {
+			before := NewConsistentPlacement(10)
+			before.Add("a", 1)
+
+			after := before.Clone()
+			after.Add("b", 1)
+
+			targets := rebalanceTargets(Topology{
+				Before: before,
+				After:  after,
+			}, "a", key)
+			return reflect.DeepEqual([]string{"b"}, targets)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("not the source", func(t *testing.T) {
+		fn := func(key selectors.Key) bool {
+			before := NewConsistentPlacement(10)
+			before.Add("a", 1)
+
+			after := before.Clone()
+			after.Add("b", 1)
+
+			targets := rebalanceTargets(Topology{
+				Before: before,
+				After:  after,
+			}, "c", key)
+			return len(targets) == 0
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("removed host", func(t *testing.T) {
+		fn := func(key selectors.Key) bool {
+			before := NewConsistentPlacement(10)
+			before.Add("a", 1)
+			before.Add("b", 1)
+			before.Add("c", 1)
+
+			after := before.Clone()
+			after.Remove("c")
+
+			// Every owner after the change should be targeted, unless they
+			// already owned the key or are the local node.
+			var (
+				k      = key.String()
+				owners = before.LookupN(k, 2)
+				source = owners[0]
+			)
+			if source == "c" {
+				source = owners[1]
+			}
+
+			want := make([]string, 0)
+			for _, v := range after.LookupN(k, 2) {
+				if v != source && !contains(owners, v) {
+					want = append(want, v)
+				}
+			}
+
+			targets := rebalanceTargets(Topology{
+				Before: before,
+				After:  after,
+			}, source, key)
+			return len(want) == len(targets) && (len(want) == 0 || reflect.DeepEqual(want, targets))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestDrainTargets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("remaining owners", func(t *testing.T) {
+		fn := func(key selectors.Key) bool {
+			before := NewConsistentPlacement(10)
+			before.Add("a", 1)
+			before.Add("b", 1)
+			before.Add("c", 1)
+
+			after := before.Clone()
+			after.Remove("a")
+
+			targets := drainTargets(Topology{
+				Before: before,
+				After:  after,
+			}, "a", key)
+			return reflect.DeepEqual(after.LookupN(key.String(), 2), targets)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestRebalancer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("idle", func(t *testing.T) {
+		rebalancer := NewRebalancer(nil, 0, log.NewNopLogger())
+
+		if expected, actual := RebalanceIdle, rebalancer.Progress().State; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			key     = selectors.Key("key")
+			field   = selectors.Field("field")
+			members = []selectors.FieldValueScore{
+				{Field: field, Value: []byte("value"), Score: 1},
+			}
+		)
+
+		local := mocks.NewMockNode(ctrl)
+		local.EXPECT().Keys().Return(elements(selectors.NewKeysElement(1, []selectors.Key{key})))
+		local.EXPECT().Members(key).Return(elements(selectors.NewFieldsElement(1, []selectors.Field{field})))
+		local.EXPECT().Select(key, field).Return(elements(selectors.NewFieldValueScoreElement(1, members[0])))
+		local.EXPECT().Score(key, field).Return(elements(selectors.NewPresenceElement(1, selectors.Presence{
+			Inserted: true,
+			Present:  true,
+			Score:    1,
+		})))
+
+		remote := mocks.NewMockNode(ctrl)
+		remote.EXPECT().Insert(key, members).Return(elements(selectors.NewChangeSetElement(2, selectors.ChangeSet{
+			Success: []selectors.Field{field},
+		})))
+
+		before := NewConsistentPlacement(10)
+		before.Add("local", 1)
+
+		after := before.Clone()
+		after.Add("remote", 1)
+
+		cluster := &fakeClusterTopology{
+			local: "local",
+			nodes: map[string]nodes.Node{
+				"local":  local,
+				"remote": remote,
+			},
+			topology: make(chan Topology, 1),
+		}
+		cluster.topology <- Topology{Before: before, After: after}
+
+		rebalancer := NewRebalancer(cluster, 1000, log.NewNopLogger())
+		go rebalancer.Run()
+		defer rebalancer.Stop()
+
+		progress := waitForProgress(t, rebalancer, RebalanceComplete)
+
+		if expected, actual := 1, progress.Keys; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := 1, progress.Streamed; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := 1, progress.Members; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []string{"remote"}, progress.Added; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("stream deleted whilst streaming", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			key     = selectors.Key("key")
+			field   = selectors.Field("field")
+			members = []selectors.FieldValueScore{
+				{Field: field, Value: []byte("value"), Score: 1},
+			}
+		)
+
+		// The member is deleted from the local node, after it's been read but
+		// before it's been streamed, so the delete has to follow it.
+		local := mocks.NewMockNode(ctrl)
+		local.EXPECT().Keys().Return(elements(selectors.NewKeysElement(1, []selectors.Key{key})))
+		local.EXPECT().Members(key).Return(elements(selectors.NewFieldsElement(1, []selectors.Field{field})))
+		local.EXPECT().Select(key, field).Return(elements(selectors.NewFieldValueScoreElement(1, members[0])))
+		local.EXPECT().Score(key, field).Return(elements(selectors.NewPresenceElement(1, selectors.Presence{
+			Inserted: false,
+			Present:  true,
+			Score:    2,
+		})))
+
+		remote := mocks.NewMockNode(ctrl)
+		remote.EXPECT().Insert(key, members).Return(elements(selectors.NewChangeSetElement(2, selectors.ChangeSet{
+			Success: []selectors.Field{field},
+		})))
+		remote.EXPECT().Delete(key, []selectors.FieldValueScore{
+			{Field: field, Score: 2},
+		}).Return(elements(selectors.NewChangeSetElement(2, selectors.ChangeSet{
+			Success: []selectors.Field{field},
+		})))
+
+		before := NewConsistentPlacement(10)
+		before.Add("local", 1)
+
+		after := before.Clone()
+		after.Add("remote", 1)
+
+		cluster := &fakeClusterTopology{
+			local: "local",
+			nodes: map[string]nodes.Node{
+				"local":  local,
+				"remote": remote,
+			},
+			topology: make(chan Topology, 1),
+		}
+		cluster.topology <- Topology{Before: before, After: after}
+
+		rebalancer := NewRebalancer(cluster, 1000, log.NewNopLogger())
+		go rebalancer.Run()
+		defer rebalancer.Stop()
+
+		progress := waitForProgress(t, rebalancer, RebalanceComplete)
+		if expected, actual := 0, progress.Failed; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("rate over a key a nanosecond", func(t *testing.T) {
+		if expected, actual := time.Nanosecond, interval(2e9); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := time.Millisecond, interval(1000); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("no local node", func(t *testing.T) {
+		before := NewConsistentPlacement(10)
+		after := before.Clone()
+		after.Add("remote", 1)
+
+		cluster := &fakeClusterTopology{
+			local:    "local",
+			nodes:    map[string]nodes.Node{},
+			topology: make(chan Topology, 1),
+		}
+		cluster.topology <- Topology{Before: before, After: after}
+
+		rebalancer := NewRebalancer(cluster, 1000, log.NewNopLogger())
+		go rebalancer.Run()
+		defer rebalancer.Stop()
+
+		waitForProgress(t, rebalancer, RebalanceFailed)
+	})
+}
+
+func TestRebalancerDrain(t *testing.T) {
+	t.Parallel()
+
+	t.Run("drain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			key     = selectors.Key("key")
+			field   = selectors.Field("field")
+			members = []selectors.FieldValueScore{
+				{Field: field, Value: []byte("value"), Score: 1},
+			}
+		)
+
+		local := mocks.NewMockNode(ctrl)
+		local.EXPECT().Keys().Return(elements(selectors.NewKeysElement(1, []selectors.Key{key})))
+		local.EXPECT().Members(key).Return(elements(selectors.NewFieldsElement(1, []selectors.Field{field})))
+		local.EXPECT().Select(key, field).Return(elements(selectors.NewFieldValueScoreElement(1, members[0])))
+		local.EXPECT().Score(key, field).Return(elements(selectors.NewPresenceElement(1, selectors.Presence{
+			Inserted: true,
+			Present:  true,
+			Score:    1,
+		})))
+
+		// The remote node was already an owner of the key, but it still
+		// receives the key when draining.
+		remote := mocks.NewMockNode(ctrl)
+		remote.EXPECT().Insert(key, members).Return(elements(selectors.NewChangeSetElement(2, selectors.ChangeSet{
+			Success: []selectors.Field{field},
+		})))
+
+		before := NewConsistentPlacement(10)
+		before.Add("local", 1)
+		before.Add("remote", 1)
+
+		after := before.Clone()
+		after.Remove("local")
+
+		cluster := &fakeClusterTopology{
+			local: "local",
+			nodes: map[string]nodes.Node{
+				"local":  local,
+				"remote": remote,
+			},
+			topology: make(chan Topology, 1),
+		}
+
+		rebalancer := NewRebalancer(cluster, 1000, log.NewNopLogger())
+		go rebalancer.Run()
+		defer rebalancer.Stop()
+
+		if err := rebalancer.Drain(Topology{Before: before, After: after}); err != nil {
+			t.Fatal(err)
+		}
+
+		progress := rebalancer.Progress()
+		if expected, actual := true, progress.Drain; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := 1, progress.Streamed; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("drain failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		key := selectors.Key("key")
+
+		local := mocks.NewMockNode(ctrl)
+		local.EXPECT().Keys().Return(elements(selectors.NewKeysElement(1, []selectors.Key{key})))
+		local.EXPECT().Members(key).Return(elements(selectors.NewErrorElement(1, errors.New("bad"))))
+
+		before := NewConsistentPlacement(10)
+		before.Add("local", 1)
+		before.Add("remote", 1)
+
+		after := before.Clone()
+		after.Remove("local")
+
+		cluster := &fakeClusterTopology{
+			local: "local",
+			nodes: map[string]nodes.Node{
+				"local": local,
+			},
+			topology: make(chan Topology, 1),
+		}
+
+		rebalancer := NewRebalancer(cluster, 1000, log.NewNopLogger())
+		go rebalancer.Run()
+		defer rebalancer.Stop()
+
+		err := rebalancer.Drain(Topology{Before: before, After: after})
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+}
+
+type fakeClusterTopology struct {
+	local    string
+	nodes    map[string]nodes.Node
+	topology chan Topology
+}
+
+func (f *fakeClusterTopology) LocalHost() string {
+	return f.local
+}
+
+func (f *fakeClusterTopology) Node(host string) (nodes.Node, bool) {
+	node, ok := f.nodes[host]
+	return node, ok
+}
+
+func (f *fakeClusterTopology) Topology() <-chan Topology {
+	return f.topology
+}
+
+func elements(e ...selectors.Element) <-chan selectors.Element {
+	ch := make(chan selectors.Element, len(e))
+	for _, v := range e {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+func waitForProgress(t *testing.T, rebalancer *Rebalancer, state RebalanceState) Progress {
+	timeout := time.After(time.Second * 5)
+	for {
+		progress := rebalancer.Progress()
+		if progress.State == state {
+			return progress
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("expected: %v, actual: %v", state, progress.State)
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package hashring
+
+import (
+	"math"
+	"sort"
+
+	"github.com/spaolacci/murmur3"
+)
+
+const defaultOwnershipSamples = 10000
+
+// rendezvousPlacement assigns keys using rendezvous (highest random weight)
+// hashing. Every host is scored against the key and the hosts with the
+// highest scores own the key. Adding or removing a host only moves the keys
+// that the host owns, without the need for virtual nodes.
+type rendezvousPlacement struct {
+	hosts map[string]int
+}
+
+// NewRendezvousPlacement creates a Placement that uses weighted rendezvous
+// hashing.
+func NewRendezvousPlacement() Placement {
+	return &rendezvousPlacement{
+		hosts: make(map[string]int),
+	}
+}
+
+func (r *rendezvousPlacement) Add(host string, weight int) bool {
+	if _, ok := r.hosts[host]; ok {
+		return false
+	}
+
+	r.hosts[host] = normaliseWeight(weight)
+	return true
+}
+
+func (r *rendezvousPlacement) Remove(host string) bool {
+	if _, ok := r.hosts[host]; !ok {
+		return false
+	}
+
+	delete(r.hosts, host)
+	return true
+}
+
+func (r *rendezvousPlacement) LookupN(key string, n int) []string {
+	scores := make([]hostScore, 0, len(r.hosts))
+	for host, weight := range r.hosts {
+		scores = append(scores, hostScore{
+			host:  host,
+			score: rendezvousScore(host, key, weight),
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score == scores[j].score {
+			return scores[i].host < scores[j].host
+		}
+		return scores[i].score > scores[j].score
+	})
+
+	if n > len(scores) {
+		n = len(scores)
+	}
+
+	res := make([]string, n)
+	for k := range res {
+		res[k] = scores[k].host
+	}
+	return res
+}
+
+func (r *rendezvousPlacement) Contains(host string) bool {
+	_, ok := r.hosts[host]
+	return ok
+}
+
+func (r *rendezvousPlacement) Weight(host string) int {
+	return r.hosts[host]
+}
+
+func (r *rendezvousPlacement) Hosts() []string {
+	res := make([]string, 0, len(r.hosts))
+	for k := range r.hosts {
+		res = append(res, k)
+	}
+	return res
+}
+
+func (r *rendezvousPlacement) Len() int {
+	return len(r.hosts)
+}
+
+// Ownership has no ring to measure, so instead the ownership is sampled from
+// a fixed set of keys.
+func (r *rendezvousPlacement) Ownership() []Ownership {
+	hosts := make(map[string]*Ownership, len(r.hosts))
+	for host, weight := range r.hosts {
+		hosts[host] = &Ownership{
+			Host:   host,
+			Weight: weight,
+		}
+	}
+
+	if len(hosts) > 0 {
+		for i := 0; i < defaultOwnershipSamples; i++ {
+			for _, v := range r.LookupN(sampleKey(i), 1) {
+				hosts[v].Points++
+			}
+		}
+		for _, v := range hosts {
+			v.Percentage = (float64(v.Points) / defaultOwnershipSamples) * 100
+		}
+	}
+
+	return sortOwnership(hosts)
+}
+
+func (r *rendezvousPlacement) Clone() Placement {
+	hosts := make(map[string]int, len(r.hosts))
+	for host, weight := range r.hosts {
+		hosts[host] = weight
+	}
+	return &rendezvousPlacement{
+		hosts: hosts,
+	}
+}
+
+type hostScore struct {
+	host  string
+	score float64
+}
+
+// rendezvousScore uses the logarithmic method for weighted rendezvous hashing,
+// so that a host with twice the weight owns twice as many keys.
+func rendezvousScore(host, key string, weight int) float64 {
+	hash := murmur3.Sum64([]byte(host + key))
+
+	// Map the hash into the open interval (0, 1)
+	unit := (float64(hash>>11) + 0.5) / float64(uint64(1)<<53)
+	return -float64(weight) / math.Log(unit)
+}
+
+func sampleKey(i int) string {
+	var buf [8]byte
+	for k := range buf {
+		buf[k] = byte(i >> uint(k*8))
+	}
+	return string(buf[:])
+}
@@ -1,10 +1,12 @@
 package members
 
 import (
+	"encoding/base64"
 	"io"
 	"strconv"
 	"time"
 
+	"github.com/hashicorp/memberlist"
 	"github.com/pkg/errors"
 )
 
@@ -15,6 +17,22 @@ func (p PeerType) String() string {
 	return string(p)
 }
 
+// PeerStatus describes the status of a peer with in the cluster.
+type PeerStatus string
+
+const (
+	// PeerStatusAlive is a peer that is serving requests.
+	PeerStatusAlive PeerStatus = "alive"
+
+	// PeerStatusLeaving is a peer that is being decommissioned and shouldn't
+	// receive any writes.
+	PeerStatusLeaving PeerStatus = "leaving"
+)
+
+func (p PeerStatus) String() string {
+	return string(p)
+}
+
 // EventHandler is an alias for the event dispatcher
 type EventHandler interface {
 	HandleEvent(Event) error
@@ -53,6 +71,13 @@ type Members interface {
 	// Walk over a set of alive members
 	Walk(func(PeerInfo) error) error
 
+	// SetStatus updates the status of the local member, which is then
+	// advertised to the rest of the cluster.
+	SetStatus(PeerStatus) error
+
+	// Keyring is used to manage the gossip encryption keys of the cluster.
+	Keyring() Keyring
+
 	// Close the current members cluster
 	Close() error
 }
@@ -73,6 +98,38 @@ type MemberList interface {
 	Members() []Member
 }
 
+// Keyring manages the keys that the gossip is encrypted with. Every operation
+// is sent to all the members in the cluster, so a key can be rotated by
+// installing the new key, using it and then removing the old key.
+type Keyring interface {
+
+	// InstallKey installs a new base64 encoded key on every member.
+	InstallKey(key string) (KeyringStatus, error)
+
+	// UseKey changes the primary key, that's used to encrypt messages, on
+	// every member. The key must already be installed.
+	UseKey(key string) (KeyringStatus, error)
+
+	// RemoveKey removes a key from every member. The primary key can't be
+	// removed.
+	RemoveKey(key string) (KeyringStatus, error)
+
+	// ListKeys returns the keys installed on the members.
+	ListKeys() (KeyringStatus, error)
+}
+
+// KeyringStatus describes the outcome of a keyring operation across the
+// cluster.
+type KeyringStatus struct {
+	// Keys maps each base64 encoded key to the number of members that have it
+	// installed.
+	Keys     map[string]int    `json:"keys,omitempty"`
+	Messages map[string]string `json:"messages,omitempty"`
+	NumNodes int               `json:"num_nodes"`
+	NumResp  int               `json:"num_resp"`
+	NumErr   int               `json:"num_err"`
+}
+
 // Member represents a node in the cluster.
 type Member interface {
 
@@ -90,6 +147,7 @@ type Config struct {
 	nodeName         string
 	apiAddr          string
 	apiPort          int
+	weight           int
 	bindAddr         string
 	bindPort         int
 	advertiseAddr    string
@@ -99,6 +157,8 @@ type Config struct {
 	existing         []string
 	logOutput        io.Writer
 	broadcastTimeout time.Duration
+	keys             [][]byte
+	keyringFile      string
 }
 
 // Option defines a option for generating a filesystem Config
@@ -142,6 +202,17 @@ func WithAPIAddrPort(addr string, port int) Option {
 	}
 }
 
+// WithWeight adds a Weight to the configuration
+func WithWeight(weight int) Option {
+	return func(config *Config) error {
+		if weight < 1 {
+			return errors.Errorf("invalid weight %d, expected a weight of 1 or more", weight)
+		}
+		config.weight = weight
+		return nil
+	}
+}
+
 // WithBindAddrPort adds a BindAddr and BindPort to the configuration
 func WithBindAddrPort(addr string, port int) Option {
 	return func(config *Config) error {
@@ -193,12 +264,56 @@ func WithBroadcastTimeout(d time.Duration) Option {
 	}
 }
 
+// WithKeyring adds the base64 encoded gossip encryption keys to the
+// configuration, the first key is the primary key.
+func WithKeyring(keys []string) Option {
+	return func(config *Config) error {
+		decoded, err := decodeKeys(keys)
+		if err != nil {
+			return err
+		}
+		config.keys = decoded
+		return nil
+	}
+}
+
+// WithKeyringFile adds a KeyringFile to the configuration. The file holds a
+// JSON list of base64 encoded keys, with the primary key first. It's loaded
+// if it exists and is rewritten every time the keys change, so that a rotation
+// survives a restart.
+func WithKeyringFile(path string) Option {
+	return func(config *Config) error {
+		config.keyringFile = path
+		return nil
+	}
+}
+
+func decodeKeys(keys []string) ([][]byte, error) {
+	res := make([][]byte, len(keys))
+	for k, v := range keys {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode key %d", k)
+		}
+		if err := memberlist.ValidateKey(key); err != nil {
+			return nil, errors.Wrapf(err, "invalid key %d", k)
+		}
+		res[k] = key
+	}
+	return res, nil
+}
+
+// DefaultWeight is the weight of a peer that doesn't advertise a weight.
+const DefaultWeight = 1
+
 // PeerInfo describes what each peer is, along with the addr and port of each
 type PeerInfo struct {
-	Name    string
-	Type    PeerType
-	APIAddr string
-	APIPort int
+	Name    string     `json:"name"`
+	Type    PeerType   `json:"type"`
+	APIAddr string     `json:"api_addr"`
+	APIPort int        `json:"api_port"`
+	Weight  int        `json:"weight"`
+	Status  PeerStatus `json:"status"`
 }
 
 // encodeTagPeerInfo encodes the peer information for the node tags.
@@ -208,6 +323,8 @@ func encodePeerInfoTag(info PeerInfo) map[string]string {
 		"type":     string(info.Type),
 		"api_addr": info.APIAddr,
 		"api_port": strconv.Itoa(info.APIPort),
+		"weight":   strconv.Itoa(info.Weight),
+		"status":   string(info.Status),
 	}
 }
 
@@ -241,5 +358,19 @@ func decodePeerInfoTag(m map[string]string) (info PeerInfo, err error) {
 		return
 	}
 
+	// Older peers don't advertise a status or weight, so fallback to the
+	// defaults.
+	info.Status = PeerStatusAlive
+	if status, ok := m["status"]; ok && status != "" {
+		info.Status = PeerStatus(status)
+	}
+
+	info.Weight = DefaultWeight
+	if weight, ok := m["weight"]; ok {
+		if info.Weight, err = strconv.Atoi(weight); err != nil {
+			return
+		}
+	}
+
 	return
 }
@@ -1,12 +1,18 @@
 package members
 
 import (
+	"bytes"
+	"encoding/base64"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"testing/quick"
 	"time"
 
+	"github.com/go-kit/kit/log"
+	"github.com/hashicorp/serf/serf"
 	"github.com/pkg/errors"
 )
 
@@ -58,6 +64,270 @@ func TestBuilding(t *testing.T) {
 			t.Errorf("expected: %t, actual: %t", expected, actual)
 		}
 	})
+
+	t.Run("weight", func(t *testing.T) {
+		fn := func(weight uint8) bool {
+			config, err := Build(
+				WithWeight(int(weight) + 1),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return config.weight == int(weight)+1
+		}
+
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid weight", func(t *testing.T) {
+		_, err := Build(
+			WithWeight(0),
+		)
+
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+}
+
+func TestKeyring(t *testing.T) {
+	t.Parallel()
+
+	var (
+		primary   = "T9jncgl9mbLus+baTTa7q7nPSUrXwbDi2dhbtqir37s="
+		secondary = "5K9OtfP7efFrNKe5WCQvXvnaXJ5cWP0SvXiwe0kkjM4="
+	)
+
+	t.Run("keys", func(t *testing.T) {
+		config, err := Build(
+			WithKeyring([]string{primary, secondary}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := 2, len(config.keys); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("invalid encoding", func(t *testing.T) {
+		_, err := Build(
+			WithKeyring([]string{"!"}),
+		)
+
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		_, err := Build(
+			WithKeyring([]string{"YWJj"}),
+		)
+
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("transform", func(t *testing.T) {
+		config, err := Build(
+			WithKeyring([]string{primary, secondary}),
+			WithLogOutput(ioutil.Discard),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, serfConfig, _, err := transformConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keyring := serfConfig.MemberlistConfig.Keyring
+		if keyring == nil {
+			t.Fatal("expected keyring")
+		}
+		if expected, actual := primary, base64.StdEncoding.EncodeToString(keyring.GetPrimaryKey()); expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+	})
+
+	t.Run("seed keyring file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "keyring")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "keyring")
+		config, err := Build(
+			WithKeyring([]string{primary}),
+			WithKeyringFile(path),
+			WithLogOutput(ioutil.Discard),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		agentConfig, serfConfig, _, err := transformConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := path, serfConfig.KeyringFile; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := "", agentConfig.KeyringFile; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := `["`+primary+`"]`, string(bytes); expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+
+		// Once the file exists, it's loaded instead.
+		if agentConfig, _, _, err = transformConfig(config); err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := path, agentConfig.KeyringFile; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+	})
+
+	t.Run("missing keyring file", func(t *testing.T) {
+		config, err := Build(
+			WithKeyringFile(filepath.Join(os.TempDir(), "missing-keyring")),
+			WithLogOutput(ioutil.Discard),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, _, err := transformConfig(config); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestUserEventPayload(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key     = []byte("0123456789abcdef")
+		rotated = []byte("fedcba9876543210")
+	)
+
+	t.Run("encode and decode", func(t *testing.T) {
+		fn := func(name, origin string, payload []byte) bool {
+			o, p, err := decodeUserEventPayload(name, encodeUserEventPayload(name, origin, payload, key), [][]byte{key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return o == origin && bytes.Equal(p, payload)
+		}
+
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("encode and decode without keys", func(t *testing.T) {
+		fn := func(name, origin string, payload []byte) bool {
+			o, p, err := decodeUserEventPayload(name, encodeUserEventPayload(name, origin, payload, nil), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return o == origin && bytes.Equal(p, payload)
+		}
+
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("decode with any installed key", func(t *testing.T) {
+		b := encodeUserEventPayload("bloom", "peer", []byte("payload"), key)
+		if _, _, err := decodeUserEventPayload("bloom", b, [][]byte{rotated, key}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name    string
+		event   string
+		payload []byte
+		keys    [][]byte
+	}{
+		{"decode invalid", "bloom", []byte{userEventVersion, 10, 'a'}, nil},
+		{"decode older version", "bloom", []byte{4, 'p', 'e', 'e', 'r'}, nil},
+		{"decode unsigned", "bloom", encodeUserEventPayload("bloom", "peer", nil, nil), [][]byte{key}},
+		{"decode with another key", "bloom", encodeUserEventPayload("bloom", "peer", nil, rotated), [][]byte{key}},
+		{"decode another event", "other", encodeUserEventPayload("bloom", "peer", nil, key), [][]byte{key}},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			if _, _, err := decodeUserEventPayload(testcase.event, testcase.payload, testcase.keys); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestRealEventHandlerUserEvent(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key     = []byte("0123456789abcdef")
+		payload = []byte("payload")
+		known   = func(name string) bool { return name == "peer" }
+	)
+
+	for _, testcase := range []struct {
+		name     string
+		payload  []byte
+		expected bool
+	}{
+		{"known peer", encodeUserEventPayload("bloom", "peer", payload, key), true},
+		{"unknown peer", encodeUserEventPayload("bloom", "intruder", payload, key), false},
+		{"unsigned", encodeUserEventPayload("bloom", "peer", payload, nil), false},
+		{"missing origin", payload, false},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			var received []Event
+			h := realEventHandler{
+				fn: eventHandlerFunc(func(e Event) error {
+					received = append(received, e)
+					return nil
+				}),
+				known:  known,
+				keys:   func() [][]byte { return [][]byte{key} },
+				logger: log.NewNopLogger(),
+			}
+			h.HandleEvent(serf.UserEvent{Name: "bloom", Payload: testcase.payload})
+
+			if expected, actual := testcase.expected, len(received) == 1; expected != actual {
+				t.Fatalf("expected: %t, actual: %t", expected, actual)
+			}
+			if testcase.expected {
+				if expected, actual := payload, received[0].(*UserEvent).Payload; !bytes.Equal(expected, actual) {
+					t.Errorf("expected: %s, actual: %s", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+type eventHandlerFunc func(Event) error
+
+func (f eventHandlerFunc) HandleEvent(e Event) error {
+	return f(e)
 }
 
 func TestPeerInfo(t *testing.T) {
@@ -113,6 +383,94 @@ func TestPeerInfo(t *testing.T) {
 		}
 	})
 
+	t.Run("decode weight", func(t *testing.T) {
+		fn := func(name, peerType, addr string, port, weight int) bool {
+			m := encodePeerInfoTag(PeerInfo{
+				Name:    name,
+				Type:    PeerType(peerType),
+				APIAddr: addr,
+				APIPort: port,
+				Weight:  weight,
+			})
+
+			info, err := decodePeerInfoTag(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return info.Weight == weight
+		}
+
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("decode missing weight", func(t *testing.T) {
+		info, err := decodePeerInfoTag(map[string]string{
+			"name":     "a",
+			"type":     "x",
+			"api_addr": "y",
+			"api_port": "1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := DefaultWeight, info.Weight; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("decode weight integer failure", func(t *testing.T) {
+		_, err := decodePeerInfoTag(map[string]string{
+			"name":     "a",
+			"type":     "x",
+			"api_addr": "y",
+			"api_port": "1",
+			"weight":   "x",
+		})
+
+		if expected, actual := false, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
+	t.Run("decode status", func(t *testing.T) {
+		m := encodePeerInfoTag(PeerInfo{
+			Name:    "a",
+			Type:    PeerType("x"),
+			APIAddr: "y",
+			APIPort: 1,
+			Status:  PeerStatusLeaving,
+		})
+
+		info, err := decodePeerInfoTag(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := PeerStatusLeaving, info.Status; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+	})
+
+	t.Run("decode missing status", func(t *testing.T) {
+		info, err := decodePeerInfoTag(map[string]string{
+			"name":     "a",
+			"type":     "x",
+			"api_addr": "y",
+			"api_port": "1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := PeerStatusAlive, info.Status; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+	})
+
 	t.Run("decode type failure", func(t *testing.T) {
 		_, err := decodePeerInfoTag(map[string]string{
 			"api_port": "1",
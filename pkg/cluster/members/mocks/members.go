@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/SimonRichardson/coherence/pkg/cluster/members (interfaces: Members,MemberList,Member)
+// Source: github.com/SimonRichardson/coherence/pkg/cluster/members (interfaces: Members,MemberList,Member,Keyring)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -82,6 +82,18 @@ func (mr *MockMembersMockRecorder) Join() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Join", reflect.TypeOf((*MockMembers)(nil).Join))
 }
 
+// Keyring mocks base method
+func (m *MockMembers) Keyring() members.Keyring {
+	ret := m.ctrl.Call(m, "Keyring")
+	ret0, _ := ret[0].(members.Keyring)
+	return ret0
+}
+
+// Keyring indicates an expected call of Keyring
+func (mr *MockMembersMockRecorder) Keyring() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keyring", reflect.TypeOf((*MockMembers)(nil).Keyring))
+}
+
 // Leave mocks base method
 func (m *MockMembers) Leave() error {
 	ret := m.ctrl.Call(m, "Leave")
@@ -118,6 +130,18 @@ func (mr *MockMembersMockRecorder) RegisterEventHandler(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterEventHandler", reflect.TypeOf((*MockMembers)(nil).RegisterEventHandler), arg0)
 }
 
+// SetStatus mocks base method
+func (m *MockMembers) SetStatus(arg0 members.PeerStatus) error {
+	ret := m.ctrl.Call(m, "SetStatus", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStatus indicates an expected call of SetStatus
+func (mr *MockMembersMockRecorder) SetStatus(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatus", reflect.TypeOf((*MockMembers)(nil).SetStatus), arg0)
+}
+
 // Walk mocks base method
 func (m *MockMembers) Walk(arg0 func(members.PeerInfo) error) error {
 	ret := m.ctrl.Call(m, "Walk", arg0)
@@ -235,3 +259,78 @@ func (m *MockMember) Name() string {
 func (mr *MockMemberMockRecorder) Name() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockMember)(nil).Name))
 }
+
+// MockKeyring is a mock of Keyring interface
+type MockKeyring struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyringMockRecorder
+}
+
+// MockKeyringMockRecorder is the mock recorder for MockKeyring
+type MockKeyringMockRecorder struct {
+	mock *MockKeyring
+}
+
+// NewMockKeyring creates a new mock instance
+func NewMockKeyring(ctrl *gomock.Controller) *MockKeyring {
+	mock := &MockKeyring{ctrl: ctrl}
+	mock.recorder = &MockKeyringMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockKeyring) EXPECT() *MockKeyringMockRecorder {
+	return m.recorder
+}
+
+// InstallKey mocks base method
+func (m *MockKeyring) InstallKey(arg0 string) (members.KeyringStatus, error) {
+	ret := m.ctrl.Call(m, "InstallKey", arg0)
+	ret0, _ := ret[0].(members.KeyringStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstallKey indicates an expected call of InstallKey
+func (mr *MockKeyringMockRecorder) InstallKey(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallKey", reflect.TypeOf((*MockKeyring)(nil).InstallKey), arg0)
+}
+
+// ListKeys mocks base method
+func (m *MockKeyring) ListKeys() (members.KeyringStatus, error) {
+	ret := m.ctrl.Call(m, "ListKeys")
+	ret0, _ := ret[0].(members.KeyringStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListKeys indicates an expected call of ListKeys
+func (mr *MockKeyringMockRecorder) ListKeys() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListKeys", reflect.TypeOf((*MockKeyring)(nil).ListKeys))
+}
+
+// RemoveKey mocks base method
+func (m *MockKeyring) RemoveKey(arg0 string) (members.KeyringStatus, error) {
+	ret := m.ctrl.Call(m, "RemoveKey", arg0)
+	ret0, _ := ret[0].(members.KeyringStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveKey indicates an expected call of RemoveKey
+func (mr *MockKeyringMockRecorder) RemoveKey(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveKey", reflect.TypeOf((*MockKeyring)(nil).RemoveKey), arg0)
+}
+
+// UseKey mocks base method
+func (m *MockKeyring) UseKey(arg0 string) (members.KeyringStatus, error) {
+	ret := m.ctrl.Call(m, "UseKey", arg0)
+	ret0, _ := ret[0].(members.KeyringStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseKey indicates an expected call of UseKey
+func (mr *MockKeyringMockRecorder) UseKey(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseKey", reflect.TypeOf((*MockKeyring)(nil).UseKey), arg0)
+}
@@ -10,12 +10,21 @@ func (nopMembers) Join() (int, error)              { return 0, nil }
 func (nopMembers) Leave() error                    { return nil }
 func (nopMembers) MemberList() MemberList          { return nopMemberList{} }
 func (nopMembers) Walk(func(PeerInfo) error) error { return nil }
+func (nopMembers) SetStatus(PeerStatus) error      { return nil }
+func (nopMembers) Keyring() Keyring                { return nopKeyring{} }
 func (nopMembers) Close() error                    { return nil }
 
 func (nopMembers) RegisterEventHandler(EventHandler) error   { return nil }
 func (nopMembers) DeregisterEventHandler(EventHandler) error { return nil }
 func (nopMembers) DispatchEvent(Event) error                 { return nil }
 
+type nopKeyring struct{}
+
+func (nopKeyring) InstallKey(string) (KeyringStatus, error) { return KeyringStatus{}, nil }
+func (nopKeyring) UseKey(string) (KeyringStatus, error)     { return KeyringStatus{}, nil }
+func (nopKeyring) RemoveKey(string) (KeyringStatus, error)  { return KeyringStatus{}, nil }
+func (nopKeyring) ListKeys() (KeyringStatus, error)         { return KeyringStatus{}, nil }
+
 type nopMemberList struct{}
 
 func (nopMemberList) NumMembers() int   { return 0 }
@@ -44,6 +44,14 @@ func TestNopMembers(t *testing.T) {
 		}
 	})
 
+	t.Run("set status", func(t *testing.T) {
+		members := NewNopMembers()
+		err := members.SetStatus(PeerStatusLeaving)
+		if expected, actual := true, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+	})
+
 	t.Run("close", func(t *testing.T) {
 		members := NewNopMembers()
 		err := members.Close()
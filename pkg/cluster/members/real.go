@@ -1,8 +1,15 @@
 package members
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"sync"
 
 	"github.com/go-kit/kit/log"
@@ -15,6 +22,10 @@ import (
 
 const (
 	defaultAgentLogLevel = "WARN"
+
+	// userEventVersion is the version of the format of the user event
+	// payloads, it's the first byte of every payload.
+	userEventVersion = 1
 )
 
 type realMembers struct {
@@ -22,13 +33,19 @@ type realMembers struct {
 	mutex         sync.Mutex
 	agent         *agent.Agent
 	members       *serf.Serf
+	keyring       *memberlist.Keyring
 	eventHandlers map[EventHandler]agent.EventHandler
 	logger        log.Logger
 }
 
 // NewRealMembers creates a new members list to join.
 func NewRealMembers(config Config, logger log.Logger) (Members, error) {
-	actor, err := agent.Create(transformConfig(config))
+	agentConfig, serfConfig, logOutput, err := transformConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := agent.Create(agentConfig, serfConfig, logOutput)
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +59,7 @@ func NewRealMembers(config Config, logger log.Logger) (Members, error) {
 		mutex:         sync.Mutex{},
 		agent:         actor,
 		members:       actor.Serf(),
+		keyring:       serfConfig.MemberlistConfig.Keyring,
 		eventHandlers: make(map[EventHandler]agent.EventHandler),
 		logger:        logger,
 	}, nil
@@ -77,6 +95,17 @@ func (r *realMembers) Walk(fn func(PeerInfo) error) error {
 	return nil
 }
 
+func (r *realMembers) SetStatus(status PeerStatus) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.members.SetTags(encodePeerInfoTag(peerInfo(r.config, status)))
+}
+
+func (r *realMembers) Keyring() Keyring {
+	return realKeyring{r.agent}
+}
+
 func (r *realMembers) Close() error {
 	if err := r.members.Leave(); err != nil {
 		level.Warn(r.logger).Log("err", err)
@@ -88,8 +117,12 @@ func (r *realMembers) RegisterEventHandler(fn EventHandler) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	eh := realEventHandler{
+	// The agent keeps the handlers in a map, so a pointer is registered as the
+	// handler holds a func.
+	eh := &realEventHandler{
 		fn:     fn,
+		known:  r.known,
+		keys:   r.keys,
 		logger: log.With(r.logger, "component", "event_handler"),
 	}
 
@@ -113,12 +146,73 @@ func (r *realMembers) DeregisterEventHandler(fn EventHandler) error {
 func (r *realMembers) DispatchEvent(e Event) error {
 	switch t := e.(type) {
 	case *UserEvent:
-		return r.agent.UserEvent(t.Name, t.Payload, true)
+		var key []byte
+		if keys := r.keys(); len(keys) > 0 {
+			key = keys[0]
+		}
+		payload := encodeUserEventPayload(t.Name, r.config.nodeName, t.Payload, key)
+		return r.agent.UserEvent(t.Name, payload, true)
 	default:
 		return errors.Errorf("Unsupported event type %v", e.Type())
 	}
 }
 
+// known returns true if the name belongs to a member of the cluster, that's
+// either alive or leaving.
+func (r *realMembers) known(name string) bool {
+	for _, v := range r.members.Members() {
+		if v.Name == name && (v.Status == serf.StatusAlive || v.Status == serf.StatusLeaving) {
+			return true
+		}
+	}
+	return false
+}
+
+// keys returns the installed gossip keys, the primary key first. There aren't
+// any if the gossip isn't encrypted.
+func (r *realMembers) keys() [][]byte {
+	if r.keyring == nil {
+		return nil
+	}
+	return r.keyring.GetKeys()
+}
+
+type realKeyring struct {
+	agent *agent.Agent
+}
+
+func (r realKeyring) InstallKey(key string) (KeyringStatus, error) {
+	return keyringStatus(r.agent.InstallKey(key))
+}
+
+func (r realKeyring) UseKey(key string) (KeyringStatus, error) {
+	return keyringStatus(r.agent.UseKey(key))
+}
+
+func (r realKeyring) RemoveKey(key string) (KeyringStatus, error) {
+	return keyringStatus(r.agent.RemoveKey(key))
+}
+
+func (r realKeyring) ListKeys() (KeyringStatus, error) {
+	return keyringStatus(r.agent.ListKeys())
+}
+
+// keyringStatus transforms the response, which is returned even if the
+// operation failed on some of the members.
+func keyringStatus(resp *serf.KeyResponse, err error) (KeyringStatus, error) {
+	var status KeyringStatus
+	if resp != nil {
+		status = KeyringStatus{
+			Keys:     resp.Keys,
+			Messages: resp.Messages,
+			NumNodes: resp.NumNodes,
+			NumResp:  resp.NumResp,
+			NumErr:   resp.NumErr,
+		}
+	}
+	return status, err
+}
+
 type realMemberList struct {
 	list   *memberlist.Memberlist
 	logger log.Logger
@@ -155,6 +249,8 @@ func (r *realMember) Address() string {
 
 type realEventHandler struct {
 	fn     EventHandler
+	known  func(string) bool
+	keys   func() [][]byte
 	logger log.Logger
 }
 
@@ -163,7 +259,7 @@ func (h realEventHandler) HandleEvent(event serf.Event) {
 	case serf.MemberEvent:
 		h.handleMemberEvent(t)
 	case serf.UserEvent:
-		h.processEvent(NewUserEvent(t.Name, t.Payload))
+		h.handleUserEvent(t)
 	case *serf.Query:
 		h.processEvent(NewQueryEvent(t.Name, t.Payload, t))
 	case error:
@@ -204,6 +300,22 @@ func (h realEventHandler) handleMemberEvent(event serf.MemberEvent) {
 	h.processEvent(NewMemberEvent(t, m))
 }
 
+// handleUserEvent only processes the user events that were signed with one of
+// the gossip keys and name a member of the cluster, any other event is
+// dropped.
+func (h realEventHandler) handleUserEvent(event serf.UserEvent) {
+	origin, payload, err := decodeUserEventPayload(event.Name, event.Payload, h.keys())
+	if err != nil {
+		level.Warn(h.logger).Log("reason", "rejected user event", "event", event.Name, "err", err)
+		return
+	}
+	if !h.known(origin) {
+		level.Warn(h.logger).Log("reason", "rejected user event", "event", event.Name, "origin", origin, "err", "unknown peer")
+		return
+	}
+	h.processEvent(NewUserEvent(event.Name, payload))
+}
+
 func (h realEventHandler) processEvent(event Event) {
 	if event == nil {
 		return
@@ -214,7 +326,7 @@ func (h realEventHandler) processEvent(event Event) {
 	}
 }
 
-func transformConfig(config Config) (*agent.Config, *serf.Config, io.Writer) {
+func transformConfig(config Config) (*agent.Config, *serf.Config, io.Writer, error) {
 	agentConfig := agent.DefaultConfig()
 	agentConfig.LogLevel = defaultAgentLogLevel
 	if config.clientAddr != "" {
@@ -233,15 +345,139 @@ func transformConfig(config Config) (*agent.Config, *serf.Config, io.Writer) {
 	serfConfig.MemberlistConfig.LogOutput = config.logOutput
 	serfConfig.LogOutput = config.logOutput
 	serfConfig.BroadcastTimeout = config.broadcastTimeout
-	serfConfig.Tags = encodePeerInfoTag(PeerInfo{
+	serfConfig.Tags = encodePeerInfoTag(peerInfo(config, PeerStatusAlive))
+	serfConfig.Init()
+
+	if len(config.keys) > 0 {
+		keyring, err := memberlist.NewKeyring(config.keys, config.keys[0])
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "keyring")
+		}
+		serfConfig.MemberlistConfig.Keyring = keyring
+	}
+
+	// The keyring file takes precedence over the keys, as it holds the keys
+	// after any rotation. If it doesn't exist yet, it's seeded from the keys.
+	if config.keyringFile != "" {
+		serfConfig.KeyringFile = config.keyringFile
+
+		_, err := os.Stat(config.keyringFile)
+		switch {
+		case err == nil:
+			agentConfig.KeyringFile = config.keyringFile
+		case !os.IsNotExist(err):
+			return nil, nil, nil, errors.Wrap(err, "keyring file")
+		case len(config.keys) == 0:
+			return nil, nil, nil, errors.Errorf("keyring file %q doesn't exist and there are no keys to seed it", config.keyringFile)
+		default:
+			if err := writeKeyringFile(config.keyringFile, config.keys); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+
+	return agentConfig, serfConfig, config.logOutput, nil
+}
+
+func writeKeyringFile(path string, keys [][]byte) error {
+	encoded := make([]string, len(keys))
+	for k, v := range keys {
+		encoded[k] = base64.StdEncoding.EncodeToString(v)
+	}
+	bytes, err := json.Marshal(encoded)
+	if err != nil {
+		return errors.Wrap(err, "encode keyring")
+	}
+	return errors.Wrap(ioutil.WriteFile(path, bytes, 0600), "write keyring file")
+}
+
+// encodeUserEventPayload prefixes the payload with the version of the format,
+// the name of the member that sent it and a HMAC of the name of the event, the
+// member and the payload under the primary gossip key.
+//
+// The HMAC only proves that the event was sent by a holder of the gossip keys,
+// rather than injected through the RPC of an agent, any holder of the keys can
+// name any member. Without any keys the gossip isn't encrypted, so the events
+// aren't signed and the name of the member is only a claim.
+//
+// The nodes from before the version was added don't send it, so their events
+// are rejected, as are ours by them, until every node has been upgraded.
+func encodeUserEventPayload(name, origin string, payload, key []byte) []byte {
+	var mac []byte
+	if len(key) > 0 {
+		mac = userEventMAC(key, name, origin, payload)
+	}
+
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64*2+len(origin)+len(mac)+len(payload))
+	buf = append(buf, userEventVersion)
+	buf = appendBytes(buf, []byte(origin))
+	buf = appendBytes(buf, mac)
+	return append(buf, payload...)
+}
+
+// decodeUserEventPayload returns the member that sent the event and its
+// payload. If there are any gossip keys, the event has to be signed with one
+// of them.
+func decodeUserEventPayload(name string, b []byte, keys [][]byte) (string, []byte, error) {
+	if len(b) == 0 || b[0] != userEventVersion {
+		return "", nil, errors.New("unsupported user event version")
+	}
+	origin, b, err := readBytes(b[1:])
+	if err != nil {
+		return "", nil, err
+	}
+	mac, payload, err := readBytes(b)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(keys) == 0 {
+		return string(origin), payload, nil
+	}
+	for _, key := range keys {
+		if hmac.Equal(mac, userEventMAC(key, name, string(origin), payload)) {
+			return string(origin), payload, nil
+		}
+	}
+	return "", nil, errors.New("invalid user event signature")
+}
+
+func userEventMAC(key []byte, name, origin string, payload []byte) []byte {
+	var buf []byte
+	buf = appendBytes(buf, []byte(name))
+	buf = appendBytes(buf, []byte(origin))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(buf)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// appendBytes appends the bytes prefixed with their length.
+func appendBytes(buf, b []byte) []byte {
+	size := make([]byte, binary.MaxVarintLen64)
+	buf = append(buf, size[:binary.PutUvarint(size, uint64(len(b)))]...)
+	return append(buf, b...)
+}
+
+func readBytes(b []byte) ([]byte, []byte, error) {
+	size, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < size {
+		return nil, nil, errors.New("invalid user event payload")
+	}
+	b = b[n:]
+	return b[:size], b[size:], nil
+}
+
+func peerInfo(config Config, status PeerStatus) PeerInfo {
+	return PeerInfo{
 		Name:    config.nodeName,
 		Type:    config.peerType,
 		APIAddr: config.apiAddr,
 		APIPort: config.apiPort,
-	})
-	serfConfig.Init()
-
-	return agentConfig, serfConfig, config.logOutput
+		Weight:  config.weight,
+		Status:  status,
+	}
 }
 
 type eventMember struct {
@@ -113,7 +113,7 @@ func TestRealMembers_Integration(t *testing.T) {
 		})
 
 		want := []PeerInfo{
-			PeerInfo{Type: PeerType(""), Name: "peer", APIAddr: "0.0.0.0", APIPort: 8079},
+			PeerInfo{Type: PeerType(""), Name: "peer", APIAddr: "0.0.0.0", APIPort: 8079, Status: PeerStatusAlive},
 		}
 		if expected, actual := want, got; !reflect.DeepEqual(expected, actual) {
 			t.Errorf("expected: %v, actual: %v", expected, actual)
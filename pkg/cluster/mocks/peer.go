@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	cluster "github.com/SimonRichardson/coherence/pkg/cluster"
 	members "github.com/SimonRichardson/coherence/pkg/cluster/members"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
@@ -129,6 +130,19 @@ func (mr *MockPeerMockRecorder) Leave() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Leave", reflect.TypeOf((*MockPeer)(nil).Leave))
 }
 
+// Leaving mocks base method
+func (m *MockPeer) Leaving(arg0 members.PeerType) ([]string, error) {
+	ret := m.ctrl.Call(m, "Leaving", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Leaving indicates an expected call of Leaving
+func (mr *MockPeerMockRecorder) Leaving(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Leaving", reflect.TypeOf((*MockPeer)(nil).Leaving), arg0)
+}
+
 // Name mocks base method
 func (m *MockPeer) Name() string {
 	ret := m.ctrl.Call(m, "Name")
@@ -153,14 +167,40 @@ func (mr *MockPeerMockRecorder) RegisterEventHandler(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterEventHandler", reflect.TypeOf((*MockPeer)(nil).RegisterEventHandler), arg0)
 }
 
+// SetStatus mocks base method
+func (m *MockPeer) SetStatus(arg0 members.PeerStatus) error {
+	ret := m.ctrl.Call(m, "SetStatus", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStatus indicates an expected call of SetStatus
+func (mr *MockPeerMockRecorder) SetStatus(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatus", reflect.TypeOf((*MockPeer)(nil).SetStatus), arg0)
+}
+
 // State mocks base method
-func (m *MockPeer) State() map[string]interface{} {
+func (m *MockPeer) State() (cluster.State, error) {
 	ret := m.ctrl.Call(m, "State")
-	ret0, _ := ret[0].(map[string]interface{})
-	return ret0
+	ret0, _ := ret[0].(cluster.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // State indicates an expected call of State
 func (mr *MockPeerMockRecorder) State() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockPeer)(nil).State))
 }
+
+// Weights mocks base method
+func (m *MockPeer) Weights(arg0 members.PeerType, arg1 bool) (map[string]int, error) {
+	ret := m.ctrl.Call(m, "Weights", arg0, arg1)
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Weights indicates an expected call of Weights
+func (mr *MockPeerMockRecorder) Weights(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Weights", reflect.TypeOf((*MockPeer)(nil).Weights), arg0, arg1)
+}
@@ -0,0 +1,281 @@
+package nodes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/api/client"
+	apiStore "github.com/SimonRichardson/coherence/pkg/api/store"
+	"github.com/SimonRichardson/coherence/pkg/api/transports"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trussle/fsys"
+)
+
+// TestVirtualRemoteEquivalence makes sure that the virtual node, which talks
+// directly to the local store, behaves in the same way as a remote node that
+// talks to the store over the transport.
+func TestVirtualRemoteEquivalence(t *testing.T) {
+	t.Parallel()
+
+	t.Run("identity", func(t *testing.T) {
+		virtual, remote, closer := newEquivalentNodes(t)
+		defer closer()
+
+		// The local replica is labelled the same as it would be remotely.
+		if expected, actual := remote.Hash(), virtual.Hash(); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+		if expected, actual := remote.Host(), virtual.Host(); expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("insert", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			return equivalent(t, virtual.Insert(key, members), remote.Insert(key, members))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			insert(t, key, members, virtual, remote)
+
+			return equivalent(t, virtual.Delete(key, members), remote.Delete(key, members))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("select", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore, missing selectors.Field) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			insert(t, key, members, virtual, remote)
+
+			for _, v := range members {
+				if !equivalent(t, virtual.Select(key, v.Field), remote.Select(key, v.Field)) {
+					return false
+				}
+			}
+			return equivalent(t, virtual.Select(key, missing), remote.Select(key, missing))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			insert(t, key, members, virtual, remote)
+
+			return equivalent(t, virtual.Keys(), remote.Keys())
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			insert(t, key, members, virtual, remote)
+
+			return equivalent(t, virtual.Size(key), remote.Size(key))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("members", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			insert(t, key, members, virtual, remote)
+
+			return equivalent(t, virtual.Members(key), remote.Members(key))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("score", func(t *testing.T) {
+		fn := func(key selectors.Key, members []selectors.FieldValueScore, missing selectors.Field) bool {
+			virtual, remote, closer := newEquivalentNodes(t)
+			defer closer()
+
+			insert(t, key, members, virtual, remote)
+
+			for _, v := range members {
+				if !equivalent(t, virtual.Score(key, v.Field), remote.Score(key, v.Field)) {
+					return false
+				}
+			}
+			return equivalent(t, virtual.Score(key, missing), remote.Score(key, missing))
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// newEquivalentNodes creates a virtual node and a remote node, each backed by
+// their own empty store.
+func newEquivalentNodes(t *testing.T) (Node, Node, func()) {
+	newStore := func() store.Store {
+		s, err := store.New(fsys.NewNopFilesystem(), 1, 1000, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	api := apiStore.NewAPI(
+		newStore(),
+		log.NewNopLogger(),
+		prometheus.NewGauge(prometheus.GaugeOpts{Name: "clients"}),
+		prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "path", "status_code"}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/store/", http.StripPrefix("/store", api))
+	server := httptest.NewServer(mux)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		transport = transports.NewHTTPTransport(client.New(http.DefaultClient, "http", u.Host))
+		virtual   = NewVirtual(transport.Hash(), transport.Host(), newStore())
+		remote    = NewRemote(transport)
+	)
+	return virtual, remote, func() {
+		server.Close()
+		api.Close()
+	}
+}
+
+func insert(t *testing.T, key selectors.Key, members []selectors.FieldValueScore, nodes ...Node) {
+	for _, node := range nodes {
+		for element := range node.Insert(key, members) {
+			if err := selectors.ErrorFromElement(element); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// equivalent checks that both channels of elements contain the same values,
+// or both contain errors.
+func equivalent(t *testing.T, a, b <-chan selectors.Element) bool {
+	x, y := normalise(collect(a)), normalise(collect(b))
+	if !reflect.DeepEqual(x, y) {
+		t.Errorf("expected: %#v, actual: %#v", x, y)
+		return false
+	}
+	return true
+}
+
+func collect(ch <-chan selectors.Element) []selectors.Element {
+	var res []selectors.Element
+	for element := range ch {
+		res = append(res, element)
+	}
+	return res
+}
+
+func normalise(elements []selectors.Element) []interface{} {
+	res := make([]interface{}, len(elements))
+	for k, element := range elements {
+		if err := selectors.ErrorFromElement(element); err != nil {
+			res[k] = struct {
+				Hash     uint32
+				NotFound bool
+			}{element.Hash(), selectors.NotFoundError(err)}
+			continue
+		}
+
+		switch element.(type) {
+		case *selectors.ChangeSetElement:
+			changeSet := selectors.ChangeSetFromElement(element)
+			res[k] = struct {
+				Hash             uint32
+				Success, Failure []string
+			}{element.Hash(), sortedFields(changeSet.Success), sortedFields(changeSet.Failure)}
+		case *selectors.KeysElement:
+			keys := selectors.KeysFromElement(element)
+			values := make([]string, len(keys))
+			for i, v := range keys {
+				values[i] = v.String()
+			}
+			sort.Strings(values)
+			res[k] = struct {
+				Hash uint32
+				Keys []string
+			}{element.Hash(), values}
+		case *selectors.FieldsElement:
+			res[k] = struct {
+				Hash   uint32
+				Fields []string
+			}{element.Hash(), sortedFields(selectors.FieldsFromElement(element))}
+		case *selectors.FieldValueScoreElement:
+			member := selectors.FieldValueScoreFromElement(element)
+			res[k] = struct {
+				Hash  uint32
+				Field string
+				Value string
+				Score int64
+			}{element.Hash(), member.Field.String(), string(member.Value), member.Score}
+		case *selectors.Int64Element:
+			res[k] = struct {
+				Hash uint32
+				Size int64
+			}{element.Hash(), selectors.Int64FromElement(element)}
+		case *selectors.PresenceElement:
+			res[k] = struct {
+				Hash     uint32
+				Presence selectors.Presence
+			}{element.Hash(), selectors.PresenceFromElement(element)}
+		default:
+			res[k] = element
+		}
+	}
+	return res
+}
+
+func sortedFields(fields []selectors.Field) []string {
+	res := make([]string, len(fields))
+	for k, v := range fields {
+		res[k] = v.String()
+	}
+	sort.Strings(res)
+	return res
+}
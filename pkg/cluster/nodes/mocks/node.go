@@ -105,6 +105,30 @@ func (mr *MockNodeMockRecorder) Members(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockNode)(nil).Members), arg0)
 }
 
+// Scan mocks base method
+func (m *MockNode) Scan(arg0 selectors.Cursor, arg1 selectors.ScanOptions) <-chan selectors.Element {
+	ret := m.ctrl.Call(m, "Scan", arg0, arg1)
+	ret0, _ := ret[0].(<-chan selectors.Element)
+	return ret0
+}
+
+// Scan indicates an expected call of Scan
+func (mr *MockNodeMockRecorder) Scan(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockNode)(nil).Scan), arg0, arg1)
+}
+
+// ScanMembers mocks base method
+func (m *MockNode) ScanMembers(arg0 selectors.Key, arg1 selectors.Cursor, arg2 selectors.ScanOptions) <-chan selectors.Element {
+	ret := m.ctrl.Call(m, "ScanMembers", arg0, arg1, arg2)
+	ret0, _ := ret[0].(<-chan selectors.Element)
+	return ret0
+}
+
+// ScanMembers indicates an expected call of ScanMembers
+func (mr *MockNodeMockRecorder) ScanMembers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanMembers", reflect.TypeOf((*MockNode)(nil).ScanMembers), arg0, arg1, arg2)
+}
+
 // Score mocks base method
 func (m *MockNode) Score(arg0 selectors.Key, arg1 selectors.Field) <-chan selectors.Element {
 	ret := m.ctrl.Call(m, "Score", arg0, arg1)
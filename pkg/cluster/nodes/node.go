@@ -1,6 +1,8 @@
 package nodes
 
 import (
+	"context"
+
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 )
 
@@ -40,4 +42,56 @@ type Node interface {
 
 	// Score returns the value of the field in a key
 	Score(selectors.Key, selectors.Field) <-chan selectors.Element
+
+	// Scan returns the next page of keys after the cursor
+	Scan(selectors.Cursor, selectors.ScanOptions) <-chan selectors.Element
+
+	// ScanMembers returns the next page of members of a key after the cursor
+	ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions) <-chan selectors.Element
+}
+
+// Watcher is implemented by the nodes that can stream the changes of a key,
+// as they're applied to the store of the node.
+type Watcher interface {
+
+	// Watch subscribes to the changes of a key, resuming from either an offset
+	// or a score.
+	Watch(selectors.Key, selectors.Resume) (selectors.Subscription, error)
+}
+
+// Swapper is implemented by the nodes that can swap a member of their store,
+// only if its score is still the token.
+type Swapper interface {
+
+	// Swap inserts the member only if its field is inserted with the score
+	// of the token. The field is a failure of the ChangeSet if the score
+	// doesn't match.
+	Swap(selectors.Key, selectors.FieldValueScore, int64) <-chan selectors.Element
+}
+
+// Contextual is implemented by the nodes that can send the trace of a context
+// along with their requests.
+type Contextual interface {
+
+	// WithContext returns a copy of the node that sends the trace of the
+	// context.
+	WithContext(context.Context) Node
+}
+
+// WithContext returns the node sending the trace of the context, if it can,
+// otherwise the node as it is.
+func WithContext(ctx context.Context, node Node) Node {
+	if contextual, ok := node.(Contextual); ok {
+		return contextual.WithContext(ctx)
+	}
+	return node
+}
+
+// Circuit is implemented by the nodes that can report the state of the
+// circuit in front of them.
+type Circuit interface {
+
+	// CircuitOpen returns if the circuit is open, refusing requests. ok is
+	// false if the node has no circuit.
+	CircuitOpen() (open, ok bool)
 }
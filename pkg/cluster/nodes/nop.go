@@ -97,6 +97,30 @@ func (nop) Score(selectors.Key, selectors.Field) <-chan selectors.Element {
 	return ch
 }
 
+func (nop) Scan(selectors.Cursor, selectors.ScanOptions) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+
+		ch <- selectors.NewKeysPageElement(defaultHash, selectors.KeysPage{
+			Keys: make([]selectors.Key, 0),
+		})
+	}()
+	return ch
+}
+
+func (nop) ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+
+		ch <- selectors.NewFieldsPageElement(defaultHash, selectors.FieldsPage{
+			Fields: make([]selectors.Field, 0),
+		})
+	}()
+	return ch
+}
+
 func (nop) Hash() uint32 {
 	return 0
 }
@@ -1,8 +1,11 @@
 package nodes
 
 import (
+	"context"
+
 	"github.com/SimonRichardson/coherence/pkg/api"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
 )
 
 type remote struct {
@@ -18,6 +21,24 @@ func NewRemote(transport api.Transport) Node {
 	}
 }
 
+// WithContext returns a copy of the node whose transport sends the trace of
+// the context, if the transport can.
+func (r *remote) WithContext(ctx context.Context) Node {
+	return &remote{
+		hash:      r.hash,
+		transport: api.WithContext(ctx, r.transport),
+	}
+}
+
+// CircuitOpen returns if the circuit of the transport is open, ok is false if
+// the transport has no circuit.
+func (r *remote) CircuitOpen() (open, ok bool) {
+	if circuit, ok := r.transport.(api.Circuit); ok {
+		return circuit.CircuitOpen(), true
+	}
+	return false, false
+}
+
 func (r *remote) Insert(key selectors.Key, fields []selectors.FieldValueScore) <-chan selectors.Element {
 	ch := make(chan selectors.Element)
 	go func() {
@@ -109,6 +130,58 @@ func (r *remote) Score(key selectors.Key, field selectors.Field) <-chan selector
 	return ch
 }
 
+func (r *remote) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+		if value, err := r.transport.Scan(cursor, opts); err != nil {
+			ch <- selectors.NewErrorElement(r.hash, err)
+		} else {
+			ch <- selectors.NewKeysPageElement(r.hash, value)
+		}
+	}()
+	return ch
+}
+
+func (r *remote) ScanMembers(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+		if value, err := r.transport.ScanMembers(key, cursor, opts); err != nil {
+			ch <- selectors.NewErrorElement(r.hash, err)
+		} else {
+			ch <- selectors.NewFieldsPageElement(r.hash, value)
+		}
+	}()
+	return ch
+}
+
+func (r *remote) Swap(key selectors.Key, field selectors.FieldValueScore, token int64) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+		swapper, ok := r.transport.(api.Swapper)
+		if !ok {
+			ch <- selectors.NewErrorElement(r.hash, errors.Errorf("transport for %s doesn't support swapping", r.transport.Host()))
+			return
+		}
+		if value, err := swapper.Swap(key, field, token); err != nil {
+			ch <- selectors.NewErrorElement(r.hash, err)
+		} else {
+			ch <- selectors.NewChangeSetElement(r.hash, value)
+		}
+	}()
+	return ch
+}
+
+func (r *remote) Watch(key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	watcher, ok := r.transport.(api.Watcher)
+	if !ok {
+		return nil, errors.Errorf("transport for %s doesn't support watching", r.transport.Host())
+	}
+	return watcher.Watch(key, resume)
+}
+
 func (r *remote) Hash() uint32 {
 	return r.hash
 }
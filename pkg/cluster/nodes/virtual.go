@@ -7,13 +7,20 @@ import (
 
 type virtual struct {
 	hash  uint32
+	host  string
 	store store.Store
 }
 
-// NewVirtual creates a local storage
-func NewVirtual(hash uint32, store store.Store) Node {
+// NewVirtual creates a Node that talks directly to the local store, without
+// going through a transport. Every element is tagged with the hash, so that
+// the node can be identified in the same way as a remote node, the host is
+// the API address of the local node, so that it's labelled in the same way.
+// The store is written from the goroutines of the farm, alongside the local
+// APIs, so it has to be safe for concurrent use.
+func NewVirtual(hash uint32, host string, store store.Store) Node {
 	return &virtual{
 		hash:  hash,
+		host:  host,
 		store: store,
 	}
 }
@@ -25,10 +32,10 @@ func (v *virtual) Insert(key selectors.Key, members []selectors.FieldValueScore)
 
 		changeSet, err := v.store.Insert(key, members)
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewChangeSetElement(defaultHash, changeSet)
+		ch <- selectors.NewChangeSetElement(v.hash, changeSet)
 	}()
 	return ch
 }
@@ -40,10 +47,10 @@ func (v *virtual) Delete(key selectors.Key, members []selectors.FieldValueScore)
 
 		changeSet, err := v.store.Delete(key, members)
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewChangeSetElement(defaultHash, changeSet)
+		ch <- selectors.NewChangeSetElement(v.hash, changeSet)
 	}()
 	return ch
 }
@@ -55,10 +62,10 @@ func (v *virtual) Select(key selectors.Key, field selectors.Field) <-chan select
 
 		member, err := v.store.Select(key, field)
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewFieldValueScoreElement(defaultHash, member)
+		ch <- selectors.NewFieldValueScoreElement(v.hash, member)
 	}()
 	return ch
 }
@@ -70,10 +77,10 @@ func (v *virtual) Keys() <-chan selectors.Element {
 
 		keys, err := v.store.Keys()
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewKeysElement(defaultHash, keys)
+		ch <- selectors.NewKeysElement(v.hash, keys)
 	}()
 	return ch
 }
@@ -85,10 +92,10 @@ func (v *virtual) Size(key selectors.Key) <-chan selectors.Element {
 
 		size, err := v.store.Size(key)
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewInt64Element(defaultHash, size)
+		ch <- selectors.NewInt64Element(v.hash, size)
 	}()
 	return ch
 }
@@ -100,10 +107,10 @@ func (v *virtual) Members(key selectors.Key) <-chan selectors.Element {
 
 		members, err := v.store.Members(key)
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewFieldsElement(defaultHash, members)
+		ch <- selectors.NewFieldsElement(v.hash, members)
 	}()
 	return ch
 }
@@ -115,18 +122,67 @@ func (v *virtual) Score(key selectors.Key, field selectors.Field) <-chan selecto
 
 		score, err := v.store.Score(key, field)
 		if err != nil {
-			ch <- selectors.NewErrorElement(defaultHash, err)
+			ch <- selectors.NewErrorElement(v.hash, err)
 			return
 		}
-		ch <- selectors.NewPresenceElement(defaultHash, score)
+		ch <- selectors.NewPresenceElement(v.hash, score)
 	}()
 	return ch
 }
 
+func (v *virtual) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+
+		page, err := v.store.Scan(cursor, opts)
+		if err != nil {
+			ch <- selectors.NewErrorElement(v.hash, err)
+			return
+		}
+		ch <- selectors.NewKeysPageElement(v.hash, page)
+	}()
+	return ch
+}
+
+func (v *virtual) ScanMembers(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+
+		page, err := v.store.ScanMembers(key, cursor, opts)
+		if err != nil {
+			ch <- selectors.NewErrorElement(v.hash, err)
+			return
+		}
+		ch <- selectors.NewFieldsPageElement(v.hash, page)
+	}()
+	return ch
+}
+
+func (v *virtual) Swap(key selectors.Key, field selectors.FieldValueScore, token int64) <-chan selectors.Element {
+	ch := make(chan selectors.Element)
+	go func() {
+		defer close(ch)
+
+		changeSet, err := v.store.Swap(key, field, token)
+		if err != nil {
+			ch <- selectors.NewErrorElement(v.hash, err)
+			return
+		}
+		ch <- selectors.NewChangeSetElement(v.hash, changeSet)
+	}()
+	return ch
+}
+
+func (v *virtual) Watch(key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	return v.store.Watch(key, resume)
+}
+
 func (v *virtual) Hash() uint32 {
 	return v.hash
 }
 
 func (v *virtual) Host() string {
-	return ""
+	return v.host
 }
@@ -2,13 +2,18 @@ package nodes
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"testing/quick"
 
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
 	"github.com/SimonRichardson/coherence/pkg/store/mocks"
+	"github.com/go-kit/kit/log"
 	"github.com/golang/mock/gomock"
+	"github.com/trussle/fsys"
 )
 
 func TestVirtualInsert(t *testing.T) {
@@ -22,7 +27,7 @@ func TestVirtualInsert(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Insert(key, members).Return(selectors.ChangeSet{}, errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Insert(key, members)
 
@@ -54,7 +59,7 @@ func TestVirtualInsert(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Insert(key, members).Return(want, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Insert(key, members)
 
@@ -90,7 +95,7 @@ func TestVirtualDelete(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Delete(key, members).Return(selectors.ChangeSet{}, errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Delete(key, members)
 
@@ -122,7 +127,7 @@ func TestVirtualDelete(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Delete(key, members).Return(want, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Delete(key, members)
 
@@ -158,7 +163,7 @@ func TestVirtualSelect(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Select(key, member.Field).Return(member, errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Select(key, member.Field)
 
@@ -184,7 +189,7 @@ func TestVirtualSelect(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Select(key, member.Field).Return(member, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Select(key, member.Field)
 
@@ -221,7 +226,7 @@ func TestVirtualKeys(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Keys().Return(nil, errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Keys()
 
@@ -247,7 +252,7 @@ func TestVirtualKeys(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Keys().Return(keys, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Keys()
 
@@ -284,7 +289,7 @@ func TestVirtualSize(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Size(key).Return(int64(0), errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Size(key)
 
@@ -310,7 +315,7 @@ func TestVirtualSize(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Size(key).Return(size, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Size(key)
 
@@ -347,7 +352,7 @@ func TestVirtualMembers(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Members(key).Return(nil, errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Members(key)
 
@@ -373,7 +378,7 @@ func TestVirtualMembers(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Members(key).Return(fields, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Members(key)
 
@@ -410,7 +415,7 @@ func TestVirtualScore(t *testing.T) {
 			store := mocks.NewMockStore(ctrl)
 			store.EXPECT().Score(key, field).Return(selectors.Presence{}, errors.New("bad"))
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Score(key, field)
 
@@ -440,7 +445,7 @@ func TestVirtualScore(t *testing.T) {
 				Score:    1,
 			}, nil)
 
-			node := NewVirtual(0, store)
+			node := NewVirtual(0, "", store)
 
 			ch := node.Score(key, field)
 
@@ -469,3 +474,66 @@ func TestVirtualScore(t *testing.T) {
 		}
 	})
 }
+
+// TestVirtualConcurrentWrites writes to the same store through the virtual
+// node, as the farm does, and directly, as the store API does, at the same
+// time. Run with -race to catch any write that isn't serialised.
+func TestVirtualConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	const (
+		writers = 8
+		writes  = 200
+		keys    = 8
+	)
+
+	persistence, err := store.New(fsys.NewNopFilesystem(), 2, 16, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := NewVirtual(0, "", persistence)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < writes; j++ {
+				var (
+					key     = selectors.Key(fmt.Sprintf("key-%d", j%keys))
+					members = []selectors.FieldValueScore{{
+						Field: selectors.Field(fmt.Sprintf("field-%d", i)),
+						Value: []byte("value"),
+						Score: int64(j),
+					}}
+				)
+				if i%2 == 0 {
+					for element := range node.Insert(key, members) {
+						if err := selectors.ErrorFromElement(element); err != nil {
+							t.Error(err)
+						}
+					}
+					for range node.Delete(key, members) {
+					}
+					continue
+				}
+				if _, err := persistence.Insert(key, members); err != nil {
+					t.Error(err)
+				}
+				if _, err := persistence.Members(key); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stored, err := persistence.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := keys, len(stored); expected != actual {
+		t.Errorf("expected: %d, actual: %d", expected, actual)
+	}
+}
@@ -85,15 +85,34 @@ func (p *peer) ClusterSize() int {
 	return p.members.MemberList().NumMembers()
 }
 
-// State returns a JSON-serializable dump of cluster state.
-// Useful for debug.
-func (p *peer) State() map[string]interface{} {
-	members := p.members.MemberList()
-	return map[string]interface{}{
-		"self":        members.LocalNode().Name(),
-		"members":     memberNames(members.Members()),
-		"num_members": members.NumMembers(),
+// State returns the state of the cluster, as this peer sees it, including the
+// tags that each of the alive members advertise.
+func (p *peer) State() (State, error) {
+	tags := make(map[string]members.PeerInfo)
+	if err := p.members.Walk(func(info members.PeerInfo) error {
+		tags[info.Name] = info
+		return nil
+	}); err != nil {
+		return State{}, errors.Wrap(err, "walking members")
+	}
+
+	list := p.members.MemberList()
+	state := State{
+		Self:       list.LocalNode().Name(),
+		NumMembers: list.NumMembers(),
+		Members:    make([]MemberState, 0),
 	}
+	for _, v := range list.Members() {
+		member := MemberState{
+			Name:    v.Name(),
+			Address: v.Address(),
+		}
+		if info, ok := tags[member.Name]; ok {
+			member.Tags = &info
+		}
+		state.Members = append(state.Members, member)
+	}
+	return state, nil
 }
 
 // Current API host:ports for the given type of node.
@@ -113,6 +132,48 @@ func (p *peer) Current(peerType members.PeerType, includeLocal bool) (res []stri
 	return
 }
 
+// Weights returns the advertised weight for each API host:port for the given
+// type of node.
+// IncludeLocal doesn't add the local cluster node to the resulting set.
+func (p *peer) Weights(peerType members.PeerType, includeLocal bool) (map[string]int, error) {
+	var (
+		localName = p.Name()
+		res       = make(map[string]int)
+	)
+	err := p.members.Walk(func(info members.PeerInfo) error {
+		if !includeLocal && info.Name == localName {
+			return nil
+		}
+
+		if peerType == PeerTypeStore && info.Type == PeerTypeStore {
+			res[net.JoinHostPort(info.APIAddr, strconv.Itoa(info.APIPort))] = info.Weight
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Leaving returns the API host:ports for the given type of node, that are
+// advertising that they're leaving the cluster.
+func (p *peer) Leaving(peerType members.PeerType) (res []string, err error) {
+	err = p.members.Walk(func(info members.PeerInfo) error {
+		if info.Status != members.PeerStatusLeaving {
+			return nil
+		}
+
+		if peerType == PeerTypeStore && info.Type == PeerTypeStore {
+			res = append(res, net.JoinHostPort(info.APIAddr, strconv.Itoa(info.APIPort)))
+		}
+		return nil
+	})
+	return
+}
+
+// SetStatus advertises the status of the local node to the cluster.
+func (p *peer) SetStatus(status members.PeerStatus) error {
+	return p.members.SetStatus(status)
+}
+
 func (p *peer) RegisterEventHandler(fn members.EventHandler) error {
 	return p.members.RegisterEventHandler(fn)
 }
@@ -124,11 +185,3 @@ func (p *peer) DeregisterEventHandler(fn members.EventHandler) error {
 func (p *peer) DispatchEvent(e members.Event) error {
 	return p.members.DispatchEvent(e)
 }
-
-func memberNames(m []members.Member) []string {
-	res := make([]string, len(m))
-	for k, v := range m {
-		res[k] = v.Name()
-	}
-	return res
-}
@@ -177,6 +177,27 @@ func TestPeer(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
+			// Only the first member is alive, so only it advertises tags.
+			var alive []members.PeerInfo
+			if len(memberNames) > 0 {
+				alive = append(alive, members.PeerInfo{
+					Name:    memberNames[0],
+					Type:    PeerTypeStore,
+					APIAddr: "10.0.0.0",
+					APIPort: 8080,
+					Weight:  members.DefaultWeight,
+					Status:  members.PeerStatusAlive,
+				})
+			}
+			walk := func(fn func(members.PeerInfo) error) error {
+				for _, v := range alive {
+					if err := fn(v); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
 			var (
 				m = make([]members.Member, len(memberNames))
 
@@ -185,13 +206,32 @@ func TestPeer(t *testing.T) {
 				member     = mocks.NewMockMember(ctrl)
 			)
 
+			want := State{
+				Self:       name,
+				NumMembers: size,
+				Members:    make([]MemberState, 0),
+			}
 			for k, v := range memberNames {
+				address := fmt.Sprintf("10.0.0.%d:8080", k)
+
 				n := mocks.NewMockMember(ctrl)
 				n.EXPECT().Name().Return(v).Times(1)
+				n.EXPECT().Address().Return(address).Times(1)
 
 				m[k] = n
+				want.Members = append(want.Members, MemberState{
+					Name:    v,
+					Address: address,
+				})
+				if v == alive[0].Name {
+					want.Members[k].Tags = &alive[0]
+				}
 			}
 
+			members.EXPECT().
+				Walk(gomock.Any()).
+				DoAndReturn(walk).
+				Times(1)
 			members.EXPECT().
 				MemberList().
 				Return(memberlist).
@@ -215,12 +255,11 @@ func TestPeer(t *testing.T) {
 
 			p := NewPeer(members, log.NewNopLogger())
 
-			want := map[string]interface{}{
-				"self":        name,
-				"members":     memberNames,
-				"num_members": size,
+			state, err := p.State()
+			if err != nil {
+				t.Fatal(err)
 			}
-			return reflect.DeepEqual(p.State(), want)
+			return reflect.DeepEqual(state, want)
 		}
 
 		if err := quick.Check(fn, nil); err != nil {
@@ -228,6 +267,22 @@ func TestPeer(t *testing.T) {
 		}
 	})
 
+	t.Run("state with walk failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		members := mocks.NewMockMembers(ctrl)
+		members.EXPECT().
+			Walk(gomock.Any()).
+			Return(errors.New("bad")).
+			Times(1)
+
+		p := NewPeer(members, log.NewNopLogger())
+		if _, err := p.State(); err == nil {
+			t.Errorf("expected: error, actual: %v", err)
+		}
+	})
+
 	t.Run("current", func(t *testing.T) {
 		fn := func(hosts generators.ASCIISlice, name generators.ASCII) bool {
 			hostStrings := hosts.Slice()
@@ -276,10 +331,133 @@ func TestPeer(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("weights", func(t *testing.T) {
+		fn := func(hosts generators.ASCIISlice, name generators.ASCII) bool {
+			hostStrings := hosts.Slice()
+			if len(hostStrings) == 0 {
+				return true
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			member := mocks.NewMockMember(ctrl)
+			member.EXPECT().
+				Name().
+				Return(name.String())
+
+			memberList := mocks.NewMockMemberList(ctrl)
+			memberList.EXPECT().
+				LocalNode().
+				Return(member)
+
+			members := mocks.NewMockMembers(ctrl)
+			members.EXPECT().
+				MemberList().
+				Return(memberList)
+			members.EXPECT().
+				Walk(Func(hostStrings)).
+				Return(nil)
+
+			p := NewPeer(members, log.NewNopLogger())
+			got, err := p.Weights(PeerTypeStore, false)
+
+			if expected, actual := true, err == nil; expected != actual {
+				t.Errorf("expected: %t, actual: %t", expected, actual)
+			}
+
+			want := make(map[string]int, len(hostStrings))
+			for _, v := range hostStrings {
+				want[fmt.Sprintf("%s:%d", v, 8080)] = 1
+			}
+
+			return reflect.DeepEqual(want, got)
+		}
+
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("leaving", func(t *testing.T) {
+		fn := func(hosts generators.ASCIISlice) bool {
+			hostStrings := hosts.Slice()
+			if len(hostStrings) == 0 {
+				return true
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			matcher := FuncWithStatus(hostStrings, members.PeerStatusLeaving)
+
+			members := mocks.NewMockMembers(ctrl)
+			members.EXPECT().
+				Walk(matcher).
+				Return(nil)
+
+			p := NewPeer(members, log.NewNopLogger())
+			got, err := p.Leaving(PeerTypeStore)
+
+			if expected, actual := true, err == nil; expected != actual {
+				t.Errorf("expected: %t, actual: %t", expected, actual)
+			}
+
+			want := make([]string, len(hostStrings))
+			for k, v := range hostStrings {
+				want[k] = fmt.Sprintf("%s:%d", v, 8080)
+			}
+
+			return reflect.DeepEqual(want, got)
+		}
+
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("not leaving", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		members := mocks.NewMockMembers(ctrl)
+		members.EXPECT().
+			Walk(Func([]string{"a", "b"})).
+			Return(nil)
+
+		p := NewPeer(members, log.NewNopLogger())
+		got, err := p.Leaving(PeerTypeStore)
+
+		if expected, actual := true, err == nil; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+		if expected, actual := 0, len(got); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("set status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		status := members.PeerStatusLeaving
+
+		members := mocks.NewMockMembers(ctrl)
+		members.EXPECT().
+			SetStatus(status).
+			Return(nil)
+
+		p := NewPeer(members, log.NewNopLogger())
+		if err := p.SetStatus(status); err != nil {
+			t.Error(err)
+		}
+	})
 }
 
 type funcMatcher struct {
-	hosts []string
+	hosts  []string
+	status members.PeerStatus
 }
 
 func (m funcMatcher) Matches(x interface{}) bool {
@@ -290,6 +468,8 @@ func (m funcMatcher) Matches(x interface{}) bool {
 				Name:    uuid.MustNew().String(),
 				APIAddr: v,
 				APIPort: 8080,
+				Weight:  members.DefaultWeight,
+				Status:  m.status,
 			}); err != nil {
 				panic(err)
 			}
@@ -303,4 +483,10 @@ func (funcMatcher) String() string {
 	return "is func"
 }
 
-func Func(hosts []string) gomock.Matcher { return funcMatcher{hosts} }
+func Func(hosts []string) gomock.Matcher {
+	return funcMatcher{hosts, members.PeerStatusAlive}
+}
+
+func FuncWithStatus(hosts []string, status members.PeerStatus) gomock.Matcher {
+	return funcMatcher{hosts, status}
+}
@@ -251,6 +251,22 @@ func find(node *RBNode, n int, key int, m map[string]struct{}, s *[]string) {
 	find(node.right, n, key, m, s)
 }
 
+// Walk iterates through the tree in ascending key order, calling fn for every
+// key and value.
+func (t *RBTree) Walk(fn func(key int, value string)) {
+	walk(t.root, fn)
+}
+
+func walk(node *RBNode, fn func(int, string)) {
+	if node == nil {
+		return
+	}
+
+	walk(node.left, fn)
+	fn(node.key, node.value)
+	walk(node.right, fn)
+}
+
 // Search searches for a value in the redBlackTree, returns the string and true
 // if found or the empty string and false if val is not in the tree.
 func (t *RBTree) Search(key int) (string, bool) {
@@ -530,6 +530,40 @@ func TestSearch(t *testing.T) {
 	})
 }
 
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty", func(t *testing.T) {
+		var keys []int
+		NewRBTree().Walk(func(key int, value string) {
+			keys = append(keys, key)
+		})
+
+		if expected, actual := 0, len(keys); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("ordered", func(t *testing.T) {
+		var (
+			keys   []int
+			values []string
+		)
+		makeTree().Walk(func(key int, value string) {
+			keys = append(keys, key)
+			values = append(values, value)
+		})
+
+		want := []int{1, 2, 3, 4, 5, 6, 7, 8}
+		if expected, actual := want, keys; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := []string{"1", "2", "3", "4", "5", "6", "7", "8"}, values; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
 func makeTreeWithAmount(amount int) *RBTree {
 	tree := NewRBTree()
 
@@ -0,0 +1,114 @@
+package keyspaces
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// Keyspace is the configuration of a keyspace.
+type Keyspace struct {
+	Name selectors.Keyspace
+
+	// Buckets and Size are the number of buckets of the keyspace and the
+	// number of members each bucket holds, 0 uses the size of the default
+	// keyspace.
+	Buckets uint
+	Size    uint
+
+	// Bytes is the maximum number of bytes of the values with in the
+	// keyspace, 0 is unlimited.
+	Bytes int64
+
+	// Quorum and Replication are the quorums of the reads and writes that
+	// don't ask for one, the empty quorum uses the default of the API.
+	Quorum      selectors.Quorum
+	Replication selectors.Quorum
+}
+
+type fileConfig struct {
+	Keyspaces []struct {
+		Name        string `json:"name"`
+		Buckets     uint   `json:"buckets"`
+		Size        uint   `json:"size"`
+		Bytes       int64  `json:"bytes"`
+		Quorum      string `json:"quorum"`
+		Replication string `json:"replication"`
+	} `json:"keyspaces"`
+}
+
+// Load reads the keyspaces from the file, which looks like:
+//
+//	{
+//	  "keyspaces": [
+//	    {"name": "team-a", "buckets": 10, "size": 1000, "bytes": 1048576, "quorum": "consensus", "replication": "strong"}
+//	  ]
+//	}
+func Load(path string) ([]Keyspace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open keyspaces config")
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads the keyspaces from the reader.
+func Parse(r io.Reader) ([]Keyspace, error) {
+	var file fileConfig
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, errors.Wrap(err, "decode keyspaces config")
+	}
+
+	var (
+		res  = make([]Keyspace, len(file.Keyspaces))
+		seen = make(map[selectors.Keyspace]struct{}, len(file.Keyspaces))
+	)
+	for k, v := range file.Keyspaces {
+		name, err := selectors.ParseKeyspace(v.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "keyspace %d", k)
+		}
+		if name == selectors.DefaultKeyspace {
+			return nil, errors.Errorf("expected a name for keyspace %d", k)
+		}
+		if _, ok := seen[name]; ok {
+			return nil, errors.Errorf("duplicate keyspace %q", name)
+		}
+		seen[name] = struct{}{}
+
+		if v.Bytes < 0 {
+			return nil, errors.Errorf("expected a positive number of bytes for keyspace %q", name)
+		}
+
+		quorum, err := parseQuorum(v.Quorum)
+		if err != nil {
+			return nil, errors.Wrapf(err, "keyspace %q", name)
+		}
+		replication, err := parseQuorum(v.Replication)
+		if err != nil {
+			return nil, errors.Wrapf(err, "keyspace %q", name)
+		}
+
+		res[k] = Keyspace{
+			Name:        name,
+			Buckets:     v.Buckets,
+			Size:        v.Size,
+			Bytes:       v.Bytes,
+			Quorum:      quorum,
+			Replication: replication,
+		}
+	}
+	return res, nil
+}
+
+func parseQuorum(s string) (selectors.Quorum, error) {
+	if s == "" {
+		return "", nil
+	}
+	return selectors.ParseQuorum(s)
+}
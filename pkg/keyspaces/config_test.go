@@ -0,0 +1,59 @@
+package keyspaces
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		keyspaces, err := Parse(strings.NewReader(`{
+			"keyspaces": [
+				{"name": "team-a", "buckets": 2, "size": 10, "bytes": 1024, "quorum": "one", "replication": "strong"},
+				{"name": "team-b"}
+			]
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []Keyspace{
+			{
+				Name:        "team-a",
+				Buckets:     2,
+				Size:        10,
+				Bytes:       1024,
+				Quorum:      selectors.One,
+				Replication: selectors.Strong,
+			},
+			{Name: "team-b"},
+		}
+		if actual := keyspaces; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name   string
+		config string
+	}{
+		{"invalid json", `{`},
+		{"missing name", `{"keyspaces": [{"buckets": 1}]}`},
+		{"invalid name", `{"keyspaces": [{"name": "Team"}]}`},
+		{"duplicate name", `{"keyspaces": [{"name": "a"}, {"name": "a"}]}`},
+		{"negative bytes", `{"keyspaces": [{"name": "a", "bytes": -1}]}`},
+		{"invalid quorum", `{"keyspaces": [{"name": "a", "quorum": "all"}]}`},
+		{"invalid replication", `{"keyspaces": [{"name": "a", "replication": "all"}]}`},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(testcase.config)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
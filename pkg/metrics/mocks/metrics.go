@@ -1,12 +1,12 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/SimonRichardson/coherence/pkg/metrics (interfaces: Gauge,HistogramVec,Counter)
+// Source: github.com/SimonRichardson/coherence/pkg/metrics (interfaces: Gauge,GaugeVec,HistogramVec,Counter,CounterVec)
 
 // Package mocks is a generated GoMock package.
 package mocks
 
 import (
-	prometheus "github.com/prometheus/client_golang/prometheus"
 	gomock "github.com/golang/mock/gomock"
+	prometheus "github.com/prometheus/client_golang/prometheus"
 	reflect "reflect"
 )
 
@@ -53,6 +53,71 @@ func (mr *MockGaugeMockRecorder) Inc() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Inc", reflect.TypeOf((*MockGauge)(nil).Inc))
 }
 
+// Set mocks base method
+func (m *MockGauge) Set(arg0 float64) {
+	m.ctrl.Call(m, "Set", arg0)
+}
+
+// Set indicates an expected call of Set
+func (mr *MockGaugeMockRecorder) Set(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockGauge)(nil).Set), arg0)
+}
+
+// MockGaugeVec is a mock of GaugeVec interface
+type MockGaugeVec struct {
+	ctrl     *gomock.Controller
+	recorder *MockGaugeVecMockRecorder
+}
+
+// MockGaugeVecMockRecorder is the mock recorder for MockGaugeVec
+type MockGaugeVecMockRecorder struct {
+	mock *MockGaugeVec
+}
+
+// NewMockGaugeVec creates a new mock instance
+func NewMockGaugeVec(ctrl *gomock.Controller) *MockGaugeVec {
+	mock := &MockGaugeVec{ctrl: ctrl}
+	mock.recorder = &MockGaugeVecMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockGaugeVec) EXPECT() *MockGaugeVecMockRecorder {
+	return m.recorder
+}
+
+// DeleteLabelValues mocks base method
+func (m *MockGaugeVec) DeleteLabelValues(arg0 ...string) bool {
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteLabelValues", varargs...)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DeleteLabelValues indicates an expected call of DeleteLabelValues
+func (mr *MockGaugeVecMockRecorder) DeleteLabelValues(arg0 ...interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLabelValues", reflect.TypeOf((*MockGaugeVec)(nil).DeleteLabelValues), arg0...)
+}
+
+// WithLabelValues mocks base method
+func (m *MockGaugeVec) WithLabelValues(arg0 ...string) prometheus.Gauge {
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WithLabelValues", varargs...)
+	ret0, _ := ret[0].(prometheus.Gauge)
+	return ret0
+}
+
+// WithLabelValues indicates an expected call of WithLabelValues
+func (mr *MockGaugeVecMockRecorder) WithLabelValues(arg0 ...interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithLabelValues", reflect.TypeOf((*MockGaugeVec)(nil).WithLabelValues), arg0...)
+}
+
 // MockHistogramVec is a mock of HistogramVec interface
 type MockHistogramVec struct {
 	ctrl     *gomock.Controller
@@ -134,3 +199,42 @@ func (m *MockCounter) Inc() {
 func (mr *MockCounterMockRecorder) Inc() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Inc", reflect.TypeOf((*MockCounter)(nil).Inc))
 }
+
+// MockCounterVec is a mock of CounterVec interface
+type MockCounterVec struct {
+	ctrl     *gomock.Controller
+	recorder *MockCounterVecMockRecorder
+}
+
+// MockCounterVecMockRecorder is the mock recorder for MockCounterVec
+type MockCounterVecMockRecorder struct {
+	mock *MockCounterVec
+}
+
+// NewMockCounterVec creates a new mock instance
+func NewMockCounterVec(ctrl *gomock.Controller) *MockCounterVec {
+	mock := &MockCounterVec{ctrl: ctrl}
+	mock.recorder = &MockCounterVecMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCounterVec) EXPECT() *MockCounterVecMockRecorder {
+	return m.recorder
+}
+
+// WithLabelValues mocks base method
+func (m *MockCounterVec) WithLabelValues(arg0 ...string) prometheus.Counter {
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WithLabelValues", varargs...)
+	ret0, _ := ret[0].(prometheus.Counter)
+	return ret0
+}
+
+// WithLabelValues indicates an expected call of WithLabelValues
+func (mr *MockCounterVecMockRecorder) WithLabelValues(arg0 ...interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithLabelValues", reflect.TypeOf((*MockCounterVec)(nil).WithLabelValues), arg0...)
+}
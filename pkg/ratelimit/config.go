@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+// Config is what's loaded from the rate limit config file, which looks like:
+//
+//	{
+//	  "client": {"rate": 100, "burst": 200},
+//	  "prefixes": [{"keyspace": "team-a", "prefix": "a/", "rate": 10, "burst": 20}],
+//	  "max_pending_writes": 1000
+//	}
+//
+// A rate of 0 doesn't limit the requests, as does a max_pending_writes of 0.
+type Config struct {
+	Client           Limit
+	Prefixes         []PrefixLimit
+	MaxPendingWrites int
+}
+
+type fileLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+type fileConfig struct {
+	Client   fileLimit `json:"client"`
+	Prefixes []struct {
+		fileLimit
+		Keyspace string `json:"keyspace"`
+		Prefix   string `json:"prefix"`
+	} `json:"prefixes"`
+	MaxPendingWrites int `json:"max_pending_writes"`
+}
+
+// Load reads the Config from the file.
+func Load(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "open rate limit config")
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads the Config from the reader.
+func Parse(r io.Reader) (Config, error) {
+	var file fileConfig
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return Config{}, errors.Wrap(err, "decode rate limit config")
+	}
+
+	client, err := file.Client.decode()
+	if err != nil {
+		return Config{}, errors.Wrap(err, "client")
+	}
+
+	prefixes := make([]PrefixLimit, len(file.Prefixes))
+	for k, v := range file.Prefixes {
+		keyspace, err := selectors.ParseKeyspace(v.Keyspace)
+		if err != nil {
+			return Config{}, errors.Wrapf(err, "prefix %d", k)
+		}
+		limit, err := v.fileLimit.decode()
+		if err != nil {
+			return Config{}, errors.Wrapf(err, "prefix %d", k)
+		}
+		prefixes[k] = PrefixLimit{
+			Keyspace: keyspace,
+			Prefix:   v.Prefix,
+			Limit:    limit,
+		}
+	}
+
+	if file.MaxPendingWrites < 0 {
+		return Config{}, errors.Errorf("expected a positive max_pending_writes, got %d", file.MaxPendingWrites)
+	}
+
+	return Config{
+		Client:           client,
+		Prefixes:         prefixes,
+		MaxPendingWrites: file.MaxPendingWrites,
+	}, nil
+}
+
+func (l fileLimit) decode() (Limit, error) {
+	if l.Rate < 0 || l.Burst < 0 {
+		return Limit{}, errors.Errorf("expected a positive rate and burst, got %v and %d", l.Rate, l.Burst)
+	}
+	return Limit{Rate: l.Rate, Burst: l.Burst}, nil
+}
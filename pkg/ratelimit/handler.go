@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	errs "github.com/SimonRichardson/coherence/pkg/api/http"
+	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// These are the reasons that requests are rejected for, they're the labels of
+// the rejected metric.
+const (
+	ReasonClient = "client"
+	ReasonPrefix = "prefix"
+	ReasonShed   = "shed"
+)
+
+// PrefixLimit limits the requests for the keys with in the keyspace that
+// start with the prefix, across every client.
+type PrefixLimit struct {
+	Keyspace selectors.Keyspace
+	Prefix   string
+	Limit    Limit
+}
+
+// Identify returns the identity of the client that sent the request.
+type Identify func(*http.Request) string
+
+// RemoteHost identifies the client by the host it's connected from.
+func RemoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Handler rate limits the requests of an API before passing them on. Every
+// client is limited on its own and every prefix is limited across all the
+// clients.
+type Handler struct {
+	next     http.Handler
+	identify Identify
+	clients  *Limiter
+	prefixes []prefixLimiter
+	rejected metrics.CounterVec
+	logger   log.Logger
+	errors   errs.Error
+}
+
+type prefixLimiter struct {
+	PrefixLimit
+	limiter *Limiter
+}
+
+// NewHandler creates a Handler that limits each client to the client limit
+// and the requests for each prefix to the limit of the prefix.
+func NewHandler(next http.Handler,
+	client Limit,
+	prefixes []PrefixLimit,
+	identify Identify,
+	rejected metrics.CounterVec,
+	logger log.Logger,
+) *Handler {
+	limiters := make([]prefixLimiter, len(prefixes))
+	for k, v := range prefixes {
+		limiters[k] = prefixLimiter{
+			PrefixLimit: v,
+			limiter:     NewLimiter(v.Limit),
+		}
+	}
+	return &Handler{
+		next:     next,
+		identify: identify,
+		clients:  NewLimiter(client),
+		prefixes: limiters,
+		rejected: rejected,
+		logger:   logger,
+		errors:   errs.NewError(logger),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := h.identify(r)
+	if ok, retryAfter := h.clients.Take(client); !ok {
+		h.reject(w, r, ReasonClient, client, retryAfter)
+		return
+	}
+
+	if limiter, ok := h.prefix(r); ok {
+		if ok, retryAfter := limiter.limiter.Take(limiter.Prefix); !ok {
+			h.reject(w, r, ReasonPrefix, client, retryAfter)
+			return
+		}
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+// prefix returns the limiter with the longest prefix that the key of the
+// request starts with.
+func (h *Handler) prefix(r *http.Request) (prefixLimiter, bool) {
+	keyspace, _, err := errs.Keyspace(r)
+	if err != nil {
+		// The request is rejected by the API.
+		return prefixLimiter{}, false
+	}
+
+	var (
+		key   = errs.KeyPrefix(r)
+		res   prefixLimiter
+		found bool
+	)
+	for _, v := range h.prefixes {
+		if v.Keyspace != keyspace || !strings.HasPrefix(key, v.Prefix) {
+			continue
+		}
+		if !found || len(v.Prefix) > len(res.Prefix) {
+			res, found = v, true
+		}
+	}
+	return res, found
+}
+
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, reason, client string, retryAfter time.Duration) {
+	level.Debug(h.logger).Log("url", r.URL.String(), "client", client, "reason", reason, "retry_after", retryAfter)
+
+	if h.rejected != nil {
+		h.rejected.WithLabelValues(reason).Inc()
+	}
+	h.errors.TooManyRequests(w, r, retryAfter)
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	identify := func(r *http.Request) string {
+		return r.Header.Get("X-Client")
+	}
+
+	serve := func(handler http.Handler, client, url string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("X-Client", client)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("client", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rejected := metricMocks.NewMockCounterVec(ctrl)
+		rejected.EXPECT().WithLabelValues(ReasonClient).Return(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rejected",
+		})).Times(1)
+
+		handler := NewHandler(next, Limit{Rate: 1, Burst: 1}, nil, identify, rejected, log.NewNopLogger())
+
+		if expected, actual := http.StatusOK, serve(handler, "a", "/select?key=a").Code; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		w := serve(handler, "a", "/select?key=a")
+		if expected, actual := http.StatusTooManyRequests, w.Code; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+		if expected, actual := "1", w.Header().Get("Retry-After"); expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+
+		if expected, actual := http.StatusOK, serve(handler, "b", "/select?key=a").Code; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rejected := metricMocks.NewMockCounterVec(ctrl)
+		rejected.EXPECT().WithLabelValues(ReasonPrefix).Return(prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rejected",
+		})).Times(2)
+
+		handler := NewHandler(next, Limit{}, []PrefixLimit{
+			{Prefix: "a/", Limit: Limit{Rate: 1, Burst: 1}},
+			{Prefix: "a/b/", Limit: Limit{Rate: 1, Burst: 2}},
+			{Keyspace: "team-a", Prefix: "", Limit: Limit{Rate: 1, Burst: 1}},
+		}, identify, rejected, log.NewNopLogger())
+
+		for _, test := range []struct {
+			client, url string
+			code        int
+		}{
+			{"a", "/select?key=a/1", http.StatusOK},
+			{"b", "/select?key=a/2", http.StatusTooManyRequests},
+			{"a", "/select?key=a/b/1", http.StatusOK},
+			{"b", "/keys?match=a/b/*", http.StatusOK},
+			{"a", "/select?key=c/1", http.StatusOK},
+			{"a", "/keyspaces/team-a/select?key=a/1", http.StatusOK},
+			{"b", "/keyspaces/team-a/select?key=c/1", http.StatusTooManyRequests},
+		} {
+			if expected, actual := test.code, serve(handler, test.client, test.url).Code; expected != actual {
+				t.Errorf("%s: expected: %d, actual: %d", test.url, expected, actual)
+			}
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid", func(t *testing.T) {
+		config, err := Parse(strings.NewReader(`{
+			"client": {"rate": 100, "burst": 200},
+			"prefixes": [{"keyspace": "team-a", "prefix": "a/", "rate": 10, "burst": 20}],
+			"max_pending_writes": 1000
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := (Limit{Rate: 100, Burst: 200}), config.Client; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := (PrefixLimit{Keyspace: "team-a", Prefix: "a/", Limit: Limit{Rate: 10, Burst: 20}}), config.Prefixes[0]; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := 1000, config.MaxPendingWrites; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name   string
+		config string
+	}{
+		{"invalid json", `{`},
+		{"negative rate", `{"client": {"rate": -1}}`},
+		{"negative burst", `{"prefixes": [{"prefix": "a", "rate": 1, "burst": -1}]}`},
+		{"invalid keyspace", `{"prefixes": [{"keyspace": "A", "rate": 1}]}`},
+		{"negative pending", `{"max_pending_writes": -1}`},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(testcase.config)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
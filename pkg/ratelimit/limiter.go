@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval is how often the buckets that are full are dropped, so
+	// that the clients that have gone away aren't kept around.
+	sweepInterval = time.Minute
+)
+
+// Limit is the rate of a token bucket, along with the number of tokens it can
+// hold, which is the burst of requests that's allowed after being idle.
+type Limit struct {
+	// Rate is the number of tokens that are added every second, 0 doesn't
+	// limit the requests.
+	Rate float64
+
+	// Burst is the number of tokens the bucket holds, it's at least 1.
+	Burst int
+}
+
+// Enabled returns true if the Limit limits the requests.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+func (l Limit) burst() float64 {
+	return math.Max(float64(l.Burst), 1)
+}
+
+// Limiter holds a token bucket for every key, each one is limited to the same
+// Limit.
+type Limiter struct {
+	mutex     sync.Mutex
+	limit     Limit
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter for the limit.
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		limit:     limit,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take takes a token from the bucket of the key. If there isn't one, it
+// returns false along with how long it is until there is.
+func (l *Limiter) Take(key string) (bool, time.Duration) {
+	if !l.limit.Enabled() {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.limit.burst(), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.fill(b, now)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / l.limit.Rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// fill returns the tokens of the bucket once the tokens since it was last
+// taken from are added.
+func (l *Limiter) fill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*l.limit.Rate
+	return math.Min(tokens, l.limit.burst())
+}
+
+// sweep drops the buckets that are full, as they're the same as a bucket that
+// hasn't been created yet.
+func (l *Limiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+		if l.fill(b, now) >= l.limit.burst() {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastSweep = now
+}
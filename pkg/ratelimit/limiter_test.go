@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	newLimiter := func(limit Limit) (*Limiter, *time.Time) {
+		now := time.Now()
+		limiter := NewLimiter(limit)
+		limiter.now = func() time.Time { return now }
+		return limiter, &now
+	}
+
+	t.Run("burst", func(t *testing.T) {
+		limiter, _ := newLimiter(Limit{Rate: 1, Burst: 3})
+
+		for i := 0; i < 3; i++ {
+			if ok, _ := limiter.Take("a"); !ok {
+				t.Fatalf("expected take %d to be allowed", i)
+			}
+		}
+		ok, retryAfter := limiter.Take("a")
+		if ok {
+			t.Fatal("expected take to be limited")
+		}
+		if expected, actual := time.Second, retryAfter; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		// Other keys have their own bucket.
+		if ok, _ := limiter.Take("b"); !ok {
+			t.Errorf("expected take to be allowed")
+		}
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		limiter, now := newLimiter(Limit{Rate: 10, Burst: 1})
+
+		if ok, _ := limiter.Take("a"); !ok {
+			t.Fatal("expected take to be allowed")
+		}
+		if ok, _ := limiter.Take("a"); ok {
+			t.Fatal("expected take to be limited")
+		}
+
+		*now = now.Add(time.Millisecond * 100)
+		if ok, _ := limiter.Take("a"); !ok {
+			t.Errorf("expected take to be allowed")
+		}
+	})
+
+	t.Run("sweep", func(t *testing.T) {
+		limiter, now := newLimiter(Limit{Rate: 1, Burst: 1})
+
+		limiter.Take("a")
+		*now = now.Add(sweepInterval * 2)
+		limiter.Take("b")
+
+		if _, ok := limiter.buckets["a"]; ok {
+			t.Errorf("expected full bucket to be swept")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		limiter, _ := newLimiter(Limit{})
+
+		for i := 0; i < 100; i++ {
+			if ok, _ := limiter.Take("a"); !ok {
+				t.Fatalf("expected take %d to be allowed", i)
+			}
+		}
+	})
+}
+
+func TestShedder(t *testing.T) {
+	t.Parallel()
+
+	shedder := NewShedder(2)
+	for i := 0; i < 2; i++ {
+		if ok, _ := shedder.Acquire(); !ok {
+			t.Fatalf("expected acquire %d to be allowed", i)
+		}
+	}
+	if ok, _ := shedder.Acquire(); ok {
+		t.Fatal("expected acquire to be shed")
+	}
+	if expected, actual := 2, shedder.Pending(); expected != actual {
+		t.Errorf("expected: %d, actual: %d", expected, actual)
+	}
+
+	shedder.Release()
+	if ok, _ := shedder.Acquire(); !ok {
+		t.Errorf("expected acquire to be allowed")
+	}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultShedRetryAfter = time.Second
+)
+
+// Shedder sheds the writes once too many of them are waiting to be applied,
+// so that a backed up queue doesn't keep on growing.
+type Shedder struct {
+	max     int64
+	pending int64
+}
+
+// NewShedder creates a Shedder that lets through max writes at once, 0 doesn't
+// shed any writes.
+func NewShedder(max int) *Shedder {
+	return &Shedder{max: int64(max)}
+}
+
+// Acquire returns true if the write can go ahead, in which case it has to be
+// released once it's been applied. Otherwise it returns how long to wait
+// before retrying.
+func (s *Shedder) Acquire() (bool, time.Duration) {
+	pending := atomic.AddInt64(&s.pending, 1)
+	if s.max > 0 && pending > s.max {
+		atomic.AddInt64(&s.pending, -1)
+		return false, defaultShedRetryAfter
+	}
+	return true, 0
+}
+
+// Release releases a write that was acquired.
+func (s *Shedder) Release() {
+	atomic.AddInt64(&s.pending, -1)
+}
+
+// Pending returns the number of writes that are waiting or being applied.
+func (s *Shedder) Pending() int {
+	return int(atomic.LoadInt64(&s.pending))
+}
@@ -27,6 +27,12 @@ const (
 
 	// FieldValueScoreElementType describes an element with a field, value score payload
 	FieldValueScoreElementType
+
+	// KeysPageElementType describes an element with a page of keys payload
+	KeysPageElementType
+
+	// FieldsPageElementType describes an element with a page of fields payload
+	FieldsPageElementType
 )
 
 // Element combines a submitted key with the resulting values. If there was an
@@ -303,3 +309,70 @@ func FieldValueScoreFromElement(e Element) FieldValueScore {
 	}
 	return FieldValueScore{}
 }
+
+// KeysPageElement defines a struct that is a container for a page of keys.
+type KeysPageElement struct {
+	typ  ElementType
+	hash uint32
+	val  KeysPage
+}
+
+// NewKeysPageElement creates a new KeysPageElement
+func NewKeysPageElement(hash uint32, val KeysPage) *KeysPageElement {
+	return &KeysPageElement{KeysPageElementType, hash, val}
+}
+
+// Type defines the type associated with the KeysPageElement
+func (e *KeysPageElement) Type() ElementType { return e.typ }
+
+// Hash defines the hash associated with the KeysPageElement
+func (e *KeysPageElement) Hash() uint32 { return e.hash }
+
+// KeysPage defines the KeysPage associated with the KeysPageElement
+func (e *KeysPageElement) KeysPage() KeysPage { return e.val }
+
+type keysPageElement interface {
+	KeysPage() KeysPage
+}
+
+// KeysPageFromElement attempts to get a KeysPage from the element if it exists.
+func KeysPageFromElement(e Element) KeysPage {
+	if v, ok := e.(keysPageElement); ok {
+		return v.KeysPage()
+	}
+	return KeysPage{}
+}
+
+// FieldsPageElement defines a struct that is a container for a page of fields.
+type FieldsPageElement struct {
+	typ  ElementType
+	hash uint32
+	val  FieldsPage
+}
+
+// NewFieldsPageElement creates a new FieldsPageElement
+func NewFieldsPageElement(hash uint32, val FieldsPage) *FieldsPageElement {
+	return &FieldsPageElement{FieldsPageElementType, hash, val}
+}
+
+// Type defines the type associated with the FieldsPageElement
+func (e *FieldsPageElement) Type() ElementType { return e.typ }
+
+// Hash defines the hash associated with the FieldsPageElement
+func (e *FieldsPageElement) Hash() uint32 { return e.hash }
+
+// FieldsPage defines the FieldsPage associated with the FieldsPageElement
+func (e *FieldsPageElement) FieldsPage() FieldsPage { return e.val }
+
+type fieldsPageElement interface {
+	FieldsPage() FieldsPage
+}
+
+// FieldsPageFromElement attempts to get a FieldsPage from the element if it
+// exists.
+func FieldsPageFromElement(e Element) FieldsPage {
+	if v, ok := e.(fieldsPageElement); ok {
+		return v.FieldsPage()
+	}
+	return FieldsPage{}
+}
@@ -21,3 +21,49 @@ func NotFoundError(err error) bool {
 	_, ok := err.(errNotFound)
 	return ok
 }
+
+type errExpired struct {
+	err error
+}
+
+// NewExpiredError creates a new ExpiredError
+func NewExpiredError(err error) error {
+	return errExpired{err}
+}
+
+func (e errExpired) Error() string {
+	return e.err.Error()
+}
+
+// ExpiredError finds if the error passed in, is actually an error for changes
+// that are no longer retained or not
+func ExpiredError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(errExpired)
+	return ok
+}
+
+type errQuota struct {
+	err error
+}
+
+// NewQuotaError creates a new QuotaError
+func NewQuotaError(err error) error {
+	return errQuota{err}
+}
+
+func (e errQuota) Error() string {
+	return e.err.Error()
+}
+
+// QuotaError finds if the error passed in, is actually an error for a write
+// that would exceed the quota of a keyspace or not
+func QuotaError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(errQuota)
+	return ok
+}
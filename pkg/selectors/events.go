@@ -0,0 +1,115 @@
+package selectors
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// EventType describes the change that was applied to a member.
+type EventType string
+
+const (
+	// EventInsert is when a member was inserted.
+	EventInsert EventType = "insert"
+
+	// EventDelete is when a member was deleted.
+	EventDelete EventType = "delete"
+)
+
+func (t EventType) String() string {
+	return string(t)
+}
+
+// Event represents a change to a member of a key, once it's been applied to
+// the store. The offset is unique and increasing for a single node only, so
+// it's zero for events that have been merged from many nodes.
+type Event struct {
+	Offset uint64
+	Type   EventType
+	Key    Key
+	Field  Field
+	Value  []byte
+	Score  int64
+}
+
+// ResumeType describes where a watch starts from.
+type ResumeType int
+
+const (
+	// ResumeNone only sends events that happen after the watch started.
+	ResumeNone ResumeType = iota
+
+	// ResumeOffset sends any events after the offset.
+	ResumeOffset
+
+	// ResumeScore sends any events with a score greater than the score.
+	ResumeScore
+)
+
+// Resume describes where a watch starts from, so that a watcher that has
+// disconnected can carry on without missing events.
+type Resume struct {
+	Type  ResumeType
+	Value int64
+}
+
+// ResumeFromOffset creates a Resume for events after the offset.
+func ResumeFromOffset(offset uint64) Resume {
+	return Resume{Type: ResumeOffset, Value: int64(offset)}
+}
+
+// ResumeFromScore creates a Resume for events with a score greater than the
+// score.
+func ResumeFromScore(score int64) Resume {
+	return Resume{Type: ResumeScore, Value: score}
+}
+
+// After returns if the event should be sent, given where the watch resumed
+// from.
+func (r Resume) After(event Event) bool {
+	switch r.Type {
+	case ResumeOffset:
+		return event.Offset > uint64(r.Value)
+	case ResumeScore:
+		return event.Score > r.Value
+	default:
+		return false
+	}
+}
+
+// ParseResume returns a Resume from either an offset or a score, only one of
+// which can be used at a time. Empty values mean ResumeNone.
+func ParseResume(offset, score string) (Resume, error) {
+	switch {
+	case offset != "" && score != "":
+		return Resume{}, errors.New("expected either 'offset' or 'score' but got both")
+	case offset != "":
+		v, err := strconv.ParseUint(offset, 10, 64)
+		if err != nil {
+			return Resume{}, errors.Errorf("expected 'offset' but got %q", offset)
+		}
+		return ResumeFromOffset(v), nil
+	case score != "":
+		v, err := strconv.ParseInt(score, 10, 64)
+		if err != nil {
+			return Resume{}, errors.Errorf("expected 'score' but got %q", score)
+		}
+		return ResumeFromScore(v), nil
+	default:
+		return Resume{}, nil
+	}
+}
+
+// Subscription is a stream of events for a key.
+type Subscription interface {
+	// Events returns the channel of events, which is closed when the
+	// subscription ends.
+	Events() <-chan Event
+
+	// Err returns why the subscription ended, if it wasn't closed.
+	Err() error
+
+	// Close ends the subscription.
+	Close()
+}
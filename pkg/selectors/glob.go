@@ -0,0 +1,126 @@
+package selectors
+
+// Match reports whether the string matches the glob style pattern used by the
+// redis KEYS command. It supports '*', '?', '[...]' classes with ranges and
+// negation, and '\' to escape.
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			pattern, s = pattern[1+end:], s[1:]
+
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches a character against a class, returning the position
+// after the closing bracket. An unterminated class matches up to the end of
+// the pattern, which is how redis treats it.
+func matchClass(class string, c byte) (int, bool) {
+	var (
+		i       = 0
+		negate  = false
+		matched = false
+	)
+	if i < len(class) && class[i] == '^' {
+		negate = true
+		i++
+	}
+	for ; i < len(class) && class[i] != ']'; i++ {
+		switch {
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			if class[i] == c {
+				matched = true
+			}
+		case i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']':
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+		default:
+			if class[i] == c {
+				matched = true
+			}
+		}
+	}
+	if i < len(class) {
+		i++
+	}
+	return i, matched != negate
+}
+
+// Prefix returns the literal prefix of the glob style pattern, every string
+// that matches the pattern starts with it.
+func Prefix(pattern string) string {
+	var res []byte
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?', '[':
+			return string(res)
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+		}
+		res = append(res, pattern[i])
+	}
+	return string(res)
+}
+
+// PrefixPattern returns a glob style pattern that matches every string that
+// starts with the prefix.
+func PrefixPattern(prefix string) string {
+	res := make([]byte, 0, len(prefix)+1)
+	for i := 0; i < len(prefix); i++ {
+		switch prefix[i] {
+		case '*', '?', '[', ']', '\\':
+			res = append(res, '\\')
+		}
+		res = append(res, prefix[i])
+	}
+	return string(append(res, '*'))
+}
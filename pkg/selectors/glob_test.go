@@ -0,0 +1,68 @@
+package selectors
+
+import (
+	"testing"
+	"testing/quick"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "hello/world", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"user:*:name", "user:1:name", true},
+		{"user:*:name", "user:1:age", false},
+	} {
+		if expected, actual := v.want, Match(v.pattern, v.s); expected != actual {
+			t.Errorf("%q %q expected: %v, actual: %v", v.pattern, v.s, expected, actual)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		pattern, want string
+	}{
+		{"", ""},
+		{"*", ""},
+		{"user:*", "user:"},
+		{"user:?:name", "user:"},
+		{"user:[12]", "user:"},
+		{"h\\*llo*", "h*llo"},
+		{"exact", "exact"},
+	} {
+		if expected, actual := v.want, Prefix(v.pattern); expected != actual {
+			t.Errorf("%q expected: %q, actual: %q", v.pattern, expected, actual)
+		}
+	}
+}
+
+func TestPrefixPattern(t *testing.T) {
+	t.Parallel()
+
+	fn := func(prefix, suffix string) bool {
+		pattern := PrefixPattern(prefix)
+		return Match(pattern, prefix+suffix) && Prefix(pattern) == prefix
+	}
+	if err := quick.Check(fn, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,98 @@
+package selectors
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KeyspaceSeparator separates the name of the keyspace from the key with
+	// in the keys of the store. It's a control character, so that it can't
+	// clash with the keys that the users choose.
+	KeyspaceSeparator = "\x1f"
+
+	maxKeyspaceLength = 64
+)
+
+// DefaultKeyspace is the keyspace of the keys that don't name one, its keys
+// are stored as they are.
+const DefaultKeyspace Keyspace = ""
+
+// Keyspace is a named partition of the keys, so that several users can share
+// the cluster without evicting each others keys.
+type Keyspace string
+
+// ParseKeyspace returns a valid Keyspace otherwise returns an error. Names are
+// made of lower case letters, digits, '-' and '_'.
+func ParseKeyspace(s string) (Keyspace, error) {
+	if s == "" {
+		return DefaultKeyspace, nil
+	}
+	if len(s) > maxKeyspaceLength {
+		return DefaultKeyspace, errors.Errorf("keyspace %q is longer than %d characters", s, maxKeyspaceLength)
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return DefaultKeyspace, errors.Errorf("invalid character %q in keyspace %q", c, s)
+		}
+	}
+	return Keyspace(s), nil
+}
+
+// Key returns the key, as it's stored, of the key with in the keyspace.
+func (k Keyspace) Key(key Key) Key {
+	if k == DefaultKeyspace {
+		return key
+	}
+	return Key(string(k) + KeyspaceSeparator + string(key))
+}
+
+// Pattern returns the glob style pattern, as it's matched against the stored
+// keys, of the pattern with in the keyspace.
+func (k Keyspace) Pattern(pattern string) string {
+	if k == DefaultKeyspace {
+		return pattern
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+	return string(k) + KeyspaceSeparator + pattern
+}
+
+// Trim returns the keys with in the keyspace, without the name of the
+// keyspace. Any key that belongs to another keyspace is dropped.
+func (k Keyspace) Trim(keys []Key) []Key {
+	res := make([]Key, 0, len(keys))
+	for _, v := range keys {
+		if keyspace, key := v.Keyspace(); keyspace == k {
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+func (k Keyspace) String() string {
+	return string(k)
+}
+
+// Keyspace splits a stored key into the keyspace and the key with in it.
+func (k Key) Keyspace() (Keyspace, Key) {
+	if i := strings.Index(string(k), KeyspaceSeparator); i >= 0 {
+		return Keyspace(k[:i]), k[i+len(KeyspaceSeparator):]
+	}
+	return DefaultKeyspace, k
+}
+
+// KeyspaceOfPattern returns the keyspace that every key matching the glob
+// style pattern belongs to. Patterns that could match keys with in several
+// keyspaces return false.
+func KeyspaceOfPattern(pattern string) (Keyspace, bool) {
+	prefix := Prefix(pattern)
+	if i := strings.Index(prefix, KeyspaceSeparator); i >= 0 {
+		return Keyspace(prefix[:i]), true
+	}
+	return DefaultKeyspace, false
+}
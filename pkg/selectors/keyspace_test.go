@@ -0,0 +1,93 @@
+package selectors
+
+import (
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+func TestParseKeyspace(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		name  string
+		valid bool
+	}{
+		{"", true},
+		{"team-a", true},
+		{"team_b2", true},
+		{"Team", false},
+		{"team a", false},
+		{"team\x1fa", false},
+		{string(make([]byte, maxKeyspaceLength+1)), false},
+	} {
+		_, err := ParseKeyspace(v.name)
+		if expected, actual := v.valid, err == nil; expected != actual {
+			t.Errorf("%q expected: %v, actual: %v", v.name, expected, actual)
+		}
+	}
+}
+
+func TestKeyspaceKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		fn := func(key Key) bool {
+			keyspace, res := Keyspace("team-a").Key(key).Keyspace()
+			return keyspace == "team-a" && res == key
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		if expected, actual := Key("a"), DefaultKeyspace.Key("a"); expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+		if keyspace, _ := Key("a").Keyspace(); keyspace != DefaultKeyspace {
+			t.Errorf("expected default keyspace, actual: %q", keyspace)
+		}
+	})
+}
+
+func TestKeyspaceTrim(t *testing.T) {
+	t.Parallel()
+
+	keys := []Key{"a", Keyspace("team-a").Key("b"), Keyspace("team-b").Key("c")}
+
+	if expected, actual := []Key{"b"}, Keyspace("team-a").Trim(keys); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if expected, actual := []Key{"a"}, DefaultKeyspace.Trim(keys); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestKeyspacePattern(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		keyspace Keyspace
+		pattern  string
+		key      Key
+		want     bool
+	}{
+		{"team-a", "", Keyspace("team-a").Key("b"), true},
+		{"team-a", "b*", Keyspace("team-a").Key("bc"), true},
+		{"team-a", "b*", Keyspace("team-b").Key("bc"), false},
+		{"team-a", "*", "b", false},
+	} {
+		pattern := v.keyspace.Pattern(v.pattern)
+		if expected, actual := v.want, Match(pattern, v.key.String()); expected != actual {
+			t.Errorf("%q %q expected: %v, actual: %v", pattern, v.key, expected, actual)
+		}
+		if keyspace, ok := KeyspaceOfPattern(pattern); !ok || keyspace != v.keyspace {
+			t.Errorf("%q expected keyspace %q, actual: %q", pattern, v.keyspace, keyspace)
+		}
+	}
+
+	if _, ok := KeyspaceOfPattern("*"); ok {
+		t.Errorf("expected no keyspace")
+	}
+}
@@ -0,0 +1,174 @@
+package selectors
+
+import (
+	"encoding/base64"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultScanCount is the number of results in a page, when the count isn't
+	// specified.
+	DefaultScanCount = 10
+
+	// MaxScanCount is the largest number of results in a page.
+	MaxScanCount = 10000
+
+	cursorPrefix = "c"
+)
+
+// Cursor is an opaque position with in a scan. Scans start from the empty
+// cursor and are finished when the empty cursor is returned.
+type Cursor string
+
+// NewCursor creates a Cursor that carries on from after the value.
+func NewCursor(after string) Cursor {
+	return Cursor(cursorPrefix + base64.RawURLEncoding.EncodeToString([]byte(after)))
+}
+
+// After returns the value that the scan carries on from, the empty cursor
+// starts from the beginning.
+func (c Cursor) After() (string, bool, error) {
+	if c == "" {
+		return "", false, nil
+	}
+	if !strings.HasPrefix(string(c), cursorPrefix) {
+		return "", false, errors.Errorf("invalid cursor %q", c)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(c)[len(cursorPrefix):])
+	if err != nil {
+		return "", false, errors.Errorf("invalid cursor %q", c)
+	}
+	return string(b), true, nil
+}
+
+func (c Cursor) String() string {
+	return string(c)
+}
+
+// ScanOptions describes the results of each page of a scan.
+type ScanOptions struct {
+	// Match is a glob style pattern that the results have to match, the empty
+	// pattern matches everything.
+	Match string
+
+	// Count is the maximum number of results in the page.
+	Count int
+}
+
+// Matches returns if the value matches the pattern.
+func (o ScanOptions) Matches(s string) bool {
+	return o.Match == "" || Match(o.Match, s)
+}
+
+// Size returns the number of results with in a page.
+func (o ScanOptions) Size() int {
+	switch {
+	case o.Count < 1:
+		return DefaultScanCount
+	case o.Count > MaxScanCount:
+		return MaxScanCount
+	default:
+		return o.Count
+	}
+}
+
+// ParseScanOptions returns ScanOptions from a match and count, the count can
+// be empty to use the default count.
+func ParseScanOptions(match, count string) (ScanOptions, error) {
+	opts := ScanOptions{Match: match}
+	if count != "" {
+		v, err := strconv.Atoi(count)
+		if err != nil || v < 1 || v > MaxScanCount {
+			return ScanOptions{}, errors.Errorf("expected 'count' between 1 and %d but got %q", MaxScanCount, count)
+		}
+		opts.Count = v
+	}
+	return opts, nil
+}
+
+// KeysPage is a page of keys from a scan, along with the cursor to get the
+// next page from.
+type KeysPage struct {
+	Keys   []Key
+	Cursor Cursor
+}
+
+// FieldsPage is a page of fields from a scan, along with the cursor to get the
+// next page from.
+type FieldsPage struct {
+	Fields []Field
+	Cursor Cursor
+}
+
+// ScanSorted returns the next page of values after the cursor, from values
+// that are sorted.
+func ScanSorted(sorted []string, cursor Cursor, opts ScanOptions) ([]string, Cursor, error) {
+	after, ok, err := cursor.After()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Every match starts with the literal prefix of the pattern, so there's no
+	// need to look at anything before or after it.
+	prefix := Prefix(opts.Match)
+	start := sort.SearchStrings(sorted, prefix)
+	if ok && after >= prefix {
+		start = sort.Search(len(sorted), func(i int) bool {
+			return sorted[i] > after
+		})
+	}
+
+	var (
+		size = opts.Size()
+		res  = make([]string, 0, size)
+	)
+	for i := start; i < len(sorted); i++ {
+		if !strings.HasPrefix(sorted[i], prefix) {
+			break
+		}
+		if !opts.Matches(sorted[i]) {
+			continue
+		}
+		if len(res) == size {
+			return res, NewCursor(res[len(res)-1]), nil
+		}
+		res = append(res, sorted[i])
+	}
+	return res, "", nil
+}
+
+// MergeSorted merges the pages from many replicas in to a single page. Each
+// page is the first values after the same cursor, so the smallest values of
+// the union are always complete.
+func MergeSorted(pages [][]string, cursors []Cursor, opts ScanOptions) ([]string, Cursor) {
+	var (
+		unique = make(map[string]struct{})
+		more   = false
+	)
+	for k, page := range pages {
+		for _, v := range page {
+			unique[v] = struct{}{}
+		}
+		if cursors[k] != "" {
+			more = true
+		}
+	}
+
+	res := make([]string, 0, len(unique))
+	for v := range unique {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+
+	if size := opts.Size(); len(res) > size {
+		res, more = res[:size], true
+	}
+	if !more || len(res) == 0 {
+		return res, ""
+	}
+	return res, NewCursor(res[len(res)-1])
+}
@@ -0,0 +1,190 @@
+package selectors
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+)
+
+func TestCursor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		fn := func(after string) bool {
+			value, ok, err := NewCursor(after).After()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return ok && value == after
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, ok, err := Cursor("").After()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Errorf("expected the empty cursor to start from the beginning")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, v := range []Cursor{"x", "c!!"} {
+			if _, _, err := v.After(); err == nil {
+				t.Errorf("%q expected error", v)
+			}
+		}
+	})
+}
+
+func TestParseScanOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []struct {
+		match, count string
+		want         ScanOptions
+		err          bool
+	}{
+		{"", "", ScanOptions{}, false},
+		{"a*", "5", ScanOptions{Match: "a*", Count: 5}, false},
+		{"", "0", ScanOptions{}, true},
+		{"", "bad", ScanOptions{}, true},
+		{"", fmt.Sprint(MaxScanCount + 1), ScanOptions{}, true},
+	} {
+		opts, err := ParseScanOptions(v.match, v.count)
+		if expected, actual := v.err, err != nil; expected != actual {
+			t.Errorf("%q %q expected error: %v, actual: %v", v.match, v.count, expected, err)
+		}
+		if expected, actual := v.want, opts; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	}
+}
+
+func TestScanSorted(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every value once", func(t *testing.T) {
+		fn := func(values []string, count uint8) bool {
+			sort.Strings(values)
+			values = uniqueStrings(values)
+
+			var (
+				res    []string
+				cursor Cursor
+				opts   = ScanOptions{Count: int(count%5) + 1}
+			)
+			for {
+				page, next, err := ScanSorted(values, cursor, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page) > opts.Size() {
+					t.Fatalf("expected at most %d, actual: %d", opts.Size(), len(page))
+				}
+				res = append(res, page...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+
+			return reflect.DeepEqual(values, res) || (len(values) == 0 && len(res) == 0)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("match", func(t *testing.T) {
+		values := []string{"a1", "a2", "b1", "b2", "b3", "c1"}
+
+		page, next, err := ScanSorted(values, "", ScanOptions{Match: "b*", Count: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []string{"b1", "b2"}, page; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		page, next, err = ScanSorted(values, next, ScanOptions{Match: "b*", Count: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []string{"b3"}, page; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := Cursor(""), next; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		if _, _, err := ScanSorted([]string{"a"}, "bad", ScanOptions{}); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestMergeSorted(t *testing.T) {
+	t.Parallel()
+
+	t.Run("union", func(t *testing.T) {
+		values, cursor := MergeSorted([][]string{
+			{"a", "c"},
+			{"b", "c"},
+		}, []Cursor{"", ""}, ScanOptions{Count: 10})
+
+		if expected, actual := []string{"a", "b", "c"}, values; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := Cursor(""), cursor; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		values, cursor := MergeSorted([][]string{
+			{"a", "c"},
+			{"b", "d"},
+		}, []Cursor{NewCursor("c"), NewCursor("d")}, ScanOptions{Count: 2})
+
+		if expected, actual := []string{"a", "b"}, values; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := NewCursor("b"), cursor; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("more", func(t *testing.T) {
+		values, cursor := MergeSorted([][]string{
+			{"a", "b"},
+			{"a"},
+		}, []Cursor{NewCursor("b"), ""}, ScanOptions{Count: 2})
+
+		if expected, actual := []string{"a", "b"}, values; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := NewCursor("b"), cursor; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+}
+
+func uniqueStrings(values []string) []string {
+	var res []string
+	for k, v := range values {
+		if k > 0 && values[k-1] == v {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
@@ -7,8 +7,12 @@ import (
 	"time"
 
 	errs "github.com/SimonRichardson/coherence/pkg/api/http"
+	"github.com/SimonRichardson/coherence/pkg/cluster"
 	"github.com/SimonRichardson/coherence/pkg/cluster/farm"
+	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
 	"github.com/SimonRichardson/coherence/pkg/metrics"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 )
@@ -17,30 +21,110 @@ import (
 const (
 	APIPathLivenessQuery  = "/health"
 	APIPathReadinessQuery = "/ready"
+	APIPathRingQuery      = "/ring"
+	APIPathRebalanceQuery = "/rebalance"
+	APIPathClusterQuery   = "/cluster"
+	APIPathStoreQuery     = "/store"
 )
 
+// Ring describes the placement of the hosts with in the cluster.
+type Ring interface {
+	// Ownership returns the percentage of the hashring that each host owns.
+	Ownership() []hashring.Ownership
+}
+
+// Actors is implemented by the rings that know what the actor of each host
+// knows of its store.
+type Actors interface {
+	// Actors returns the status of the actor of each host, ordered by host.
+	Actors() []hashring.ActorStatus
+}
+
+// Circuits is implemented by the rings that have a circuit in front of each
+// host.
+type Circuits interface {
+	// Circuits returns if the circuit in front of each host is open, keyed by
+	// host.
+	Circuits() map[string]bool
+}
+
+// Circuit is implemented by the farms that have a circuit in front of them.
+type Circuit interface {
+	// CircuitOpen returns if the circuit is open, refusing requests.
+	CircuitOpen() bool
+}
+
+// Peer describes the members of the cluster.
+type Peer interface {
+	// State returns the state of the cluster, as the peer sees it.
+	State() (cluster.State, error)
+}
+
+// Store describes how full the local store is.
+type Store interface {
+	// Occupancy returns how full the buckets of each keyspace are.
+	Occupancy() []store.KeyspaceOccupancy
+}
+
+// Rebalancer describes the progress of moving keys between hosts.
+type Rebalancer interface {
+	// Progress returns the progress of the current or last rebalance.
+	Progress() hashring.Progress
+}
+
 // API serves the status API
 type API struct {
-	farm     farm.Farm
-	logger   log.Logger
-	clients  metrics.Gauge
-	duration metrics.HistogramVec
-	errors   errs.Error
+	farm       farm.Farm
+	ring       Ring
+	rebalancer Rebalancer
+	peer       Peer
+	store      Store
+	logger     log.Logger
+	clients    metrics.Gauge
+	duration   metrics.HistogramVec
+	errors     errs.Error
+}
+
+// Option defines a option for configuring the API
+type Option func(*API)
+
+// WithPeer serves the members of the cluster, along with their tags, from the
+// peer.
+func WithPeer(peer Peer) Option {
+	return func(a *API) {
+		a.peer = peer
+	}
+}
+
+// WithStore serves how full the buckets of the local store are.
+func WithStore(store Store) Option {
+	return func(a *API) {
+		a.store = store
+	}
 }
 
 // NewAPI creates a API with the correct dependencies.
 func NewAPI(farm farm.Farm,
+	ring Ring,
+	rebalancer Rebalancer,
 	logger log.Logger,
 	clients metrics.Gauge,
 	duration metrics.HistogramVec,
+	opts ...Option,
 ) *API {
-	return &API{
-		farm:     farm,
-		logger:   logger,
-		clients:  clients,
-		duration: duration,
-		errors:   errs.NewError(logger),
+	api := &API{
+		farm:       farm,
+		ring:       ring,
+		rebalancer: rebalancer,
+		logger:     logger,
+		clients:    clients,
+		duration:   duration,
+		errors:     errs.NewError(logger),
+	}
+	for _, opt := range opts {
+		opt(api)
 	}
+	return api
 }
 
 func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -68,6 +152,14 @@ func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		a.handleLiveness(w, r)
 	case method == "GET" && path == APIPathReadinessQuery:
 		a.handleReadiness(w, r)
+	case method == "GET" && path == APIPathRingQuery:
+		a.handleRing(w, r)
+	case method == "GET" && path == APIPathRebalanceQuery:
+		a.handleRebalance(w, r)
+	case method == "GET" && path == APIPathClusterQuery && a.peer != nil:
+		a.handleCluster(w, r)
+	case method == "GET" && path == APIPathStoreQuery && a.store != nil:
+		a.handleStore(w, r)
 	default:
 		// Nothing found
 		a.errors.NotFound(w, r)
@@ -87,7 +179,7 @@ func (a *API) handleLiveness(w http.ResponseWriter, r *http.Request) {
 func (a *API) handleReadiness(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	if _, err := a.farm.Keys(); err == nil {
+	if _, err := a.farm.Keys(selectors.Strong); err == nil {
 		w.WriteHeader(http.StatusOK)
 	} else {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -98,6 +190,96 @@ func (a *API) handleReadiness(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (a *API) handleRing(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// The actors are only known by the rings that talk to the hosts.
+	var actors []hashring.ActorStatus
+	if v, ok := a.ring.(Actors); ok {
+		actors = v.Actors()
+	}
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Hosts  []hashring.Ownership   `json:"hosts"`
+		Actors []hashring.ActorStatus `json:"actors,omitempty"`
+	}{
+		Hosts:  a.ring.Ownership(),
+		Actors: actors,
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *API) handleCluster(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	state, err := a.peer.State()
+	if err != nil {
+		a.errors.InternalServerError(w, r, err.Error())
+		return
+	}
+
+	// The farm and each of the hosts only report the state of their circuit
+	// if they have one.
+	var circuits clusterCircuits
+	if v, ok := a.farm.(Circuit); ok {
+		open := v.CircuitOpen()
+		circuits.Farm = &open
+	}
+	if v, ok := a.ring.(Circuits); ok {
+		circuits.Hosts = v.Circuits()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(struct {
+		cluster.State
+		Circuits clusterCircuits `json:"circuits"`
+	}{
+		State:    state,
+		Circuits: circuits,
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *API) handleStore(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(struct {
+		Keyspaces []store.KeyspaceOccupancy `json:"keyspaces"`
+	}{
+		Keyspaces: a.store.Occupancy(),
+	}); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (a *API) handleRebalance(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(a.rebalancer.Progress()); err != nil {
+		a.errors.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// clusterCircuits are the states of the circuits, true if a circuit is open
+// and refusing requests.
+type clusterCircuits struct {
+	Farm  *bool           `json:"farm,omitempty"`
+	Hosts map[string]bool `json:"hosts,omitempty"`
+}
+
 type interceptingWriter struct {
 	code int
 	http.ResponseWriter
@@ -1,15 +1,25 @@
 package status
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	"github.com/SimonRichardson/coherence/pkg/cluster"
 	"github.com/SimonRichardson/coherence/pkg/cluster/farm/mocks"
+	"github.com/SimonRichardson/coherence/pkg/cluster/hashring"
+	"github.com/SimonRichardson/coherence/pkg/cluster/members"
+	clusterMocks "github.com/SimonRichardson/coherence/pkg/cluster/mocks"
 	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store"
+	storeMocks "github.com/SimonRichardson/coherence/pkg/store/mocks"
 	"github.com/go-kit/kit/log"
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/trussle/harness/matchers"
 )
 
@@ -25,7 +35,7 @@ func TestAPI(t *testing.T) {
 			duration = metricMocks.NewMockHistogramVec(ctrl)
 			observer = metricMocks.NewMockObserver(ctrl)
 			farm     = mocks.NewMockFarm(ctrl)
-			api      = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			api      = NewAPI(farm, hashring.NewHashRing(1), hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration)
 			server   = httptest.NewServer(api)
 		)
 		defer server.Close()
@@ -55,7 +65,7 @@ func TestAPI(t *testing.T) {
 			duration = metricMocks.NewMockHistogramVec(ctrl)
 			observer = metricMocks.NewMockObserver(ctrl)
 			farm     = mocks.NewMockFarm(ctrl)
-			api      = NewAPI(farm, log.NewNopLogger(), clients, duration)
+			api      = NewAPI(farm, hashring.NewHashRing(1), hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration)
 			server   = httptest.NewServer(api)
 		)
 		defer server.Close()
@@ -66,7 +76,7 @@ func TestAPI(t *testing.T) {
 		duration.EXPECT().WithLabelValues("GET", "/ready", "200").Return(observer).Times(1)
 		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
 
-		farm.EXPECT().Keys().Times(1)
+		farm.EXPECT().Keys(selectors.Strong).Times(1)
 
 		response, err := http.Get(fmt.Sprintf("%s/ready", server.URL))
 		if err != nil {
@@ -77,4 +87,359 @@ func TestAPI(t *testing.T) {
 			t.Errorf("expected: %d, actual: %d", expected, actual)
 		}
 	})
+	t.Run("ring", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ring := hashring.NewHashRing(10)
+		ring.AddWeighted("0.0.0.0:8080", 2)
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = mocks.NewMockFarm(ctrl)
+			api      = NewAPI(farm, ring, hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration)
+			server   = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/ring", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/ring", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var body struct {
+			Hosts []hashring.Ownership `json:"hosts"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := ring.Ownership(), body.Hosts; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+	t.Run("rebalance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = mocks.NewMockFarm(ctrl)
+			api      = NewAPI(farm, hashring.NewHashRing(1), hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration)
+			server   = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/rebalance", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/rebalance", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var progress hashring.Progress
+		if err := json.NewDecoder(response.Body).Decode(&progress); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := hashring.RebalanceIdle, progress.State; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("ring with actors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ring := actorRing{
+			HashRing: hashring.NewHashRing(10),
+			actors: []hashring.ActorStatus{
+				{Host: "0.0.0.0:8080", Hash: 1, Clock: 2, BloomFill: 0.5},
+			},
+		}
+		ring.AddWeighted("0.0.0.0:8080", 1)
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = mocks.NewMockFarm(ctrl)
+			api      = NewAPI(farm, ring, hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration)
+			server   = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/ring", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/ring", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		var body struct {
+			Hosts  []hashring.Ownership   `json:"hosts"`
+			Actors []hashring.ActorStatus `json:"actors"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := ring.actors, body.Actors; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = circuitFarm{MockFarm: mocks.NewMockFarm(ctrl), open: true}
+			peer     = clusterMocks.NewMockPeer(ctrl)
+			ring     = actorRing{
+				HashRing: hashring.NewHashRing(1),
+				circuits: map[string]bool{"0.0.0.0:8080": false},
+			}
+			api    = NewAPI(farm, ring, hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration, WithPeer(peer))
+			server = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		state := cluster.State{
+			Self:       "a",
+			NumMembers: 2,
+			Members: []cluster.MemberState{
+				{Name: "a", Address: "10.0.0.1:8079", Tags: &members.PeerInfo{
+					Name:    "a",
+					Type:    cluster.PeerTypeStore,
+					APIAddr: "10.0.0.1",
+					APIPort: 8080,
+					Weight:  members.DefaultWeight,
+					Status:  members.PeerStatusAlive,
+				}},
+				{Name: "b", Address: "10.0.0.2:8079"},
+			},
+		}
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/cluster", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		peer.EXPECT().State().Return(state, nil).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/cluster", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var body struct {
+			cluster.State
+			Circuits struct {
+				Farm  *bool           `json:"farm"`
+				Hosts map[string]bool `json:"hosts"`
+			} `json:"circuits"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := state, body.State; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if body.Circuits.Farm == nil || !*body.Circuits.Farm {
+			t.Errorf("expected: open farm circuit, actual: %v", body.Circuits.Farm)
+		}
+		if expected, actual := ring.circuits, body.Circuits.Hosts; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("cluster with state failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = mocks.NewMockFarm(ctrl)
+			peer     = clusterMocks.NewMockPeer(ctrl)
+			api      = NewAPI(farm, hashring.NewHashRing(1), hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration, WithPeer(peer))
+			server   = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/cluster", "500").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		peer.EXPECT().State().Return(cluster.State{}, errors.New("bad")).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/cluster", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusInternalServerError, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("cluster without peer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = mocks.NewMockFarm(ctrl)
+			api      = NewAPI(farm, hashring.NewHashRing(1), hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration)
+			server   = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/cluster", "404").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/cluster", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusNotFound, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("store", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var (
+			clients  = metricMocks.NewMockGauge(ctrl)
+			duration = metricMocks.NewMockHistogramVec(ctrl)
+			observer = metricMocks.NewMockObserver(ctrl)
+			farm     = mocks.NewMockFarm(ctrl)
+			persist  = storeMocks.NewMockStore(ctrl)
+			api      = NewAPI(farm, hashring.NewHashRing(1), hashring.NewRebalancer(nil, 0, log.NewNopLogger()), log.NewNopLogger(), clients, duration, WithStore(persist))
+			server   = httptest.NewServer(api)
+		)
+		defer server.Close()
+
+		occupancy := []store.KeyspaceOccupancy{
+			{
+				Keyspace: selectors.DefaultKeyspace,
+				Capacity: 10,
+				Bytes:    4,
+				Buckets:  []store.BucketOccupancy{{Keys: 1, Members: 2}, {}},
+			},
+			{
+				Keyspace: "team-a",
+				Capacity: 5,
+				Limit:    100,
+				Buckets:  []store.BucketOccupancy{{}},
+			},
+		}
+
+		clients.EXPECT().Inc().Times(1)
+		clients.EXPECT().Dec().Times(1)
+
+		duration.EXPECT().WithLabelValues("GET", "/store", "200").Return(observer).Times(1)
+		observer.EXPECT().Observe(matchers.MatchAnyFloat64()).Times(1)
+
+		persist.EXPECT().Occupancy().Return(occupancy).Times(1)
+
+		response, err := http.Get(fmt.Sprintf("%s/store", server.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		if expected, actual := http.StatusOK, response.StatusCode; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+
+		var body struct {
+			Keyspaces []store.KeyspaceOccupancy `json:"keyspaces"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := occupancy, body.Keyspaces; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+// actorRing is a ring that knows the actors and circuits of its hosts.
+type actorRing struct {
+	*hashring.HashRing
+	actors   []hashring.ActorStatus
+	circuits map[string]bool
+}
+
+func (r actorRing) Actors() []hashring.ActorStatus {
+	return r.actors
+}
+
+func (r actorRing) Circuits() map[string]bool {
+	return r.circuits
+}
+
+// circuitFarm is a farm with a circuit in front of it.
+type circuitFarm struct {
+	*mocks.MockFarm
+	open bool
+}
+
+func (f circuitFarm) CircuitOpen() bool {
+	return f.open
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"sync"
+	"sync/atomic"
 
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/SimonRichardson/coherence/pkg/store/lru"
@@ -16,18 +17,29 @@ import (
 // Bucket conforms to the Key/Val store interface and provides locking mechanism
 // for each bucket.
 type Bucket struct {
-	mutex  sync.RWMutex
-	file   fsys.File
-	insert *lru.LRU
-	delete *lru.LRU
-	logger log.Logger
+	mutex   sync.RWMutex
+	file    fsys.File
+	insert  *lru.LRU
+	delete  *lru.LRU
+	members *Index
+	usage   *Usage
+	evicted func(lru.EvictionReason)
+	logger  log.Logger
 }
 
 // NewBucket creates a store from a singular bucket
 func NewBucket(file fsys.File, amountPerBucket int, logger log.Logger) *Bucket {
+	return newBucket(file, amountPerBucket, NewUsage(0), logger)
+}
+
+// newBucket creates a bucket that counts the bytes of its values with in the
+// usage, which is shared by every bucket of a keyspace.
+func newBucket(file fsys.File, amountPerBucket int, usage *Usage, logger log.Logger) *Bucket {
 	b := &Bucket{
-		file:   file,
-		logger: logger,
+		file:    file,
+		members: NewIndex(),
+		usage:   usage,
+		logger:  logger,
 	}
 	b.insert = lru.NewLRU(amountPerBucket, b.onInsertionEviction)
 	b.delete = lru.NewLRU(amountPerBucket, b.onDeletionEviction)
@@ -36,30 +48,64 @@ func NewBucket(file fsys.File, amountPerBucket int, logger log.Logger) *Bucket {
 
 // Insert inserts a member associated with a field and a store
 func (b *Bucket) Insert(field selectors.Field, value selectors.ValueScore) (selectors.ChangeSet, error) {
+	return b.write(selectors.EventInsert, field, value, nil)
+}
+
+// Delete removes a member associated with a field and a store
+func (b *Bucket) Delete(field selectors.Field, value selectors.ValueScore) (selectors.ChangeSet, error) {
+	return b.write(selectors.EventDelete, field, value, nil)
+}
+
+// Swap inserts the value only if the field is inserted with the score of the
+// token. The score is checked under the same lock as the insert, so of the
+// swaps of the same token only the first one succeeds. The field is a failure
+// of the ChangeSet if the score doesn't match.
+func (b *Bucket) Swap(field selectors.Field, value selectors.ValueScore, token int64) (selectors.ChangeSet, error) {
+	return b.swap(field, value, token, nil)
+}
+
+func (b *Bucket) swap(field selectors.Field,
+	value selectors.ValueScore,
+	token int64,
+	applied func(),
+) (selectors.ChangeSet, error) {
+	if value.Score <= token {
+		return failureChangeSet(field, value), errors.Errorf("expected a score larger than the token %d", token)
+	}
+
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	// If we've already got a larger score, this is a nop!
-	if v, ok := b.insert.Get(field); ok && v.Score >= value.Score {
-		return successChangeSet(field, value), nil
+	current, ok := b.insert.Peek(field)
+	if v, deleted := b.delete.Peek(field); !ok || (deleted && v.Score > current.Score) {
+		return failureChangeSet(field, value), selectors.NewNotFoundError(errors.New("not found"))
 	}
-	if v, ok := b.delete.Get(field); ok && v.Score >= value.Score {
-		return successChangeSet(field, value), nil
+	if current.Score != token {
+		return failureChangeSet(field, value), nil
 	}
-
-	b.insert.Remove(field)
-	b.delete.Remove(field)
-
-	b.insert.Add(field, value)
-
-	return successChangeSet(field, value), nil
+	return b.apply(selectors.EventInsert, field, value, applied)
 }
 
-// Delete removes a member associated with a field and a store
-func (b *Bucket) Delete(field selectors.Field, value selectors.ValueScore) (selectors.ChangeSet, error) {
+// write either inserts or deletes a member, the applied function is called
+// whilst the bucket is still locked, only if the member was changed, so that
+// the changes are observed in the same order as they were applied.
+func (b *Bucket) write(kind selectors.EventType,
+	field selectors.Field,
+	value selectors.ValueScore,
+	applied func(),
+) (selectors.ChangeSet, error) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
+	return b.apply(kind, field, value, applied)
+}
+
+// apply writes the member, the bucket has to be locked.
+func (b *Bucket) apply(kind selectors.EventType,
+	field selectors.Field,
+	value selectors.ValueScore,
+	applied func(),
+) (selectors.ChangeSet, error) {
 	// If we've already got a larger score, this is a nop!
 	if v, ok := b.insert.Get(field); ok && v.Score >= value.Score {
 		return successChangeSet(field, value), nil
@@ -68,10 +114,36 @@ func (b *Bucket) Delete(field selectors.Field, value selectors.ValueScore) (sele
 		return successChangeSet(field, value), nil
 	}
 
+	if kind == selectors.EventInsert {
+		// The value replaces any value that's already inserted, so only the
+		// difference counts towards the quota.
+		var replaced int
+		if v, ok := b.insert.Peek(field); ok {
+			replaced = len(v.Value)
+		}
+		if !b.usage.fits(int64(len(value.Value) - replaced)) {
+			return failureChangeSet(field, value), selectors.NewQuotaError(
+				errors.Errorf("inserting %q exceeds the quota of %d bytes", field, b.usage.limit),
+			)
+		}
+	}
+
 	b.insert.Remove(field)
 	b.delete.Remove(field)
 
-	b.delete.Add(field, value)
+	if kind == selectors.EventInsert {
+		// Index the member before adding it, as adding can evict it straight
+		// away, which takes it back out of the index.
+		b.members.Add(field.String())
+		b.usage.add(int64(len(value.Value)))
+		b.insert.Add(field, value)
+	} else {
+		b.delete.Add(field, value)
+	}
+
+	if applied != nil {
+		applied()
+	}
 
 	return successChangeSet(field, value), nil
 }
@@ -91,31 +163,37 @@ func (b *Bucket) Select(field selectors.Field) (selectors.FieldValueScore, error
 	return selectors.FieldValueScore{}, selectors.NewNotFoundError(errors.New("not found"))
 }
 
-// Members defines a way to return all members
+// Members defines a way to return all members in sorted order
 func (b *Bucket) Members() ([]selectors.Field, error) {
-	var res []selectors.Field
-	b.insert.Walk(func(field selectors.Field, value selectors.ValueScore) error {
-		// Prevent future deletes becoming members
-		if v, ok := b.delete.Peek(field); !ok || v.Score < value.Score {
-			res = append(res, field)
-		}
-		return nil
-	})
-	return res, nil
+	return toFields(b.members.Values()), nil
 }
 
-// Len returns the number of members
-func (b *Bucket) Len() (int64, error) {
-	m, err := b.Members()
+// Match returns the members that match the glob style pattern in sorted
+// order.
+func (b *Bucket) Match(pattern string) ([]selectors.Field, error) {
+	return toFields(b.members.Match(pattern)), nil
+}
+
+// Scan returns the next page of members after the cursor.
+func (b *Bucket) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.FieldsPage, error) {
+	values, next, err := b.members.Scan(cursor, opts)
 	if err != nil {
-		return int64(0), err
+		return selectors.FieldsPage{}, err
 	}
-	return int64(len(m)), nil
+	return selectors.FieldsPage{Fields: toFields(values), Cursor: next}, nil
+}
+
+// Len returns the number of members
+func (b *Bucket) Len() (int64, error) {
+	return int64(b.members.Len()), nil
 }
 
 // Score defines a way to find out the score associated with a field with in a
 // key
 func (b *Bucket) Score(field selectors.Field) (selectors.Presence, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
 	presence := selectors.Presence{
 		Inserted: false,
 		Present:  false,
@@ -134,7 +212,26 @@ func (b *Bucket) Score(field selectors.Field) (selectors.Presence, error) {
 	return presence, nil
 }
 
+// Walk calls the function with each member that's inserted, from the least
+// recently used.
+func (b *Bucket) Walk(fn func(selectors.Field, selectors.ValueScore) error) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.insert.Walk(fn)
+}
+
 func (b *Bucket) onInsertionEviction(reason lru.EvictionReason, field selectors.Field, value selectors.ValueScore) {
+	// Once it's left the insert cache, it's no longer a member.
+	b.members.Remove(field.String())
+	b.usage.add(-int64(len(value.Value)))
+
+	// Every reason is counted, the members pushed out because the bucket is
+	// full are the ones that are popped.
+	if b.evicted != nil {
+		b.evicted(reason)
+	}
+
 	switch reason {
 	case lru.Popped:
 		// TODO (Simon): Store this in some sort of LSM, but for now just persist it.
@@ -166,6 +263,40 @@ func (b *Bucket) onDeletionEviction(reason lru.EvictionReason, field selectors.F
 	// Make sure we remove the value from the key
 }
 
+// Usage counts the bytes of the values with in a set of buckets, so that a
+// keyspace can be limited to a number of bytes.
+type Usage struct {
+	bytes int64
+	limit int64
+}
+
+// NewUsage creates a Usage that's limited to the number of bytes, a limit of
+// 0 doesn't limit the usage.
+func NewUsage(limit int64) *Usage {
+	return &Usage{limit: limit}
+}
+
+// Bytes returns the number of bytes that are used.
+func (u *Usage) Bytes() int64 {
+	return atomic.LoadInt64(&u.bytes)
+}
+
+// Limit returns the number of bytes that can be used, 0 is unlimited.
+func (u *Usage) Limit() int64 {
+	return u.limit
+}
+
+func (u *Usage) add(n int64) {
+	atomic.AddInt64(&u.bytes, n)
+}
+
+// fits returns if the usage can grow by n bytes. Buckets check the usage
+// independently of each other, so concurrent writes can go over the limit by
+// the size of the values being written.
+func (u *Usage) fits(n int64) bool {
+	return u.limit <= 0 || n <= 0 || u.Bytes()+n <= u.limit
+}
+
 func successChangeSet(field selectors.Field, value selectors.ValueScore) selectors.ChangeSet {
 	return selectors.ChangeSet{
 		Success: []selectors.Field{field},
@@ -179,3 +310,11 @@ func failureChangeSet(field selectors.Field, value selectors.ValueScore) selecto
 		Failure: []selectors.Field{field},
 	}
 }
+
+func toFields(values []string) []selectors.Field {
+	res := make([]selectors.Field, len(values))
+	for k, v := range values {
+		res[k] = selectors.Field(v)
+	}
+	return res
+}
@@ -1,6 +1,8 @@
 package store
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/quick"
 
@@ -495,3 +497,116 @@ func TestBucketDeletion(t *testing.T) {
 		}
 	})
 }
+
+func TestBucketSwap(t *testing.T) {
+	t.Parallel()
+
+	newBucket := func(t *testing.T) *Bucket {
+		file, err := fsys.NewNopFilesystem().Create("swap")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewBucket(file, 10, log.NewNopLogger())
+	}
+
+	t.Run("swapping the token", func(t *testing.T) {
+		bucket := newBucket(t)
+		if _, err := bucket.Insert("a", selectors.ValueScore{Value: []byte("b"), Score: 1}); err != nil {
+			t.Fatal(err)
+		}
+
+		changeSet, err := bucket.Swap("a", selectors.ValueScore{Value: []byte("c"), Score: 2}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"a"}, changeSet.Success; len(actual) != 1 || expected[0] != actual[0] {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		member, err := bucket.Select("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := int64(2), member.Score; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("swapping an old token", func(t *testing.T) {
+		bucket := newBucket(t)
+		if _, err := bucket.Insert("a", selectors.ValueScore{Value: []byte("b"), Score: 2}); err != nil {
+			t.Fatal(err)
+		}
+
+		changeSet, err := bucket.Swap("a", selectors.ValueScore{Value: []byte("c"), Score: 3}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"a"}, changeSet.Failure; len(actual) != 1 || expected[0] != actual[0] {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("swapping a missing field", func(t *testing.T) {
+		bucket := newBucket(t)
+
+		if _, err := bucket.Swap("a", selectors.ValueScore{Value: []byte("c"), Score: 2}, 1); !selectors.NotFoundError(err) {
+			t.Errorf("expected not found error, actual: %v", err)
+		}
+	})
+
+	t.Run("swapping a deleted field", func(t *testing.T) {
+		bucket := newBucket(t)
+		if _, err := bucket.Insert("a", selectors.ValueScore{Value: []byte("b"), Score: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bucket.Delete("a", selectors.ValueScore{Score: 2}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := bucket.Swap("a", selectors.ValueScore{Value: []byte("c"), Score: 3}, 1); !selectors.NotFoundError(err) {
+			t.Errorf("expected not found error, actual: %v", err)
+		}
+	})
+
+	t.Run("swapping with a score before the token", func(t *testing.T) {
+		bucket := newBucket(t)
+		if _, err := bucket.Insert("a", selectors.ValueScore{Value: []byte("b"), Score: 1}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := bucket.Swap("a", selectors.ValueScore{Value: []byte("c"), Score: 1}, 1); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("concurrent swaps of a token", func(t *testing.T) {
+		bucket := newBucket(t)
+		if _, err := bucket.Insert("a", selectors.ValueScore{Value: []byte("b"), Score: 1}); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			wg      sync.WaitGroup
+			swapped int32
+		)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				changeSet, err := bucket.Swap("a", selectors.ValueScore{Value: []byte("c"), Score: int64(2 + i)}, 1)
+				if err != nil {
+					t.Error(err)
+				}
+				if len(changeSet.Success) > 0 {
+					atomic.AddInt32(&swapped, 1)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if expected, actual := int32(1), atomic.LoadInt32(&swapped); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+}
@@ -0,0 +1,483 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSegmentSize = 10000
+
+	segmentExt       = ".log"
+	recordHeaderSize = 8
+	maxRecordSize    = 64 << 20
+)
+
+// ChangeLogConfig describes where the change log is kept and how much of it
+// is retained.
+type ChangeLogConfig struct {
+	// Dir is the directory the segments of the log are written to.
+	Dir string
+
+	// SegmentSize is the number of changes in each segment, retention removes
+	// whole segments at a time.
+	SegmentSize int
+
+	// MaxEntries is the number of changes to retain, zero retains everything.
+	MaxEntries int
+
+	// MaxAge is how long to retain changes for, zero retains everything.
+	MaxAge time.Duration
+
+	// Sync flushes every change to disk before it's acknowledged.
+	Sync bool
+}
+
+// ChangeLog is a durable, ordered log of every change that's applied to the
+// store. Each change is given an offset, which increases monotonically, even
+// across restarts, so that consumers can read the changes since the last one
+// they saw.
+type ChangeLog struct {
+	mutex    sync.Mutex
+	turn     *sync.Cond
+	config   ChangeLogConfig
+	segments []*segment
+	file     *os.File
+	offset   uint64
+	now      func() time.Time
+	logger   log.Logger
+}
+
+// segment is a single file of the log, named after the offset of the first
+// change with in it.
+type segment struct {
+	base     uint64
+	last     uint64
+	count    int
+	path     string
+	modified time.Time
+}
+
+// NewChangeLog opens the change log with in the directory, carrying on from the
+// last change that was written.
+func NewChangeLog(config ChangeLogConfig, logger log.Logger) (*ChangeLog, error) {
+	if config.SegmentSize < 1 {
+		config.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "create directory")
+	}
+
+	c := &ChangeLog{
+		config: config,
+		now:    time.Now,
+		logger: logger,
+	}
+	c.turn = sync.NewCond(&c.mutex)
+	if err := c.recover(); err != nil {
+		return nil, err
+	}
+	c.prune()
+	return c, nil
+}
+
+// Append assigns the next offset to the event and writes it to the log. The
+// offset is assigned even if writing fails, so that the offsets always match
+// the order of the changes.
+//
+// An event that already has an offset, such as from the Feed, keeps it and
+// waits for the events before it to be appended, so that the offsets can be
+// given out in order whilst the writes happen outside of any other lock.
+func (c *ChangeLog) Append(event selectors.Event) (selectors.Event, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if event.Offset == 0 {
+		event.Offset = c.offset + 1
+	}
+	for event.Offset > c.offset+1 {
+		c.turn.Wait()
+	}
+	if event.Offset <= c.offset {
+		return event, errors.Errorf("offset %d has already been appended", event.Offset)
+	}
+	c.offset = event.Offset
+	defer c.turn.Broadcast()
+
+	active := c.active()
+	if active == nil || active.count >= c.config.SegmentSize {
+		if err := c.rotate(); err != nil {
+			return event, err
+		}
+		active = c.active()
+	}
+
+	record, err := encodeRecord(event)
+	if err != nil {
+		return event, err
+	}
+	if _, err := c.file.Write(record); err != nil {
+		return event, errors.Wrap(err, "write change")
+	}
+	if c.config.Sync {
+		if err := c.file.Sync(); err != nil {
+			return event, errors.Wrap(err, "sync change")
+		}
+	}
+
+	if active.count == 0 {
+		active.base = event.Offset
+	}
+	active.last = event.Offset
+	active.count++
+	active.modified = c.now()
+
+	c.prune()
+	return event, nil
+}
+
+// Read returns at most limit changes after the offset. It errors if the
+// changes after the offset are no longer retained.
+func (c *ChangeLog) Read(since uint64, limit int) ([]selectors.Event, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if oldest := c.oldest(); oldest > 0 && since+1 < oldest {
+		return nil, selectors.NewExpiredError(errors.Errorf("offset %d is no longer retained, oldest offset is %d", since, oldest))
+	}
+
+	var res []selectors.Event
+	for _, s := range c.segments {
+		if s.count == 0 || s.last <= since {
+			continue
+		}
+		if err := readSegment(s.path, func(event selectors.Event, _ int64) bool {
+			if event.Offset <= since {
+				return true
+			}
+			res = append(res, event)
+			return limit <= 0 || len(res) < limit
+		}); err != nil {
+			return nil, err
+		}
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+	}
+	return res, nil
+}
+
+// Offsets returns the oldest retained offset and the latest offset.
+func (c *ChangeLog) Offsets() (oldest, latest uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.oldest(), c.offset
+}
+
+// Close the log, flushing any changes to disk.
+func (c *ChangeLog) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	if err := c.file.Sync(); err != nil {
+		return err
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+func (c *ChangeLog) active() *segment {
+	if c.file == nil || len(c.segments) == 0 {
+		return nil
+	}
+	return c.segments[len(c.segments)-1]
+}
+
+func (c *ChangeLog) oldest() uint64 {
+	for _, s := range c.segments {
+		if s.count > 0 {
+			return s.base
+		}
+	}
+	return 0
+}
+
+// rotate seals the active segment and starts a new one.
+func (c *ChangeLog) rotate() error {
+	if c.file != nil {
+		if err := c.file.Sync(); err != nil {
+			return errors.Wrap(err, "sync segment")
+		}
+		if err := c.file.Close(); err != nil {
+			return errors.Wrap(err, "close segment")
+		}
+		c.file = nil
+	}
+
+	s := &segment{
+		base:     c.offset,
+		path:     filepath.Join(c.config.Dir, segmentName(c.offset)),
+		modified: c.now(),
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrap(err, "create segment")
+	}
+	c.file = file
+	c.segments = append(c.segments, s)
+	return nil
+}
+
+// prune removes the oldest sealed segments, whilst they're beyond the
+// retention of the log. The active segment is never removed.
+func (c *ChangeLog) prune() {
+	total := 0
+	for _, s := range c.segments {
+		total += s.count
+	}
+
+	for len(c.segments) > 1 {
+		var (
+			oldest  = c.segments[0]
+			entries = c.config.MaxEntries > 0 && total-oldest.count >= c.config.MaxEntries
+			age     = c.config.MaxAge > 0 && c.now().Sub(oldest.modified) > c.config.MaxAge
+		)
+		if !entries && !age {
+			return
+		}
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			level.Warn(c.logger).Log("segment", oldest.path, "err", err)
+			return
+		}
+		total -= oldest.count
+		c.segments = c.segments[1:]
+	}
+}
+
+// recover reads the existing segments, truncating any partially written
+// change at the end of the last segment. New changes are always written to a
+// new segment.
+func (c *ChangeLog) recover() error {
+	infos, err := ioutil.ReadDir(c.config.Dir)
+	if err != nil {
+		return errors.Wrap(err, "read directory")
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		if _, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64); err != nil {
+			continue
+		}
+		c.segments = append(c.segments, &segment{
+			path:     filepath.Join(c.config.Dir, name),
+			modified: info.ModTime(),
+		})
+	}
+	sort.Slice(c.segments, func(i, j int) bool {
+		return c.segments[i].path < c.segments[j].path
+	})
+
+	for k, s := range c.segments {
+		var size int64
+		err := readSegment(s.path, func(event selectors.Event, n int64) bool {
+			if s.count == 0 {
+				s.base = event.Offset
+			}
+			s.last = event.Offset
+			s.count++
+			size += n
+			return true
+		})
+		if err == nil {
+			continue
+		}
+		if k != len(c.segments)-1 || errors.Cause(err) != errCorruptRecord {
+			return errors.Wrapf(err, "recover segment %s", s.path)
+		}
+
+		// Only the last change of the last segment can be partially written.
+		level.Warn(c.logger).Log("segment", s.path, "err", err, "truncate", size)
+		if err := os.Truncate(s.path, size); err != nil {
+			return errors.Wrap(err, "truncate segment")
+		}
+	}
+
+	// Empty segments are removed, as new changes are written to a new segment
+	// which could otherwise have the same name.
+	segments := c.segments[:0]
+	for _, s := range c.segments {
+		if s.count == 0 {
+			if err := os.Remove(s.path); err != nil {
+				return errors.Wrap(err, "remove segment")
+			}
+			continue
+		}
+		if s.last > c.offset {
+			c.offset = s.last
+		}
+		segments = append(segments, s)
+	}
+	c.segments = segments
+	return nil
+}
+
+func segmentName(offset uint64) string {
+	return fmt.Sprintf("%020d%s", offset, segmentExt)
+}
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// readSegment calls the function for each change in the segment, along with
+// the size of its record, until the function returns false.
+func readSegment(path string, fn func(selectors.Event, int64) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "open segment")
+	}
+	defer file.Close()
+
+	var (
+		reader = io.Reader(file)
+		header = make([]byte, recordHeaderSize)
+	)
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errCorruptRecord
+		}
+
+		size, sum := binary.BigEndian.Uint32(header[0:4]), binary.BigEndian.Uint32(header[4:8])
+		if size > maxRecordSize {
+			return errCorruptRecord
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(reader, payload); err != nil || crc32.ChecksumIEEE(payload) != sum {
+			return errCorruptRecord
+		}
+
+		event, err := decodeRecord(payload)
+		if err != nil {
+			return errCorruptRecord
+		}
+		if !fn(event, int64(recordHeaderSize)+int64(size)) {
+			return nil
+		}
+	}
+}
+
+// encodeRecord encodes the event as a record; the size and checksum of the
+// payload, followed by the payload itself.
+func encodeRecord(event selectors.Event) ([]byte, error) {
+	payload := new(bytes.Buffer)
+	writeUvarint(payload, event.Offset)
+	switch event.Type {
+	case selectors.EventInsert:
+		payload.WriteByte(0)
+	case selectors.EventDelete:
+		payload.WriteByte(1)
+	default:
+		return nil, errors.Errorf("unexpected event type %q", event.Type)
+	}
+	writeBytes(payload, []byte(event.Key.String()))
+	writeBytes(payload, []byte(event.Field.String()))
+	writeBytes(payload, event.Value)
+	writeVarint(payload, event.Score)
+
+	res := make([]byte, recordHeaderSize, recordHeaderSize+payload.Len())
+	binary.BigEndian.PutUint32(res[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(res[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	return append(res, payload.Bytes()...), nil
+}
+
+func decodeRecord(payload []byte) (event selectors.Event, err error) {
+	reader := bytes.NewReader(payload)
+	if event.Offset, err = binary.ReadUvarint(reader); err != nil {
+		return
+	}
+
+	var kind byte
+	if kind, err = reader.ReadByte(); err != nil {
+		return
+	}
+	switch kind {
+	case 0:
+		event.Type = selectors.EventInsert
+	case 1:
+		event.Type = selectors.EventDelete
+	default:
+		err = errors.Errorf("unexpected event type %d", kind)
+		return
+	}
+
+	var key, field []byte
+	if key, err = readBytes(reader); err != nil {
+		return
+	}
+	if field, err = readBytes(reader); err != nil {
+		return
+	}
+	if event.Value, err = readBytes(reader); err != nil {
+		return
+	}
+	if event.Score, err = binary.ReadVarint(reader); err != nil {
+		return
+	}
+	event.Key, event.Field = selectors.Key(key), selectors.Field(field)
+	return
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	x := make([]byte, binary.MaxVarintLen64)
+	buf.Write(x[:binary.PutUvarint(x, v)])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+	x := make([]byte, binary.MaxVarintLen64)
+	buf.Write(x[:binary.PutVarint(x, v)])
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	writeUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+func readBytes(reader *bytes.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(reader.Len()) {
+		return nil, errors.New("unexpected size")
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	b := make([]byte, size)
+	_, err = io.ReadFull(reader, b)
+	return b, err
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/go-kit/kit/log"
+)
+
+func TestChangeLog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("append and read", func(t *testing.T) {
+		fn := func(members []selectors.FieldValueScore) bool {
+			changes, closer := newChangeLog(t, ChangeLogConfig{SegmentSize: 3})
+			defer closer()
+
+			expected := appendMembers(t, changes, members)
+
+			actual, err := changes.Read(0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return equalEvents(expected, actual)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("read since with limit", func(t *testing.T) {
+		changes, closer := newChangeLog(t, ChangeLogConfig{SegmentSize: 2})
+		defer closer()
+
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 5))
+
+		events, err := changes.Read(1, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var offsets []uint64
+		for _, event := range events {
+			offsets = append(offsets, event.Offset)
+		}
+		if expected, actual := []uint64{2, 3, 4}, offsets; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("offsets carry on after reopening", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "changes")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		config := ChangeLogConfig{Dir: dir, SegmentSize: 2}
+		changes, err := NewChangeLog(config, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 3))
+		if err := changes.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if changes, err = NewChangeLog(config, log.NewNopLogger()); err != nil {
+			t.Fatal(err)
+		}
+		defer changes.Close()
+
+		event := appendMembers(t, changes, make([]selectors.FieldValueScore, 1))[0]
+		if expected, actual := uint64(4), event.Offset; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		events, err := changes.Read(0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 4, len(events); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("partially written change is truncated", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "changes")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		config := ChangeLogConfig{Dir: dir}
+		changes, err := NewChangeLog(config, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 2))
+		if err := changes.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := os.OpenFile(filepath.Join(dir, segmentName(1)), os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte{0, 0, 0, 9, 1}); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		if changes, err = NewChangeLog(config, log.NewNopLogger()); err != nil {
+			t.Fatal(err)
+		}
+		defer changes.Close()
+
+		if _, latest := changes.Offsets(); latest != 2 {
+			t.Errorf("expected: %v, actual: %v", 2, latest)
+		}
+	})
+
+	t.Run("retains max entries", func(t *testing.T) {
+		changes, closer := newChangeLog(t, ChangeLogConfig{SegmentSize: 2, MaxEntries: 3})
+		defer closer()
+
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 7))
+
+		oldest, latest := changes.Offsets()
+		if expected, actual := uint64(5), oldest; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := uint64(7), latest; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		if _, err := changes.Read(1, 0); !selectors.ExpiredError(err) {
+			t.Errorf("expected expired error, actual: %v", err)
+		}
+		events, err := changes.Read(4, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 3, len(events); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("retains max age", func(t *testing.T) {
+		changes, closer := newChangeLog(t, ChangeLogConfig{SegmentSize: 2, MaxAge: time.Minute})
+		defer closer()
+
+		now := time.Now()
+		changes.now = func() time.Time { return now }
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 4))
+
+		now = now.Add(time.Hour)
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 1))
+
+		if oldest, _ := changes.Offsets(); oldest != 5 {
+			t.Errorf("expected: %v, actual: %v", 5, oldest)
+		}
+	})
+
+	t.Run("appends given offsets in order", func(t *testing.T) {
+		changes, closer := newChangeLog(t, ChangeLogConfig{})
+		defer closer()
+
+		// The later offsets wait for the earlier ones to be appended.
+		var wg sync.WaitGroup
+		for offset := uint64(5); offset > 0; offset-- {
+			wg.Add(1)
+			go func(offset uint64) {
+				defer wg.Done()
+				if _, err := changes.Append(selectors.Event{
+					Offset: offset,
+					Type:   selectors.EventInsert,
+					Key:    "key",
+				}); err != nil {
+					t.Error(err)
+				}
+			}(offset)
+		}
+		wg.Wait()
+
+		events, err := changes.Read(0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, event := range events {
+			if expected, actual := uint64(k+1), event.Offset; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+		if expected, actual := 5, len(events); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		if _, err := changes.Append(selectors.Event{Offset: 3, Type: selectors.EventInsert}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func newChangeLog(t *testing.T, config ChangeLogConfig) (*ChangeLog, func()) {
+	dir, err := ioutil.TempDir("", "changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Dir = dir
+	changes, err := NewChangeLog(config, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return changes, func() {
+		changes.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func appendMembers(t *testing.T, changes *ChangeLog, members []selectors.FieldValueScore) []selectors.Event {
+	var res []selectors.Event
+	for k, member := range members {
+		kind := selectors.EventInsert
+		if k%2 == 1 {
+			kind = selectors.EventDelete
+		}
+		event, err := changes.Append(selectors.Event{
+			Type:  kind,
+			Key:   "key",
+			Field: member.Field,
+			Value: member.Value,
+			Score: member.Score,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, event)
+	}
+	return res
+}
+
+func equalEvents(a, b []selectors.Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		w := b[k]
+		if v.Offset != w.Offset ||
+			v.Type != w.Type ||
+			v.Key != w.Key ||
+			v.Field != w.Field ||
+			string(v.Value) != string(w.Value) ||
+			v.Score != w.Score {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/pkg/errors"
+)
+
+const (
+	// subscriberQueueSize is the number of new events that can be waiting for
+	// a subscriber, on top of the kept events that are replayed, before the
+	// subscriber is considered too slow and is ended.
+	subscriberQueueSize = 4096
+)
+
+// Feed keeps the most recent events that have been applied to the store, so
+// that watchers can resume from an offset or a score, and sends new events to
+// the subscribers of each key.
+type Feed struct {
+	mutex       sync.Mutex
+	offset      uint64
+	events      []selectors.Event
+	next        int
+	full        bool
+	subscribers map[*subscription]struct{}
+}
+
+// NewFeed creates a Feed that keeps the capacity most recent events.
+func NewFeed(capacity int) *Feed {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Feed{
+		events:      make([]selectors.Event, capacity),
+		subscribers: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish assigns the next offset to the event, keeps it and sends it to the
+// subscribers of the key. Events that already have an offset, such as from the
+// ChangeLog, keep their offset.
+func (f *Feed) Publish(event selectors.Event) selectors.Event {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if event.Offset == 0 {
+		event.Offset = f.offset + 1
+	}
+	f.offset = event.Offset
+
+	f.events[f.next] = event
+	f.next = (f.next + 1) % len(f.events)
+	if f.next == 0 {
+		f.full = true
+	}
+
+	for s := range f.subscribers {
+		if s.key != event.Key {
+			continue
+		}
+		if !s.push(event) {
+			delete(f.subscribers, s)
+		}
+	}
+
+	return event
+}
+
+// Read returns at most limit of the kept events after the offset. It errors if
+// the events after the offset are no longer kept.
+func (f *Feed) Read(since uint64, limit int) ([]selectors.Event, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	kept := f.kept()
+	if len(kept) > 0 && since+1 < kept[0].Offset {
+		return nil, selectors.NewExpiredError(errors.Errorf("offset %d is no longer available, oldest offset is %d", since, kept[0].Offset))
+	}
+
+	var res []selectors.Event
+	for _, event := range kept {
+		if event.Offset <= since {
+			continue
+		}
+		res = append(res, event)
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+	}
+	return res, nil
+}
+
+// Offset returns the offset of the most recent event.
+func (f *Feed) Offset() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.offset
+}
+
+// Subscribe to the events of a key. Any kept events after the resume are sent
+// first, followed by new events as they're published. Resuming from an offset
+// that is no longer kept is an error, as events would be missed.
+func (f *Feed) Subscribe(key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	kept := f.kept()
+	if resume.Type == selectors.ResumeOffset && len(kept) > 0 {
+		if oldest := kept[0].Offset; uint64(resume.Value)+1 < oldest {
+			return nil, selectors.NewExpiredError(errors.Errorf("offset %d is no longer available, oldest offset is %d", resume.Value, oldest))
+		}
+	}
+
+	s := newSubscription(f, key, len(f.events)+subscriberQueueSize)
+	for _, event := range kept {
+		if event.Key == key && resume.After(event) {
+			if !s.push(event) {
+				return nil, s.Err()
+			}
+		}
+	}
+
+	f.subscribers[s] = struct{}{}
+	return s, nil
+}
+
+func (f *Feed) unsubscribe(s *subscription) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.subscribers, s)
+}
+
+// kept returns the kept events, oldest first.
+func (f *Feed) kept() []selectors.Event {
+	if !f.full {
+		return f.events[:f.next]
+	}
+	res := make([]selectors.Event, 0, len(f.events))
+	res = append(res, f.events[f.next:]...)
+	return append(res, f.events[:f.next]...)
+}
+
+// subscription queues the events for a subscriber, so that publishing never
+// blocks on a slow subscriber.
+type subscription struct {
+	feed   *Feed
+	key    selectors.Key
+	limit  int
+	mutex  sync.Mutex
+	queue  []selectors.Event
+	err    error
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	events chan selectors.Event
+}
+
+func newSubscription(feed *Feed, key selectors.Key, limit int) *subscription {
+	s := &subscription{
+		feed:   feed,
+		key:    key,
+		limit:  limit,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		events: make(chan selectors.Event),
+	}
+	go s.run()
+	return s
+}
+
+func (s *subscription) Events() <-chan selectors.Event {
+	return s.events
+}
+
+func (s *subscription) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+func (s *subscription) Close() {
+	s.feed.unsubscribe(s)
+	s.stop(nil)
+}
+
+// push queues the event, returning false if the subscriber is too slow and has
+// been ended.
+func (s *subscription) push(event selectors.Event) bool {
+	s.mutex.Lock()
+	if len(s.queue) >= s.limit {
+		s.mutex.Unlock()
+		s.stop(errors.New("subscriber is too slow"))
+		return false
+	}
+	s.queue = append(s.queue, event)
+	s.mutex.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *subscription) stop(err error) {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		s.err = err
+		s.mutex.Unlock()
+
+		close(s.done)
+	})
+}
+
+func (s *subscription) run() {
+	defer close(s.events)
+
+	for {
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+
+		s.mutex.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mutex.Unlock()
+
+		for _, event := range queue {
+			select {
+			case s.events <- event:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestFeed(t *testing.T) {
+	t.Parallel()
+
+	t.Run("publish assigns offsets", func(t *testing.T) {
+		feed := NewFeed(2)
+		for i := uint64(1); i <= 3; i++ {
+			event := feed.Publish(selectors.Event{Key: "a"})
+			if expected, actual := i, event.Offset; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+		if expected, actual := uint64(3), feed.Offset(); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("subscribe receives new events for key", func(t *testing.T) {
+		feed := NewFeed(10)
+		sub, err := feed.Subscribe("a", selectors.Resume{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		feed.Publish(selectors.Event{Key: "b", Field: "x"})
+		feed.Publish(selectors.Event{Key: "a", Field: "y"})
+
+		event := receive(t, sub)
+		if expected, actual := selectors.Field("y"), event.Field; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("subscribe resumes from offset", func(t *testing.T) {
+		feed := NewFeed(10)
+		for _, field := range []selectors.Field{"x", "y", "z"} {
+			feed.Publish(selectors.Event{Key: "a", Field: field})
+		}
+
+		sub, err := feed.Subscribe("a", selectors.ResumeFromOffset(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		for _, field := range []selectors.Field{"y", "z"} {
+			if expected, actual := field, receive(t, sub).Field; expected != actual {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+	})
+
+	t.Run("subscribe resumes from score", func(t *testing.T) {
+		feed := NewFeed(10)
+		for k, field := range []selectors.Field{"x", "y", "z"} {
+			feed.Publish(selectors.Event{Key: "a", Field: field, Score: int64(k)})
+		}
+
+		sub, err := feed.Subscribe("a", selectors.ResumeFromScore(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		if expected, actual := selectors.Field("z"), receive(t, sub).Field; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("subscribe from a lost offset", func(t *testing.T) {
+		feed := NewFeed(2)
+		for i := 0; i < 5; i++ {
+			feed.Publish(selectors.Event{Key: "a"})
+		}
+
+		if _, err := feed.Subscribe("a", selectors.ResumeFromOffset(1)); err == nil {
+			t.Errorf("expected err")
+		}
+		sub, err := feed.Subscribe("a", selectors.ResumeFromOffset(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub.Close()
+	})
+
+	t.Run("close ends subscription", func(t *testing.T) {
+		feed := NewFeed(2)
+		sub, err := feed.Subscribe("a", selectors.Resume{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub.Close()
+
+		if _, ok := <-sub.Events(); ok {
+			t.Errorf("expected closed")
+		}
+		if err := sub.Err(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func receive(t *testing.T, sub selectors.Subscription) selectors.Event {
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("subscription ended: %v", sub.Err())
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return selectors.Event{}
+}
+
+func TestFeedRead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("read since", func(t *testing.T) {
+		feed := NewFeed(3)
+		for i := 0; i < 5; i++ {
+			feed.Publish(selectors.Event{Key: "a"})
+		}
+
+		if _, err := feed.Read(0, 0); !selectors.ExpiredError(err) {
+			t.Errorf("expected expired error, actual: %v", err)
+		}
+
+		events, err := feed.Read(3, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 1, len(events); expected != actual {
+			t.Fatalf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := uint64(4), events[0].Offset; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("publish keeps offsets", func(t *testing.T) {
+		feed := NewFeed(3)
+		if expected, actual := uint64(10), feed.Publish(selectors.Event{Offset: 10}).Offset; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := uint64(11), feed.Publish(selectors.Event{}).Offset; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
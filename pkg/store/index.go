@@ -0,0 +1,113 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+// Index keeps a set of values in sorted order, so that the values matching a
+// pattern or after a cursor can be found without walking every value.
+type Index struct {
+	mutex  sync.RWMutex
+	values []string
+}
+
+// NewIndex creates an empty Index
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// Add inserts the value in to the index, returning false if it was already
+// present.
+func (i *Index) Add(value string) bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	n := sort.SearchStrings(i.values, value)
+	if n < len(i.values) && i.values[n] == value {
+		return false
+	}
+
+	i.values = append(i.values, "")
+	copy(i.values[n+1:], i.values[n:])
+	i.values[n] = value
+	return true
+}
+
+// Remove takes the value out of the index, returning false if it wasn't
+// present.
+func (i *Index) Remove(value string) bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	n := sort.SearchStrings(i.values, value)
+	if n == len(i.values) || i.values[n] != value {
+		return false
+	}
+
+	copy(i.values[n:], i.values[n+1:])
+	i.values[len(i.values)-1] = ""
+	i.values = i.values[:len(i.values)-1]
+	return true
+}
+
+// Contains returns if the value is with in the index.
+func (i *Index) Contains(value string) bool {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	n := sort.SearchStrings(i.values, value)
+	return n < len(i.values) && i.values[n] == value
+}
+
+// Len returns the number of values with in the index.
+func (i *Index) Len() int {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	return len(i.values)
+}
+
+// Values returns every value in sorted order.
+func (i *Index) Values() []string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	res := make([]string, len(i.values))
+	copy(res, i.values)
+	return res
+}
+
+// Match returns the values that match the glob style pattern in sorted order.
+// Only the values that start with the literal prefix of the pattern are
+// visited.
+func (i *Index) Match(pattern string) []string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	var (
+		res    = make([]string, 0)
+		prefix = selectors.Prefix(pattern)
+	)
+	for n := sort.SearchStrings(i.values, prefix); n < len(i.values); n++ {
+		value := i.values[n]
+		if !strings.HasPrefix(value, prefix) {
+			break
+		}
+		if selectors.Match(pattern, value) {
+			res = append(res, value)
+		}
+	}
+	return res
+}
+
+// Scan returns the next page of values after the cursor.
+func (i *Index) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) ([]string, selectors.Cursor, error) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	return selectors.ScanSorted(i.values, cursor, opts)
+}
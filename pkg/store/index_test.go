@@ -0,0 +1,107 @@
+package store
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/SimonRichardson/coherence/pkg/selectors"
+)
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sorted", func(t *testing.T) {
+		fn := func(values []string) bool {
+			index := NewIndex()
+			unique := make(map[string]struct{})
+			for _, v := range values {
+				_, ok := unique[v]
+				if expected, actual := !ok, index.Add(v); expected != actual {
+					t.Errorf("%q expected: %v, actual: %v", v, expected, actual)
+				}
+				unique[v] = struct{}{}
+			}
+
+			want := make([]string, 0, len(unique))
+			for v := range unique {
+				want = append(want, v)
+			}
+			sort.Strings(want)
+
+			return reflect.DeepEqual(want, index.Values()) && index.Len() == len(want)
+		}
+		if err := quick.Check(fn, nil); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		index := NewIndex()
+		for _, v := range []string{"c", "a", "b"} {
+			index.Add(v)
+		}
+
+		if !index.Remove("b") {
+			t.Errorf("expected remove")
+		}
+		if index.Remove("b") {
+			t.Errorf("expected nothing to remove")
+		}
+		if index.Contains("b") {
+			t.Errorf("expected not to contain")
+		}
+		if expected, actual := []string{"a", "c"}, index.Values(); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("match", func(t *testing.T) {
+		index := NewIndex()
+		for _, v := range []string{"order:1", "user:2", "user:1", "user", "users:1", "admin:user:1"} {
+			index.Add(v)
+		}
+
+		for _, v := range []struct {
+			pattern string
+			want    []string
+		}{
+			{"user:*", []string{"user:1", "user:2"}},
+			{"user*", []string{"user", "user:1", "user:2", "users:1"}},
+			{"*user:1", []string{"admin:user:1", "user:1"}},
+			{"user:?", []string{"user:1", "user:2"}},
+			{"missing*", []string{}},
+		} {
+			if expected, actual := v.want, index.Match(v.pattern); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("%q expected: %v, actual: %v", v.pattern, expected, actual)
+			}
+		}
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		index := NewIndex()
+		for _, v := range []string{"a:1", "b:1", "b:2", "b:3", "c:1"} {
+			index.Add(v)
+		}
+
+		values, cursor, err := index.Scan("", selectors.ScanOptions{Match: "b:*", Count: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []string{"b:1", "b:2"}, values; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		values, cursor, err = index.Scan(cursor, selectors.ScanOptions{Match: "b:*", Count: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []string{"b:3"}, values; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := selectors.Cursor(""), cursor; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+}
@@ -21,6 +21,20 @@ const (
 	Dequeued
 )
 
+func (r EvictionReason) String() string {
+	switch r {
+	case Purged:
+		return "purged"
+	case Popped:
+		return "popped"
+	case Removed:
+		return "removed"
+	case Dequeued:
+		return "dequeued"
+	}
+	return "unknown"
+}
+
 // EvictCallback lets you know when an eviction has happened in the cache
 type EvictCallback func(EvictionReason, selectors.Field, selectors.ValueScore)
 
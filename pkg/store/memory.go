@@ -4,69 +4,267 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 
+	"github.com/SimonRichardson/coherence/pkg/metrics"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
+	"github.com/SimonRichardson/coherence/pkg/store/lru"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
 	"github.com/trussle/fsys"
 )
 
+const (
+	// defaultFeedCapacity is the amount of recent changes that are kept, so
+	// that watchers can resume from them.
+	defaultFeedCapacity = 10000
+)
+
 // TODO: We should run some sort of internal cleaning process to remove keys
 // that have no value.
 
 type memory struct {
-	size    uint
-	fsys    fsys.Filesystem
-	buckets []*Bucket
-	keys    map[selectors.Key]struct{}
-	logger  log.Logger
+	fsys      fsys.Filesystem
+	spaces    map[selectors.Keyspace]*space
+	feed      *Feed
+	changes   *ChangeLog
+	bytes     metrics.GaugeVec
+	rejected  metrics.CounterVec
+	sizes     metrics.GaugeVec
+	evictions metrics.CounterVec
+	logger    log.Logger
+}
+
+// space is the set of buckets of a keyspace, the keys of one keyspace can't
+// evict the keys of another.
+//
+// The keys are indexed per bucket, each index has its own lock, so that a
+// delete can't take a key out of the index whilst an insert of the same
+// bucket is putting members in to it. Inserts share the lock, deletes only
+// hold it whilst they check if the bucket is empty.
+type space struct {
+	name     selectors.Keyspace
+	size     uint
+	capacity uint
+	buckets  []*Bucket
+	keys     []*Index
+	locks    []sync.RWMutex
+	usage    *Usage
+}
+
+// KeyspaceConfig defines the size of a keyspace.
+type KeyspaceConfig struct {
+	// Buckets is the number of buckets of the keyspace.
+	Buckets uint
+
+	// Size is the number of members each bucket holds.
+	Size uint
+
+	// Bytes is the maximum number of bytes of the values with in the
+	// keyspace, inserts that go over it are rejected. 0 is unlimited.
+	Bytes int64
+}
+
+// Option defines a option for configuring the in-memory Store
+type Option func(*memory) error
+
+// WithChangeLog records every change that's applied to the store with in the
+// ChangeLog, so that the changes can be read after they've left the feed.
+func WithChangeLog(changes *ChangeLog) Option {
+	return func(m *memory) error {
+		m.changes = changes
+
+		// Carry on from the offset of the change log, so that the offsets of
+		// the feed and the change log match.
+		_, latest := changes.Offsets()
+		m.feed.offset = latest
+		return nil
+	}
+}
+
+// WithKeyspace gives the keyspace its own set of buckets, the keys of any
+// keyspace without one are kept with in the buckets of the default keyspace.
+// Configuring the default keyspace replaces its buckets.
+func WithKeyspace(keyspace selectors.Keyspace, config KeyspaceConfig) Option {
+	return func(m *memory) error {
+		s, err := newSpace(m.fsys, keyspace, config, m.logger)
+		if err != nil {
+			return err
+		}
+		m.spaces[keyspace] = s
+		return nil
+	}
+}
+
+// WithKeyspaceMetrics reports the bytes used by each keyspace and the members
+// that are rejected for going over the quota, both by keyspace.
+func WithKeyspaceMetrics(bytes metrics.GaugeVec, rejected metrics.CounterVec) Option {
+	return func(m *memory) error {
+		m.bytes = bytes
+		m.rejected = rejected
+		return nil
+	}
+}
+
+// WithBucketMetrics reports the number of members of each bucket, by keyspace
+// and bucket, and counts the members evicted from the buckets, by keyspace and
+// the lru.EvictionReason. Only the "popped" reason is a bucket being full, the
+// rest are members being overwritten or deleted.
+func WithBucketMetrics(sizes metrics.GaugeVec, evictions metrics.CounterVec) Option {
+	return func(m *memory) error {
+		m.sizes = sizes
+		m.evictions = evictions
+		return nil
+	}
 }
 
 // New creates a new in-memory Store according to the size required by
 // the value requested.
-func New(fsys fsys.Filesystem, amountBuckets, amountPerBucket uint, logger log.Logger) (Store, error) {
-	buckets := make([]*Bucket, amountBuckets)
+func New(fsys fsys.Filesystem, amountBuckets, amountPerBucket uint, logger log.Logger, opts ...Option) (Store, error) {
+	s, err := newSpace(fsys, selectors.DefaultKeyspace, KeyspaceConfig{
+		Buckets: amountBuckets,
+		Size:    amountPerBucket,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &memory{
+		fsys: fsys,
+		spaces: map[selectors.Keyspace]*space{
+			selectors.DefaultKeyspace: s,
+		},
+		feed:   NewFeed(defaultFeedCapacity),
+		logger: logger,
+	}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+
+	// The keyspaces can be configured before or after the metrics, so the
+	// buckets are only told about the evictions once every option is applied.
+	if m.evictions != nil {
+		for _, s := range m.spaces {
+			name := s.name.String()
+			for _, b := range s.buckets {
+				b.evicted = func(reason lru.EvictionReason) {
+					m.evictions.WithLabelValues(name, reason.String()).Inc()
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+func newSpace(fsys fsys.Filesystem, keyspace selectors.Keyspace, config KeyspaceConfig, logger log.Logger) (*space, error) {
+	if config.Buckets == 0 {
+		return nil, errors.Errorf("expected buckets for keyspace %q", keyspace)
+	}
+
+	// The default keyspace keeps the names of the buckets from before there
+	// were keyspaces.
+	prefix := "bucket"
+	if keyspace != selectors.DefaultKeyspace {
+		prefix = fmt.Sprintf("keyspace-%s-bucket", keyspace)
+	}
+
+	var (
+		usage   = NewUsage(config.Bytes)
+		buckets = make([]*Bucket, config.Buckets)
+		keys    = make([]*Index, config.Buckets)
+	)
 	for k := range buckets {
-		file, err := fsys.Create(fmt.Sprintf("bucket-%d", k))
+		file, err := fsys.Create(fmt.Sprintf("%s-%d", prefix, k))
 		if err != nil {
 			return nil, err
 		}
-		buckets[k] = NewBucket(file, int(amountPerBucket), log.With(logger, "component", "bucket"))
+		buckets[k] = newBucket(file, int(config.Size), usage, log.With(logger, "component", "bucket", "keyspace", keyspace))
+		keys[k] = NewIndex()
 	}
-
-	return &memory{
-		size:    amountBuckets,
-		buckets: buckets,
-		keys:    make(map[selectors.Key]struct{}),
-		logger:  logger,
+	return &space{
+		name:     keyspace,
+		size:     config.Buckets,
+		capacity: config.Size,
+		buckets:  buckets,
+		keys:     keys,
+		locks:    make([]sync.RWMutex, config.Buckets),
+		usage:    usage,
 	}, nil
 }
 
-func (m *memory) Insert(key selectors.Key, members []selectors.FieldValueScore) (selectors.ChangeSet, error) {
-	if _, ok := m.keys[key]; !ok {
-		m.keys[key] = struct{}{}
+// space returns the space the key is kept with in.
+func (m *memory) space(key selectors.Key) *space {
+	if keyspace, _ := key.Keyspace(); keyspace != selectors.DefaultKeyspace {
+		if s, ok := m.spaces[keyspace]; ok {
+			return s
+		}
 	}
+	return m.spaces[selectors.DefaultKeyspace]
+}
 
+// bucket returns the bucket the key is kept with in.
+func (m *memory) bucket(key selectors.Key) *Bucket {
+	s := m.space(key)
+	return s.buckets[index(key, s.size)]
+}
+
+// matching returns the spaces that can have keys matching the pattern, which
+// is every space unless the pattern is with in a keyspace.
+func (m *memory) matching(pattern string) []*space {
+	if keyspace, ok := selectors.KeyspaceOfPattern(pattern); ok {
+		return []*space{m.space(keyspace.Key(""))}
+	}
+
+	res := make([]*space, 0, len(m.spaces))
+	for _, s := range m.spaces {
+		res = append(res, s)
+	}
+	return res
+}
+
+func (m *memory) Insert(key selectors.Key, members []selectors.FieldValueScore) (selectors.ChangeSet, error) {
 	var (
-		errors    []error
+		errs      []error
+		rejected  int
 		changeSet selectors.ChangeSet
 
-		index = uint(key.Hash()) % m.size
+		s     = m.space(key)
+		index = index(key, s.size)
 	)
+	s.locks[index].RLock()
+	s.keys[index].Add(key.String())
 	for _, member := range members {
-		res, err := m.buckets[index].Insert(member.Field, member.ValueScore())
+		res, err := m.write(selectors.EventInsert, key, s.buckets[index], member)
 		if err != nil {
-			errors = append(errors, err)
+			if selectors.QuotaError(err) {
+				rejected++
+			}
+			errs = append(errs, err)
 			continue
 		}
 
 		changeSet = changeSet.Append(res)
 	}
+	s.locks[index].RUnlock()
 
-	return changeSet, joinErrors(errors)
+	m.observe(s, index, rejected)
+
+	if err := joinErrors(errs); err != nil {
+		// Keep the error a quota error if that's the only reason the members
+		// weren't inserted, so that it's reported as such.
+		if rejected == len(errs) {
+			return changeSet, selectors.NewQuotaError(err)
+		}
+		return changeSet, err
+	}
+	return changeSet, nil
 }
 
 func (m *memory) Delete(key selectors.Key, members []selectors.FieldValueScore) (selectors.ChangeSet, error) {
@@ -74,11 +272,12 @@ func (m *memory) Delete(key selectors.Key, members []selectors.FieldValueScore)
 		errors    []error
 		changeSet selectors.ChangeSet
 
-		index = uint(key.Hash()) % m.size
+		s     = m.space(key)
+		index = index(key, s.size)
 	)
 
 	for _, member := range members {
-		res, err := m.buckets[index].Delete(member.Field, member.ValueScore())
+		res, err := m.write(selectors.EventDelete, key, s.buckets[index], member)
 		if err != nil {
 			errors = append(errors, err)
 			continue
@@ -87,71 +286,274 @@ func (m *memory) Delete(key selectors.Key, members []selectors.FieldValueScore)
 		changeSet = changeSet.Append(res)
 	}
 
-	if amount, err := m.buckets[index].Len(); err != nil {
+	m.observe(s, index, 0)
+
+	s.locks[index].Lock()
+	defer s.locks[index].Unlock()
+
+	if amount, err := s.buckets[index].Len(); err != nil {
 		return changeSet, joinErrors(append(errors, err))
 	} else if amount == 0 {
-		delete(m.keys, key)
+		s.keys[index].Remove(key.String())
 	}
 
 	return changeSet, joinErrors(errors)
 }
 
+// Swap inserts the member only if its field is inserted with the score of the
+// token, see Bucket.Swap.
+func (m *memory) Swap(key selectors.Key, member selectors.FieldValueScore, token int64) (selectors.ChangeSet, error) {
+	var (
+		s     = m.space(key)
+		index = index(key, s.size)
+	)
+	record := m.recorder(selectors.EventInsert, key, member)
+	s.locks[index].RLock()
+	changeSet, err := s.buckets[index].swap(member.Field, member.ValueScore(), token, record.publish)
+	s.locks[index].RUnlock()
+	record.append()
+
+	var rejected int
+	if selectors.QuotaError(err) {
+		rejected = 1
+	}
+	m.observe(s, index, rejected)
+
+	return changeSet, err
+}
+
+// observe reports the bytes used by the space and the size of the bucket after
+// a write, values are only ever evicted by writes.
+func (m *memory) observe(s *space, index uint, rejected int) {
+	if m.bytes != nil {
+		m.bytes.WithLabelValues(s.name.String()).Set(float64(s.usage.Bytes()))
+	}
+	if m.sizes != nil {
+		if amount, err := s.buckets[index].Len(); err == nil {
+			m.sizes.WithLabelValues(s.name.String(), strconv.FormatUint(uint64(index), 10)).Set(float64(amount))
+		}
+	}
+	if m.rejected != nil && rejected > 0 {
+		m.rejected.WithLabelValues(s.name.String()).Add(float64(rejected))
+	}
+}
+
 func (m *memory) Select(key selectors.Key, field selectors.Field) (selectors.FieldValueScore, error) {
-	idx := index(key, m.size)
+	s := m.space(key)
+	idx := index(key, s.size)
 	level.Info(m.logger).Log("key", key, "index", idx, "field", field)
-	return m.buckets[idx].Select(field)
+	return s.buckets[idx].Select(field)
 }
 
 func (m *memory) Keys() ([]selectors.Key, error) {
-	var res []selectors.Key
-	for k := range m.keys {
-		s, err := m.Size(k)
-		if err != nil {
-			return nil, err
-		}
-		if s > 0 {
-			res = append(res, k)
-		}
-	}
-	return res, nil
+	return m.matchKeys(m.matching(""), func(index *Index) []string {
+		return index.Values()
+	})
+}
+
+func (m *memory) KeysMatching(pattern string) ([]selectors.Key, error) {
+	return m.matchKeys(m.matching(pattern), func(index *Index) []string {
+		return index.Match(pattern)
+	})
 }
 
 func (m *memory) Size(key selectors.Key) (int64, error) {
-	idx := index(key, m.size)
-	return m.buckets[idx].Len()
+	return m.bucket(key).Len()
 }
 
 func (m *memory) Members(key selectors.Key) ([]selectors.Field, error) {
-	idx := index(key, m.size)
-	return m.buckets[idx].Members()
+	return m.bucket(key).Members()
+}
+
+func (m *memory) MembersWithPrefix(key selectors.Key, prefix string) ([]selectors.Field, error) {
+	return m.bucket(key).Match(selectors.PrefixPattern(prefix))
+}
+
+func (m *memory) Scan(cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.KeysPage, error) {
+	var (
+		pages   [][]string
+		cursors []selectors.Cursor
+	)
+	for _, s := range m.matching(opts.Match) {
+		for k, index := range s.keys {
+			if !s.occupied(k) {
+				continue
+			}
+
+			values, next, err := index.Scan(cursor, opts)
+			if err != nil {
+				return selectors.KeysPage{}, err
+			}
+			pages = append(pages, values)
+			cursors = append(cursors, next)
+		}
+	}
+
+	// Each bucket is its own sorted index, so the pages are merged in the same
+	// way as the pages from different replicas.
+	values, next := selectors.MergeSorted(pages, cursors, opts)
+	return selectors.KeysPage{Keys: toKeys(values), Cursor: next}, nil
+}
+
+func (m *memory) ScanMembers(key selectors.Key, cursor selectors.Cursor, opts selectors.ScanOptions) (selectors.FieldsPage, error) {
+	return m.bucket(key).Scan(cursor, opts)
+}
+
+// matchKeys collects the keys from the index of every bucket that has members,
+// returning them in sorted order.
+func (m *memory) matchKeys(spaces []*space, fn func(*Index) []string) ([]selectors.Key, error) {
+	var res []string
+	for _, s := range spaces {
+		for k, index := range s.keys {
+			if s.occupied(k) {
+				res = append(res, fn(index)...)
+			}
+		}
+	}
+	sort.Strings(res)
+	return toKeys(res), nil
+}
+
+// occupied returns if the bucket has any members, a key without any members
+// isn't reported.
+func (s *space) occupied(index int) bool {
+	amount, err := s.buckets[index].Len()
+	return err == nil && amount > 0
 }
 
 func (m *memory) Score(key selectors.Key, field selectors.Field) (selectors.Presence, error) {
-	idx := index(key, m.size)
-	return m.buckets[idx].Score(field)
+	return m.bucket(key).Score(field)
+}
+
+func (m *memory) Watch(key selectors.Key, resume selectors.Resume) (selectors.Subscription, error) {
+	return m.feed.Subscribe(key, resume)
+}
+
+func (m *memory) Changes(since uint64, limit int) ([]selectors.Event, error) {
+	if m.changes != nil {
+		return m.changes.Read(since, limit)
+	}
+	return m.feed.Read(since, limit)
+}
+
+// write applies the member to the bucket, publishing the change to any
+// watchers if it was applied.
+func (m *memory) write(kind selectors.EventType,
+	key selectors.Key,
+	bucket *Bucket,
+	member selectors.FieldValueScore,
+) (selectors.ChangeSet, error) {
+	record := m.recorder(kind, key, member)
+	changeSet, err := bucket.write(kind, member.Field, member.ValueScore(), record.publish)
+	record.append()
+	return changeSet, err
+}
+
+// recorder records the write of a member once it's been applied, in two
+// steps. The change is published whilst the bucket is still locked, which
+// gives it the next offset, then it's appended to the change log once the
+// bucket is unlocked, so that writing the log doesn't hold up the bucket.
+type recorder struct {
+	memory *memory
+	event  selectors.Event
+	ok     bool
+}
+
+func (m *memory) recorder(kind selectors.EventType, key selectors.Key, member selectors.FieldValueScore) *recorder {
+	return &recorder{
+		memory: m,
+		event: selectors.Event{
+			Type:  kind,
+			Key:   key,
+			Field: member.Field,
+			Value: member.Value,
+			Score: member.Score,
+		},
+	}
+}
+
+// publish the change to the feed, which gives out the offsets in the same
+// order as the changes are applied.
+func (r *recorder) publish() {
+	r.event = r.memory.feed.Publish(r.event)
+	r.ok = true
+}
+
+// append the change to the change log, if there is one and the change was
+// applied. The change log appends the changes in the order of their offsets,
+// so the write is only acknowledged once it's been logged.
+func (r *recorder) append() {
+	m := r.memory
+	if !r.ok || m.changes == nil {
+		return
+	}
+	if _, err := m.changes.Append(r.event); err != nil {
+		level.Error(m.logger).Log("offset", r.event.Offset, "err", err)
+	}
 }
 
 func (m *memory) Repair([]selectors.KeyFieldValue) error {
 	return nil
 }
 
+// Occupancy returns how full the buckets of each keyspace are, ordered by
+// keyspace.
+func (m *memory) Occupancy() []KeyspaceOccupancy {
+	res := make([]KeyspaceOccupancy, 0, len(m.spaces))
+	for _, s := range m.spaces {
+		buckets := make([]BucketOccupancy, len(s.buckets))
+		for k, b := range s.buckets {
+			members, err := b.Len()
+			if err != nil {
+				continue
+			}
+			buckets[k] = BucketOccupancy{
+				Keys:    s.keys[k].Len(),
+				Members: members,
+			}
+		}
+		res = append(res, KeyspaceOccupancy{
+			Keyspace: s.name,
+			Capacity: s.capacity,
+			Bytes:    s.usage.Bytes(),
+			Limit:    s.usage.Limit(),
+			Buckets:  buckets,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Keyspace < res[j].Keyspace
+	})
+	return res
+}
+
 func (m *memory) String() string {
 	buf := new(bytes.Buffer)
 	writer := tabwriter.NewWriter(buf, 0, 0, 1, ' ', tabwriter.Debug)
 
 	fmt.Fprintln(writer, "bucket key\t field\t score\t value\t")
-	for k := range m.keys {
-		idx := index(k, m.size)
-		m.buckets[idx].insert.Walk(func(field selectors.Field, value selectors.ValueScore) error {
-			fmt.Fprintf(writer, "%s\t %s\t %d\t %s\t\n", k, field, value.Score, hex.EncodeToString(value.Value))
-			return nil
-		})
+	for _, s := range m.spaces {
+		for idx, keys := range s.keys {
+			for _, k := range keys.Values() {
+				s.buckets[idx].Walk(func(field selectors.Field, value selectors.ValueScore) error {
+					fmt.Fprintf(writer, "%s\t %s\t %d\t %s\t\n", k, field, value.Score, hex.EncodeToString(value.Value))
+					return nil
+				})
+			}
+		}
 	}
 	writer.Flush()
 
 	return fmt.Sprintf("\n%s", buf.String())
 }
 
+func toKeys(values []string) []selectors.Key {
+	res := make([]selectors.Key, len(values))
+	for k, v := range values {
+		res[k] = selectors.Key(v)
+	}
+	return res
+}
+
 func index(key selectors.Key, size uint) uint {
 	return uint(key.Hash()) % size
 }
@@ -1,13 +1,20 @@
 package store
 
 import (
+	"fmt"
+	"math/rand"
 	"reflect"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/quick"
 
+	metricMocks "github.com/SimonRichardson/coherence/pkg/metrics/mocks"
 	"github.com/SimonRichardson/coherence/pkg/selectors"
 	"github.com/go-kit/kit/log"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/trussle/fsys"
 )
 
@@ -421,3 +428,595 @@ func incScore(members []selectors.FieldValueScore) []selectors.FieldValueScore {
 	}
 	return res
 }
+
+func TestMemoryWatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("watch applied changes", func(t *testing.T) {
+		store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sub, err := store.Watch("a", selectors.Resume{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		member := selectors.FieldValueScore{Field: "x", Value: []byte("v"), Score: 2}
+		if _, err = store.Insert("a", []selectors.FieldValueScore{member}); err != nil {
+			t.Fatal(err)
+		}
+		// Older scores aren't applied, so aren't sent.
+		older := selectors.FieldValueScore{Field: "x", Score: 1}
+		if _, err = store.Delete("a", []selectors.FieldValueScore{older}); err != nil {
+			t.Fatal(err)
+		}
+		newer := selectors.FieldValueScore{Field: "x", Score: 3}
+		if _, err = store.Delete("a", []selectors.FieldValueScore{newer}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, expected := range []selectors.Event{
+			{Offset: 1, Type: selectors.EventInsert, Key: "a", Field: "x", Value: []byte("v"), Score: 2},
+			{Offset: 2, Type: selectors.EventDelete, Key: "a", Field: "x", Score: 3},
+		} {
+			if actual := receive(t, sub); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("expected: %v, actual: %v", expected, actual)
+			}
+		}
+	})
+}
+
+func TestMemorySwap(t *testing.T) {
+	t.Parallel()
+
+	t.Run("swap records the change", func(t *testing.T) {
+		store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = store.Insert("a", []selectors.FieldValueScore{{Field: "x", Score: 1}}); err != nil {
+			t.Fatal(err)
+		}
+		// The token doesn't match, so nothing is recorded.
+		if _, err = store.Swap("a", selectors.FieldValueScore{Field: "x", Value: []byte("v"), Score: 3}, 2); err != nil {
+			t.Fatal(err)
+		}
+		changeSet, err := store.Swap("a", selectors.FieldValueScore{Field: "x", Value: []byte("w"), Score: 4}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 1, len(changeSet.Success); expected != actual {
+			t.Fatalf("expected: %v, actual: %v", expected, actual)
+		}
+
+		events, err := store.Changes(0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []selectors.Event{
+			{Offset: 1, Type: selectors.EventInsert, Key: "a", Field: "x", Score: 1},
+			{Offset: 2, Type: selectors.EventInsert, Key: "a", Field: "x", Value: []byte("w"), Score: 4},
+		}
+		if actual := events; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("swap a missing key", func(t *testing.T) {
+		store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.Swap("a", selectors.FieldValueScore{Field: "x", Score: 2}, 1); !selectors.NotFoundError(err) {
+			t.Errorf("expected not found error, actual: %v", err)
+		}
+	})
+}
+
+func TestMemoryChanges(t *testing.T) {
+	t.Parallel()
+
+	t.Run("changes from feed", func(t *testing.T) {
+		store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = store.Insert("a", []selectors.FieldValueScore{{Field: "x", Score: 1}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = store.Delete("a", []selectors.FieldValueScore{{Field: "x", Score: 2}}); err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := store.Changes(1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 1, len(events); expected != actual {
+			t.Fatalf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := selectors.EventDelete, events[0].Type; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("changes from change log", func(t *testing.T) {
+		changes, closer := newChangeLog(t, ChangeLogConfig{})
+		defer closer()
+
+		appendMembers(t, changes, make([]selectors.FieldValueScore, 2))
+
+		store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger(), WithChangeLog(changes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sub, err := store.Watch("a", selectors.Resume{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		if _, err = store.Insert("a", []selectors.FieldValueScore{{Field: "x", Score: 1}}); err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := store.Changes(0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := 3, len(events); expected != actual {
+			t.Fatalf("expected: %v, actual: %v", expected, actual)
+		}
+
+		// The offsets of the watch and the change log are the same.
+		if expected, actual := events[2].Offset, receive(t, sub).Offset; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestMemoryMatching(t *testing.T) {
+	t.Parallel()
+
+	store, err := New(fsys.NewNopFilesystem(), 4, 10, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []selectors.Key{"user:2", "order:1", "user:1", "user:10"} {
+		if _, err := store.Insert(key, []selectors.FieldValueScore{
+			{Field: "name:first", Score: 1},
+			{Field: "name:last", Score: 1},
+			{Field: "age", Score: 1},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		keys, err := store.KeysMatching("user:*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Key{"user:1", "user:10", "user:2"}, keys; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("scan across buckets", func(t *testing.T) {
+		var (
+			res    []selectors.Key
+			cursor selectors.Cursor
+		)
+		for {
+			page, err := store.Scan(cursor, selectors.ScanOptions{Count: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, page.Keys...)
+			if page.Cursor == "" {
+				break
+			}
+			cursor = page.Cursor
+		}
+		if expected, actual := []selectors.Key{"order:1", "user:1", "user:10", "user:2"}, res; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("members", func(t *testing.T) {
+		if _, err := store.Delete("user:1", []selectors.FieldValueScore{
+			{Field: "name:last", Score: 2},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		fields, err := store.MembersWithPrefix("user:1", "name:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"name:first"}, fields; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestMemoryKeyspaces(t *testing.T) {
+	t.Parallel()
+
+	var (
+		teamA = selectors.Keyspace("team-a")
+		teamB = selectors.Keyspace("team-b")
+	)
+	store, err := New(fsys.NewNopFilesystem(), 2, 10, log.NewNopLogger(),
+		WithKeyspace(teamA, KeyspaceConfig{Buckets: 1, Size: 1}),
+		WithKeyspace(teamB, KeyspaceConfig{Buckets: 1, Size: 10, Bytes: 4}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("evictions stay with in the keyspace", func(t *testing.T) {
+		if _, err := store.Insert("a", []selectors.FieldValueScore{
+			{Field: "x", Value: []byte("1"), Score: 1},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Insert(teamA.Key("a"), []selectors.FieldValueScore{
+			{Field: "x", Value: []byte("1"), Score: 1},
+			{Field: "y", Value: []byte("2"), Score: 1},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		fields, err := store.Members(teamA.Key("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"y"}, fields; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		fields, err = store.Members("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Field{"x"}, fields; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		if _, err := store.Insert(teamB.Key("a"), []selectors.FieldValueScore{
+			{Field: "x", Value: []byte("abc"), Score: 1},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := store.Insert(teamB.Key("a"), []selectors.FieldValueScore{
+			{Field: "y", Value: []byte("de"), Score: 1},
+		})
+		if expected, actual := true, selectors.QuotaError(err); expected != actual {
+			t.Errorf("expected: %t, actual: %t (%v)", expected, actual, err)
+		}
+
+		// Replacing a value only counts the difference.
+		if _, err := store.Insert(teamB.Key("a"), []selectors.FieldValueScore{
+			{Field: "x", Value: []byte("defg"), Score: 2},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		keys, err := store.KeysMatching(teamA.Pattern(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Key{teamA.Key("a")}, keys; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+
+		keys, err = store.Keys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := []selectors.Key{"a", teamA.Key("a"), teamB.Key("a")}, keys; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+}
+
+func TestMemoryOccupancy(t *testing.T) {
+	t.Parallel()
+
+	teamA := selectors.Keyspace("team-a")
+	store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger(),
+		WithKeyspace(teamA, KeyspaceConfig{Buckets: 2, Size: 5, Bytes: 100}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Insert("a", []selectors.FieldValueScore{
+		{Field: "x", Value: []byte("1"), Score: 1},
+		{Field: "y", Value: []byte("2"), Score: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Insert(teamA.Key("a"), []selectors.FieldValueScore{
+		{Field: "x", Value: []byte("abc"), Score: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		occupancy = store.Occupancy()
+		bucket    = index(teamA.Key("a"), 2)
+	)
+	if expected, actual := 2, len(occupancy); expected != actual {
+		t.Fatalf("expected: %d, actual: %d", expected, actual)
+	}
+
+	expected := KeyspaceOccupancy{
+		Keyspace: selectors.DefaultKeyspace,
+		Capacity: 10,
+		Bytes:    2,
+		Buckets:  []BucketOccupancy{{Keys: 1, Members: 2}},
+	}
+	if actual := occupancy[0]; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+
+	expected = KeyspaceOccupancy{
+		Keyspace: teamA,
+		Capacity: 5,
+		Bytes:    3,
+		Limit:    100,
+		Buckets:  make([]BucketOccupancy, 2),
+	}
+	expected.Buckets[bucket] = BucketOccupancy{Keys: 1, Members: 1}
+	if actual := occupancy[1]; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestMemoryBucketMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		teamA = selectors.Keyspace("team-a")
+
+		popped  = &counterValue{Counter: prometheus.NewCounter(prometheus.CounterOpts{Name: "popped"})}
+		removed = &counterValue{Counter: prometheus.NewCounter(prometheus.CounterOpts{Name: "removed"})}
+		size    = &gaugeValue{Gauge: prometheus.NewGauge(prometheus.GaugeOpts{Name: "size"})}
+
+		sizes     = metricMocks.NewMockGaugeVec(ctrl)
+		evictions = metricMocks.NewMockCounterVec(ctrl)
+	)
+	sizes.EXPECT().WithLabelValues("team-a", "0").Return(size).AnyTimes()
+	evictions.EXPECT().WithLabelValues("team-a", "popped").Return(popped).AnyTimes()
+	evictions.EXPECT().WithLabelValues("team-a", "removed").Return(removed).AnyTimes()
+
+	// The keyspace is configured after the metrics, its buckets should still
+	// report their evictions.
+	store, err := New(fsys.NewNopFilesystem(), 1, 10, log.NewNopLogger(),
+		WithBucketMetrics(sizes, evictions),
+		WithKeyspace(teamA, KeyspaceConfig{Buckets: 1, Size: 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Insert(teamA.Key("a"), []selectors.FieldValueScore{
+		{Field: "x", Value: []byte("1"), Score: 1},
+		{Field: "y", Value: []byte("2"), Score: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := float64(1), size.value; expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+
+	// Overwriting and deleting a member are counted by their own reason.
+	if _, err := store.Insert(teamA.Key("a"), []selectors.FieldValueScore{
+		{Field: "y", Value: []byte("3"), Score: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Delete(teamA.Key("a"), []selectors.FieldValueScore{
+		{Field: "y", Value: []byte("3"), Score: 3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := 1, popped.value; expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if removed.value == 0 {
+		t.Errorf("expected the overwrite and delete to be counted as removed")
+	}
+}
+
+// counterValue records the number of times the counter is incremented.
+type counterValue struct {
+	prometheus.Counter
+	value int
+}
+
+func (c *counterValue) Inc() {
+	c.value++
+}
+
+// gaugeValue records the value that the gauge is set to.
+type gaugeValue struct {
+	prometheus.Gauge
+	value float64
+}
+
+func (g *gaugeValue) Set(value float64) {
+	g.value = value
+}
+
+// TestMemoryConcurrency hammers the store from many goroutines at once, it's
+// meant to be run with -race.
+func TestMemoryConcurrency(t *testing.T) {
+	t.Parallel()
+
+	const (
+		writers = 8
+		readers = 4
+		writes  = 500
+		keys    = 16
+		fields  = 4
+	)
+
+	changes, closer := newChangeLog(t, ChangeLogConfig{})
+	defer closer()
+
+	// Few buckets of few members, so that the keys share buckets and the
+	// members are evicted whilst they're being read.
+	store, err := New(fsys.NewNopFilesystem(), 4, 8, log.NewNopLogger(),
+		WithChangeLog(changes),
+		WithKeyspace("team-a", KeyspaceConfig{Buckets: 2, Size: 8, Bytes: 64}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := func(n int) selectors.Key {
+		k := selectors.Key(fmt.Sprintf("key-%d", n%keys))
+		if n%2 == 0 {
+			return selectors.Keyspace("team-a").Key(k)
+		}
+		return k
+	}
+	field := func(key selectors.Key, n int) selectors.Field {
+		return selectors.Field(fmt.Sprintf("%s-%d", key, n%fields))
+	}
+
+	sub, err := store.Watch(key(0), selectors.Resume{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	go func() {
+		for range sub.Events() {
+		}
+	}()
+
+	var (
+		score int64
+		wg    sync.WaitGroup
+		done  = make(chan struct{})
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(int64(i)))
+			for j := 0; j < writes; j++ {
+				k := key(r.Intn(keys))
+				members := []selectors.FieldValueScore{{
+					Field: field(k, r.Intn(fields)),
+					Value: []byte("value"),
+					Score: atomic.AddInt64(&score, 1),
+				}}
+
+				var err error
+				if r.Intn(3) == 0 {
+					_, err = store.Delete(k, members)
+				} else {
+					_, err = store.Insert(k, members)
+				}
+				if err != nil && !selectors.QuotaError(err) {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+
+	var rg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		rg.Add(1)
+		go func(i int) {
+			defer rg.Done()
+
+			r := rand.New(rand.NewSource(int64(writers + i)))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				k := key(r.Intn(keys))
+				f := field(k, r.Intn(fields))
+				store.Select(k, f)
+				store.Score(k, f)
+				store.Size(k)
+				store.Members(k)
+				store.MembersWithPrefix(k, "key")
+				store.ScanMembers(k, selectors.Cursor(""), selectors.ScanOptions{Count: 2})
+				store.Keys()
+				store.KeysMatching("key-*")
+				store.Scan(selectors.Cursor(""), selectors.ScanOptions{Count: 2})
+				store.Changes(0, 10)
+				_ = store.(fmt.Stringer).String()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(done)
+	rg.Wait()
+
+	// The change log has every change that was published, in order of the
+	// offsets, even though the writes were appended outside of the buckets.
+	events, err := store.Changes(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, event := range events {
+		if expected, actual := uint64(k+1), event.Offset; expected != actual {
+			t.Fatalf("expected: %v, actual: %v", expected, actual)
+		}
+	}
+	if _, latest := changes.Offsets(); uint64(len(events)) != latest {
+		t.Errorf("expected: %v, actual: %v", latest, len(events))
+	}
+
+	// Every key with a member that's still inserted has to be found.
+	found, err := store.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := make(map[selectors.Key]bool)
+	for _, k := range found {
+		index[k] = true
+	}
+	for n := 0; n < keys; n++ {
+		k := key(n)
+		for f := 0; f < fields; f++ {
+			presence, err := store.Score(k, field(k, f))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if presence.Inserted && !index[k] {
+				t.Errorf("expected key %q to be found, as %q is inserted", k, field(k, f))
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ package mocks
 
 import (
 	selectors "github.com/SimonRichardson/coherence/pkg/selectors"
+	store "github.com/SimonRichardson/coherence/pkg/store"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 )
@@ -33,6 +34,19 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// Changes mocks base method
+func (m *MockStore) Changes(arg0 uint64, arg1 int) ([]selectors.Event, error) {
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1)
+	ret0, _ := ret[0].([]selectors.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes
+func (mr *MockStoreMockRecorder) Changes(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockStore)(nil).Changes), arg0, arg1)
+}
+
 // Delete mocks base method
 func (m *MockStore) Delete(arg0 selectors.Key, arg1 []selectors.FieldValueScore) (selectors.ChangeSet, error) {
 	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
@@ -72,6 +86,19 @@ func (mr *MockStoreMockRecorder) Keys() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockStore)(nil).Keys))
 }
 
+// KeysMatching mocks base method
+func (m *MockStore) KeysMatching(arg0 string) ([]selectors.Key, error) {
+	ret := m.ctrl.Call(m, "KeysMatching", arg0)
+	ret0, _ := ret[0].([]selectors.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// KeysMatching indicates an expected call of KeysMatching
+func (mr *MockStoreMockRecorder) KeysMatching(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeysMatching", reflect.TypeOf((*MockStore)(nil).KeysMatching), arg0)
+}
+
 // Members mocks base method
 func (m *MockStore) Members(arg0 selectors.Key) ([]selectors.Field, error) {
 	ret := m.ctrl.Call(m, "Members", arg0)
@@ -85,6 +112,57 @@ func (mr *MockStoreMockRecorder) Members(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Members", reflect.TypeOf((*MockStore)(nil).Members), arg0)
 }
 
+// MembersWithPrefix mocks base method
+func (m *MockStore) MembersWithPrefix(arg0 selectors.Key, arg1 string) ([]selectors.Field, error) {
+	ret := m.ctrl.Call(m, "MembersWithPrefix", arg0, arg1)
+	ret0, _ := ret[0].([]selectors.Field)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MembersWithPrefix indicates an expected call of MembersWithPrefix
+func (mr *MockStoreMockRecorder) MembersWithPrefix(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MembersWithPrefix", reflect.TypeOf((*MockStore)(nil).MembersWithPrefix), arg0, arg1)
+}
+
+// Occupancy mocks base method
+func (m *MockStore) Occupancy() []store.KeyspaceOccupancy {
+	ret := m.ctrl.Call(m, "Occupancy")
+	ret0, _ := ret[0].([]store.KeyspaceOccupancy)
+	return ret0
+}
+
+// Occupancy indicates an expected call of Occupancy
+func (mr *MockStoreMockRecorder) Occupancy() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Occupancy", reflect.TypeOf((*MockStore)(nil).Occupancy))
+}
+
+// Scan mocks base method
+func (m *MockStore) Scan(arg0 selectors.Cursor, arg1 selectors.ScanOptions) (selectors.KeysPage, error) {
+	ret := m.ctrl.Call(m, "Scan", arg0, arg1)
+	ret0, _ := ret[0].(selectors.KeysPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Scan indicates an expected call of Scan
+func (mr *MockStoreMockRecorder) Scan(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockStore)(nil).Scan), arg0, arg1)
+}
+
+// ScanMembers mocks base method
+func (m *MockStore) ScanMembers(arg0 selectors.Key, arg1 selectors.Cursor, arg2 selectors.ScanOptions) (selectors.FieldsPage, error) {
+	ret := m.ctrl.Call(m, "ScanMembers", arg0, arg1, arg2)
+	ret0, _ := ret[0].(selectors.FieldsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScanMembers indicates an expected call of ScanMembers
+func (mr *MockStoreMockRecorder) ScanMembers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanMembers", reflect.TypeOf((*MockStore)(nil).ScanMembers), arg0, arg1, arg2)
+}
+
 // Score mocks base method
 func (m *MockStore) Score(arg0 selectors.Key, arg1 selectors.Field) (selectors.Presence, error) {
 	ret := m.ctrl.Call(m, "Score", arg0, arg1)
@@ -135,3 +213,29 @@ func (m *MockStore) String() string {
 func (mr *MockStoreMockRecorder) String() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockStore)(nil).String))
 }
+
+// Swap mocks base method
+func (m *MockStore) Swap(arg0 selectors.Key, arg1 selectors.FieldValueScore, arg2 int64) (selectors.ChangeSet, error) {
+	ret := m.ctrl.Call(m, "Swap", arg0, arg1, arg2)
+	ret0, _ := ret[0].(selectors.ChangeSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Swap indicates an expected call of Swap
+func (mr *MockStoreMockRecorder) Swap(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Swap", reflect.TypeOf((*MockStore)(nil).Swap), arg0, arg1, arg2)
+}
+
+// Watch mocks base method
+func (m *MockStore) Watch(arg0 selectors.Key, arg1 selectors.Resume) (selectors.Subscription, error) {
+	ret := m.ctrl.Call(m, "Watch", arg0, arg1)
+	ret0, _ := ret[0].(selectors.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Watch indicates an expected call of Watch
+func (mr *MockStoreMockRecorder) Watch(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Watch", reflect.TypeOf((*MockStore)(nil).Watch), arg0, arg1)
+}
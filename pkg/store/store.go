@@ -5,6 +5,26 @@ import "github.com/SimonRichardson/coherence/pkg/selectors"
 // StoreContext holds the base of the store
 type StoreContext interface {
 	String() string
+
+	// Occupancy returns how full the buckets of each keyspace are, ordered by
+	// keyspace.
+	Occupancy() []KeyspaceOccupancy
+}
+
+// KeyspaceOccupancy describes how full the buckets of a keyspace are.
+type KeyspaceOccupancy struct {
+	Keyspace selectors.Keyspace `json:"keyspace"`
+	Capacity uint               `json:"capacity"`
+	Bytes    int64              `json:"bytes"`
+	Limit    int64              `json:"limit"`
+	Buckets  []BucketOccupancy  `json:"buckets"`
+}
+
+// BucketOccupancy describes how full a bucket is, the keys are the keys that
+// hash to the bucket and the members are the members of all of those keys.
+type BucketOccupancy struct {
+	Keys    int   `json:"keys"`
+	Members int64 `json:"members"`
 }
 
 // Store represents a in-memory Key/Value implementation
@@ -19,6 +39,11 @@ type Store interface {
 	// Returns ChangeSet of success and failure
 	Delete(selectors.Key, []selectors.FieldValueScore) (selectors.ChangeSet, error)
 
+	// Swap inserts the member only if its field is inserted with the score
+	// of the token, the score of the member has to be larger than the token.
+	// The field is a failure of the ChangeSet if the score doesn't match.
+	Swap(selectors.Key, selectors.FieldValueScore, int64) (selectors.ChangeSet, error)
+
 	// Select retrieves a field and score associated with the store.
 	// Returns Field, Value and Score if the value found
 	Select(selectors.Key, selectors.Field) (selectors.FieldValueScore, error)
@@ -26,12 +51,33 @@ type Store interface {
 	// Keys returns all the potential keys that are stored with in the store.
 	Keys() ([]selectors.Key, error)
 
+	// KeysMatching returns the keys that match the glob style pattern.
+	KeysMatching(string) ([]selectors.Key, error)
+
 	// Size returns the number of members for the key are stored in the store.
 	Size(selectors.Key) (int64, error)
 
 	// Members returns the members associated for a key
 	Members(selectors.Key) ([]selectors.Field, error)
 
+	// MembersWithPrefix returns the members associated for a key that start
+	// with the prefix.
+	MembersWithPrefix(selectors.Key, string) ([]selectors.Field, error)
+
+	// Scan returns the next page of keys after the cursor.
+	Scan(selectors.Cursor, selectors.ScanOptions) (selectors.KeysPage, error)
+
+	// ScanMembers returns the next page of members for a key after the cursor.
+	ScanMembers(selectors.Key, selectors.Cursor, selectors.ScanOptions) (selectors.FieldsPage, error)
+
 	// Score returns the specific score for the field with in the key.
 	Score(selectors.Key, selectors.Field) (selectors.Presence, error)
+
+	// Watch subscribes to the changes of a key, as they're applied to the
+	// store, resuming from either an offset or a score.
+	Watch(selectors.Key, selectors.Resume) (selectors.Subscription, error)
+
+	// Changes returns at most limit changes that have been applied to the
+	// store after the offset, in the order they were applied.
+	Changes(since uint64, limit int) ([]selectors.Event, error)
 }
@@ -0,0 +1,60 @@
+package trace
+
+import "sync"
+
+// Exporter sends the spans that have ended to be stored.
+type Exporter interface {
+	// Export a batch of spans.
+	Export([]SpanData) error
+}
+
+// InMemoryExporter keeps the spans in memory, so that tests can check the
+// spans that were exported.
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty InMemoryExporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// Export keeps the spans.
+func (e *InMemoryExporter) Export(spans []SpanData) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Spans returns every span that's been exported, in the order they were
+// exported.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	res := make([]SpanData, len(e.spans))
+	copy(res, e.spans)
+	return res
+}
+
+// Named returns the spans with the name, in the order they were exported.
+func (e *InMemoryExporter) Named(name string) []SpanData {
+	var res []SpanData
+	for _, v := range e.Spans() {
+		if v.Name == name {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// Reset drops the spans that have been exported.
+func (e *InMemoryExporter) Reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.spans = nil
+}
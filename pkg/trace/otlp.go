@@ -0,0 +1,186 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultOTLPPath is the path of the OTLP/HTTP endpoint of a collector
+	// that receives traces.
+	DefaultOTLPPath = "/v1/traces"
+
+	scopeName = "github.com/SimonRichardson/coherence"
+)
+
+// OTLP span kinds and status codes, as they're numbered by the protocol.
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+
+	otlpStatusError = 2
+)
+
+// OTLPExporter exports the spans to a collector with the JSON encoding of
+// OTLP/HTTP.
+type OTLPExporter struct {
+	client   *http.Client
+	endpoint string
+	service  string
+}
+
+// NewOTLPExporter creates an OTLPExporter that posts the spans to the
+// endpoint (e.g. http://localhost:4318/v1/traces) as the service.
+func NewOTLPExporter(client *http.Client, endpoint, service string) *OTLPExporter {
+	return &OTLPExporter{
+		client:   client,
+		endpoint: endpoint,
+		service:  service,
+	}
+}
+
+// Export posts the spans to the collector.
+func (e *OTLPExporter) Export(spans []SpanData) error {
+	b, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body, so that the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("invalid status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	res := make([]otlpSpan, len(spans))
+	for k, v := range spans {
+		span := otlpSpan{
+			TraceID:           v.Context.TraceID.String(),
+			SpanID:            v.Context.SpanID.String(),
+			Name:              v.Name,
+			Kind:              otlpKind(v.Kind),
+			StartTimeUnixNano: strconv.FormatInt(v.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(v.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(v.Attributes),
+		}
+		if v.Parent.IsValid() {
+			span.ParentSpanID = v.Parent.String()
+		}
+		if v.Err != "" {
+			span.Status = &otlpStatus{Code: otlpStatusError, Message: v.Err}
+		}
+		res[k] = span
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes([]Attribute{String("service.name", e.service)}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: res,
+			}},
+		}},
+	}
+}
+
+func otlpKind(kind Kind) int {
+	switch kind {
+	case KindServer:
+		return otlpKindServer
+	case KindClient:
+		return otlpKindClient
+	default:
+		return otlpKindInternal
+	}
+}
+
+func otlpAttributes(attrs []Attribute) []otlpAttribute {
+	res := make([]otlpAttribute, 0, len(attrs))
+	for _, v := range attrs {
+		var value otlpValue
+		switch t := v.Value.(type) {
+		case string:
+			value.StringValue = &t
+		case int64:
+			// 64 bit integers are encoded as strings, as JSON can't hold all
+			// of them.
+			s := strconv.FormatInt(t, 10)
+			value.IntValue = &s
+		case bool:
+			value.BoolValue = &t
+		default:
+			continue
+		}
+		res = append(res, otlpAttribute{Key: v.Key, Value: value})
+	}
+	return res
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
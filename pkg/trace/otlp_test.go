@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("export", func(t *testing.T) {
+		var req otlpRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if expected, actual := DefaultOTLPPath, r.URL.Path; expected != actual {
+				t.Errorf("expected: %s, actual: %s", expected, actual)
+			}
+			if expected, actual := "application/json", r.Header.Get("Content-Type"); expected != actual {
+				t.Errorf("expected: %s, actual: %s", expected, actual)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+		}))
+		defer server.Close()
+
+		var (
+			parent = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+			start  = time.Unix(1, 0)
+		)
+		exporter := NewOTLPExporter(http.DefaultClient, server.URL+DefaultOTLPPath, "coherence")
+		if err := exporter.Export([]SpanData{{
+			Name:       "farm.node",
+			Kind:       KindClient,
+			Context:    SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: true},
+			Parent:     parent.SpanID,
+			Start:      start,
+			End:        start.Add(time.Second),
+			Attributes: []Attribute{String("node.host", "a"), Int("node.elements", 2), Bool("ok", false)},
+			Err:        "bad",
+		}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if expected, actual := 1, len(req.ResourceSpans); expected != actual {
+			t.Fatalf("expected: %d, actual: %d", expected, actual)
+		}
+		resource := req.ResourceSpans[0]
+		if expected, actual := "coherence", *resource.Resource.Attributes[0].Value.StringValue; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+
+		span := resource.ScopeSpans[0].Spans[0]
+		if expected, actual := parent.TraceID.String(), span.TraceID; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := parent.SpanID.String(), span.ParentSpanID; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := otlpKindClient, span.Kind; expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+		if expected, actual := "1000000000", span.StartTimeUnixNano; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := "2", *span.Attributes[1].Value.IntValue; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := false, *span.Attributes[2].Value.BoolValue; expected != actual {
+			t.Errorf("expected: %t, actual: %t", expected, actual)
+		}
+		if span.Status == nil || span.Status.Code != otlpStatusError || span.Status.Message != "bad" {
+			t.Errorf("expected an error status, actual: %v", span.Status)
+		}
+	})
+
+	t.Run("collector failure", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		exporter := NewOTLPExporter(http.DefaultClient, server.URL+DefaultOTLPPath, "coherence")
+		if err := exporter.Export([]SpanData{{Name: "a"}}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
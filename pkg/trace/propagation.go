@@ -0,0 +1,127 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderTraceParent is the W3C Trace Context header that carries the span
+// context between the nodes.
+const HeaderTraceParent = "traceparent"
+
+const (
+	traceParentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// Inject writes the span context that the context carries to the headers,
+// so that the spans of the node receiving the request join the trace.
+func Inject(ctx context.Context, header http.Header) {
+	if sc, ok := SpanContextFromContext(ctx); ok {
+		header.Set(HeaderTraceParent, FormatTraceParent(sc))
+	}
+}
+
+// Extract returns a context that carries the span context from the headers,
+// if they have a valid one.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if sc, err := ParseTraceParent(header.Get(HeaderTraceParent)); err == nil {
+		return NewRemoteContext(ctx, sc)
+	}
+	return ctx
+}
+
+// FormatTraceParent formats the span context as a traceparent header.
+func FormatTraceParent(sc SpanContext) string {
+	var flags byte
+	if sc.Sampled {
+		flags |= flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceParentVersion, sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceParent returns the span context of the traceparent header
+// otherwise returns an error.
+func ParseTraceParent(s string) (SpanContext, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 {
+		return SpanContext{}, errors.Errorf("invalid traceparent %q", s)
+	}
+	// Versions after 00 can add more parts, but have to keep the first four.
+	if len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == traceParentVersion && len(parts) != 4) {
+		return SpanContext{}, errors.Errorf("invalid traceparent version %q", parts[0])
+	}
+
+	var sc SpanContext
+	if err := decodeHex(sc.TraceID[:], parts[1]); err != nil {
+		return SpanContext{}, errors.Wrap(err, "trace id")
+	}
+	if err := decodeHex(sc.SpanID[:], parts[2]); err != nil {
+		return SpanContext{}, errors.Wrap(err, "span id")
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, errors.Errorf("invalid traceparent %q", s)
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil || len(parts[3]) != 2 {
+		return SpanContext{}, errors.Errorf("invalid traceparent flags %q", parts[3])
+	}
+	sc.Sampled = flags&flagSampled != 0
+	return sc, nil
+}
+
+func decodeHex(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return errors.Errorf("expected %d lower case hex characters, actual %q", hex.EncodedLen(len(dst)), s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// Handler traces the requests to the next handler, as server spans that join
+// the trace of the client if it sent one.
+func Handler(tracer *Tracer, next http.Handler) http.Handler {
+	if tracer == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(Extract(r.Context(), r.Header), KindServer, r.Method+" "+r.URL.Path,
+			String("http.method", r.Method),
+			String("http.target", r.URL.Path),
+		)
+		defer span.End()
+
+		sw := &statusWriter{http.StatusOK, w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(Int("http.status_code", sw.code))
+		if sw.code >= http.StatusInternalServerError {
+			span.SetError(errors.Errorf("status code: %d", sw.code))
+		}
+	})
+}
+
+type statusWriter struct {
+	code int
+	http.ResponseWriter
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes through to the underlying http.ResponseWriter, so that the
+// events of a watch are sent straight away.
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,109 @@
+package trace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestTraceParent(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+
+		actual, err := ParseTraceParent(FormatTraceParent(sc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := sc; expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, actual := "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String(); expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := "00f067aa0ba902b7", sc.SpanID.String(); expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if !sc.Sampled {
+			t.Errorf("expected sampled")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, v := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-x1",
+		} {
+			if _, err := ParseTraceParent(v); err == nil {
+				t.Errorf("expected %q to be invalid", v)
+			}
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter, log.NewNopLogger())
+	defer tracer.Close()
+
+	var inner SpanContext
+	handler := Handler(tracer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner, _ = SpanContextFromContext(r.Context())
+
+		// Anything the handler sends on joins the same trace.
+		header := make(http.Header)
+		Inject(r.Context(), header)
+		if expected, actual := FormatTraceParent(inner), header.Get(HeaderTraceParent); expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	parent := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/cache/select?key=a", nil)
+	r.Header.Set(HeaderTraceParent, FormatTraceParent(parent))
+	handler.ServeHTTP(w, r)
+	tracer.Flush()
+
+	spans := exporter.Named("GET /cache/select")
+	if expected, actual := 1, len(spans); expected != actual {
+		t.Fatalf("expected: %d, actual: %d", expected, actual)
+	}
+	span := spans[0]
+	if expected, actual := parent.TraceID, span.Context.TraceID; expected != actual {
+		t.Errorf("expected: %s, actual: %s", expected, actual)
+	}
+	if expected, actual := parent.SpanID, span.Parent; expected != actual {
+		t.Errorf("expected: %s, actual: %s", expected, actual)
+	}
+	if expected, actual := inner, span.Context; expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if expected, actual := int64(http.StatusInternalServerError), span.Attribute("http.status_code"); expected != actual {
+		t.Errorf("expected: %v, actual: %v", expected, actual)
+	}
+	if span.Err == "" {
+		t.Errorf("expected the span to have failed")
+	}
+}
@@ -0,0 +1,140 @@
+package trace
+
+import (
+	"sync"
+	"time"
+)
+
+// Kind describes the relationship of a span to the other spans of the trace.
+type Kind int
+
+const (
+	// KindInternal is a span of work with in a node.
+	KindInternal Kind = iota + 1
+
+	// KindServer is a span of a request that's received from a client.
+	KindServer
+
+	// KindClient is a span of a request that's sent to another node.
+	KindClient
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Attribute describes a span, the value is either a string, an int64 or a
+// bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String creates an Attribute with a string value.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int creates an Attribute with an integer value.
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Bool creates an Attribute with a bool value.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is a span once it's ended, as it's exported.
+type SpanData struct {
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+
+	// Err is the reason the span failed, empty if it didn't.
+	Err string
+}
+
+// Attribute returns the value of the attribute with the key, or nil if the
+// span doesn't have one.
+func (s SpanData) Attribute(key string) interface{} {
+	for _, v := range s.Attributes {
+		if v.Key == key {
+			return v.Value
+		}
+	}
+	return nil
+}
+
+// Span is a timed operation with in a trace. The methods of a nil Span do
+// nothing, so that the code that's traced doesn't have to check if tracing
+// is on.
+type Span struct {
+	tracer *Tracer
+	mutex  sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// Context returns the span context, to propagate to the spans started from
+// the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetAttributes adds the attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// SetError marks the span as failed, a nil error leaves it as it is.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data.Err = err.Error()
+}
+
+// End the span, sending it to the exporter if it's sampled. Only the first
+// call to End counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mutex.Unlock()
+
+	if data.Context.Sampled {
+		s.tracer.export(data)
+	}
+}
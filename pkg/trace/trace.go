@@ -0,0 +1,97 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// TraceID identifies a trace, which is every span of a request as it crosses
+// the nodes of the cluster.
+type TraceID [16]byte
+
+// IsValid returns if the TraceID isn't all zeros.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span with in a trace.
+type SpanID [8]byte
+
+// IsValid returns if the SpanID isn't all zeros.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span that's propagated to the spans that are
+// started from it, including the spans of other nodes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns if the SpanContext has both a TraceID and a SpanID.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID.IsValid() && s.SpanID.IsValid()
+}
+
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteKey
+)
+
+// NewContext returns a context that carries the span, so that the spans
+// started with it are children of the span.
+func NewContext(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// FromContext returns the span that the context carries, or nil if it
+// doesn't carry one.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// NewRemoteContext returns a context that carries the span context of a span
+// from another node, so that the spans started with it are its children.
+func NewRemoteContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// SpanContextFromContext returns the span context of the span that the
+// context carries, otherwise the span context of a span from another node.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if span := FromContext(ctx); span != nil {
+		return span.Context(), true
+	}
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(remoteKey).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+func newTraceID() (res TraceID) {
+	rand.Read(res[:])
+	return
+}
+
+func newSpanID() (res SpanID) {
+	rand.Read(res[:])
+	return
+}
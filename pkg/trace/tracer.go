@@ -0,0 +1,190 @@
+package trace
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultQueueSize     = 4096
+	defaultFlushInterval = time.Second * 5
+)
+
+// Tracer starts spans and sends them to the exporter in batches once they've
+// ended. The methods of a nil Tracer start no spans, so that tracing can be
+// turned off by not creating one.
+type Tracer struct {
+	exporter Exporter
+	ratio    float64
+	logger   log.Logger
+	spans    chan SpanData
+	flush    chan chan struct{}
+	stop     chan chan struct{}
+
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// Option defines a option for configuring the Tracer
+type Option func(*Tracer)
+
+// WithRatio samples that ratio of the traces that start with in the node,
+// traces that are started by another node keep its decision. The default
+// samples every trace.
+func WithRatio(ratio float64) Option {
+	return func(t *Tracer) {
+		t.ratio = ratio
+	}
+}
+
+// NewTracer creates a Tracer that exports the spans with the exporter.
+func NewTracer(exporter Exporter, logger log.Logger, opts ...Option) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		ratio:    1,
+		logger:   logger,
+		spans:    make(chan SpanData, defaultQueueSize),
+		flush:    make(chan chan struct{}),
+		stop:     make(chan chan struct{}),
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	go t.run()
+
+	return t
+}
+
+// Start a span with in the context, which is a child of any span that the
+// context carries. The returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, kind Kind, name string, attrs ...Attribute) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if t == nil {
+		return ctx, nil
+	}
+
+	data := SpanData{
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: attrs,
+	}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		data.Context = SpanContext{
+			TraceID: parent.TraceID,
+			SpanID:  newSpanID(),
+			Sampled: parent.Sampled,
+		}
+		data.Parent = parent.SpanID
+	} else {
+		data.Context = SpanContext{
+			TraceID: newTraceID(),
+			SpanID:  newSpanID(),
+			Sampled: t.sample(),
+		}
+	}
+
+	span := &Span{
+		tracer: t,
+		data:   data,
+	}
+	return NewContext(ctx, span), span
+}
+
+// Flush exports the spans that have ended, waiting until they're exported.
+func (t *Tracer) Flush() {
+	if t == nil {
+		return
+	}
+	c := make(chan struct{})
+	t.flush <- c
+	<-c
+}
+
+// Close exports the spans that have ended and stops the Tracer, spans that
+// end after it's closed are dropped.
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	c := make(chan struct{})
+	t.stop <- c
+	<-c
+}
+
+func (t *Tracer) sample() bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.random.Float64() < t.ratio
+}
+
+// export queues the span, dropping it if the queue is full, rather than
+// holding up the request it's tracing.
+func (t *Tracer) export(data SpanData) {
+	select {
+	case t.spans <- data:
+	default:
+		level.Debug(t.logger).Log("state", "dropped span", "name", data.Name)
+	}
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(defaultFlushInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	send := func() {
+		// Take anything that's already queued, so that a flush includes
+		// every span that ended before it.
+	drain:
+		for {
+			select {
+			case data := <-t.spans:
+				batch = append(batch, data)
+			default:
+				break drain
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.Export(batch); err != nil {
+			level.Warn(t.logger).Log("state", "export", "spans", len(batch), "err", err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case data := <-t.spans:
+			if batch = append(batch, data); len(batch) >= defaultBatchSize {
+				send()
+			}
+
+		case <-ticker.C:
+			send()
+
+		case c := <-t.flush:
+			send()
+			close(c)
+
+		case c := <-t.stop:
+			send()
+			close(c)
+			return
+		}
+	}
+}
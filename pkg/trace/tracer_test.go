@@ -0,0 +1,101 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("children join the trace", func(t *testing.T) {
+		exporter := NewInMemoryExporter()
+		tracer := NewTracer(exporter, log.NewNopLogger())
+		defer tracer.Close()
+
+		ctx, parent := tracer.Start(context.Background(), KindServer, "parent")
+		_, child := tracer.Start(ctx, KindClient, "child", String("node.host", "a"))
+		child.SetError(errors.New("bad"))
+		child.End()
+		parent.End()
+		tracer.Flush()
+
+		spans := exporter.Spans()
+		if expected, actual := 2, len(spans); expected != actual {
+			t.Fatalf("expected: %d, actual: %d", expected, actual)
+		}
+		c, p := spans[0], spans[1]
+		if expected, actual := p.Context.TraceID, c.Context.TraceID; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := p.Context.SpanID, c.Parent; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if p.Parent.IsValid() {
+			t.Errorf("expected no parent, actual: %s", p.Parent)
+		}
+		if expected, actual := "a", c.Attribute("node.host"); expected != actual {
+			t.Errorf("expected: %v, actual: %v", expected, actual)
+		}
+		if expected, actual := "bad", c.Err; expected != actual {
+			t.Errorf("expected: %q, actual: %q", expected, actual)
+		}
+	})
+
+	t.Run("remote parent", func(t *testing.T) {
+		exporter := NewInMemoryExporter()
+		tracer := NewTracer(exporter, log.NewNopLogger())
+		defer tracer.Close()
+
+		remote := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+		_, span := tracer.Start(NewRemoteContext(context.Background(), remote), KindServer, "server")
+		span.End()
+		tracer.Flush()
+
+		spans := exporter.Named("server")
+		if expected, actual := 1, len(spans); expected != actual {
+			t.Fatalf("expected: %d, actual: %d", expected, actual)
+		}
+		if expected, actual := remote.TraceID, spans[0].Context.TraceID; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+		if expected, actual := remote.SpanID, spans[0].Parent; expected != actual {
+			t.Errorf("expected: %s, actual: %s", expected, actual)
+		}
+	})
+
+	t.Run("unsampled", func(t *testing.T) {
+		exporter := NewInMemoryExporter()
+		tracer := NewTracer(exporter, log.NewNopLogger(), WithRatio(0))
+		defer tracer.Close()
+
+		ctx, parent := tracer.Start(context.Background(), KindServer, "parent")
+		_, child := tracer.Start(ctx, KindClient, "child")
+		child.End()
+		parent.End()
+		tracer.Flush()
+
+		if child.Context().Sampled {
+			t.Errorf("expected the child to keep the decision of the parent")
+		}
+		if expected, actual := 0, len(exporter.Spans()); expected != actual {
+			t.Errorf("expected: %d, actual: %d", expected, actual)
+		}
+	})
+
+	t.Run("nil tracer", func(t *testing.T) {
+		var tracer *Tracer
+
+		ctx, span := tracer.Start(context.Background(), KindInternal, "nothing")
+		span.SetAttributes(Int("n", 1))
+		span.SetError(errors.New("bad"))
+		span.End()
+
+		if FromContext(ctx) != nil {
+			t.Errorf("expected no span")
+		}
+	})
+}